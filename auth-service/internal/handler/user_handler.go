@@ -50,6 +50,7 @@ func Register(repo *repository.UserRepository) gin.HandlerFunc {
             Name:     req.Name,
             Username: req.Username,
             Password: string(hashed),
+            Role:     model.RoleCustomer,
         }
         if err := repo.CreateUser(&user); err != nil {
             c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to insert user"})
@@ -129,3 +130,39 @@ func Profile() gin.HandlerFunc {
         c.JSON(http.StatusOK, claims)
     }
 }
+
+type SetRoleRequest struct {
+    Role string `json:"role" binding:"required"`
+}
+
+// SetUserRole handles PATCH /users/:id/role (ops only), e.g. to onboard couriers.
+// The new role is carried in the user's tokens from the next login.
+func SetUserRole(repo *repository.UserRepository) gin.HandlerFunc {
+    return func(c *gin.Context) {
+        claims, ok := c.MustGet("claims").(jwt.MapClaims)
+        if !ok || claims["role"] != model.RoleOps {
+            c.JSON(http.StatusForbidden, gin.H{"error": "only ops can change roles"})
+            return
+        }
+        var req SetRoleRequest
+        if err := c.ShouldBindJSON(&req); err != nil {
+            c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+            return
+        }
+        if req.Role != model.RoleCustomer && req.Role != model.RoleCourier && req.Role != model.RoleOps {
+            c.JSON(http.StatusBadRequest, gin.H{"error": "role must be customer, courier or ops"})
+            return
+        }
+        user, err := repo.FindByID(c.Param("id"))
+        if err != nil {
+            c.JSON(http.StatusNotFound, gin.H{"error": "user not found"})
+            return
+        }
+        if err := repo.UpdateRole(user.ID, req.Role); err != nil {
+            c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update role"})
+            return
+        }
+        user.Role = req.Role
+        c.JSON(http.StatusOK, user)
+    }
+}
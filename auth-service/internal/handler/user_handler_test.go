@@ -0,0 +1,96 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"auth-service/internal/model"
+	"auth-service/internal/repository"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// testUserRepo returns a repository on a throwaway database of the MongoDB at MONGO_URI,
+// dropped when the test ends. Tests using it are skipped without MONGO_URI.
+func testUserRepo(t *testing.T) *repository.UserRepository {
+	t.Helper()
+	uri := os.Getenv("MONGO_URI")
+	if uri == "" {
+		t.Skip("MONGO_URI not set")
+	}
+	client, err := mongo.Connect(context.Background(), options.Client().ApplyURI(uri))
+	if err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	db := client.Database("auth_test_" + uuid.NewString()[:8])
+	t.Cleanup(func() {
+		db.Drop(context.Background())
+		client.Disconnect(context.Background())
+	})
+	return repository.NewUserRepository(db)
+}
+
+// setRole calls SetUserRole as a caller with the given claims
+func setRole(repo *repository.UserRepository, claims jwt.MapClaims, userID, body string) *httptest.ResponseRecorder {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.PATCH("/users/:id/role", func(c *gin.Context) { c.Set("claims", claims) }, SetUserRole(repo))
+	req := httptest.NewRequest(http.MethodPatch, "/users/"+userID+"/role", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestSetUserRoleRejected(t *testing.T) {
+	ops := jwt.MapClaims{"user_id": "ops-1", "role": model.RoleOps}
+	tests := []struct {
+		name   string
+		claims jwt.MapClaims
+		body   string
+		want   int
+	}{
+		{"customer", jwt.MapClaims{"user_id": "u1", "role": model.RoleCustomer}, `{"role":"ops"}`, http.StatusForbidden},
+		{"courier", jwt.MapClaims{"user_id": "u1", "role": model.RoleCourier}, `{"role":"courier"}`, http.StatusForbidden},
+		{"missing role claim", jwt.MapClaims{"user_id": "u1"}, `{"role":"ops"}`, http.StatusForbidden},
+		{"invalid role", ops, `{"role":"admin"}`, http.StatusBadRequest},
+		{"missing role", ops, `{}`, http.StatusBadRequest},
+		{"invalid body", ops, `role=ops`, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Rejected before the repository is used
+			if w := setRole(nil, tt.claims, "u2", tt.body); w.Code != tt.want {
+				t.Errorf("status %d, want %d: %s", w.Code, tt.want, w.Body)
+			}
+		})
+	}
+}
+
+func TestSetUserRole(t *testing.T) {
+	repo := testUserRepo(t)
+	user := &model.User{ID: uuid.NewString(), Username: "kurir1", Role: model.RoleCustomer}
+	if err := repo.CreateUser(user); err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	ops := jwt.MapClaims{"user_id": "ops-1", "role": model.RoleOps}
+
+	if w := setRole(repo, ops, "unknown", `{"role":"courier"}`); w.Code != http.StatusNotFound {
+		t.Errorf("unknown user: status %d, want 404", w.Code)
+	}
+	if w := setRole(repo, ops, user.ID, `{"role":"courier"}`); w.Code != http.StatusOK {
+		t.Fatalf("status %d, want 200: %s", w.Code, w.Body)
+	}
+	stored, err := repo.FindByID(user.ID)
+	if err != nil || stored.Role != model.RoleCourier {
+		t.Errorf("stored role %q (%v), want courier", stored.Role, err)
+	}
+}
@@ -1,9 +1,17 @@
 package model
 
+// Roles carried in the JWT "role" claim. Users without a role are customers.
+const (
+	RoleCustomer = "customer"
+	RoleCourier  = "courier"
+	RoleOps      = "ops"
+)
+
 type User struct {
 	ID       string 			`bson:"_id,omitempty" json:"id"`
 	Msisdn   string             `bson:"msisdn" json:"msisdn"`
 	Name     string             `bson:"name" json:"name"`
 	Username string             `bson:"username" json:"username"`
 	Password string             `bson:"password" json:"-"`
+	Role     string             `bson:"role" json:"role"`
 }
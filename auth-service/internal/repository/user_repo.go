@@ -35,3 +35,16 @@ func (r *UserRepository) FindByMsisdn(msisdn string) (*model.User, error) {
 	err := r.col.FindOne(ctx, bson.M{"msisdn": msisdn}).Decode(&user)
 	return &user, err
 }
+func (r *UserRepository) FindByID(id string) (*model.User, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	var user model.User
+	err := r.col.FindOne(ctx, bson.M{"_id": id}).Decode(&user)
+	return &user, err
+}
+func (r *UserRepository) UpdateRole(id, role string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	_, err := r.col.UpdateOne(ctx, bson.M{"_id": id}, bson.M{"$set": bson.M{"role": role}})
+	return err
+}
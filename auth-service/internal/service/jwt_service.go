@@ -9,10 +9,15 @@ import (
 )
 
 func GenerateJWT(user *model.User) (string, error) {
+	role := user.Role
+	if role == "" {
+		role = model.RoleCustomer // akun lama sebelum ada field role
+	}
 	claims := jwt.MapClaims{
 		"user_id":  user.ID,  // langsung pakai string ID
 		"msisdn":   user.Msisdn,
 		"username": user.Username,
+		"role":     role,
 		"exp":      time.Now().Add(24 * time.Hour).Unix(),
 	}
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
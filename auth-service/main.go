@@ -40,6 +40,7 @@ func main() {
     r.POST("/register", handler.Register(repo))
     r.POST("/login", handler.Login(repo))
     r.GET("/profile", middleware.JWTAuthMiddleware(), handler.Profile())
+    r.PATCH("/users/:id/role", middleware.JWTAuthMiddleware(), handler.SetUserRole(repo))
 
     r.Run(":8081")
 }
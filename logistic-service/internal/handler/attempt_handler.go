@@ -0,0 +1,244 @@
+package handler
+
+import (
+	"fmt"
+	"log"
+	"logistic-service/internal/model"
+	"logistic-service/internal/repository"
+	"logistic-service/internal/service"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+// GetAttemptFailureReasons handles GET /delivery-attempt-reasons and returns the reason code catalog
+func GetAttemptFailureReasons() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.JSON(http.StatusOK, service.AttemptFailureReasons)
+	}
+}
+
+// RecordDeliveryAttempt handles POST /shipments/:trackingNumber/attempts (couriers and ops).
+// Records a failed doorstep attempt with its reason. When the number of failed attempts reaches
+// MAX_DELIVERY_ATTEMPTS the shipment is returned to sender automatically.
+func RecordDeliveryAttempt(repo *repository.ShipmentRepository, calendar *service.DeliveryCalendar, ch *amqp.Channel, hooks *service.WebhookDispatcher) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		trackingNumber := c.Param("trackingNumber")
+		var req struct {
+			ReasonCode string   `json:"reason_code" binding:"required"`
+			Note       string   `json:"note"`
+			Latitude   *float64 `json:"latitude"`
+			Longitude  *float64 `json:"longitude"`
+		}
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		reason, err := service.ValidateAttemptReason(req.ReasonCode, req.Note)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if (req.Latitude != nil && (*req.Latitude < -90 || *req.Latitude > 90)) ||
+			(req.Longitude != nil && (*req.Longitude < -180 || *req.Longitude > 180)) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "latitude or longitude out of range"})
+			return
+		}
+
+		principal, ok := currentPrincipal(c)
+		if !ok {
+			return
+		}
+		shipment, err := repo.FindByTrackingNumber(trackingNumber)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch shipment"})
+			return
+		}
+		if !principal.CanAccessShipment(shipment) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "shipment not found"})
+			return
+		}
+		if !principal.IsStaff() {
+			c.JSON(http.StatusForbidden, gin.H{"error": "only couriers and ops can record delivery attempts"})
+			return
+		}
+		if !hasStatus(shipment, model.DeliveryAttemptStatuses) {
+			c.JSON(http.StatusConflict, gin.H{"error": "delivery attempts can't be recorded in status " + shipment.Status})
+			return
+		}
+
+		attempt := model.DoorstepAttempt{
+			Number:      len(shipment.DeliveryAttempts) + 1,
+			ReasonCode:  reason.Code,
+			Note:        req.Note,
+			CourierID:   principal.UserID,
+			Latitude:    req.Latitude,
+			Longitude:   req.Longitude,
+			AttemptedAt: time.Now(),
+		}
+		event := model.TrackingEvent{
+			Status:      shipment.Status,
+			Description: fmt.Sprintf("delivery attempt %d failed: %s", attempt.Number, reason.Description),
+			Actor:       principal.UserID,
+			Timestamp:   attempt.AttemptedAt,
+		}
+		err = repo.AddDeliveryAttempt(trackingNumber, shipment.Version, model.DeliveryAttemptStatuses, attempt, event)
+		switch err {
+		case nil:
+		case repository.ErrVersionConflict, repository.ErrStatusConflict:
+			c.JSON(http.StatusConflict, gin.H{"error": "shipment was modified by someone else, retry"})
+			return
+		default:
+			log.Printf("[RecordDeliveryAttempt] AddDeliveryAttempt error: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to record delivery attempt"})
+			return
+		}
+
+		result, err := repo.FindByTrackingNumber(trackingNumber)
+		if err != nil || result == nil {
+			log.Printf("[RecordDeliveryAttempt] Warning: failed to find shipment after update: %v", err)
+			c.JSON(http.StatusCreated, gin.H{"attempt": attempt})
+			return
+		}
+		publishShipmentUpdated(ch, result)
+		hooks.Enqueue(result.UserID, model.EventShipmentUpdated, result)
+		hooks.Enqueue(result.UserID, model.EventShipmentDeliveryFailed, result)
+
+		remaining := service.MaxDeliveryAttempts() - attempt.Number
+		resp := gin.H{"attempt": attempt}
+		if remaining <= 0 {
+			remaining = 0
+			if rto := autoReturnToSender(repo, calendar, ch, hooks, result, reason, principal); rto != nil {
+				resp["return_shipment"] = rto
+			}
+		}
+		resp["attempts_remaining"] = remaining
+		c.JSON(http.StatusCreated, resp)
+	}
+}
+
+// autoReturnToSender starts the RTO of a shipment that used up its delivery attempts.
+// Failures are only logged; ops can still start the RTO manually.
+func autoReturnToSender(repo *repository.ShipmentRepository, calendar *service.DeliveryCalendar, ch *amqp.Channel, hooks *service.WebhookDispatcher, shipment *model.Shipment, reason *service.AttemptFailureReason, principal *service.Principal) *model.Shipment {
+	returnShipment, err := service.NewReturnShipment(shipment, model.ShipmentReturn{
+		Type:        model.ReturnRTO,
+		ReasonCode:  reason.RTOReason,
+		Note:        fmt.Sprintf("automatic after %d failed delivery attempts", len(shipment.DeliveryAttempts)),
+		RequestedBy: principal.UserID,
+		Role:        principal.Role,
+		RequestedAt: time.Now(),
+	}, nil, "")
+	if err == nil {
+		err = startReturn(repo, calendar, ch, hooks, shipment, returnShipment, model.RTOStatuses)
+	}
+	if err != nil {
+		log.Printf("[autoReturnToSender] RTO of %s failed: %v", shipment.TrackingNumber, err)
+		return nil
+	}
+	log.Printf("[autoReturnToSender] %s returned to sender as %s", shipment.TrackingNumber, returnShipment.TrackingNumber)
+	return returnShipment
+}
+
+// UpdateDeliveryPreferences handles POST /public/track/:trackingNumber/delivery-preferences.
+// No JWT required: the recipient proves who they are with the last 4 digits of their phone and
+// can reschedule the next attempt (date and time_slot) and/or change the delivery instructions.
+// Phone checks are locked per shipment like on the public tracking endpoint. Every change is
+// recorded in the timeline and published to delivery.preferences_changed to notify the recipient.
+func UpdateDeliveryPreferences(repo *repository.ShipmentRepository, checks *repository.PhoneCheckRepository, ch *amqp.Channel, hooks *service.WebhookDispatcher) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		trackingNumber := c.Param("trackingNumber")
+		var req struct {
+			PhoneLast4   string  `json:"phone_last4" binding:"required"`
+			Date         string  `json:"date"`
+			TimeSlot     string  `json:"time_slot"`
+			Instructions *string `json:"instructions"`
+		}
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if req.Date == "" && req.TimeSlot == "" && req.Instructions == nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "date and time_slot or instructions are required"})
+			return
+		}
+
+		var schedule *model.DeliverySchedule
+		if req.Date != "" || req.TimeSlot != "" {
+			var err error
+			schedule, err = service.ValidateSchedule(req.Date, req.TimeSlot, time.Now())
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+		}
+		if req.Instructions != nil {
+			trimmed := strings.TrimSpace(*req.Instructions)
+			if len(trimmed) > service.MaxDeliveryInstructionsLen {
+				c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("instructions must be at most %d characters", service.MaxDeliveryInstructionsLen)})
+				return
+			}
+			req.Instructions = &trimmed
+		}
+
+		shipment, err := repo.FindByTrackingNumber(trackingNumber)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch shipment"})
+			return
+		}
+		if shipment == nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "shipment not found"})
+			return
+		}
+		if !verifyRecipientPhone(c, checks, shipment, req.PhoneLast4) {
+			return
+		}
+
+		now := time.Now()
+		var changes []string
+		if schedule != nil {
+			changes = append(changes, fmt.Sprintf("rescheduled delivery to %s (%s)", schedule.Date, schedule.TimeSlot))
+		}
+		if req.Instructions != nil {
+			changes = append(changes, "changed the delivery instructions")
+		}
+		event := &model.TrackingEvent{
+			Status:      shipment.Status,
+			Description: "recipient " + strings.Join(changes, " and "),
+			Timestamp:   now,
+		}
+		err = repo.SetDeliveryPreferences(trackingNumber, model.ReschedulableStatuses, schedule, req.Instructions, event)
+		if err == repository.ErrStatusConflict {
+			c.JSON(http.StatusConflict, gin.H{"error": "delivery can no longer be changed in status " + shipment.Status})
+			return
+		}
+		if err != nil {
+			log.Printf("[UpdateDeliveryPreferences] SetDeliveryPreferences error: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update delivery preferences"})
+			return
+		}
+
+		result, err := repo.FindByTrackingNumber(trackingNumber)
+		if err != nil || result == nil {
+			log.Printf("[UpdateDeliveryPreferences] Warning: failed to find shipment after update: %v", err)
+			c.JSON(http.StatusOK, gin.H{"message": "delivery preferences updated"})
+			return
+		}
+		log.Printf("[UpdateDeliveryPreferences] %s: %s, from %s", trackingNumber, event.Description, c.ClientIP())
+		publishEvent(ch, "delivery.preferences_changed", model.DeliveryPreferencesChangedEvent{
+			TrackingNumber:      trackingNumber,
+			UserID:              result.UserID,
+			RecipientPhone:      result.Recipient.Phone,
+			NextAttempt:         schedule,
+			InstructionsChanged: req.Instructions != nil,
+			ClientIP:            c.ClientIP(),
+			ChangedAt:           now,
+		})
+		publishShipmentUpdated(ch, result)
+		hooks.Enqueue(result.UserID, model.EventShipmentUpdated, result)
+
+		c.JSON(http.StatusOK, service.NewPublicTracking(result, true))
+	}
+}
@@ -0,0 +1,251 @@
+package handler
+
+import (
+	"crypto/sha256"
+	"encoding/csv"
+	"encoding/hex"
+	"errors"
+	"io"
+	"log"
+	"logistic-service/internal/model"
+	"logistic-service/internal/repository"
+	"logistic-service/internal/service"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	amqp "github.com/rabbitmq/amqp091-go"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// Progress is written to the job every bulkProgressBatch shipments
+const bulkProgressBatch = 25
+
+// CreateBulkShipments handles POST /shipments/bulk.
+// Accepts a multipart "file" (.csv or .xlsx) or a JSON array body, and creates the shipments
+// asynchronously. Returns 202 with the new job, or 200 with the existing job when the same
+// file was already uploaded by this user.
+func CreateBulkShipments(jobs *repository.BulkJobRepository, repo *repository.ShipmentRepository, regions *service.RegionIndex, calendar *service.DeliveryCalendar, insurance *model.InsurancePolicy, ch *amqp.Channel, hooks *service.WebhookDispatcher) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		principal, ok := currentPrincipal(c)
+		if !ok {
+			return
+		}
+
+		format, fileName, data, err := readBulkUpload(c)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		sum := sha256.Sum256(data)
+		fileHash := hex.EncodeToString(sum[:])
+
+		existing, err := jobs.FindByHash(principal.UserID, fileHash)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to check existing job"})
+			return
+		}
+		if existing != nil && existing.Status != model.BulkJobFailed {
+			c.JSON(http.StatusOK, existing)
+			return
+		}
+
+		rows, err := service.ParseBulkFile(format, data, regions)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		job := &model.BulkJob{
+			ID:        uuid.New().String(),
+			UserID:    principal.UserID,
+			FileHash:  fileHash,
+			FileName:  fileName,
+			Format:    format,
+			Status:    model.BulkJobQueued,
+			Total:     len(rows),
+			Errors:    []model.BulkRowError{},
+			CreatedAt: time.Now(),
+		}
+		if existing != nil {
+			// Restart a failed job under the same ID; shipments it already created are skipped
+			job.ID = existing.ID
+			err = jobs.Replace(job)
+		} else {
+			err = jobs.Insert(job)
+		}
+		if mongo.IsDuplicateKeyError(err) {
+			// Same file uploaded concurrently: return the job that won
+			if existing, _ := jobs.FindByHash(principal.UserID, fileHash); existing != nil {
+				c.JSON(http.StatusOK, existing)
+				return
+			}
+		}
+		if err != nil {
+			log.Printf("[CreateBulkShipments] save job error: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create bulk job"})
+			return
+		}
+
+		go runBulkJob(jobs, repo, calendar, insurance, ch, hooks, job, rows)
+
+		c.JSON(http.StatusAccepted, job)
+	}
+}
+
+// GetBulkJob handles GET /shipments/bulk/:jobId and returns the job progress
+func GetBulkJob(jobs *repository.BulkJobRepository) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		job, ok := ownedBulkJob(c, jobs)
+		if !ok {
+			return
+		}
+		c.JSON(http.StatusOK, job)
+	}
+}
+
+// GetBulkJobErrors handles GET /shipments/bulk/:jobId/errors and returns the row errors as CSV
+func GetBulkJobErrors(jobs *repository.BulkJobRepository) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		job, ok := ownedBulkJob(c, jobs)
+		if !ok {
+			return
+		}
+
+		c.Header("Content-Type", "text/csv")
+		c.Header("Content-Disposition", `attachment; filename="bulk-`+job.ID+`-errors.csv"`)
+		w := csv.NewWriter(c.Writer)
+		w.Write([]string{"row", "tracking_number", "error"})
+		for _, e := range job.Errors {
+			w.Write([]string{strconv.Itoa(e.Row), e.TrackingNumber, e.Error})
+		}
+		w.Flush()
+	}
+}
+
+// GetBulkTemplate handles GET /shipments/bulk/template and returns an empty CSV with the expected header
+func GetBulkTemplate() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Header("Content-Type", "text/csv")
+		c.Header("Content-Disposition", `attachment; filename="bulk-shipments-template.csv"`)
+		w := csv.NewWriter(c.Writer)
+		w.Write(service.BulkColumns)
+		w.Flush()
+	}
+}
+
+// runBulkJob creates the shipments of a job one by one and records progress and row errors.
+// A tracking number the same user already created counts as success, so a restarted job
+// resumes where it stopped. Insured rows get their premium from the insurance policy.
+func runBulkJob(jobs *repository.BulkJobRepository, repo *repository.ShipmentRepository, calendar *service.DeliveryCalendar, insurance *model.InsurancePolicy, ch *amqp.Channel, hooks *service.WebhookDispatcher, job *model.BulkJob, rows []service.BulkRow) {
+	if err := jobs.MarkStarted(job.ID); err != nil {
+		log.Printf("[runBulkJob] MarkStarted error: %v", err)
+	}
+
+	succeeded, failed := 0, 0
+	var rowErrors []model.BulkRowError
+	flush := func() {
+		if succeeded+failed == 0 {
+			return
+		}
+		if err := jobs.RecordProgress(job.ID, succeeded, failed, rowErrors); err != nil {
+			log.Printf("[runBulkJob] RecordProgress error: %v", err)
+		}
+		succeeded, failed, rowErrors = 0, 0, nil
+	}
+
+	for i := range rows {
+		row := &rows[i]
+		errMsg := row.Err
+		if errMsg == "" {
+			err := service.ApplyInsurance(insurance, &row.Shipment)
+			if err == nil {
+				err = createShipment(repo, calendar, ch, hooks, &row.Shipment, job.UserID)
+			}
+			if err == errTrackingNumberExists {
+				if existing, _ := repo.FindByTrackingNumber(row.Shipment.TrackingNumber); existing != nil && existing.UserID == job.UserID {
+					err = nil
+				}
+			}
+			if err != nil {
+				errMsg = err.Error()
+			}
+		}
+
+		if errMsg == "" {
+			succeeded++
+		} else {
+			failed++
+			rowErrors = append(rowErrors, model.BulkRowError{
+				Row:            row.Row,
+				TrackingNumber: row.Shipment.TrackingNumber,
+				Error:          errMsg,
+			})
+		}
+		if (i+1)%bulkProgressBatch == 0 {
+			flush()
+		}
+	}
+	flush()
+
+	if err := jobs.MarkFinished(job.ID, model.BulkJobCompleted, ""); err != nil {
+		log.Printf("[runBulkJob] MarkFinished error: %v", err)
+	}
+	log.Printf("[runBulkJob] Finished bulk job %s (%d shipments)", job.ID, len(rows))
+}
+
+// readBulkUpload returns the upload format, file name and content of a bulk request
+func readBulkUpload(c *gin.Context) (format, fileName string, data []byte, err error) {
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, service.MaxBulkFileSize+1<<20)
+
+	if strings.HasPrefix(c.ContentType(), "application/json") {
+		data, err = io.ReadAll(c.Request.Body)
+		return "json", "", data, err
+	}
+
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		return "", "", nil, errors.New("multipart field \"file\" or a JSON array body is required")
+	}
+	if fileHeader.Size > service.MaxBulkFileSize {
+		return "", "", nil, errors.New("file is larger than 10 MB")
+	}
+	switch strings.ToLower(filepath.Ext(fileHeader.Filename)) {
+	case ".csv":
+		format = "csv"
+	case ".xlsx":
+		format = "xlsx"
+	default:
+		return "", "", nil, errors.New("file must be .csv or .xlsx")
+	}
+
+	f, err := fileHeader.Open()
+	if err != nil {
+		return "", "", nil, err
+	}
+	defer f.Close()
+	data, err = io.ReadAll(f)
+	return format, fileHeader.Filename, data, err
+}
+
+// ownedBulkJob loads the :jobId job and checks it belongs to the caller
+func ownedBulkJob(c *gin.Context, jobs *repository.BulkJobRepository) (*model.BulkJob, bool) {
+	principal, ok := currentPrincipal(c)
+	if !ok {
+		return nil, false
+	}
+	job, err := jobs.FindByID(c.Param("jobId"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch bulk job"})
+		return nil, false
+	}
+	if job == nil || job.UserID != principal.UserID {
+		c.JSON(http.StatusNotFound, gin.H{"error": "bulk job not found"})
+		return nil, false
+	}
+	return job, true
+}
@@ -0,0 +1,91 @@
+package handler
+
+import (
+	"log"
+	"logistic-service/internal/model"
+	"logistic-service/internal/repository"
+	"logistic-service/internal/service"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+// GetCancelReasons handles GET /cancellation-reasons and returns the reason code catalog
+func GetCancelReasons() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.JSON(http.StatusOK, service.CancelReasons)
+	}
+}
+
+// CancelShipment handles POST /shipments/:trackingNumber/cancel.
+// Allowed only while the shipment is in a cancellable status (before pickup by default),
+// requires a reason_code from the catalog and publishes shipment.cancelled.
+func CancelShipment(repo *repository.ShipmentRepository, ch *amqp.Channel, hooks *service.WebhookDispatcher) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		trackingNumber := c.Param("trackingNumber")
+		var req struct {
+			ReasonCode string `json:"reason_code" binding:"required"`
+			Note       string `json:"note"`
+		}
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if err := service.ValidateCancelReason(req.ReasonCode, req.Note); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		principal, ok := currentPrincipal(c)
+		if !ok {
+			return
+		}
+
+		shipment, err := repo.FindByTrackingNumber(trackingNumber)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch shipment"})
+			return
+		}
+		if !principal.CanAccessShipment(shipment) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "shipment not found"})
+			return
+		}
+
+		cancellation := model.ShipmentCancellation{
+			ReasonCode:  req.ReasonCode,
+			Note:        req.Note,
+			CancelledBy: principal.UserID,
+			Role:        principal.Role,
+			CancelledAt: time.Now(),
+		}
+		err = repo.Cancel(trackingNumber, service.CancellableStatuses(), cancellation)
+		if err == repository.ErrStatusConflict {
+			c.JSON(http.StatusConflict, gin.H{"error": "shipment can no longer be cancelled in status " + shipment.Status})
+			return
+		}
+		if err != nil {
+			log.Printf("[CancelShipment] Cancel error: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to cancel shipment"})
+			return
+		}
+
+		publishEvent(ch, "shipment.cancelled", model.ShipmentCancelledEvent{
+			TrackingNumber: trackingNumber,
+			UserID:         shipment.UserID,
+			ReasonCode:     cancellation.ReasonCode,
+			Note:           cancellation.Note,
+			CancelledBy:    cancellation.CancelledBy,
+			Role:           cancellation.Role,
+			CancelledAt:    cancellation.CancelledAt,
+		})
+		if result, err := repo.FindByTrackingNumber(trackingNumber); err == nil && result != nil {
+			publishShipmentUpdated(ch, result)
+			hooks.Enqueue(result.UserID, model.EventShipmentUpdated, result)
+			hooks.Enqueue(result.UserID, model.EventShipmentCancelled, result)
+		}
+
+		c.JSON(http.StatusOK, gin.H{"message": "shipment cancelled", "cancellation": cancellation})
+	}
+}
@@ -0,0 +1,471 @@
+package handler
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"log"
+	"logistic-service/internal/model"
+	"logistic-service/internal/repository"
+	"logistic-service/internal/service"
+	"mime/multipart"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	amqp "github.com/rabbitmq/amqp091-go"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// Limits of claim evidence: size of each file, files per upload and files per claim
+const (
+	maxClaimEvidenceSize   = 5 << 20 // 5 MB
+	maxClaimEvidenceUpload = 5
+	maxClaimEvidence       = 10
+)
+
+// Accepted evidence types and the file extension used for their blob keys
+var claimEvidenceTypes = map[string]string{
+	"image/jpeg":      ".jpg",
+	"image/png":       ".png",
+	"application/pdf": ".pdf",
+}
+
+// GetInsurancePolicy handles GET /insurance/policy and returns the premium rules and claim limits
+func GetInsurancePolicy(insurance *model.InsurancePolicy) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.JSON(http.StatusOK, insurance)
+	}
+}
+
+// QuoteInsurance handles GET /insurance/quote?insured_value=&logistic_name=&service_level=
+// and returns the premium a shipment would pay, so it can be shown before booking.
+func QuoteInsurance(insurance *model.InsurancePolicy) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		value, err := strconv.ParseInt(c.Query("insured_value"), 10, 64)
+		if err != nil || value < 1 || value > insurance.MaxInsuredValue {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("insured_value must be between 1 and %d", insurance.MaxInsuredValue)})
+			return
+		}
+		serviceLevel := c.DefaultQuery("service_level", model.ServiceRegular)
+		if service.FindServiceLevel(serviceLevel) == nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": service.ErrUnknownServiceLevel.Error()})
+			return
+		}
+		quote, err := service.QuoteInsurance(insurance, c.Query("logistic_name"), serviceLevel, value)
+		if err != nil {
+			c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, quote)
+	}
+}
+
+// FileClaim handles POST /shipments/:trackingNumber/claims (multipart).
+// The shipment owner or ops file a claim with type, description, claimed_amount and one to five
+// "evidence" files (JPEG, PNG or PDF). Damage is claimed within the claim window after delivery,
+// loss once the shipment missed its promised date. A shipment has at most one claim.
+func FileClaim(claims *repository.ClaimRepository, repo *repository.ShipmentRepository, insurance *model.InsurancePolicy, blobs service.BlobStorage, ch *amqp.Channel, hooks *service.WebhookDispatcher) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		principal, ok := currentPrincipal(c)
+		if !ok {
+			return
+		}
+		shipment, err := repo.FindByTrackingNumber(c.Param("trackingNumber"))
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch shipment"})
+			return
+		}
+		if !principal.CanAccessShipment(shipment) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "shipment not found"})
+			return
+		}
+		if principal.Role == service.RoleCourier {
+			c.JSON(http.StatusForbidden, gin.H{"error": "only the shipment owner and ops can file claims"})
+			return
+		}
+
+		c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxClaimEvidenceUpload*maxClaimEvidenceSize+1<<20)
+		claimType := c.PostForm("type")
+		if err := service.ValidateClaimType(claimType); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		description := strings.TrimSpace(c.PostForm("description"))
+		if description == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "description is required"})
+			return
+		}
+		amount, err := strconv.ParseInt(c.PostForm("claimed_amount"), 10, 64)
+		if err != nil || amount <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "claimed_amount must be greater than 0"})
+			return
+		}
+		now := time.Now()
+		if err := service.CheckClaimable(insurance, shipment, claimType, now); err != nil {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+
+		claim := &model.Claim{
+			ID:             uuid.New().String(),
+			TrackingNumber: shipment.TrackingNumber,
+			MerchantID:     shipment.UserID,
+			LogisticName:   shipment.LogisticName,
+			Type:           claimType,
+			Description:    description,
+			Currency:       insurance.Currency,
+			ClaimedAmount:  amount,
+			Insured:        shipment.Insurance != nil,
+			PayoutLimit:    service.ClaimPayoutLimit(insurance, shipment),
+			Status:         model.ClaimSubmitted,
+			Events: []model.ClaimEvent{{
+				Status:    model.ClaimSubmitted,
+				Actor:     principal.UserID,
+				Timestamp: now,
+			}},
+			FiledBy:   principal.UserID,
+			CreatedAt: now,
+			UpdatedAt: now,
+		}
+		claim.Evidence, err = storeClaimEvidence(c, blobs, claim.ID, principal.UserID, maxClaimEvidence)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		err = claims.Insert(claim)
+		if mongo.IsDuplicateKeyError(err) {
+			c.JSON(http.StatusConflict, gin.H{"error": "a claim was already filed for this shipment"})
+			return
+		}
+		if err != nil {
+			log.Printf("[FileClaim] Insert error: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to file claim"})
+			return
+		}
+		announceClaim(ch, hooks, claim)
+
+		c.JSON(http.StatusCreated, claim)
+	}
+}
+
+// ListClaims handles GET /claims?status=&type=&tracking_number=&limit=
+// Customers see the claims on their shipments; ops see every claim and can also filter by
+// merchant_id and logistic_name.
+func ListClaims(claims *repository.ClaimRepository) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		principal, ok := currentPrincipal(c)
+		if !ok {
+			return
+		}
+		if principal.Role == service.RoleCourier {
+			c.JSON(http.StatusForbidden, gin.H{"error": "claims are only available to merchants and ops"})
+			return
+		}
+		limit, err := strconv.ParseInt(c.DefaultQuery("limit", "50"), 10, 64)
+		if err != nil || limit < 1 || limit > 200 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be between 1 and 200"})
+			return
+		}
+
+		filter := repository.ClaimFilter{
+			TrackingNumber: c.Query("tracking_number"),
+			Status:         c.Query("status"),
+			Type:           c.Query("type"),
+		}
+		if principal.Role == service.RoleOps {
+			filter.MerchantID = c.Query("merchant_id")
+			filter.LogisticName = c.Query("logistic_name")
+		} else {
+			filter.MerchantID = principal.UserID
+		}
+		results, err := claims.List(filter, limit)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch claims"})
+			return
+		}
+		c.JSON(http.StatusOK, results)
+	}
+}
+
+// GetClaim handles GET /claims/:id
+func GetClaim(claims *repository.ClaimRepository) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		claim, _, ok := ownedClaim(c, claims)
+		if !ok {
+			return
+		}
+		c.JSON(http.StatusOK, claim)
+	}
+}
+
+// AddClaimEvidence handles POST /claims/:id/evidence (multipart "evidence" files).
+// Evidence can be added until the claim is decided, up to 10 files per claim.
+func AddClaimEvidence(claims *repository.ClaimRepository, blobs service.BlobStorage, ch *amqp.Channel, hooks *service.WebhookDispatcher) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		claim, principal, ok := ownedClaim(c, claims)
+		if !ok {
+			return
+		}
+		if !hasClaimStatus(claim, model.ClaimEvidenceStatuses) {
+			c.JSON(http.StatusConflict, gin.H{"error": "evidence can't be added to a claim that is " + claim.Status})
+			return
+		}
+
+		c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxClaimEvidenceUpload*maxClaimEvidenceSize+1<<20)
+		evidence, err := storeClaimEvidence(c, blobs, claim.ID, principal.UserID, maxClaimEvidence-len(claim.Evidence))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		err = claims.AddEvidence(claim.ID, model.ClaimEvidenceStatuses, evidence)
+		if err == repository.ErrStatusConflict {
+			c.JSON(http.StatusConflict, gin.H{"error": "claim was decided in the meantime, evidence not added"})
+			return
+		}
+		if err != nil {
+			log.Printf("[AddClaimEvidence] AddEvidence error: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to add evidence"})
+			return
+		}
+
+		result, err := claims.FindByID(claim.ID)
+		if err != nil || result == nil {
+			log.Printf("[AddClaimEvidence] Warning: failed to find claim after update: %v", err)
+			c.JSON(http.StatusCreated, gin.H{"evidence": evidence})
+			return
+		}
+		announceClaim(ch, hooks, result)
+		c.JSON(http.StatusCreated, result)
+	}
+}
+
+// GetClaimEvidence handles GET /claims/:id/evidence/:evidenceId and returns the file
+func GetClaimEvidence(claims *repository.ClaimRepository, blobs service.BlobStorage) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		claim, _, ok := ownedClaim(c, claims)
+		if !ok {
+			return
+		}
+		var evidence *model.ClaimEvidence
+		for i := range claim.Evidence {
+			if claim.Evidence[i].ID == c.Param("evidenceId") {
+				evidence = &claim.Evidence[i]
+			}
+		}
+		if evidence == nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "evidence not found"})
+			return
+		}
+
+		r, err := blobs.Get(c.Request.Context(), evidence.Key)
+		if errors.Is(err, service.ErrBlobNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "evidence not found"})
+			return
+		}
+		if err != nil {
+			log.Printf("[GetClaimEvidence] Get %s error: %v", evidence.Key, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to read evidence"})
+			return
+		}
+		defer r.Close()
+
+		c.Header("Cache-Control", "private, max-age=3600")
+		c.DataFromReader(http.StatusOK, evidence.Size, evidence.ContentType, r, map[string]string{
+			"Content-Disposition": fmt.Sprintf("inline; filename=%q", evidence.FileName),
+		})
+	}
+}
+
+// UpdateClaimStatus handles PATCH /claims/:id/status (ops only).
+// Ops take a claim in review, approve it with a payout_amount up to the claimed amount and the
+// payout limit, or reject it with a note. Approved claims are marked paid with the
+// payout_reference of the transfer.
+func UpdateClaimStatus(claims *repository.ClaimRepository, ch *amqp.Channel, hooks *service.WebhookDispatcher) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req struct {
+			Status          string `json:"status" binding:"required"`
+			Note            string `json:"note"`
+			PayoutAmount    int64  `json:"payout_amount"`
+			PayoutReference string `json:"payout_reference"`
+		}
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		claim, principal, ok := ownedClaim(c, claims)
+		if !ok {
+			return
+		}
+		if principal.Role != service.RoleOps {
+			c.JSON(http.StatusForbidden, gin.H{"error": "only ops can review claims"})
+			return
+		}
+		if !service.CanTransitionClaim(claim.Status, req.Status) {
+			c.JSON(http.StatusConflict, gin.H{"error": "claim can't move from " + claim.Status + " to " + req.Status})
+			return
+		}
+
+		now := time.Now()
+		note := strings.TrimSpace(req.Note)
+		updated := *claim
+		switch req.Status {
+		case model.ClaimInReview:
+			updated.ReviewerID = principal.UserID
+		case model.ClaimApproved:
+			limit := service.MaxClaimPayout(claim)
+			if req.PayoutAmount < 1 || req.PayoutAmount > limit {
+				c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("payout_amount must be between 1 and %d", limit)})
+				return
+			}
+			updated.PayoutAmount = req.PayoutAmount
+			updated.ReviewerID = principal.UserID
+			updated.Resolution = note
+			updated.ResolvedAt = &now
+		case model.ClaimRejected:
+			if note == "" {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "note is required when a claim is rejected"})
+				return
+			}
+			updated.ReviewerID = principal.UserID
+			updated.Resolution = note
+			updated.ResolvedAt = &now
+		case model.ClaimPaid:
+			updated.PayoutReference = strings.TrimSpace(req.PayoutReference)
+			if updated.PayoutReference == "" {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "payout_reference is required"})
+				return
+			}
+			updated.PaidAt = &now
+		}
+
+		err := claims.UpdateStatus(&updated, claim.Status, model.ClaimEvent{
+			Status:    req.Status,
+			Note:      note,
+			Actor:     principal.UserID,
+			Timestamp: now,
+		})
+		if err == repository.ErrStatusConflict {
+			c.JSON(http.StatusConflict, gin.H{"error": "claim was modified by someone else, retry"})
+			return
+		}
+		if err != nil {
+			log.Printf("[UpdateClaimStatus] UpdateStatus error: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update claim"})
+			return
+		}
+
+		result, err := claims.FindByID(claim.ID)
+		if err != nil || result == nil {
+			log.Printf("[UpdateClaimStatus] Warning: failed to find claim after update: %v", err)
+			c.JSON(http.StatusOK, gin.H{"message": "claim updated"})
+			return
+		}
+		announceClaim(ch, hooks, result)
+		c.JSON(http.StatusOK, result)
+	}
+}
+
+// ownedClaim loads the :id claim for the caller. Customers only see claims on their own
+// shipments, couriers none.
+func ownedClaim(c *gin.Context, claims *repository.ClaimRepository) (*model.Claim, *service.Principal, bool) {
+	principal, ok := currentPrincipal(c)
+	if !ok {
+		return nil, nil, false
+	}
+	if principal.Role == service.RoleCourier {
+		c.JSON(http.StatusForbidden, gin.H{"error": "claims are only available to merchants and ops"})
+		return nil, nil, false
+	}
+	claim, err := claims.FindByID(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch claim"})
+		return nil, nil, false
+	}
+	if claim == nil || (principal.Role != service.RoleOps && claim.MerchantID != principal.UserID) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "claim not found"})
+		return nil, nil, false
+	}
+	return claim, principal, true
+}
+
+// announceClaim publishes claim.updated for the worker and sends the webhook to the merchant
+func announceClaim(ch *amqp.Channel, hooks *service.WebhookDispatcher, claim *model.Claim) {
+	publishEvent(ch, "claim.updated", claim)
+	hooks.Enqueue(claim.MerchantID, model.EventClaimUpdated, claim)
+}
+
+// storeClaimEvidence validates and stores the multipart "evidence" files of a claim upload.
+// At least one file is required and at most limit, never more than maxClaimEvidenceUpload.
+func storeClaimEvidence(c *gin.Context, blobs service.BlobStorage, claimID, uploadedBy string, limit int) ([]model.ClaimEvidence, error) {
+	form, err := c.MultipartForm()
+	if err != nil {
+		return nil, errors.New("evidence files are required")
+	}
+	files := form.File["evidence"]
+	if len(files) == 0 {
+		return nil, errors.New("at least one evidence file is required")
+	}
+	if limit > maxClaimEvidenceUpload {
+		limit = maxClaimEvidenceUpload
+	}
+	if len(files) > limit {
+		return nil, fmt.Errorf("at most %d evidence files can be added", limit)
+	}
+
+	evidence := make([]model.ClaimEvidence, 0, len(files))
+	for _, fileHeader := range files {
+		e, err := storeClaimFile(c, blobs, claimID, fileHeader)
+		if err != nil {
+			return nil, err
+		}
+		e.UploadedBy = uploadedBy
+		evidence = append(evidence, e)
+	}
+	return evidence, nil
+}
+
+func storeClaimFile(c *gin.Context, blobs service.BlobStorage, claimID string, fileHeader *multipart.FileHeader) (model.ClaimEvidence, error) {
+	name := filepath.Base(fileHeader.Filename)
+	if fileHeader.Size > maxClaimEvidenceSize {
+		return model.ClaimEvidence{}, fmt.Errorf("%s is larger than 5 MB", name)
+	}
+	data, err := readFormFile(fileHeader)
+	if err != nil {
+		return model.ClaimEvidence{}, fmt.Errorf("failed to read %s", name)
+	}
+	// Trust the content, not the client supplied Content-Type
+	contentType := http.DetectContentType(data)
+	ext, ok := claimEvidenceTypes[contentType]
+	if !ok {
+		return model.ClaimEvidence{}, fmt.Errorf("%s must be a JPEG or PNG image or a PDF document", name)
+	}
+
+	e := model.ClaimEvidence{
+		ID:          uuid.New().String(),
+		FileName:    name,
+		ContentType: contentType,
+		Size:        int64(len(data)),
+		UploadedAt:  time.Now(),
+	}
+	e.Key = "claims/" + claimID + "/" + e.ID + ext
+	if err := blobs.Put(c.Request.Context(), e.Key, bytes.NewReader(data)); err != nil {
+		log.Printf("[storeClaimFile] Put %s error: %v", e.Key, err)
+		return model.ClaimEvidence{}, fmt.Errorf("failed to store %s", name)
+	}
+	return e, nil
+}
+
+func hasClaimStatus(claim *model.Claim, statuses []string) bool {
+	for _, status := range statuses {
+		if claim.Status == status {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,188 @@
+package handler
+
+import (
+	"log"
+	"logistic-service/internal/model"
+	"logistic-service/internal/repository"
+	"logistic-service/internal/service"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+// ListCODLedger handles GET /cod/ledger?status=pending|settled&limit=
+// Merchants see their own entries; ops see every merchant or one with merchant_id.
+func ListCODLedger(codRepo *repository.CODRepository) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		merchantID, ok := codMerchantScope(c)
+		if !ok {
+			return
+		}
+		status := c.Query("status")
+		if status != "" && status != model.RemittancePending && status != model.RemittanceSettled {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "status must be pending or settled"})
+			return
+		}
+		limit, ok := parseCODLimit(c)
+		if !ok {
+			return
+		}
+
+		entries, err := codRepo.ListEntries(merchantID, status, limit)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch COD ledger"})
+			return
+		}
+		c.JSON(http.StatusOK, entries)
+	}
+}
+
+// ListCODRemittances handles GET /cod/remittances?status=pending|settled (default pending).
+// Pending returns the unsettled totals per merchant and currency; settled returns past remittances.
+func ListCODRemittances(codRepo *repository.CODRepository) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		merchantID, ok := codMerchantScope(c)
+		if !ok {
+			return
+		}
+
+		var (
+			remittances []*model.CODRemittance
+			err         error
+		)
+		switch c.DefaultQuery("status", model.RemittancePending) {
+		case model.RemittancePending:
+			remittances, err = codRepo.PendingRemittances(merchantID)
+		case model.RemittanceSettled:
+			limit, ok := parseCODLimit(c)
+			if !ok {
+				return
+			}
+			remittances, err = codRepo.ListRemittances(merchantID, limit)
+		default:
+			c.JSON(http.StatusBadRequest, gin.H{"error": "status must be pending or settled"})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch remittances"})
+			return
+		}
+		c.JSON(http.StatusOK, remittances)
+	}
+}
+
+// GetCODRemittance handles GET /cod/remittances/:id and returns the remittance with its entries
+func GetCODRemittance(codRepo *repository.CODRepository) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		merchantID, ok := codMerchantScope(c)
+		if !ok {
+			return
+		}
+		remittance, err := codRepo.FindRemittance(c.Param("id"))
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch remittance"})
+			return
+		}
+		if remittance == nil || (merchantID != "" && remittance.MerchantID != merchantID) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "remittance not found"})
+			return
+		}
+		entries, err := codRepo.EntriesByRemittance(remittance.ID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch remittance entries"})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"remittance": remittance, "entries": entries})
+	}
+}
+
+// SettleCODRemittance handles POST /cod/remittances (ops only).
+// Settles every pending entry of the merchant in the given currency and publishes cod.remitted.
+func SettleCODRemittance(codRepo *repository.CODRepository, ch *amqp.Channel) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req struct {
+			MerchantID string `json:"merchant_id" binding:"required"`
+			Currency   string `json:"currency"`
+			Reference  string `json:"reference"`
+		}
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		principal, ok := currentPrincipal(c)
+		if !ok {
+			return
+		}
+		if principal.Role != service.RoleOps {
+			c.JSON(http.StatusForbidden, gin.H{"error": "only ops can settle remittances"})
+			return
+		}
+
+		currency := strings.ToUpper(strings.TrimSpace(req.Currency))
+		if currency == "" {
+			currency = model.DefaultCODCurrency
+		}
+		now := time.Now()
+		remittance, err := codRepo.Settle(&model.CODRemittance{
+			ID:         uuid.New().String(),
+			MerchantID: req.MerchantID,
+			Currency:   currency,
+			Reference:  req.Reference,
+			SettledBy:  principal.UserID,
+			SettledAt:  &now,
+		})
+		if err != nil {
+			log.Printf("[SettleCODRemittance] Settle error: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to settle remittance"})
+			return
+		}
+		if remittance == nil {
+			c.JSON(http.StatusConflict, gin.H{"error": "no pending COD entries for this merchant and currency"})
+			return
+		}
+
+		entries, err := codRepo.EntriesByRemittance(remittance.ID)
+		if err != nil {
+			log.Printf("[SettleCODRemittance] EntriesByRemittance error: %v", err)
+		}
+		event := model.CODRemittedEvent{Remittance: *remittance, EntryIDs: []string{}}
+		for _, e := range entries {
+			event.EntryIDs = append(event.EntryIDs, e.ID)
+		}
+		publishEvent(ch, "cod.remitted", event)
+
+		c.JSON(http.StatusCreated, remittance)
+	}
+}
+
+// codMerchantScope returns the merchant whose COD data the caller may see: customers are limited
+// to their own, ops may pass merchant_id (empty means all merchants). Couriers get 403.
+func codMerchantScope(c *gin.Context) (string, bool) {
+	principal, ok := currentPrincipal(c)
+	if !ok {
+		return "", false
+	}
+	switch principal.Role {
+	case service.RoleOps:
+		return c.Query("merchant_id"), true
+	case service.RoleCustomer:
+		return principal.UserID, true
+	default:
+		c.JSON(http.StatusForbidden, gin.H{"error": "COD ledger is only available to merchants and ops"})
+		return "", false
+	}
+}
+
+func parseCODLimit(c *gin.Context) (int64, bool) {
+	limit, err := strconv.ParseInt(c.DefaultQuery("limit", "50"), 10, 64)
+	if err != nil || limit < 1 || limit > 500 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be between 1 and 500"})
+		return 0, false
+	}
+	return limit, true
+}
@@ -0,0 +1,225 @@
+package handler
+
+import (
+	"log"
+	"logistic-service/internal/model"
+	"logistic-service/internal/repository"
+	"logistic-service/internal/service"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// ContactRequest is the body of POST /contacts and PATCH /contacts/:id
+type ContactRequest struct {
+	Label           *string `json:"label"`
+	Name            *string `json:"name"`
+	Phone           *string `json:"phone"`
+	Address         *string `json:"address"`
+	IsDefaultSender *bool   `json:"is_default_sender"`
+
+	// Replaces the structured address; send an empty object to remove it
+	StructuredAddress *model.StructuredAddress `json:"structured_address"`
+}
+
+// apply copies the fields present in the request to contact
+func (req *ContactRequest) apply(contact *model.Contact) {
+	if req.Label != nil {
+		contact.Label = strings.TrimSpace(*req.Label)
+	}
+	if req.Name != nil {
+		contact.Name = strings.TrimSpace(*req.Name)
+	}
+	if req.Phone != nil {
+		contact.Phone = strings.TrimSpace(*req.Phone)
+	}
+	if req.Address != nil {
+		contact.Address = strings.TrimSpace(*req.Address)
+	}
+	if req.IsDefaultSender != nil {
+		contact.IsDefaultSender = *req.IsDefaultSender
+	}
+	if req.StructuredAddress != nil {
+		contact.Structured = req.StructuredAddress
+		if *req.StructuredAddress == (model.StructuredAddress{}) {
+			contact.Structured = nil
+		}
+	}
+}
+
+// CreateContact handles POST /contacts
+func CreateContact(repo *repository.ContactRepository, regions *service.RegionIndex) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		principal, ok := currentPrincipal(c)
+		if !ok {
+			return
+		}
+		var req ContactRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		now := time.Now()
+		contact := &model.Contact{
+			ID:        uuid.New().String(),
+			UserID:    principal.UserID,
+			CreatedAt: now,
+			UpdatedAt: now,
+		}
+		req.apply(contact)
+		if msg := validateContact(regions, contact); msg != "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+			return
+		}
+
+		if err := repo.Insert(contact); err != nil {
+			log.Printf("[CreateContact] Insert error: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create contact"})
+			return
+		}
+		c.JSON(http.StatusCreated, contact)
+	}
+}
+
+// ListContacts handles GET /contacts?q=&limit=
+// q matches the contact name (anywhere, case insensitive) or the start of the phone number.
+func ListContacts(repo *repository.ContactRepository) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		principal, ok := currentPrincipal(c)
+		if !ok {
+			return
+		}
+		limit, err := strconv.ParseInt(c.DefaultQuery("limit", "50"), 10, 64)
+		if err != nil || limit < 1 || limit > 200 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be between 1 and 200"})
+			return
+		}
+
+		contacts, err := repo.Search(principal.UserID, strings.TrimSpace(c.Query("q")), limit)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch contacts"})
+			return
+		}
+		c.JSON(http.StatusOK, contacts)
+	}
+}
+
+// GetContact handles GET /contacts/:id
+func GetContact(repo *repository.ContactRepository) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		contact, ok := ownedContact(c, repo)
+		if !ok {
+			return
+		}
+		c.JSON(http.StatusOK, contact)
+	}
+}
+
+// GetDefaultSender handles GET /contacts/default-sender
+func GetDefaultSender(repo *repository.ContactRepository) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		principal, ok := currentPrincipal(c)
+		if !ok {
+			return
+		}
+		contact, err := repo.FindDefaultSender(principal.UserID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch default sender"})
+			return
+		}
+		if contact == nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "no default sender set"})
+			return
+		}
+		c.JSON(http.StatusOK, contact)
+	}
+}
+
+// UpdateContact handles PATCH /contacts/:id.
+// Setting is_default_sender=true makes this the only default sender of the user.
+func UpdateContact(repo *repository.ContactRepository, regions *service.RegionIndex) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		contact, ok := ownedContact(c, repo)
+		if !ok {
+			return
+		}
+		var req ContactRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		req.apply(contact)
+		if msg := validateContact(regions, contact); msg != "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+			return
+		}
+
+		contact.UpdatedAt = time.Now()
+		if err := repo.Update(contact); err != nil {
+			log.Printf("[UpdateContact] Update error: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update contact"})
+			return
+		}
+		c.JSON(http.StatusOK, contact)
+	}
+}
+
+// DeleteContact handles DELETE /contacts/:id. Shipments keep their copy of the contact.
+func DeleteContact(repo *repository.ContactRepository) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		contact, ok := ownedContact(c, repo)
+		if !ok {
+			return
+		}
+		if err := repo.Delete(contact.ID); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to delete contact"})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"message": "contact deleted"})
+	}
+}
+
+// ownedContact loads the :id contact of the caller; other users' contacts are reported as not found
+func ownedContact(c *gin.Context, repo *repository.ContactRepository) (*model.Contact, bool) {
+	principal, ok := currentPrincipal(c)
+	if !ok {
+		return nil, false
+	}
+	contact, err := repo.FindByID(principal.UserID, c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch contact"})
+		return nil, false
+	}
+	if contact == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "contact not found"})
+		return nil, false
+	}
+	return contact, true
+}
+
+// validateContact checks the required fields and resolves the structured address, if any.
+// The free text address is composed from the structured one when left empty.
+func validateContact(regions *service.RegionIndex, contact *model.Contact) string {
+	if contact.Structured != nil {
+		if err := regions.ResolveAddress(contact.Structured); err != nil {
+			return "structured_address: " + err.Error()
+		}
+		if contact.Address == "" {
+			contact.Address = service.FormatAddress(contact.Structured)
+		}
+	}
+	switch {
+	case contact.Name == "":
+		return "name is required"
+	case contact.Phone == "":
+		return "phone is required"
+	case contact.Address == "":
+		return "address is required"
+	}
+	return ""
+}
@@ -0,0 +1,641 @@
+package handler
+
+import (
+	"errors"
+	"io"
+	"log"
+	"logistic-service/internal/model"
+	"logistic-service/internal/repository"
+	"logistic-service/internal/service"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+// Limits of the driver task list and of one auto-assign run
+const (
+	MaxPickupTasks = 200  // pickups listed on one driver's task list
+	MaxAutoAssign  = 1000 // deliveries and pickups handled by one auto-assign run
+)
+
+// SaveDriverProfile handles PUT /driver/profile (couriers only).
+// Creates or updates the caller's driver profile, including the base their route starts from.
+// Areas and company are set by ops.
+func SaveDriverProfile(drivers *repository.DriverRepository) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req struct {
+			Name        string          `json:"name" binding:"required"`
+			Phone       string          `json:"phone" binding:"required"`
+			VehicleType string          `json:"vehicle_type"`
+			PlateNumber string          `json:"plate_number"`
+			Base        *model.GeoPoint `json:"base"`
+		}
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if err := service.ValidateGeoPoint(req.Base); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "base " + err.Error()})
+			return
+		}
+		principal, ok := requireCourier(c)
+		if !ok {
+			return
+		}
+
+		err := drivers.SaveProfile(&model.Driver{
+			UserID:      principal.UserID,
+			Name:        strings.TrimSpace(req.Name),
+			Phone:       strings.TrimSpace(req.Phone),
+			VehicleType: strings.TrimSpace(req.VehicleType),
+			PlateNumber: strings.ToUpper(strings.TrimSpace(req.PlateNumber)),
+			Base:        req.Base,
+			UpdatedAt:   time.Now(),
+		})
+		if err != nil {
+			log.Printf("[SaveDriverProfile] SaveProfile error: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to save driver profile"})
+			return
+		}
+		driver, err := drivers.FindByID(principal.UserID)
+		if err != nil || driver == nil {
+			log.Printf("[SaveDriverProfile] Warning: failed to find driver after save: %v", err)
+			c.JSON(http.StatusOK, gin.H{"message": "driver profile saved"})
+			return
+		}
+		c.JSON(http.StatusOK, driver)
+	}
+}
+
+// GetDriverProfile handles GET /driver/profile (couriers only)
+func GetDriverProfile(drivers *repository.DriverRepository) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		principal, ok := requireCourier(c)
+		if !ok {
+			return
+		}
+		driver, ok := findDriver(c, drivers, principal.UserID)
+		if !ok {
+			return
+		}
+		c.JSON(http.StatusOK, driver)
+	}
+}
+
+// GetDriverTasks handles GET /driver/tasks?date= (couriers only).
+// Lists the caller's stops for the date (default today, WIB) in the planned visiting order:
+// assigned pickups of that date and the deliveries assigned to them. Deliveries that failed an
+// attempt that day or were rescheduled to another date are left out. The route is planned on the
+// first request and replanned from where the driver is whenever stops were added or dropped out.
+func GetDriverTasks(drivers *repository.DriverRepository, pickups *repository.PickupRepository, repo *repository.ShipmentRepository) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		principal, ok := requireCourier(c)
+		if !ok {
+			return
+		}
+		date, err := service.ParseTaskDate(c.Query("date"), time.Now())
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		tasks, ok := driverTasks(c, pickups, repo, principal.UserID, date)
+		if !ok {
+			return
+		}
+
+		route, err := drivers.FindRoute(principal.UserID, date)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch route"})
+			return
+		}
+		if _, planned := service.ApplyRoute(tasks, route); !planned {
+			start := service.ResumePoint(route, tasks)
+			if start == nil {
+				start = driverBase(drivers, principal.UserID)
+			}
+			route = planDriverRoute(drivers, principal.UserID, date, tasks, start)
+		}
+		respondDriverTasks(c, route, tasks)
+	}
+}
+
+// OptimizeDriverRoute handles POST /driver/route (couriers only).
+// Replans the caller's route for the date (default today, WIB) from the given position, e.g. after
+// a detour, or from the driver's base without one.
+func OptimizeDriverRoute(drivers *repository.DriverRepository, pickups *repository.PickupRepository, repo *repository.ShipmentRepository) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req struct {
+			Date     string          `json:"date"`
+			Location *model.GeoPoint `json:"location"` // Current position of the driver
+		}
+		if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if err := service.ValidateGeoPoint(req.Location); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		principal, ok := requireCourier(c)
+		if !ok {
+			return
+		}
+		date, err := service.ParseTaskDate(req.Date, time.Now())
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		tasks, ok := driverTasks(c, pickups, repo, principal.UserID, date)
+		if !ok {
+			return
+		}
+
+		start := req.Location
+		if start == nil {
+			start = driverBase(drivers, principal.UserID)
+		}
+		route := planDriverRoute(drivers, principal.UserID, date, tasks, start)
+		respondDriverTasks(c, route, tasks)
+	}
+}
+
+// driverTasks collects the stops of a driver on date, unordered. Writes a 500 on failure.
+func driverTasks(c *gin.Context, pickups *repository.PickupRepository, repo *repository.ShipmentRepository, driverID, date string) ([]model.DriverTask, bool) {
+	assigned, err := pickups.List(repository.PickupFilter{
+		CourierID: driverID,
+		Status:    model.PickupAssigned,
+		Date:      date,
+	}, MaxPickupTasks)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch pickups"})
+		return nil, false
+	}
+	shipments, err := repo.FindByDriver(driverID, model.DeliveryAssignableStatuses)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch shipments"})
+		return nil, false
+	}
+
+	tasks := []model.DriverTask{}
+	for _, p := range assigned {
+		tasks = append(tasks, model.DriverTask{
+			Type:          model.TaskPickup,
+			TimeSlot:      p.TimeSlot,
+			PickupID:      p.ID,
+			Status:        p.Status,
+			Contact:       p.Address,
+			AreaCode:      p.AreaCode,
+			ShipmentCount: len(p.TrackingNumbers),
+			Instructions:  p.Notes,
+			Location:      personLocation(p.Address),
+		})
+	}
+	for _, s := range shipments {
+		if !dueOn(s, date) {
+			continue
+		}
+		task := model.DriverTask{
+			Type:           model.TaskDelivery,
+			TrackingNumber: s.TrackingNumber,
+			Status:         s.Status,
+			Contact:        s.Recipient,
+			AreaCode:       deliveryArea(s),
+			ShipmentCount:  1,
+			COD:            s.COD,
+			Instructions:   s.DeliveryInstructions,
+			Location:       personLocation(s.Recipient),
+		}
+		if s.NextAttempt != nil {
+			task.TimeSlot = s.NextAttempt.TimeSlot
+		}
+		tasks = append(tasks, task)
+	}
+	return tasks, true
+}
+
+// planDriverRoute orders tasks from start and stores the route. A failure to store is only
+// logged: the order is still valid for this response.
+func planDriverRoute(drivers *repository.DriverRepository, driverID, date string, tasks []model.DriverTask, start *model.GeoPoint) *model.DriverRoute {
+	route := &model.DriverRoute{
+		DriverID:   driverID,
+		Date:       date,
+		Start:      start,
+		DistanceKm: service.PlanRoute(tasks, start),
+		ComputedAt: time.Now(),
+	}
+	route.Stops = service.RouteStops(tasks)
+	if err := drivers.SaveRoute(route); err != nil {
+		log.Printf("[planDriverRoute] SaveRoute error: %v", err)
+	}
+	return route
+}
+
+// driverBase returns where the driver starts the day, nil if unknown
+func driverBase(drivers *repository.DriverRepository, driverID string) *model.GeoPoint {
+	driver, err := drivers.FindByID(driverID)
+	if err != nil {
+		log.Printf("[driverBase] FindByID error: %v", err)
+	}
+	if driver == nil {
+		return nil
+	}
+	return driver.Base
+}
+
+func respondDriverTasks(c *gin.Context, route *model.DriverRoute, tasks []model.DriverTask) {
+	c.JSON(http.StatusOK, gin.H{
+		"date":        route.Date,
+		"distance_km": route.DistanceKm,
+		"planned_at":  route.ComputedAt,
+		"tasks":       tasks,
+	})
+}
+
+// personLocation returns the geocoded position of a sender or recipient, nil if unknown
+func personLocation(p model.ShipmentPerson) *model.GeoPoint {
+	if p.Structured == nil {
+		return nil
+	}
+	return p.Structured.Location
+}
+
+// UpdateDriverShipmentStatus handles PATCH /driver/shipments/:trackingNumber/status (couriers only).
+// The driver assigned to a shipment moves it to in_transit or delivered; the tracking event
+// records them as actor together with the optional location and note, which public tracking
+// leaves out. Delivered has the same requirements and effects as PATCH /shipments/:trackingNumber/status.
+func UpdateDriverShipmentStatus(repo *repository.ShipmentRepository, codRepo *repository.CODRepository, ch *amqp.Channel, hooks *service.WebhookDispatcher) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req struct {
+			Status   string `json:"status" binding:"required"`
+			Location string `json:"location"`
+			Note     string `json:"note"`
+		}
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if req.Status != model.StatusInTransit && req.Status != model.StatusDelivered {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "status must be in_transit or delivered"})
+			return
+		}
+		principal, ok := requireCourier(c)
+		if !ok {
+			return
+		}
+
+		trackingNumber := c.Param("trackingNumber")
+		shipment, err := repo.FindByTrackingNumber(trackingNumber)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch shipment"})
+			return
+		}
+		if shipment == nil || shipment.DriverID != principal.UserID {
+			c.JSON(http.StatusNotFound, gin.H{"error": "shipment not found in your assignments"})
+			return
+		}
+		if err := service.CheckShipmentScan(shipment, req.Status); err != nil {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+		if req.Status == model.StatusDelivered {
+			if msg := checkDeliverable(shipment); msg != "" {
+				c.JSON(http.StatusConflict, gin.H{"error": msg})
+				return
+			}
+		}
+
+		description := "in transit with the courier"
+		if req.Status == model.StatusDelivered {
+			description = "delivered by the courier"
+		}
+		err = repo.UpdateAssignedStatus(trackingNumber, principal.UserID, model.DeliveryAssignableStatuses, model.TrackingEvent{
+			Status:      req.Status,
+			Description: description,
+			Note:        strings.TrimSpace(req.Note),
+			Location:    strings.TrimSpace(req.Location),
+			Actor:       principal.UserID,
+			Timestamp:   time.Now(),
+		})
+		if err == repository.ErrStatusConflict {
+			c.JSON(http.StatusConflict, gin.H{"error": "shipment is no longer assigned to you or can't change status"})
+			return
+		}
+		if err != nil {
+			log.Printf("[UpdateDriverShipmentStatus] UpdateAssignedStatus error: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update status"})
+			return
+		}
+
+		shipment, err = repo.FindByTrackingNumber(trackingNumber)
+		if err != nil || shipment == nil {
+			log.Printf("[UpdateDriverShipmentStatus] Warning: failed to find shipment after update: %v", err)
+			c.JSON(http.StatusOK, gin.H{"message": "status updated"})
+			return
+		}
+		publishShipmentUpdated(ch, shipment)
+		hooks.Enqueue(shipment.UserID, model.EventShipmentUpdated, shipment)
+		if shipment.Status == model.StatusDelivered {
+			afterDelivered(repo, codRepo, ch, hooks, shipment, principal.UserID)
+		}
+		c.JSON(http.StatusOK, shipment)
+	}
+}
+
+// ListDrivers handles GET /drivers?area_code=&include_inactive=true (couriers and ops)
+func ListDrivers(drivers *repository.DriverRepository) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if _, ok := requireStaff(c, "only couriers and ops can list drivers"); !ok {
+			return
+		}
+		results, err := drivers.List(c.Query("area_code"), c.Query("include_inactive") == "true")
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch drivers"})
+			return
+		}
+		c.JSON(http.StatusOK, results)
+	}
+}
+
+// GetDriver handles GET /drivers/:id (couriers and ops)
+func GetDriver(drivers *repository.DriverRepository) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if _, ok := requireStaff(c, "only couriers and ops can view drivers"); !ok {
+			return
+		}
+		driver, ok := findDriver(c, drivers, c.Param("id"))
+		if !ok {
+			return
+		}
+		c.JSON(http.StatusOK, driver)
+	}
+}
+
+// UpdateDriver handles PATCH /drivers/:id (ops only).
+// Sets the courier company a driver works for, the region codes they serve and whether they
+// get new assignments.
+func UpdateDriver(drivers *repository.DriverRepository, regions *service.RegionIndex) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req struct {
+			LogisticName *string   `json:"logistic_name"`
+			AreaCodes    *[]string `json:"area_codes"`
+			Active       *bool     `json:"active"`
+		}
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if _, ok := requireOps(c, "only ops can manage drivers"); !ok {
+			return
+		}
+		driver, ok := findDriver(c, drivers, c.Param("id"))
+		if !ok {
+			return
+		}
+
+		if req.LogisticName != nil {
+			driver.LogisticName = strings.TrimSpace(*req.LogisticName)
+		}
+		if req.AreaCodes != nil {
+			driver.AreaCodes = []string{}
+			seen := make(map[string]bool)
+			for _, code := range *req.AreaCodes {
+				code = strings.TrimSpace(code)
+				if code == "" || seen[code] {
+					continue
+				}
+				seen[code] = true
+				if regions.Find(code) == nil {
+					c.JSON(http.StatusBadRequest, gin.H{"error": "unknown area code " + code})
+					return
+				}
+				driver.AreaCodes = append(driver.AreaCodes, code)
+			}
+		}
+		if req.Active != nil {
+			driver.Active = *req.Active
+		}
+		driver.UpdatedAt = time.Now()
+		if err := drivers.UpdateAssignment(driver); err != nil {
+			log.Printf("[UpdateDriver] UpdateAssignment error: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update driver"})
+			return
+		}
+		c.JSON(http.StatusOK, driver)
+	}
+}
+
+// AssignShipmentDriver handles POST /shipments/:trackingNumber/driver (ops only).
+// Assigns a picked up or in transit shipment to an active driver; an empty driver_id unassigns it.
+func AssignShipmentDriver(drivers *repository.DriverRepository, repo *repository.ShipmentRepository, ch *amqp.Channel, hooks *service.WebhookDispatcher) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req struct {
+			DriverID string `json:"driver_id"`
+		}
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if _, ok := requireOps(c, "only ops can assign drivers"); !ok {
+			return
+		}
+		if req.DriverID != "" {
+			driver, ok := findDriver(c, drivers, req.DriverID)
+			if !ok {
+				return
+			}
+			if !driver.Active {
+				c.JSON(http.StatusConflict, gin.H{"error": "driver is inactive"})
+				return
+			}
+		}
+
+		trackingNumber := c.Param("trackingNumber")
+		shipment, err := repo.FindByTrackingNumber(trackingNumber)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch shipment"})
+			return
+		}
+		if shipment == nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "shipment not found"})
+			return
+		}
+		err = repo.AssignDriver(trackingNumber, req.DriverID, model.DeliveryAssignableStatuses)
+		if err == repository.ErrStatusConflict {
+			c.JSON(http.StatusConflict, gin.H{"error": "only picked_up or in_transit shipments can be assigned to a driver"})
+			return
+		}
+		if err != nil {
+			log.Printf("[AssignShipmentDriver] AssignDriver error: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to assign driver"})
+			return
+		}
+		announceShipments(repo, ch, hooks, []string{trackingNumber})
+		c.JSON(http.StatusOK, gin.H{"tracking_number": trackingNumber, "driver_id": req.DriverID})
+	}
+}
+
+// UnassignedTask is a stop auto-assign found no driver for
+type UnassignedTask struct {
+	Type           string `json:"type"`
+	PickupID       string `json:"pickup_id,omitempty"`
+	TrackingNumber string `json:"tracking_number,omitempty"`
+	AreaCode       string `json:"area_code"`
+}
+
+// AutoAssignDrivers handles POST /drivers/auto-assign (ops only).
+// Assigns the deliveries without a driver that are due on the date (default today, WIB) by
+// destination area, and the scheduled pickups of that date by pickup area. Each stop goes to the
+// least loaded active driver serving the area and the courier company. Stops no driver serves are
+// listed in "unassigned".
+func AutoAssignDrivers(drivers *repository.DriverRepository, pickups *repository.PickupRepository, repo *repository.ShipmentRepository, ch *amqp.Channel, hooks *service.WebhookDispatcher) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req struct {
+			Date string `json:"date"` // Empty body or date means today
+		}
+		if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		principal, ok := requireOps(c, "only ops can assign drivers")
+		if !ok {
+			return
+		}
+		date, err := service.ParseTaskDate(req.Date, time.Now())
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		active, err := drivers.List("", false)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch drivers"})
+			return
+		}
+		// Load is the number of stops a driver already has, so work is spread evenly
+		load := make(map[string]int, len(active))
+		for _, d := range active {
+			assigned, err := repo.FindByDriver(d.UserID, model.DeliveryAssignableStatuses)
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch driver workload"})
+				return
+			}
+			load[d.UserID] = len(assigned)
+		}
+
+		unassigned := []UnassignedTask{}
+		var assignedShipments []string
+		shipments, err := repo.FindUnassigned(model.DeliveryAssignableStatuses, MaxAutoAssign)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch shipments"})
+			return
+		}
+		for _, s := range shipments {
+			if !dueOn(s, date) {
+				continue
+			}
+			area := deliveryArea(s)
+			driver := service.MatchDriver(active, area, s.LogisticName, load)
+			if driver == nil {
+				unassigned = append(unassigned, UnassignedTask{Type: model.TaskDelivery, TrackingNumber: s.TrackingNumber, AreaCode: area})
+				continue
+			}
+			err := repo.AssignDriver(s.TrackingNumber, driver.UserID, model.DeliveryAssignableStatuses)
+			if err != nil {
+				log.Printf("[AutoAssignDrivers] AssignDriver error for %s: %v", s.TrackingNumber, err)
+				continue
+			}
+			load[driver.UserID]++
+			assignedShipments = append(assignedShipments, s.TrackingNumber)
+		}
+		announceShipments(repo, ch, hooks, assignedShipments)
+
+		scheduled, err := pickups.List(repository.PickupFilter{Status: model.PickupScheduled, Date: date}, MaxAutoAssign)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch pickups"})
+			return
+		}
+		assignedPickups := 0
+		for _, p := range scheduled {
+			driver := service.MatchDriver(active, p.AreaCode, p.LogisticName, load)
+			if driver == nil {
+				unassigned = append(unassigned, UnassignedTask{Type: model.TaskPickup, PickupID: p.ID, AreaCode: p.AreaCode})
+				continue
+			}
+			err := pickups.UpdateStatus(p.ID, []string{model.PickupScheduled}, driver.UserID, model.PickupEvent{
+				Status:    model.PickupAssigned,
+				Note:      "assigned automatically by area",
+				Actor:     principal.UserID,
+				Timestamp: time.Now(),
+			})
+			if err != nil {
+				log.Printf("[AutoAssignDrivers] UpdateStatus error for pickup %s: %v", p.ID, err)
+				continue
+			}
+			load[driver.UserID]++
+			assignedPickups++
+			if result, err := pickups.FindByID(p.ID); err == nil && result != nil {
+				publishEvent(ch, "pickup.updated", result)
+			}
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"date":                date,
+			"deliveries_assigned": len(assignedShipments),
+			"pickups_assigned":    assignedPickups,
+			"unassigned":          unassigned,
+		})
+	}
+}
+
+// dueOn reports whether a delivery belongs on the task list of date: it is due every day
+// unless the recipient rescheduled it to another date or an attempt already failed that day.
+func dueOn(s *model.Shipment, date string) bool {
+	if s.NextAttempt != nil {
+		return s.NextAttempt.Date == date
+	}
+	for _, a := range s.DeliveryAttempts {
+		if service.Today(a.AttemptedAt) == date {
+			return false
+		}
+	}
+	return true
+}
+
+// deliveryArea is the area a delivery is assigned by: the destination district code, or the
+// lowercased destination text for shipments without a structured address
+func deliveryArea(s *model.Shipment) string {
+	if s.DestinationCode != "" {
+		return s.DestinationCode
+	}
+	return strings.ToLower(strings.TrimSpace(s.Destination))
+}
+
+// requireCourier returns the caller if they are a courier, else writes a 403
+func requireCourier(c *gin.Context) (*service.Principal, bool) {
+	principal, ok := currentPrincipal(c)
+	if !ok {
+		return nil, false
+	}
+	if principal.Role != service.RoleCourier {
+		c.JSON(http.StatusForbidden, gin.H{"error": "only couriers have a driver profile"})
+		return nil, false
+	}
+	return principal, true
+}
+
+// findDriver loads a driver, writing a 404 when the user has no driver profile
+func findDriver(c *gin.Context, drivers *repository.DriverRepository, userID string) (*model.Driver, bool) {
+	driver, err := drivers.FindByID(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch driver"})
+		return nil, false
+	}
+	if driver == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "driver not found"})
+		return nil, false
+	}
+	return driver, true
+}
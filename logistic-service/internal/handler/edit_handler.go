@@ -0,0 +1,131 @@
+package handler
+
+import (
+	"fmt"
+	"log"
+	"logistic-service/internal/model"
+	"logistic-service/internal/repository"
+	"logistic-service/internal/service"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+// EditShipmentRequest is the body of PATCH /shipments/:trackingNumber.
+// Omitted fields are left unchanged; version must be the version the client last read.
+type EditShipmentRequest struct {
+	Version   *int                  `json:"version" binding:"required"`
+	Sender    *model.ShipmentPerson `json:"sender"`
+	Recipient *model.ShipmentPerson `json:"recipient"`
+	Items     *[]model.ShipmentItem `json:"items"`
+	Notes     *string               `json:"notes"`
+}
+
+// EditShipment handles PATCH /shipments/:trackingNumber.
+// Sender, recipient, items and notes can be edited while the shipment is still before pickup.
+// Each edit is stored as a revision and published as shipment.updated. An edit that changes the
+// route zone also moves the promised date and ETA to those of the new route.
+func EditShipment(repo *repository.ShipmentRepository, regions *service.RegionIndex, calendar *service.DeliveryCalendar, ch *amqp.Channel, hooks *service.WebhookDispatcher) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		trackingNumber := c.Param("trackingNumber")
+		var req EditShipmentRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		principal, ok := currentPrincipal(c)
+		if !ok {
+			return
+		}
+
+		shipment, err := repo.FindByTrackingNumber(trackingNumber)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch shipment"})
+			return
+		}
+		if !principal.CanAccessShipment(shipment) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "shipment not found"})
+			return
+		}
+
+		updated := *shipment
+		if req.Sender != nil {
+			updated.Sender = *req.Sender
+		}
+		if req.Recipient != nil {
+			updated.Recipient = *req.Recipient
+		}
+		if req.Items != nil {
+			if len(*req.Items) == 0 {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "items must not be empty"})
+				return
+			}
+			updated.Items = *req.Items
+			if total := service.ItemsDeclaredValue(updated.Items); shipment.DeclaredValue > 0 && total > shipment.DeclaredValue {
+				c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("items are declared at %d, more than the declared_value %d", total, shipment.DeclaredValue)})
+				return
+			}
+		}
+		if req.Notes != nil {
+			updated.Notes = *req.Notes
+		}
+		if req.Sender != nil || req.Recipient != nil {
+			if err := regions.ApplyRegions(&updated); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+		}
+
+		changes := service.DiffShipment(shipment, &updated)
+		if len(changes) == 0 {
+			c.JSON(http.StatusOK, shipment)
+			return
+		}
+
+		now := time.Now()
+		repromised, err := calendar.UpdatePromise(shipment, &updated, now)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if repromised {
+			changes = append(changes, model.FieldChange{Field: "promised_date", Old: shipment.PromisedDate, New: updated.PromisedDate})
+		}
+
+		updated.SearchKeys = service.ShipmentSearchKeys(&updated)
+		revision := model.ShipmentRevision{
+			Version:  *req.Version + 1,
+			EditedBy: principal.UserID,
+			EditedAt: now,
+			Changes:  changes,
+		}
+		err = repo.UpdateDetails(trackingNumber, *req.Version, model.PrePickupStatuses, &updated, revision)
+		switch err {
+		case nil:
+		case repository.ErrVersionConflict:
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error(), "current_version": shipment.Version})
+			return
+		case repository.ErrStatusConflict:
+			c.JSON(http.StatusConflict, gin.H{"error": "shipment can no longer be edited in status " + shipment.Status})
+			return
+		default:
+			log.Printf("[EditShipment] UpdateDetails error: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update shipment"})
+			return
+		}
+
+		result, err := repo.FindByTrackingNumber(trackingNumber)
+		if err != nil || result == nil {
+			log.Printf("[EditShipment] Warning: failed to find shipment after update: %v", err)
+			c.JSON(http.StatusOK, gin.H{"message": "shipment updated"})
+			return
+		}
+		publishShipmentUpdated(ch, result)
+		hooks.Enqueue(result.UserID, model.EventShipmentUpdated, result)
+
+		c.JSON(http.StatusOK, result)
+	}
+}
@@ -0,0 +1,84 @@
+package handler
+
+import (
+	"fmt"
+	"log"
+	"logistic-service/internal/model"
+	"logistic-service/internal/repository"
+	"logistic-service/internal/service"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ExportShipments handles GET /shipments/export?format=csv|xlsx&columns=...
+// Streams the caller's shipments matching the filters of GET /shipments (see parseShipmentFilter),
+// oldest first. columns is a comma separated list of column groups (shipment, sender, recipient,
+// items, cost, timestamps) and column names, default all; see service.ExportColumns.
+func ExportShipments(repo *repository.ShipmentRepository) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		principal, ok := currentPrincipal(c)
+		if !ok {
+			return
+		}
+		filter, err := parseShipmentFilter(c)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		filter.UserID = principal.UserID
+
+		format := c.DefaultQuery("format", "csv")
+		contentType, ok := service.ExportContentTypes[format]
+		if !ok {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "format must be csv or xlsx"})
+			return
+		}
+		columns, err := service.ParseExportColumns(c.Query("columns"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		if format == "xlsx" {
+			n, err := repo.CountUpTo(filter, service.MaxXLSXExportRows+1)
+			if err != nil {
+				log.Printf("[ExportShipments] CountUpTo error: %v", err)
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to export shipments"})
+				return
+			}
+			if n > service.MaxXLSXExportRows {
+				c.JSON(http.StatusBadRequest, gin.H{"error": service.ErrExportTooLarge.Error()})
+				return
+			}
+		}
+
+		// Headers go out with the first row, so errors after that can only end the download early
+		c.Header("Content-Type", contentType)
+		c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="shipments-%s.%s"`, service.Today(time.Now()), format))
+		c.Status(http.StatusOK)
+		w, err := service.NewExportWriter(format, c.Writer)
+		if err != nil {
+			log.Printf("[ExportShipments] NewExportWriter error: %v", err)
+			return
+		}
+		defer w.Discard()
+		if err := w.WriteRow(service.ExportHeader(columns)); err != nil {
+			log.Printf("[ExportShipments] WriteRow error: %v", err)
+			return
+		}
+		rows := 0
+		err = repo.Export(filter, func(s *model.Shipment) error {
+			rows++
+			return w.WriteRow(service.ExportRow(columns, s))
+		})
+		if err != nil {
+			log.Printf("[ExportShipments] Export for user %s stopped after %d rows: %v", principal.UserID, rows, err)
+			return
+		}
+		if err := w.Close(); err != nil {
+			log.Printf("[ExportShipments] Close error: %v", err)
+		}
+	}
+}
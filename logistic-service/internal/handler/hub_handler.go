@@ -0,0 +1,347 @@
+package handler
+
+import (
+	"errors"
+	"log"
+	"logistic-service/internal/model"
+	"logistic-service/internal/repository"
+	"logistic-service/internal/service"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	amqp "github.com/rabbitmq/amqp091-go"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// MaxScanBatch is the largest number of tracking numbers accepted in one scan request
+const MaxScanBatch = 500
+
+// HubRequest is the body of POST /hubs and PATCH /hubs/:code
+type HubRequest struct {
+	Code       string  `json:"code"` // POST only
+	Name       *string `json:"name"`
+	Address    *string `json:"address"`
+	RegionCode *string `json:"region_code"`
+	Active     *bool   `json:"active"`
+}
+
+// apply copies the fields present in the request to hub
+func (req *HubRequest) apply(hub *model.Hub) {
+	if req.Name != nil {
+		hub.Name = strings.TrimSpace(*req.Name)
+	}
+	if req.Address != nil {
+		hub.Address = strings.TrimSpace(*req.Address)
+	}
+	if req.RegionCode != nil {
+		hub.RegionCode = strings.TrimSpace(*req.RegionCode)
+	}
+	if req.Active != nil {
+		hub.Active = *req.Active
+	}
+}
+
+// CreateHub handles POST /hubs (ops only)
+func CreateHub(hubs *repository.HubRepository, regions *service.RegionIndex) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req HubRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if _, ok := requireOps(c, "only ops can manage hubs"); !ok {
+			return
+		}
+
+		now := time.Now()
+		hub := &model.Hub{
+			Code:      strings.ToUpper(strings.TrimSpace(req.Code)),
+			Active:    true,
+			CreatedAt: now,
+			UpdatedAt: now,
+		}
+		req.apply(hub)
+		if hub.Code == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "code is required"})
+			return
+		}
+		if msg := validateHub(regions, hub); msg != "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+			return
+		}
+
+		err := hubs.InsertHub(hub)
+		if mongo.IsDuplicateKeyError(err) {
+			c.JSON(http.StatusConflict, gin.H{"error": "hub " + hub.Code + " already exists"})
+			return
+		}
+		if err != nil {
+			log.Printf("[CreateHub] InsertHub error: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create hub"})
+			return
+		}
+		c.JSON(http.StatusCreated, hub)
+	}
+}
+
+// ListHubs handles GET /hubs?include_inactive=true
+func ListHubs(hubs *repository.HubRepository) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		results, err := hubs.ListHubs(c.Query("include_inactive") == "true")
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch hubs"})
+			return
+		}
+		c.JSON(http.StatusOK, results)
+	}
+}
+
+// GetHub handles GET /hubs/:code
+func GetHub(hubs *repository.HubRepository) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		hub, err := hubs.FindHub(c.Param("code"))
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch hub"})
+			return
+		}
+		if hub == nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "hub not found"})
+			return
+		}
+		c.JSON(http.StatusOK, hub)
+	}
+}
+
+// UpdateHub handles PATCH /hubs/:code (ops only). Deactivated hubs can't scan or receive manifests.
+func UpdateHub(hubs *repository.HubRepository, regions *service.RegionIndex) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req HubRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if _, ok := requireOps(c, "only ops can manage hubs"); !ok {
+			return
+		}
+		hub, err := hubs.FindHub(c.Param("code"))
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch hub"})
+			return
+		}
+		if hub == nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "hub not found"})
+			return
+		}
+
+		req.apply(hub)
+		hub.UpdatedAt = time.Now()
+		if msg := validateHub(regions, hub); msg != "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+			return
+		}
+		if err := hubs.UpdateHub(hub); err != nil {
+			log.Printf("[UpdateHub] UpdateHub error: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update hub"})
+			return
+		}
+		c.JSON(http.StatusOK, hub)
+	}
+}
+
+// ScanResult is the outcome of scanning one tracking number
+type ScanResult struct {
+	TrackingNumber string `json:"tracking_number"`
+	OK             bool   `json:"ok"`
+	Error          string `json:"error,omitempty"`
+}
+
+// ScanAtHub handles POST /hubs/:code/scans (couriers and ops).
+// Records an inbound or outbound scan of one (tracking_number) or many (tracking_numbers)
+// shipments: the shipment moves to in_transit and gets a tracking event at the hub. Outbound
+// scans only accept shipments last scanned in at this hub. Each tracking number is scanned on
+// its own; the response lists the result of every one.
+func ScanAtHub(hubs *repository.HubRepository, repo *repository.ShipmentRepository, ch *amqp.Channel, hooks *service.WebhookDispatcher) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req struct {
+			Type            string   `json:"type" binding:"required"`
+			TrackingNumber  string   `json:"tracking_number"`
+			TrackingNumbers []string `json:"tracking_numbers"`
+		}
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if req.Type != model.ScanInbound && req.Type != model.ScanOutbound {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "type must be inbound or outbound"})
+			return
+		}
+		trackingNumbers := req.TrackingNumbers
+		if req.TrackingNumber != "" {
+			trackingNumbers = append([]string{req.TrackingNumber}, trackingNumbers...)
+		}
+		trackingNumbers = uniqueTrackingNumbers(trackingNumbers)
+		if len(trackingNumbers) == 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "tracking_number or tracking_numbers is required"})
+			return
+		}
+		if len(trackingNumbers) > MaxScanBatch {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "too many tracking numbers in one scan"})
+			return
+		}
+
+		principal, ok := requireStaff(c, "only couriers and ops can scan at hubs")
+		if !ok {
+			return
+		}
+		hub, ok := activeHub(c, hubs, c.Param("code"))
+		if !ok {
+			return
+		}
+
+		results := make([]ScanResult, 0, len(trackingNumbers))
+		scanned := 0
+		for _, tn := range trackingNumbers {
+			result := ScanResult{TrackingNumber: tn, OK: true}
+			if err := scanShipment(repo, ch, hooks, hub, req.Type, tn, principal.UserID, ""); err != nil {
+				result.OK, result.Error = false, err.Error()
+			} else {
+				scanned++
+			}
+			results = append(results, result)
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"hub":     hub.Code,
+			"type":    req.Type,
+			"scanned": scanned,
+			"failed":  len(results) - scanned,
+			"results": results,
+		})
+	}
+}
+
+// Errors returned by scanShipment that describe why a tracking number can't be scanned
+var (
+	errScanNotFound   = errors.New("shipment not found")
+	errScanDuplicate  = errors.New("shipment is already at this hub")
+	errScanNotAtHub   = errors.New("shipment was not scanned in at this hub")
+	errScanConcurrent = errors.New("shipment was modified by someone else, retry")
+)
+
+// scanShipment records an inbound or outbound scan of one shipment at hub and announces the
+// change. note is appended to the event description, e.g. the manifest the parcel travels in.
+func scanShipment(repo *repository.ShipmentRepository, ch *amqp.Channel, hooks *service.WebhookDispatcher, hub *model.Hub, scanType, trackingNumber, actor, note string) error {
+	shipment, err := repo.FindByTrackingNumber(trackingNumber)
+	if err != nil {
+		log.Printf("[scanShipment] FindByTrackingNumber error: %v", err)
+		return errors.New("failed to fetch shipment")
+	}
+	if shipment == nil {
+		return errScanNotFound
+	}
+	if !hasStatus(shipment, model.HubScanStatuses) {
+		return errors.New("shipment can't be scanned in status " + shipment.Status)
+	}
+
+	description := "arrived at hub " + hub.Name
+	if scanType == model.ScanOutbound {
+		if shipment.CurrentHub != hub.Code {
+			return errScanNotAtHub
+		}
+		description = "departed hub " + hub.Name
+	} else if shipment.CurrentHub == hub.Code {
+		return errScanDuplicate
+	}
+	if note != "" {
+		description += " " + note
+	}
+
+	err = repo.RecordHubScan(trackingNumber, scanType, model.TrackingEvent{
+		Status:      model.StatusInTransit,
+		Description: description,
+		Location:    hub.Name,
+		HubCode:     hub.Code,
+		Actor:       actor,
+		Timestamp:   time.Now(),
+	})
+	if err == repository.ErrStatusConflict {
+		return errScanConcurrent
+	}
+	if err != nil {
+		log.Printf("[scanShipment] RecordHubScan error for %s: %v", trackingNumber, err)
+		return errors.New("failed to record scan")
+	}
+	announceShipments(repo, ch, hooks, []string{trackingNumber})
+	return nil
+}
+
+// activeHub loads the hub with the given code. It writes a 404 when it doesn't exist and a
+// 409 when it was deactivated.
+func activeHub(c *gin.Context, hubs *repository.HubRepository, code string) (*model.Hub, bool) {
+	hub, err := hubs.FindHub(code)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch hub"})
+		return nil, false
+	}
+	if hub == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "hub " + code + " not found"})
+		return nil, false
+	}
+	if !hub.Active {
+		c.JSON(http.StatusConflict, gin.H{"error": "hub " + code + " is inactive"})
+		return nil, false
+	}
+	return hub, true
+}
+
+func validateHub(regions *service.RegionIndex, hub *model.Hub) string {
+	if hub.Name == "" {
+		return "name is required"
+	}
+	if hub.RegionCode != "" && regions.Find(hub.RegionCode) == nil {
+		return "unknown region_code " + hub.RegionCode
+	}
+	return ""
+}
+
+// requireStaff returns the caller if they are a courier or ops, else writes a 403 with msg
+func requireStaff(c *gin.Context, msg string) (*service.Principal, bool) {
+	principal, ok := currentPrincipal(c)
+	if !ok {
+		return nil, false
+	}
+	if !principal.IsStaff() {
+		c.JSON(http.StatusForbidden, gin.H{"error": msg})
+		return nil, false
+	}
+	return principal, true
+}
+
+// requireOps returns the caller if they are ops, else writes a 403 with msg
+func requireOps(c *gin.Context, msg string) (*service.Principal, bool) {
+	principal, ok := currentPrincipal(c)
+	if !ok {
+		return nil, false
+	}
+	if principal.Role != service.RoleOps {
+		c.JSON(http.StatusForbidden, gin.H{"error": msg})
+		return nil, false
+	}
+	return principal, true
+}
+
+// uniqueTrackingNumbers trims the tracking numbers and drops empty and repeated ones, keeping order
+func uniqueTrackingNumbers(trackingNumbers []string) []string {
+	seen := make(map[string]bool)
+	var result []string
+	for _, tn := range trackingNumbers {
+		tn = strings.TrimSpace(tn)
+		if tn == "" || seen[tn] {
+			continue
+		}
+		seen[tn] = true
+		result = append(result, tn)
+	}
+	return result
+}
@@ -0,0 +1,123 @@
+package handler
+
+import (
+	"log"
+	"logistic-service/internal/model"
+	"logistic-service/internal/repository"
+	"logistic-service/internal/service"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetShipmentLabel handles GET /shipments/:trackingNumber/label?format=a6|4x6
+// and returns the printable shipping label as PDF.
+func GetShipmentLabel(repo *repository.ShipmentRepository) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		format, err := service.ParseLabelFormat(c.Query("format"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		principal, ok := currentPrincipal(c)
+		if !ok {
+			return
+		}
+		shipment, err := repo.FindByTrackingNumber(c.Param("trackingNumber"))
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch shipment"})
+			return
+		}
+		if !principal.CanAccessShipment(shipment) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "shipment not found"})
+			return
+		}
+		if shipment.Status == model.StatusCancelled {
+			c.JSON(http.StatusConflict, gin.H{"error": "shipment is cancelled"})
+			return
+		}
+
+		sendLabels(c, format, []*model.Shipment{shipment}, "label-"+shipment.TrackingNumber+".pdf")
+	}
+}
+
+// GetShipmentLabels handles POST /shipments/labels and merges the labels of many shipments
+// into one PDF, one page per shipment in request order.
+// Fails with 404 listing the tracking numbers that don't exist or can't be accessed.
+func GetShipmentLabels(repo *repository.ShipmentRepository) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req struct {
+			TrackingNumbers []string `json:"tracking_numbers" binding:"required,min=1"`
+			Format          string   `json:"format"`
+		}
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if len(req.TrackingNumbers) > service.MaxBatchLabels {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "too many tracking numbers, the limit is 100"})
+			return
+		}
+		format, err := service.ParseLabelFormat(req.Format)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		principal, ok := currentPrincipal(c)
+		if !ok {
+			return
+		}
+
+		var (
+			shipments []*model.Shipment
+			notFound  []string
+			cancelled []string
+		)
+		seen := make(map[string]bool)
+		for _, trackingNumber := range req.TrackingNumbers {
+			if seen[trackingNumber] {
+				continue
+			}
+			seen[trackingNumber] = true
+
+			shipment, err := repo.FindByTrackingNumber(trackingNumber)
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch shipment"})
+				return
+			}
+			switch {
+			case !principal.CanAccessShipment(shipment):
+				notFound = append(notFound, trackingNumber)
+			case shipment.Status == model.StatusCancelled:
+				cancelled = append(cancelled, trackingNumber)
+			default:
+				shipments = append(shipments, shipment)
+			}
+		}
+		if len(notFound) > 0 {
+			c.JSON(http.StatusNotFound, gin.H{"error": "shipment not found", "tracking_numbers": notFound})
+			return
+		}
+		if len(cancelled) > 0 {
+			c.JSON(http.StatusConflict, gin.H{"error": "shipment is cancelled", "tracking_numbers": cancelled})
+			return
+		}
+
+		sendLabels(c, format, shipments, "labels-"+time.Now().Format("20060102-150405")+".pdf")
+	}
+}
+
+// sendLabels renders the labels and writes them as an inline PDF
+func sendLabels(c *gin.Context, format service.LabelFormat, shipments []*model.Shipment, fileName string) {
+	pdf, err := service.RenderLabels(format, shipments)
+	if err != nil {
+		log.Printf("[sendLabels] RenderLabels error: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to render label"})
+		return
+	}
+	c.Header("Content-Disposition", `inline; filename="`+fileName+`"`)
+	c.Data(http.StatusOK, "application/pdf", pdf)
+}
@@ -3,26 +3,47 @@ package handler
 import (
 	"logistic-service/internal/model"
 	"logistic-service/internal/repository"
+	"logistic-service/internal/service"
 	"net/http"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
-	jwt "github.com/golang-jwt/jwt/v5"
     "log"
-    "encoding/json"
+    "errors"
+    "fmt"
+    "strconv"
     amqp "github.com/rabbitmq/amqp091-go"
 )
 
 // GetCourierRates handles GET /courier-rates?origin_name=...&destination_name=...
+// or GET /courier-rates?origin_code=...&destination_code=... with region codes of any level.
 // Requires valid JWT. Returns courier rates filtered by origin and destination.
-func GetCourierRates(repo *repository.ShipmentRepository) gin.HandlerFunc {
+func GetCourierRates(repo *repository.ShipmentRepository, regions *service.RegionIndex) gin.HandlerFunc {
 	return func(c *gin.Context) {
+		originCode := c.Query("origin_code")
+		destinationCode := c.Query("destination_code")
+		if originCode != "" || destinationCode != "" {
+			for _, code := range []string{originCode, destinationCode} {
+				if regions.Find(code) == nil {
+					c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("unknown region code %q", code)})
+					return
+				}
+			}
+			rates, err := repo.FindByRegionCodes(originCode, destinationCode)
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch courier rates"})
+				return
+			}
+			c.JSON(http.StatusOK, rates)
+			return
+		}
+
 		origin := c.Query("origin_name")
 		destination := c.Query("destination_name")
 
 		if origin == "" || destination == "" {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "origin_name and destination_name are required"})
+			c.JSON(http.StatusBadRequest, gin.H{"error": "origin_name and destination_name (or origin_code and destination_code) are required"})
 			return
 		}
 
@@ -36,85 +57,182 @@ func GetCourierRates(repo *repository.ShipmentRepository) gin.HandlerFunc {
 	}
 }
 
-// CreateShipment menerima channel RabbitMQ sebagai argumen tambahan
-func CreateShipment(repo *repository.ShipmentRepository, ch *amqp.Channel) gin.HandlerFunc {
+// CreateShipment menerima channel RabbitMQ sebagai argumen tambahan.
+// sender_id and recipient_id reference address book contacts and are copied into sender and
+// recipient; without sender and sender_id the user's default sender is used.
+// Structured addresses are validated against the region dataset and set origin_code/destination_code.
+// The insurance premium of insured shipments is computed from the insurance policy.
+func CreateShipment(repo *repository.ShipmentRepository, contacts *repository.ContactRepository, regions *service.RegionIndex, calendar *service.DeliveryCalendar, insurance *model.InsurancePolicy, ch *amqp.Channel, hooks *service.WebhookDispatcher) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		var input model.Shipment
+		var req struct {
+			model.Shipment
+			SenderID    string `json:"sender_id"`
+			RecipientID string `json:"recipient_id"`
+		}
 
-		if err := c.ShouldBindJSON(&input); err != nil {
+		if err := c.ShouldBindJSON(&req); err != nil {
 			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 			return
 		}
-
-		// Validasi duplikat tracking_number
-		existingShipment, err := repo.FindByTrackingNumber(input.TrackingNumber)
-		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to check existing shipment"})
+		input := req.Shipment
+		input.Return = nil // return shipments are created through POST /shipments/:trackingNumber/return
+		if err := service.ValidateCOD(input.COD); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 			return
 		}
-		if existingShipment != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "tracking_number already exists"})
+
+		principal, ok := currentPrincipal(c)
+		if !ok {
 			return
 		}
 
-		input.ID = uuid.New().String()
-		now := time.Now()
-		input.CreatedAt = now
-		input.UpdatedAt = now
-
-		claimsRaw, exists := c.Get("claims")
-		if !exists {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		if msg, err := expandContacts(contacts, principal.UserID, &input, req.SenderID, req.RecipientID); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch contacts"})
+			return
+		} else if msg != "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": msg})
 			return
 		}
-
-		claims, ok := claimsRaw.(jwt.MapClaims)
-		if !ok {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid token claims"})
+		if err := regions.ApplyRegions(&input); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 			return
 		}
-
-		userID, ok := claims["user_id"].(string)
-		if !ok {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid user_id in claims"})
+		if err := service.ApplyServiceLevel(&input); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if err := service.ValidateDeclaredValue(&input); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if err := service.ApplyInsurance(insurance, &input); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if err := service.ValidateParcels(input.Parcels); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 			return
 		}
 
-		input.UserID = userID
-
-		if err := repo.Insert(&input); err != nil {
+		err := createShipment(repo, calendar, ch, hooks, &input, principal.UserID)
+		if err == errTrackingNumberExists {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if err != nil {
+			log.Printf("[CreateShipment] createShipment error: %v", err)
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create shipment"})
 			return
 		}
 
-		// Publish ke RabbitMQ pakai channel yang sudah ada
-		body, err := json.Marshal(input)
+		c.JSON(http.StatusCreated, input)
+	}
+}
+
+// expandContacts copies the referenced address book contacts into the shipment.
+// Returns a message for the client when a contact doesn't exist.
+func expandContacts(contacts *repository.ContactRepository, userID string, s *model.Shipment, senderID, recipientID string) (string, error) {
+	if senderID != "" {
+		contact, err := contacts.FindByID(userID, senderID)
 		if err != nil {
-			log.Printf("[CreateShipment] Marshal shipment error: %v", err)
-		} else {
-			err = ch.Publish(
-				"",
-				"shipment.created",
-				false,
-				false,
-				amqp.Publishing{
-					ContentType: "application/json",
-					Body:        body,
-				},
-			)
-			if err != nil {
-				log.Printf("[CreateShipment] RabbitMQ publish error: %v", err)
-			} else {
-				log.Printf("[CreateShipment] Published shipment.created for tracking number: %s", input.TrackingNumber)
-			}
+			return "", err
+		}
+		if contact == nil {
+			return "sender_id not found in address book", nil
+		}
+		s.Sender = contact.Person()
+	} else if s.Sender == (model.ShipmentPerson{}) {
+		contact, err := contacts.FindDefaultSender(userID)
+		if err != nil {
+			return "", err
 		}
+		if contact != nil {
+			s.Sender = contact.Person()
+		}
+	}
 
-		c.JSON(http.StatusCreated, input)
+	if recipientID != "" {
+		contact, err := contacts.FindByID(userID, recipientID)
+		if err != nil {
+			return "", err
+		}
+		if contact == nil {
+			return "recipient_id not found in address book", nil
+		}
+		s.Recipient = contact.Person()
 	}
+	return "", nil
+}
+
+// errTrackingNumberExists is returned by createShipment for duplicate tracking numbers
+var errTrackingNumberExists = errors.New("tracking_number already exists")
+
+// createShipment stores a new shipment owned by userID and announces it through
+// RabbitMQ (shipment.created) and webhooks. Shared by single and bulk creation.
+// The promised delivery date is computed from the service level, route and holiday calendar.
+// Parcels of multi-parcel shipments get their piece tracking numbers here.
+func createShipment(repo *repository.ShipmentRepository, calendar *service.DeliveryCalendar, ch *amqp.Channel, hooks *service.WebhookDispatcher, input *model.Shipment, userID string) error {
+	// Validasi duplikat tracking_number
+	existingShipment, err := repo.FindByTrackingNumber(input.TrackingNumber)
+	if err != nil {
+		return err
+	}
+	if existingShipment != nil {
+		return errTrackingNumberExists
+	}
+
+	input.ID = uuid.New().String()
+	now := time.Now()
+	input.CreatedAt = now
+	input.UpdatedAt = now
+	input.UserID = userID
+	input.Status = model.StatusOnProcess // later statuses are reached through pickups, scans and POD only
+	input.Version = 1
+	input.Revisions = nil
+	input.Cancellation = nil
+	input.ProofOfDelivery = nil
+	input.ReturnTrackingNumbers = nil
+	input.PickupID = ""
+	input.CurrentHub = ""
+	input.ManifestID = ""
+	input.DriverID = ""
+	input.DeliveryAttempts = nil
+	input.NextAttempt = nil
+	input.DeliveryInstructions = ""
+	if err := service.ApplyServiceLevel(input); err != nil {
+		return err
+	}
+	promised, err := calendar.PromisedDate(input, now)
+	if err != nil {
+		return err
+	}
+	input.PromisedDate = promised
+	input.ETA = promised
+	input.SLABreachedAt = nil
+	input.Events = []model.TrackingEvent{{
+		Status:      input.Status,
+		Description: "shipment created",
+		Actor:       userID,
+		Timestamp:   now,
+	}}
+	service.NumberParcels(input, userID, now)
+	input.SearchKeys = service.ShipmentSearchKeys(input)
+
+	if err := repo.Insert(input); err != nil {
+		return err
+	}
+
+	// Publish ke RabbitMQ pakai channel yang sudah ada
+	publishEvent(ch, "shipment.created", input)
+	hooks.Enqueue(input.UserID, model.EventShipmentCreated, input)
+	return nil
 }
 
-// UpdateShipmentStatus menerima channel RabbitMQ sebagai argumen tambahan
-func UpdateShipmentStatus(repo *repository.ShipmentRepository, ch *amqp.Channel) gin.HandlerFunc {
+// UpdateShipmentStatus menerima channel RabbitMQ sebagai argumen tambahan.
+// Only couriers and ops can change the status, and only along model.StatusUpdateFrom; customers
+// cancel and edit their shipments through the dedicated endpoints.
+// Delivering a COD shipment records the collected cash in the COD ledger.
+func UpdateShipmentStatus(repo *repository.ShipmentRepository, codRepo *repository.CODRepository, ch *amqp.Channel, hooks *service.WebhookDispatcher) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		trackingNumber := c.Param("trackingNumber")
 		var req struct {
@@ -125,37 +243,72 @@ func UpdateShipmentStatus(repo *repository.ShipmentRepository, ch *amqp.Channel)
 			return
 		}
 
-		err := repo.UpdateStatus(trackingNumber, req.Status)
+		if req.Status == model.StatusCancelled {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "use POST /shipments/:trackingNumber/cancel to cancel a shipment"})
+			return
+		}
+		if req.Status == model.StatusPickedUp {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "shipments are picked up by completing their pickup, see PATCH /pickups/:id/status"})
+			return
+		}
+		allowedFrom, ok := model.StatusUpdateFrom[req.Status]
+		if !ok {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "status must be in_transit or delivered"})
+			return
+		}
+
+		principal, ok := requireStaff(c, "only couriers and ops can change the status of a shipment")
+		if !ok {
+			return
+		}
+
+		existing, err := repo.FindByTrackingNumber(trackingNumber)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch shipment"})
+			return
+		}
+		if existing == nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "shipment not found"})
+			return
+		}
+		if !hasStatus(existing, allowedFrom) {
+			c.JSON(http.StatusConflict, gin.H{"error": "a shipment can't move from " + existing.Status + " to " + req.Status})
+			return
+		}
+		if err := service.CheckShipmentScan(existing, req.Status); err != nil {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+
+		if req.Status == model.StatusDelivered {
+			if msg := checkDeliverable(existing); msg != "" {
+				c.JSON(http.StatusConflict, gin.H{"error": msg})
+				return
+			}
+		}
+
+		err = repo.UpdateStatusFrom(trackingNumber, allowedFrom, model.TrackingEvent{
+			Status: req.Status,
+			Actor:  principal.UserID,
+		})
+		if err == repository.ErrStatusConflict {
+			c.JSON(http.StatusConflict, gin.H{"error": "shipment status changed in the meantime, try again"})
+			return
+		}
 		if err != nil {
 			log.Printf("UpdateStatus error: %v", err)
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update status"})
 			return
 		}
 
-
 		shipment, err := repo.FindByTrackingNumber(trackingNumber)
 		if err != nil || shipment == nil {
 			log.Printf("[UpdateShipmentStatus] Warning: failed to find shipment after update: %v", err)
 		} else {
-			body, err := json.Marshal(shipment)
-			if err != nil {
-				log.Printf("[UpdateShipmentStatus] Marshal shipment error: %v", err)
-			} else {
-				err = ch.Publish(
-					"",
-					"shipment.updated",
-					false,
-					false,
-					amqp.Publishing{
-						ContentType: "application/json",
-						Body:        body,
-					},
-				)
-				if err != nil {
-					log.Printf("[UpdateShipmentStatus] RabbitMQ publish error: %v", err)
-				} else {
-					log.Printf("[UpdateShipmentStatus] Published shipment.updated for tracking number: %s", trackingNumber)
-				}
+			publishShipmentUpdated(ch, shipment)
+			hooks.Enqueue(shipment.UserID, model.EventShipmentUpdated, shipment)
+			if shipment.Status == model.StatusDelivered {
+				afterDelivered(repo, codRepo, ch, hooks, shipment, principal.UserID)
 			}
 		}
 
@@ -166,56 +319,125 @@ func UpdateShipmentStatus(repo *repository.ShipmentRepository, ch *amqp.Channel)
 
 
 // TrackShipment handles GET /shipments/:trackingNumber
+// Only the owner, couriers and ops can see a shipment; everyone else gets 404.
+// Linked return shipments are included in "returns", and the original of a return in "original".
 func TrackShipment(repo *repository.ShipmentRepository) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		trackingNumber := c.Param("trackingNumber")
 
+		principal, ok := currentPrincipal(c)
+		if !ok {
+			return
+		}
+
 		shipment, err := repo.FindByTrackingNumber(trackingNumber)
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch shipment"})
 			return
 		}
-		if shipment == nil {
+		// Shipment milik user lain diperlakukan sama seperti tidak ada
+		if !principal.CanAccessShipment(shipment) {
 			c.JSON(http.StatusNotFound, gin.H{"error": "shipment not found"})
 			return
 		}
 
-		c.JSON(http.StatusOK, shipment)
+		resp, err := withReturns(repo, shipment)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch linked shipments"})
+			return
+		}
+		c.JSON(http.StatusOK, resp)
 	}
 }
 
-// GetShipments handles GET /shipments to fetch all shipments for logged-in user
+// GetShipments handles GET /shipments to fetch shipments for logged-in user.
+// Supports filters (see parseShipmentFilter), sort=created_at|updated_at|tracking_number,
+// order=asc|desc (default desc), limit (default 20, max 100) and cursor for the next page.
 func GetShipments(repo *repository.ShipmentRepository) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		// Ambil claim JWT yang sudah disimpan oleh middleware JWTAuthMiddleware
-		claimsRaw, exists := c.Get("claims")
-		if !exists {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		// Ambil user dari claim JWT yang sudah disimpan oleh middleware JWTAuthMiddleware
+		principal, ok := currentPrincipal(c)
+		if !ok {
 			return
 		}
 
-		// Casting claim ke tipe jwt.MapClaims
-		claims, ok := claimsRaw.(jwt.MapClaims)
-		if !ok {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid token claims"})
+		filter, err := parseShipmentFilter(c)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 			return
 		}
+		filter.UserID = principal.UserID
 
-		// Ambil user_id dari claim
-		userID, ok := claims["user_id"].(string)
-		if !ok {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid user_id in claims"})
+		limit, err := strconv.ParseInt(c.DefaultQuery("limit", "20"), 10, 64)
+		if err != nil || limit < 1 || limit > 100 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be between 1 and 100"})
+			return
+		}
+		order := c.DefaultQuery("order", "desc")
+		if order != "asc" && order != "desc" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "order must be asc or desc"})
 			return
 		}
 
-		// Query MongoDB untuk mendapatkan shipment yang terkait dengan user_id ini
-		shipments, err := repo.FindByUserID(userID)
+		// Query MongoDB untuk mendapatkan satu halaman shipment milik user ini
+		page, err := repo.List(repository.ShipmentListOptions{
+			Filter: filter,
+			Sort:   c.DefaultQuery("sort", "created_at"),
+			Desc:   order == "desc",
+			Limit:  limit,
+			Cursor: c.Query("cursor"),
+		})
+		if err == repository.ErrInvalidCursor || err == repository.ErrInvalidSort {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
 		if err != nil {
+			log.Printf("[GetShipments] List error: %v", err)
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch shipments"})
 			return
 		}
 
-		// Kirimkan response JSON berisi list shipment
-		c.JSON(http.StatusOK, shipments)
+		// Kirimkan response JSON berisi list shipment beserta total dan cursor berikutnya
+		c.JSON(http.StatusOK, page)
+	}
+}
+
+// parseShipmentFilter reads the shipment list filters from the query string:
+// status, logistic_name, origin, destination, recipient_phone,
+// created_from and created_to (RFC3339 or YYYY-MM-DD; created_to is inclusive for dates).
+func parseShipmentFilter(c *gin.Context) (repository.ShipmentFilter, error) {
+	filter := repository.ShipmentFilter{
+		Status:         c.Query("status"),
+		LogisticName:   c.Query("logistic_name"),
+		Origin:         c.Query("origin"),
+		Destination:    c.Query("destination"),
+		RecipientPhone: c.Query("recipient_phone"),
+	}
+	if v := c.Query("created_from"); v != "" {
+		t, _, err := parseDateParam(v)
+		if err != nil {
+			return filter, fmt.Errorf("invalid created_from: %v", err)
+		}
+		filter.CreatedFrom = &t
+	}
+	if v := c.Query("created_to"); v != "" {
+		t, dateOnly, err := parseDateParam(v)
+		if err != nil {
+			return filter, fmt.Errorf("invalid created_to: %v", err)
+		}
+		if dateOnly {
+			t = t.AddDate(0, 0, 1) // include the whole day
+		}
+		filter.CreatedTo = &t
+	}
+	return filter, nil
+}
+
+// parseDateParam accepts RFC3339 timestamps or plain YYYY-MM-DD dates
+func parseDateParam(v string) (time.Time, bool, error) {
+	if t, err := time.Parse("2006-01-02", v); err == nil {
+		return t, true, nil
 	}
+	t, err := time.Parse(time.RFC3339, v)
+	return t, false, err
 }
@@ -0,0 +1,372 @@
+package handler
+
+import (
+	"log"
+	"logistic-service/internal/model"
+	"logistic-service/internal/repository"
+	"logistic-service/internal/service"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+// CreateManifest handles POST /manifests (couriers and ops).
+// Opens an empty manifest (bag) from origin_hub to destination_hub; add shipments with
+// POST /manifests/:id/items, then close, dispatch and receive it.
+func CreateManifest(hubs *repository.HubRepository) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req struct {
+			OriginHub      string `json:"origin_hub" binding:"required"`
+			DestinationHub string `json:"destination_hub" binding:"required"`
+		}
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if req.OriginHub == req.DestinationHub {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "origin_hub and destination_hub must differ"})
+			return
+		}
+		principal, ok := requireStaff(c, "only couriers and ops can manage manifests")
+		if !ok {
+			return
+		}
+		if _, ok := activeHub(c, hubs, req.OriginHub); !ok {
+			return
+		}
+		if _, ok := activeHub(c, hubs, req.DestinationHub); !ok {
+			return
+		}
+
+		now := time.Now()
+		manifest := &model.Manifest{
+			ID:              uuid.New().String(),
+			OriginHub:       req.OriginHub,
+			DestinationHub:  req.DestinationHub,
+			TrackingNumbers: []string{},
+			Status:          model.ManifestOpen,
+			CreatedBy:       principal.UserID,
+			CreatedAt:       now,
+			UpdatedAt:       now,
+		}
+		if err := hubs.InsertManifest(manifest); err != nil {
+			log.Printf("[CreateManifest] InsertManifest error: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create manifest"})
+			return
+		}
+		c.JSON(http.StatusCreated, manifest)
+	}
+}
+
+// ListManifests handles GET /manifests?hub=&status=&discrepancy=true&limit= (couriers and ops).
+// hub matches the origin or destination hub; discrepancy=true only returns received manifests
+// with missing or extra parcels.
+func ListManifests(hubs *repository.HubRepository) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if _, ok := requireStaff(c, "only couriers and ops can view manifests"); !ok {
+			return
+		}
+		limit, err := strconv.ParseInt(c.DefaultQuery("limit", "50"), 10, 64)
+		if err != nil || limit < 1 || limit > 200 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be between 1 and 200"})
+			return
+		}
+		manifests, err := hubs.ListManifests(repository.ManifestFilter{
+			Hub:            c.Query("hub"),
+			Status:         c.Query("status"),
+			HasDiscrepancy: c.Query("discrepancy") == "true",
+		}, limit)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch manifests"})
+			return
+		}
+		c.JSON(http.StatusOK, manifests)
+	}
+}
+
+// GetManifest handles GET /manifests/:id (couriers and ops)
+func GetManifest(hubs *repository.HubRepository) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		manifest, _, ok := staffManifest(c, hubs)
+		if !ok {
+			return
+		}
+		c.JSON(http.StatusOK, manifest)
+	}
+}
+
+// AddManifestItems handles POST /manifests/:id/items while the manifest is open.
+// Each shipment must have been scanned in at the origin hub and not be in another manifest;
+// the response lists the result of every tracking number.
+func AddManifestItems(hubs *repository.HubRepository, repo *repository.ShipmentRepository, ch *amqp.Channel, hooks *service.WebhookDispatcher) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req struct {
+			TrackingNumbers []string `json:"tracking_numbers" binding:"required"`
+		}
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		trackingNumbers := uniqueTrackingNumbers(req.TrackingNumbers)
+		if len(trackingNumbers) == 0 || len(trackingNumbers) > MaxScanBatch {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "tracking_numbers must have between 1 and 500 entries"})
+			return
+		}
+		manifest, _, ok := staffManifest(c, hubs)
+		if !ok {
+			return
+		}
+		if manifest.Status != model.ManifestOpen {
+			c.JSON(http.StatusConflict, gin.H{"error": "manifest is " + manifest.Status})
+			return
+		}
+
+		results := make([]ScanResult, 0, len(trackingNumbers))
+		var claimed []string
+		for _, tn := range trackingNumbers {
+			result := ScanResult{TrackingNumber: tn}
+			switch err := repo.ClaimForManifest(tn, manifest.ID, manifest.OriginHub); err {
+			case nil:
+				result.OK = true
+				claimed = append(claimed, tn)
+			case repository.ErrStatusConflict:
+				result.Error = manifestClaimError(repo, tn, manifest)
+			default:
+				log.Printf("[AddManifestItems] ClaimForManifest error for %s: %v", tn, err)
+				result.Error = "failed to add shipment"
+			}
+			results = append(results, result)
+		}
+
+		if len(claimed) > 0 {
+			err := hubs.AddToManifest(manifest.ID, claimed)
+			if err != nil {
+				for _, tn := range claimed {
+					if err := repo.ReleaseFromManifest(tn, manifest.ID); err != nil {
+						log.Printf("[AddManifestItems] ReleaseFromManifest error for %s: %v", tn, err)
+					}
+				}
+				if err == repository.ErrStatusConflict {
+					c.JSON(http.StatusConflict, gin.H{"error": "manifest was closed in the meantime"})
+					return
+				}
+				log.Printf("[AddManifestItems] AddToManifest error: %v", err)
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update manifest"})
+				return
+			}
+			announceShipments(repo, ch, hooks, claimed)
+		}
+
+		updated, err := hubs.FindManifest(manifest.ID)
+		if err != nil || updated == nil {
+			log.Printf("[AddManifestItems] Warning: failed to find manifest after update: %v", err)
+			updated = manifest
+		}
+		c.JSON(http.StatusOK, gin.H{"manifest": updated, "results": results})
+	}
+}
+
+// manifestClaimError explains why a shipment could not be added to manifest
+func manifestClaimError(repo *repository.ShipmentRepository, trackingNumber string, manifest *model.Manifest) string {
+	shipment, err := repo.FindByTrackingNumber(trackingNumber)
+	switch {
+	case err != nil:
+		return "failed to fetch shipment"
+	case shipment == nil:
+		return errScanNotFound.Error()
+	case shipment.ManifestID == manifest.ID:
+		return "shipment is already in this manifest"
+	case shipment.ManifestID != "":
+		return "shipment is in manifest " + shipment.ManifestID
+	case !hasStatus(shipment, model.HubScanStatuses):
+		return "shipment can't be bagged in status " + shipment.Status
+	case shipment.CurrentHub != manifest.OriginHub:
+		return "shipment was not scanned in at hub " + manifest.OriginHub
+	default:
+		return errScanConcurrent.Error()
+	}
+}
+
+// RemoveManifestItem handles DELETE /manifests/:id/items/:trackingNumber while the manifest is open
+func RemoveManifestItem(hubs *repository.HubRepository, repo *repository.ShipmentRepository, ch *amqp.Channel, hooks *service.WebhookDispatcher) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		manifest, _, ok := staffManifest(c, hubs)
+		if !ok {
+			return
+		}
+		trackingNumber := c.Param("trackingNumber")
+		found := false
+		for _, tn := range manifest.TrackingNumbers {
+			found = found || tn == trackingNumber
+		}
+		if !found {
+			c.JSON(http.StatusNotFound, gin.H{"error": "shipment is not in this manifest"})
+			return
+		}
+
+		err := hubs.RemoveFromManifest(manifest.ID, trackingNumber)
+		if err == repository.ErrStatusConflict {
+			c.JSON(http.StatusConflict, gin.H{"error": "shipments can only be removed from an open manifest"})
+			return
+		}
+		if err != nil {
+			log.Printf("[RemoveManifestItem] RemoveFromManifest error: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update manifest"})
+			return
+		}
+		if err := repo.ReleaseFromManifest(trackingNumber, manifest.ID); err != nil {
+			log.Printf("[RemoveManifestItem] ReleaseFromManifest error: %v", err)
+		}
+		announceShipments(repo, ch, hooks, []string{trackingNumber})
+		c.JSON(http.StatusOK, gin.H{"message": "shipment removed from manifest"})
+	}
+}
+
+// CloseManifest handles POST /manifests/:id/close: seals an open manifest with at least one shipment
+func CloseManifest(hubs *repository.HubRepository) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		manifest, _, ok := staffManifest(c, hubs)
+		if !ok {
+			return
+		}
+		if len(manifest.TrackingNumbers) == 0 {
+			c.JSON(http.StatusConflict, gin.H{"error": "an empty manifest can't be closed"})
+			return
+		}
+		if !transitionManifest(c, hubs, manifest, model.ManifestOpen, model.ManifestClosed, "", nil) {
+			return
+		}
+		respondManifest(c, hubs, manifest.ID)
+	}
+}
+
+// DispatchManifest handles POST /manifests/:id/dispatch: the closed manifest leaves the origin hub
+// and every shipment in it gets an outbound scan there. Shipments that can no longer be scanned
+// (e.g. cancelled) are skipped and show up as missing when the manifest is received.
+func DispatchManifest(hubs *repository.HubRepository, repo *repository.ShipmentRepository, ch *amqp.Channel, hooks *service.WebhookDispatcher) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		manifest, principal, ok := staffManifest(c, hubs)
+		if !ok {
+			return
+		}
+		origin, ok := activeHub(c, hubs, manifest.OriginHub)
+		if !ok {
+			return
+		}
+		if !transitionManifest(c, hubs, manifest, model.ManifestClosed, model.ManifestDispatched, "", nil) {
+			return
+		}
+
+		for _, tn := range manifest.TrackingNumbers {
+			err := scanShipment(repo, ch, hooks, origin, model.ScanOutbound, tn, principal.UserID, "in manifest "+manifest.ID)
+			if err != nil {
+				log.Printf("[DispatchManifest] outbound scan of %s skipped: %v", tn, err)
+			}
+		}
+		respondManifest(c, hubs, manifest.ID)
+	}
+}
+
+// ReceiveManifest handles POST /manifests/:id/receive with the tracking numbers scanned when the
+// dispatched manifest is opened at the destination hub. Every scanned shipment gets an inbound scan
+// there, the shipments are released from the manifest and parcels missing from the bag or found in
+// it without being listed are recorded in the manifest discrepancy report.
+func ReceiveManifest(hubs *repository.HubRepository, repo *repository.ShipmentRepository, ch *amqp.Channel, hooks *service.WebhookDispatcher) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req struct {
+			TrackingNumbers []string `json:"tracking_numbers"`
+		}
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		scanned := uniqueTrackingNumbers(req.TrackingNumbers)
+		if len(scanned) > MaxScanBatch {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "too many tracking numbers in one scan"})
+			return
+		}
+		manifest, principal, ok := staffManifest(c, hubs)
+		if !ok {
+			return
+		}
+		destination, ok := activeHub(c, hubs, manifest.DestinationHub)
+		if !ok {
+			return
+		}
+
+		discrepancy := service.CompareManifest(manifest.TrackingNumbers, scanned)
+		if !transitionManifest(c, hubs, manifest, model.ManifestDispatched, model.ManifestReceived, principal.UserID, discrepancy) {
+			return
+		}
+
+		for _, tn := range scanned {
+			err := scanShipment(repo, ch, hooks, destination, model.ScanInbound, tn, principal.UserID, "from manifest "+manifest.ID)
+			if err != nil {
+				log.Printf("[ReceiveManifest] inbound scan of %s skipped: %v", tn, err)
+			}
+		}
+		for _, tn := range manifest.TrackingNumbers {
+			if err := repo.ReleaseFromManifest(tn, manifest.ID); err != nil {
+				log.Printf("[ReceiveManifest] ReleaseFromManifest error for %s: %v", tn, err)
+			}
+		}
+		if discrepancy != nil {
+			log.Printf("[ReceiveManifest] manifest %s received with %d missing and %d extra parcels",
+				manifest.ID, len(discrepancy.Missing), len(discrepancy.Extra))
+		}
+		respondManifest(c, hubs, manifest.ID)
+	}
+}
+
+// staffManifest loads the manifest in the :id parameter for a courier or ops caller
+func staffManifest(c *gin.Context, hubs *repository.HubRepository) (*model.Manifest, *service.Principal, bool) {
+	principal, ok := requireStaff(c, "only couriers and ops can manage manifests")
+	if !ok {
+		return nil, nil, false
+	}
+	manifest, err := hubs.FindManifest(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch manifest"})
+		return nil, nil, false
+	}
+	if manifest == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "manifest not found"})
+		return nil, nil, false
+	}
+	return manifest, principal, true
+}
+
+// transitionManifest moves manifest from status from to status to, writing a 409 when it isn't in
+// status from (anymore)
+func transitionManifest(c *gin.Context, hubs *repository.HubRepository, manifest *model.Manifest, from, to, receivedBy string, discrepancy *model.ManifestDiscrepancy) bool {
+	if manifest.Status != from {
+		c.JSON(http.StatusConflict, gin.H{"error": "manifest is " + manifest.Status + ", expected " + from})
+		return false
+	}
+	err := hubs.SetManifestStatus(manifest.ID, from, to, time.Now(), receivedBy, discrepancy)
+	if err == repository.ErrStatusConflict {
+		c.JSON(http.StatusConflict, gin.H{"error": "manifest was modified by someone else, retry"})
+		return false
+	}
+	if err != nil {
+		log.Printf("[transitionManifest] SetManifestStatus error: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update manifest"})
+		return false
+	}
+	return true
+}
+
+// respondManifest writes the current state of the manifest after a change
+func respondManifest(c *gin.Context, hubs *repository.HubRepository, id string) {
+	manifest, err := hubs.FindManifest(id)
+	if err != nil || manifest == nil {
+		log.Printf("[respondManifest] Warning: failed to find manifest after update: %v", err)
+		c.JSON(http.StatusOK, gin.H{"message": "manifest updated"})
+		return
+	}
+	c.JSON(http.StatusOK, manifest)
+}
@@ -0,0 +1,120 @@
+package handler
+
+import (
+	"fmt"
+	"log"
+	"logistic-service/internal/model"
+	"logistic-service/internal/repository"
+	"logistic-service/internal/service"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+// UpdateParcelStatus handles PATCH /shipments/:trackingNumber/parcels/:parcelTrackingNumber/status
+// (couriers and ops). Scans one parcel of a multi-parcel shipment to picked_up, in_transit or
+// delivered. The shipment moves to the status of its least advanced parcel and gets a tracking
+// event for every scan, so partial deliveries show on its timeline. The scan delivering the
+// last parcel has the same requirements and effects as PATCH /shipments/:trackingNumber/status.
+func UpdateParcelStatus(repo *repository.ShipmentRepository, codRepo *repository.CODRepository, ch *amqp.Channel, hooks *service.WebhookDispatcher) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req struct {
+			Status   string `json:"status" binding:"required"`
+			Location string `json:"location"`
+			HubCode  string `json:"hub_code"`
+			Note     string `json:"note"`
+		}
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		principal, ok := requireStaff(c, "only couriers and ops can scan parcels")
+		if !ok {
+			return
+		}
+
+		trackingNumber := c.Param("trackingNumber")
+		shipment, err := repo.FindByTrackingNumber(trackingNumber)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch shipment"})
+			return
+		}
+		if shipment == nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "shipment not found"})
+			return
+		}
+		parcel := service.FindParcel(shipment, c.Param("parcelTrackingNumber"))
+		if parcel == nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "parcel not found in this shipment"})
+			return
+		}
+		if err := service.CheckParcelScan(parcel, req.Status); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if !hasStatus(shipment, model.ParcelScanShipmentStatuses) {
+			c.JSON(http.StatusConflict, gin.H{"error": "parcels can only be scanned between pickup and delivery, the shipment is " + shipment.Status})
+			return
+		}
+
+		parcelTrackingNumber := parcel.TrackingNumber
+		parcel.Status = req.Status
+		status := service.ParcelShipmentStatus(shipment.Parcels)
+		if status == model.StatusDelivered {
+			if msg := checkDeliverable(shipment); msg != "" {
+				c.JSON(http.StatusConflict, gin.H{"error": msg})
+				return
+			}
+		}
+		if hasStatus(shipment, model.ParcelStatusesAtOrPast(status)) {
+			status = shipment.Status // the shipment never moves back, e.g. picked up as a whole
+		}
+
+		now := time.Now()
+		progress := service.CountParcels(shipment)
+		parcelEvent := model.TrackingEvent{
+			Status:      req.Status,
+			Description: strings.ReplaceAll(req.Status, "_", " "),
+			Note:        strings.TrimSpace(req.Note),
+			Location:    strings.TrimSpace(req.Location),
+			HubCode:     strings.TrimSpace(req.HubCode),
+			Actor:       principal.UserID,
+			Timestamp:   now,
+		}
+		event := parcelEvent
+		event.Status = status
+		event.Description = fmt.Sprintf("parcel %d of %d %s (%d of %d delivered)",
+			parcel.PieceNumber, progress.Total, strings.ReplaceAll(req.Status, "_", " "), progress.Delivered, progress.Total)
+
+		err = repo.UpdateParcelStatus(trackingNumber, shipment.Version, model.ParcelScanShipmentStatuses, parcelTrackingNumber, parcelEvent, event)
+		if err == repository.ErrVersionConflict {
+			c.JSON(http.StatusConflict, gin.H{"error": "shipment was updated in the meantime, scan the parcel again"})
+			return
+		}
+		if err == repository.ErrStatusConflict {
+			c.JSON(http.StatusConflict, gin.H{"error": "shipment status no longer allows parcel scans"})
+			return
+		}
+		if err != nil {
+			log.Printf("[UpdateParcelStatus] UpdateParcelStatus error: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update parcel status"})
+			return
+		}
+
+		shipment, err = repo.FindByTrackingNumber(trackingNumber)
+		if err != nil || shipment == nil {
+			log.Printf("[UpdateParcelStatus] Warning: failed to find shipment after update: %v", err)
+			c.JSON(http.StatusOK, gin.H{"message": "parcel status updated"})
+			return
+		}
+		publishShipmentUpdated(ch, shipment)
+		hooks.Enqueue(shipment.UserID, model.EventShipmentUpdated, shipment)
+		if shipment.Status == model.StatusDelivered {
+			afterDelivered(repo, codRepo, ch, hooks, shipment, principal.UserID)
+		}
+		c.JSON(http.StatusOK, shipment)
+	}
+}
@@ -0,0 +1,416 @@
+package handler
+
+import (
+	"log"
+	"logistic-service/internal/model"
+	"logistic-service/internal/repository"
+	"logistic-service/internal/service"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+// CreatePickup handles POST /pickups.
+// Books a courier pickup of one or more on_process shipments at their sender address in a time
+// slot (date up to 7 days ahead, WIB). All shipments must share courier and sender address, and
+// the slot must have capacity left for that courier in the sender's area.
+func CreatePickup(pickups *repository.PickupRepository, repo *repository.ShipmentRepository, ch *amqp.Channel, hooks *service.WebhookDispatcher) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req struct {
+			TrackingNumbers []string `json:"tracking_numbers" binding:"required"`
+			Date            string   `json:"date" binding:"required"`
+			TimeSlot        string   `json:"time_slot" binding:"required"`
+			Notes           string   `json:"notes"`
+		}
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		now := time.Now()
+		schedule, err := service.ValidateSchedule(req.Date, req.TimeSlot, now)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		principal, ok := currentPrincipal(c)
+		if !ok {
+			return
+		}
+
+		seen := make(map[string]bool)
+		var shipments []*model.Shipment
+		for _, tn := range req.TrackingNumbers {
+			tn = strings.TrimSpace(tn)
+			if tn == "" || seen[tn] {
+				continue
+			}
+			seen[tn] = true
+			shipment, err := repo.FindByTrackingNumber(tn)
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch shipment"})
+				return
+			}
+			if !principal.CanAccessShipment(shipment) {
+				c.JSON(http.StatusNotFound, gin.H{"error": "shipment " + tn + " not found"})
+				return
+			}
+			if !hasStatus(shipment, model.PickupStatuses) {
+				c.JSON(http.StatusConflict, gin.H{"error": "shipment " + tn + " can't be picked up in status " + shipment.Status})
+				return
+			}
+			if shipment.PickupID != "" {
+				c.JSON(http.StatusConflict, gin.H{"error": "shipment " + tn + " is already booked in a pickup", "pickup_id": shipment.PickupID})
+				return
+			}
+			shipments = append(shipments, shipment)
+		}
+
+		pickup, err := service.NewPickup(shipments, schedule)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		pickup.ID = uuid.New().String()
+		pickup.Notes = strings.TrimSpace(req.Notes)
+		pickup.CreatedAt = now
+		pickup.UpdatedAt = now
+		pickup.Events = []model.PickupEvent{{Status: model.PickupScheduled, Actor: principal.UserID, Timestamp: now}}
+
+		capacity, err := pickups.Capacity(pickup.LogisticName, pickup.AreaCode, service.PickupSlotCapacity())
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch pickup capacity"})
+			return
+		}
+		err = pickups.BookSlot(pickup.LogisticName, pickup.AreaCode, pickup.Date, pickup.TimeSlot, capacity)
+		if err == repository.ErrSlotFull {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+		if err != nil {
+			log.Printf("[CreatePickup] BookSlot error: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to book pickup slot"})
+			return
+		}
+
+		// Claim the shipments so a concurrent request can't book them in another pickup
+		var claimed []string
+		for _, tn := range pickup.TrackingNumbers {
+			if err = repo.ClaimForPickup(tn, pickup.ID, model.PickupStatuses); err != nil {
+				break
+			}
+			claimed = append(claimed, tn)
+		}
+		if err == nil {
+			err = pickups.Insert(pickup)
+		}
+		if err != nil {
+			releasePickup(pickups, repo, pickup, claimed)
+			if err == repository.ErrStatusConflict {
+				c.JSON(http.StatusConflict, gin.H{"error": "shipment was modified by someone else, retry"})
+				return
+			}
+			log.Printf("[CreatePickup] error: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create pickup"})
+			return
+		}
+
+		announceShipments(repo, ch, hooks, pickup.TrackingNumbers)
+		publishEvent(ch, "pickup.updated", pickup)
+		c.JSON(http.StatusCreated, pickup)
+	}
+}
+
+// ListPickups handles GET /pickups?status=&date=&limit=
+// Customers see their own pickups; couriers and ops see all of them and can also filter by
+// logistic_name, area_code and courier_id.
+func ListPickups(pickups *repository.PickupRepository) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		principal, ok := currentPrincipal(c)
+		if !ok {
+			return
+		}
+		limit, err := strconv.ParseInt(c.DefaultQuery("limit", "50"), 10, 64)
+		if err != nil || limit < 1 || limit > 200 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be between 1 and 200"})
+			return
+		}
+
+		filter := repository.PickupFilter{Status: c.Query("status"), Date: c.Query("date")}
+		if principal.IsStaff() {
+			filter.LogisticName = c.Query("logistic_name")
+			filter.AreaCode = c.Query("area_code")
+			filter.CourierID = c.Query("courier_id")
+		} else {
+			filter.UserID = principal.UserID
+		}
+		results, err := pickups.List(filter, limit)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch pickups"})
+			return
+		}
+		c.JSON(http.StatusOK, results)
+	}
+}
+
+// GetPickup handles GET /pickups/:id
+func GetPickup(pickups *repository.PickupRepository) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		pickup, _, ok := ownedPickup(c, pickups)
+		if !ok {
+			return
+		}
+		c.JSON(http.StatusOK, pickup)
+	}
+}
+
+// UpdatePickupStatus handles PATCH /pickups/:id/status.
+// Couriers and ops move a pickup through assigned, completed or failed; customers can only
+// cancel their own pickup while it is scheduled. Completing moves the included shipments that
+// are still on_process to picked_up; failing or cancelling releases them and the slot.
+func UpdatePickupStatus(pickups *repository.PickupRepository, repo *repository.ShipmentRepository, ch *amqp.Channel, hooks *service.WebhookDispatcher) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req struct {
+			Status    string `json:"status" binding:"required"`
+			CourierID string `json:"courier_id"`
+			Note      string `json:"note"`
+		}
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		pickup, principal, ok := ownedPickup(c, pickups)
+		if !ok {
+			return
+		}
+
+		if !principal.IsStaff() && (req.Status != model.PickupCancelled || pickup.Status != model.PickupScheduled) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "customers can only cancel a scheduled pickup"})
+			return
+		}
+		if !service.CanTransitionPickup(pickup.Status, req.Status) {
+			c.JSON(http.StatusConflict, gin.H{"error": "pickup can't move from " + pickup.Status + " to " + req.Status})
+			return
+		}
+		courierID := ""
+		if req.Status == model.PickupAssigned {
+			courierID = req.CourierID
+			if courierID == "" && principal.Role == service.RoleCourier {
+				courierID = principal.UserID
+			}
+			if courierID == "" {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "courier_id is required"})
+				return
+			}
+		}
+		if req.Status == model.PickupFailed && strings.TrimSpace(req.Note) == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "note is required when a pickup fails"})
+			return
+		}
+
+		now := time.Now()
+		err := pickups.UpdateStatus(pickup.ID, []string{pickup.Status}, courierID, model.PickupEvent{
+			Status:    req.Status,
+			Note:      strings.TrimSpace(req.Note),
+			Actor:     principal.UserID,
+			Timestamp: now,
+		})
+		if err == repository.ErrStatusConflict {
+			c.JSON(http.StatusConflict, gin.H{"error": "pickup was modified by someone else, retry"})
+			return
+		}
+		if err != nil {
+			log.Printf("[UpdatePickupStatus] UpdateStatus error: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update pickup"})
+			return
+		}
+
+		switch req.Status {
+		case model.PickupCompleted:
+			var pickedUp []string
+			for _, tn := range pickup.TrackingNumbers {
+				err := repo.MarkPickedUp(tn, pickup.ID, model.TrackingEvent{
+					Status:      model.StatusPickedUp,
+					Description: "picked up in pickup " + pickup.ID,
+					Actor:       principal.UserID,
+					Timestamp:   now,
+				})
+				if err == repository.ErrStatusConflict {
+					log.Printf("[UpdatePickupStatus] %s is no longer waiting for pickup, skipped", tn)
+					continue
+				}
+				if err != nil {
+					log.Printf("[UpdatePickupStatus] MarkPickedUp error for %s: %v", tn, err)
+					continue
+				}
+				pickedUp = append(pickedUp, tn)
+			}
+			announceShipments(repo, ch, hooks, pickedUp)
+		case model.PickupFailed, model.PickupCancelled:
+			releasePickup(pickups, repo, pickup, pickup.TrackingNumbers)
+			announceShipments(repo, ch, hooks, pickup.TrackingNumbers)
+		}
+
+		result, err := pickups.FindByID(pickup.ID)
+		if err != nil || result == nil {
+			log.Printf("[UpdatePickupStatus] Warning: failed to find pickup after update: %v", err)
+			c.JSON(http.StatusOK, gin.H{"message": "pickup updated"})
+			return
+		}
+		publishEvent(ch, "pickup.updated", result)
+		c.JSON(http.StatusOK, result)
+	}
+}
+
+// GetPickupSlots handles GET /pickups/slots?date=&logistic_name=&area_code=
+// Returns the booked pickups and remaining capacity of every time slot. Instead of logistic_name
+// and area_code, tracking_number takes both from a shipment the caller can access.
+func GetPickupSlots(pickups *repository.PickupRepository, repo *repository.ShipmentRepository) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		date := c.Query("date")
+		if _, err := time.Parse("2006-01-02", date); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "date must be formatted as YYYY-MM-DD"})
+			return
+		}
+		logisticName, areaCode := c.Query("logistic_name"), c.Query("area_code")
+		if tn := c.Query("tracking_number"); tn != "" {
+			principal, ok := currentPrincipal(c)
+			if !ok {
+				return
+			}
+			shipment, err := repo.FindByTrackingNumber(tn)
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch shipment"})
+				return
+			}
+			if !principal.CanAccessShipment(shipment) {
+				c.JSON(http.StatusNotFound, gin.H{"error": "shipment not found"})
+				return
+			}
+			logisticName, areaCode = shipment.LogisticName, service.PickupArea(shipment)
+		}
+		if logisticName == "" || areaCode == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "logistic_name and area_code, or tracking_number, are required"})
+			return
+		}
+
+		capacity, err := pickups.Capacity(logisticName, areaCode, service.PickupSlotCapacity())
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch pickup capacity"})
+			return
+		}
+		booked, err := pickups.BookedSlots(logisticName, areaCode, date)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch pickup slots"})
+			return
+		}
+		slots := []model.PickupSlot{}
+		for _, timeSlot := range model.DeliverySlots {
+			slot := model.PickupSlot{
+				LogisticName: logisticName,
+				AreaCode:     areaCode,
+				Date:         date,
+				TimeSlot:     timeSlot,
+				Booked:       booked[timeSlot],
+				Capacity:     capacity,
+			}
+			if slot.Available = capacity - slot.Booked; slot.Available < 0 {
+				slot.Available = 0
+			}
+			slots = append(slots, slot)
+		}
+		c.JSON(http.StatusOK, slots)
+	}
+}
+
+// SetPickupCapacity handles PUT /pickups/capacity (ops only).
+// Sets how many pickups a courier takes per time slot in an area, overriding PICKUP_SLOT_CAPACITY.
+// Lowering it doesn't cancel pickups already booked.
+func SetPickupCapacity(pickups *repository.PickupRepository) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req struct {
+			LogisticName string `json:"logistic_name" binding:"required"`
+			AreaCode     string `json:"area_code" binding:"required"`
+			Capacity     *int   `json:"capacity" binding:"required"`
+		}
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if *req.Capacity < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "capacity must not be negative"})
+			return
+		}
+		principal, ok := currentPrincipal(c)
+		if !ok {
+			return
+		}
+		if principal.Role != service.RoleOps {
+			c.JSON(http.StatusForbidden, gin.H{"error": "only ops can set pickup capacity"})
+			return
+		}
+
+		capacity := &model.PickupCapacity{
+			LogisticName: req.LogisticName,
+			AreaCode:     req.AreaCode,
+			Capacity:     *req.Capacity,
+			UpdatedBy:    principal.UserID,
+			UpdatedAt:    time.Now(),
+		}
+		if err := pickups.SetCapacity(capacity); err != nil {
+			log.Printf("[SetPickupCapacity] SetCapacity error: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to set pickup capacity"})
+			return
+		}
+		c.JSON(http.StatusOK, capacity)
+	}
+}
+
+// ownedPickup loads the pickup in the :id parameter. Customers only see their own pickups;
+// everyone else gets 404.
+func ownedPickup(c *gin.Context, pickups *repository.PickupRepository) (*model.Pickup, *service.Principal, bool) {
+	principal, ok := currentPrincipal(c)
+	if !ok {
+		return nil, nil, false
+	}
+	pickup, err := pickups.FindByID(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch pickup"})
+		return nil, nil, false
+	}
+	if pickup == nil || (!principal.IsStaff() && pickup.UserID != principal.UserID) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "pickup not found"})
+		return nil, nil, false
+	}
+	return pickup, principal, true
+}
+
+// releasePickup takes the given shipments out of the pickup and gives back its slot
+func releasePickup(pickups *repository.PickupRepository, repo *repository.ShipmentRepository, pickup *model.Pickup, trackingNumbers []string) {
+	for _, tn := range trackingNumbers {
+		if err := repo.ReleaseFromPickup(tn, pickup.ID); err != nil {
+			log.Printf("[releasePickup] ReleaseFromPickup error for %s: %v", tn, err)
+		}
+	}
+	if err := pickups.ReleaseSlot(pickup.LogisticName, pickup.AreaCode, pickup.Date, pickup.TimeSlot); err != nil {
+		log.Printf("[releasePickup] ReleaseSlot error: %v", err)
+	}
+}
+
+// announceShipments publishes shipment.updated and the webhook for each changed shipment
+func announceShipments(repo *repository.ShipmentRepository, ch *amqp.Channel, hooks *service.WebhookDispatcher, trackingNumbers []string) {
+	for _, tn := range trackingNumbers {
+		shipment, err := repo.FindByTrackingNumber(tn)
+		if err != nil || shipment == nil {
+			log.Printf("[announceShipments] Warning: failed to find shipment %s: %v", tn, err)
+			continue
+		}
+		publishShipmentUpdated(ch, shipment)
+		hooks.Enqueue(shipment.UserID, model.EventShipmentUpdated, shipment)
+	}
+}
@@ -0,0 +1,304 @@
+package handler
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"logistic-service/internal/model"
+	"logistic-service/internal/repository"
+	"logistic-service/internal/service"
+	"mime/multipart"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+// Maximum size of each proof of delivery image
+const maxPODImageSize = 5 << 20 // 5 MB
+
+// Accepted image types and the file extension used for their blob keys
+var podImageTypes = map[string]string{
+	"image/jpeg": ".jpg",
+	"image/png":  ".png",
+}
+
+const podStatusConflict = "proof of delivery can only be captured while the shipment is picked_up or in_transit"
+
+// CaptureProofOfDelivery handles POST /shipments/:trackingNumber/pod (multipart).
+// Couriers and ops upload the "photo" and "signature" images together with receiver_name,
+// latitude and longitude while the shipment is picked_up or in_transit. COD shipments also need
+// cod_collected_amount. The shipment can be marked delivered only after this.
+func CaptureProofOfDelivery(repo *repository.ShipmentRepository, blobs service.BlobStorage, ch *amqp.Channel, hooks *service.WebhookDispatcher) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		trackingNumber := c.Param("trackingNumber")
+
+		principal, ok := currentPrincipal(c)
+		if !ok {
+			return
+		}
+		shipment, err := repo.FindByTrackingNumber(trackingNumber)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch shipment"})
+			return
+		}
+		if !principal.CanAccessShipment(shipment) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "shipment not found"})
+			return
+		}
+		if !principal.IsStaff() {
+			c.JSON(http.StatusForbidden, gin.H{"error": "only couriers and ops can capture proof of delivery"})
+			return
+		}
+
+		// Checked again atomically when saving; this early check avoids storing images for nothing
+		if !hasStatus(shipment, model.PODCaptureStatuses) {
+			c.JSON(http.StatusConflict, gin.H{"error": podStatusConflict})
+			return
+		}
+
+		c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, 2*maxPODImageSize+1<<20)
+		receiverName := strings.TrimSpace(c.PostForm("receiver_name"))
+		if receiverName == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "receiver_name is required"})
+			return
+		}
+		latitude, err := parseCoordinate(c.PostForm("latitude"), 90)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "latitude " + err.Error()})
+			return
+		}
+		longitude, err := parseCoordinate(c.PostForm("longitude"), 180)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "longitude " + err.Error()})
+			return
+		}
+
+		// COD shipments: the courier confirms the cash collected, which must match the COD amount
+		var cod *model.CashOnDelivery
+		if shipment.COD != nil {
+			collected, err := strconv.ParseInt(c.PostForm("cod_collected_amount"), 10, 64)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "cod_collected_amount is required for COD shipments"})
+				return
+			}
+			if collected != shipment.COD.Amount {
+				c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("cod_collected_amount must equal the COD amount %d %s", shipment.COD.Amount, shipment.COD.Currency)})
+				return
+			}
+			cod = shipment.COD
+		}
+
+		pod := model.ProofOfDelivery{
+			ReceiverName: receiverName,
+			Latitude:     latitude,
+			Longitude:    longitude,
+			CapturedBy:   principal.UserID,
+			CapturedAt:   time.Now(),
+		}
+		// Keys are unique per capture, so a re-capture never overwrites the evidence of an earlier one
+		captureID := uuid.New().String()
+		pod.PhotoKey, pod.PhotoContentType, err = storePODImage(c, blobs, trackingNumber, captureID, "photo")
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		pod.SignatureKey, pod.SignatureContentType, err = storePODImage(c, blobs, trackingNumber, captureID, "signature")
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		if cod != nil {
+			cod.CollectedAmount = cod.Amount
+			cod.CollectedBy = principal.UserID
+			cod.CollectedAt = &pod.CapturedAt
+		}
+		err = repo.SetProofOfDelivery(trackingNumber, model.PODCaptureStatuses, pod, cod)
+		if err == repository.ErrStatusConflict {
+			c.JSON(http.StatusConflict, gin.H{"error": podStatusConflict})
+			return
+		}
+		if err != nil {
+			log.Printf("[CaptureProofOfDelivery] SetProofOfDelivery error: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to save proof of delivery"})
+			return
+		}
+
+		updated, err := repo.FindByTrackingNumber(trackingNumber)
+		if err != nil || updated == nil {
+			log.Printf("[CaptureProofOfDelivery] Warning: failed to find shipment after update: %v", err)
+		} else {
+			publishShipmentUpdated(ch, updated)
+			hooks.Enqueue(updated.UserID, model.EventShipmentUpdated, updated)
+		}
+
+		c.JSON(http.StatusCreated, podResponse(trackingNumber, &pod))
+	}
+}
+
+// GetProofOfDelivery handles GET /shipments/:trackingNumber/pod and returns the POD details
+// with links to the photo and signature.
+func GetProofOfDelivery(repo *repository.ShipmentRepository) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		shipment, ok := shipmentWithPOD(c, repo)
+		if !ok {
+			return
+		}
+		c.JSON(http.StatusOK, podResponse(shipment.TrackingNumber, shipment.ProofOfDelivery))
+	}
+}
+
+// GetProofOfDeliveryImage handles GET /shipments/:trackingNumber/pod/photo and
+// GET /shipments/:trackingNumber/pod/signature.
+func GetProofOfDeliveryImage(repo *repository.ShipmentRepository, blobs service.BlobStorage, kind string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		shipment, ok := shipmentWithPOD(c, repo)
+		if !ok {
+			return
+		}
+		pod := shipment.ProofOfDelivery
+		key, contentType := pod.PhotoKey, pod.PhotoContentType
+		if kind == "signature" {
+			key, contentType = pod.SignatureKey, pod.SignatureContentType
+		}
+
+		r, err := blobs.Get(c.Request.Context(), key)
+		if errors.Is(err, service.ErrBlobNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": kind + " not found"})
+			return
+		}
+		if err != nil {
+			log.Printf("[GetProofOfDeliveryImage] Get %s error: %v", key, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to read " + kind})
+			return
+		}
+		defer r.Close()
+
+		c.Header("Cache-Control", "private, max-age=3600")
+		c.DataFromReader(http.StatusOK, -1, contentType, r, nil)
+	}
+}
+
+// shipmentWithPOD loads the :trackingNumber shipment for the caller and checks it has a POD
+func shipmentWithPOD(c *gin.Context, repo *repository.ShipmentRepository) (*model.Shipment, bool) {
+	principal, ok := currentPrincipal(c)
+	if !ok {
+		return nil, false
+	}
+	shipment, err := repo.FindByTrackingNumber(c.Param("trackingNumber"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch shipment"})
+		return nil, false
+	}
+	if !principal.CanAccessShipment(shipment) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "shipment not found"})
+		return nil, false
+	}
+	if shipment.ProofOfDelivery == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "proof of delivery not found"})
+		return nil, false
+	}
+	return shipment, true
+}
+
+func podResponse(trackingNumber string, pod *model.ProofOfDelivery) gin.H {
+	return gin.H{
+		"tracking_number": trackingNumber,
+		"receiver_name":   pod.ReceiverName,
+		"latitude":        pod.Latitude,
+		"longitude":       pod.Longitude,
+		"captured_by":     pod.CapturedBy,
+		"captured_at":     pod.CapturedAt,
+		"photo_url":       "/shipments/" + trackingNumber + "/pod/photo",
+		"signature_url":   "/shipments/" + trackingNumber + "/pod/signature",
+	}
+}
+
+// storePODImage validates the multipart image in field and stores it, returning key and content type
+func storePODImage(c *gin.Context, blobs service.BlobStorage, trackingNumber, captureID, field string) (string, string, error) {
+	fileHeader, err := c.FormFile(field)
+	if err != nil {
+		return "", "", fmt.Errorf("%s image is required", field)
+	}
+	if fileHeader.Size > maxPODImageSize {
+		return "", "", fmt.Errorf("%s is larger than 5 MB", field)
+	}
+	data, err := readFormFile(fileHeader)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to read %s", field)
+	}
+	// Trust the content, not the client supplied Content-Type
+	contentType := http.DetectContentType(data)
+	ext, ok := podImageTypes[contentType]
+	if !ok {
+		return "", "", fmt.Errorf("%s must be a JPEG or PNG image", field)
+	}
+
+	key := "pod/" + trackingNumber + "/" + captureID + "-" + field + ext
+	if err := blobs.Put(c.Request.Context(), key, bytes.NewReader(data)); err != nil {
+		log.Printf("[storePODImage] Put %s error: %v", key, err)
+		return "", "", fmt.Errorf("failed to store %s", field)
+	}
+	return key, contentType, nil
+}
+
+func readFormFile(fileHeader *multipart.FileHeader) ([]byte, error) {
+	f, err := fileHeader.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return io.ReadAll(f)
+}
+
+// parseCoordinate parses a latitude (limit 90) or longitude (limit 180) in decimal degrees
+func parseCoordinate(value string, limit float64) (float64, error) {
+	if value == "" {
+		return 0, errors.New("is required")
+	}
+	v, err := strconv.ParseFloat(value, 64)
+	if err != nil || v < -limit || v > limit {
+		return 0, fmt.Errorf("must be a number between -%g and %g", limit, limit)
+	}
+	return v, nil
+}
+
+// checkDeliverable returns why shipment can't be marked delivered yet, or "". Delivery must be
+// backed by evidence, see POST /shipments/:trackingNumber/pod.
+func checkDeliverable(shipment *model.Shipment) string {
+	if shipment.ProofOfDelivery == nil {
+		return "proof of delivery is required before marking the shipment delivered"
+	}
+	if shipment.COD != nil && shipment.COD.CollectedAt == nil {
+		return "COD collection must be confirmed with the proof of delivery"
+	}
+	return ""
+}
+
+// afterDelivered runs the side effects of a shipment reaching delivered, whichever scan delivered
+// it: the shipment.delivered webhook, the COD ledger entry and the completion of an RTO return
+func afterDelivered(repo *repository.ShipmentRepository, codRepo *repository.CODRepository, ch *amqp.Channel, hooks *service.WebhookDispatcher, shipment *model.Shipment, actor string) {
+	hooks.Enqueue(shipment.UserID, model.EventShipmentDelivered, shipment)
+	if shipment.COD != nil {
+		if err := service.RecordCODCollection(codRepo, repo, ch, shipment); err != nil {
+			log.Printf("[afterDelivered] RecordCODCollection error for %s, left to the ledger sweep: %v", shipment.TrackingNumber, err)
+		}
+	}
+	completeReturn(repo, ch, hooks, shipment, actor)
+}
+
+func hasStatus(s *model.Shipment, statuses []string) bool {
+	for _, status := range statuses {
+		if s.Status == status {
+			return true
+		}
+	}
+	return false
+}
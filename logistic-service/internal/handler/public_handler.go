@@ -0,0 +1,82 @@
+package handler
+
+import (
+	"log"
+	"logistic-service/internal/model"
+	"logistic-service/internal/repository"
+	"logistic-service/internal/service"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// PublicTrackShipment handles GET /public/track/:trackingNumber?phone_last4=1234
+// No JWT required. Returns status and timeline with masked sender/recipient details;
+// supplying the last 4 digits of the recipient phone unlocks the full details.
+func PublicTrackShipment(repo *repository.ShipmentRepository, checks *repository.PhoneCheckRepository) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		trackingNumber := c.Param("trackingNumber")
+
+		shipment, err := repo.FindByTrackingNumber(trackingNumber)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch shipment"})
+			return
+		}
+		if shipment == nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "shipment not found"})
+			return
+		}
+
+		verified := false
+		if last4 := c.Query("phone_last4"); last4 != "" {
+			if !verifyRecipientPhone(c, checks, shipment, last4) {
+				return
+			}
+			verified = true
+		}
+
+		c.JSON(http.StatusOK, service.NewPublicTracking(shipment, verified))
+	}
+}
+
+// verifyRecipientPhone checks last4 against the recipient phone of shipment. Failures are
+// counted per tracking number, and once it is locked every check fails, right or wrong, until
+// the lockout ends. Writes the error response and returns false otherwise.
+func verifyRecipientPhone(c *gin.Context, checks *repository.PhoneCheckRepository, shipment *model.Shipment, last4 string) bool {
+	lockedUntil, err := checks.LockedUntil(shipment.TrackingNumber)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to verify phone"})
+		return false
+	}
+	if !lockedUntil.IsZero() {
+		phoneCheckLocked(c, lockedUntil)
+		return false
+	}
+
+	if service.VerifyPhoneLast4(shipment.Recipient.Phone, last4) {
+		if err := checks.Reset(shipment.TrackingNumber); err != nil {
+			log.Printf("[verifyRecipientPhone] Warning: failed to reset phone checks of %s: %v", shipment.TrackingNumber, err)
+		}
+		return true
+	}
+	lockedUntil, err = checks.RecordFailure(shipment.TrackingNumber)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to verify phone"})
+		return false
+	}
+	if !lockedUntil.IsZero() {
+		log.Printf("[verifyRecipientPhone] %s locked until %s after failed phone verifications", shipment.TrackingNumber, lockedUntil.Format(time.RFC3339))
+		phoneCheckLocked(c, lockedUntil)
+		return false
+	}
+	c.JSON(http.StatusForbidden, gin.H{"error": "phone verification failed"})
+	return false
+}
+
+func phoneCheckLocked(c *gin.Context, until time.Time) {
+	c.Header("Retry-After", strconv.Itoa(int(math.Ceil(time.Until(until).Seconds()))))
+	c.JSON(http.StatusTooManyRequests, gin.H{"error": "too many failed phone verifications, try again later"})
+}
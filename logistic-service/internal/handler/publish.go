@@ -0,0 +1,38 @@
+package handler
+
+import (
+	"encoding/json"
+	"log"
+	"logistic-service/internal/service"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+// publishEvent marshals payload to JSON and publishes it to the given queue.
+// Failures are only logged: the HTTP request already succeeded at this point.
+func publishEvent(ch *amqp.Channel, queue string, payload interface{}) {
+	publish(ch, "", queue, payload)
+}
+
+// publishShipmentUpdated publishes the full shipment to the shipment.updated fanout exchange,
+// which feeds both the worker and the live tracking streams.
+func publishShipmentUpdated(ch *amqp.Channel, payload interface{}) {
+	publish(ch, service.ShipmentUpdatesExchange, "shipment.updated", payload)
+}
+
+func publish(ch *amqp.Channel, exchange, key string, payload interface{}) {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		log.Printf("[publishEvent] Marshal %s payload error: %v", key, err)
+		return
+	}
+	err = ch.Publish(exchange, key, false, false, amqp.Publishing{
+		ContentType: "application/json",
+		Body:        body,
+	})
+	if err != nil {
+		log.Printf("[publishEvent] RabbitMQ publish %s error: %v", key, err)
+		return
+	}
+	log.Printf("[publishEvent] Published %s", key)
+}
@@ -0,0 +1,58 @@
+package handler
+
+import (
+	"logistic-service/internal/service"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// regionParents maps each region level to the query parameter naming its parent
+var regionParents = map[string]string{
+	service.RegionProvince:    "",
+	service.RegionCity:        "province_code",
+	service.RegionDistrict:    "city_code",
+	service.RegionSubdistrict: "district_code",
+}
+
+// ListRegions handles the address autocomplete endpoints:
+// GET /regions/provinces, /regions/cities?province_code=, /regions/districts?city_code=
+// and /regions/subdistricts?district_code=, each with optional q and limit.
+// q matches the name (case insensitive) or, for sub-districts, the start of the postal code.
+func ListRegions(regions *service.RegionIndex, level string) gin.HandlerFunc {
+	parentParam := regionParents[level]
+	return func(c *gin.Context) {
+		limit, err := strconv.Atoi(c.DefaultQuery("limit", "50"))
+		if err != nil || limit < 1 || limit > 1000 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be between 1 and 1000"})
+			return
+		}
+
+		parentCode := ""
+		if parentParam != "" {
+			parentCode = c.Query(parentParam)
+			if parentCode == "" {
+				c.JSON(http.StatusBadRequest, gin.H{"error": parentParam + " is required"})
+				return
+			}
+			if regions.Find(parentCode) == nil {
+				c.JSON(http.StatusNotFound, gin.H{"error": "region " + parentCode + " not found"})
+				return
+			}
+		}
+		c.JSON(http.StatusOK, regions.Children(parentCode, c.Query("q"), limit))
+	}
+}
+
+// GetRegion handles GET /regions/:code
+func GetRegion(regions *service.RegionIndex) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		region := regions.Find(c.Param("code"))
+		if region == nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "region not found"})
+			return
+		}
+		c.JSON(http.StatusOK, region)
+	}
+}
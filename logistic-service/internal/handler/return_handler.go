@@ -0,0 +1,215 @@
+package handler
+
+import (
+	"errors"
+	"log"
+	"logistic-service/internal/model"
+	"logistic-service/internal/repository"
+	"logistic-service/internal/service"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+// GetReturnReasons handles GET /return-reasons and returns the reason code catalog
+func GetReturnReasons() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.JSON(http.StatusOK, service.ReturnReasons)
+	}
+}
+
+// CreateReturn handles POST /shipments/:trackingNumber/return.
+// type rto (couriers and ops) sends an undeliverable picked up or in transit shipment back to the
+// sender and moves it to return_to_sender. type customer_return sends a delivered shipment back
+// within the return window, optionally only some of its items. The return shipment swaps sender and
+// recipient, gets tracking number <original>-R<n> and references the original in "return".
+func CreateReturn(repo *repository.ShipmentRepository, calendar *service.DeliveryCalendar, ch *amqp.Channel, hooks *service.WebhookDispatcher) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		trackingNumber := c.Param("trackingNumber")
+		var req struct {
+			Type       string               `json:"type" binding:"required"`
+			ReasonCode string               `json:"reason_code" binding:"required"`
+			Note       string               `json:"note"`
+			Items      []model.ShipmentItem `json:"items"`
+		}
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if err := service.ValidateReturnReason(req.Type, req.ReasonCode, req.Note); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		principal, ok := currentPrincipal(c)
+		if !ok {
+			return
+		}
+
+		original, err := repo.FindByTrackingNumber(trackingNumber)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch shipment"})
+			return
+		}
+		if !principal.CanAccessShipment(original) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "shipment not found"})
+			return
+		}
+		if original.Return != nil {
+			c.JSON(http.StatusConflict, gin.H{"error": "a return shipment can't be returned"})
+			return
+		}
+
+		allowedStatuses := model.CustomerReturnStatuses
+		if req.Type == model.ReturnRTO {
+			if !principal.IsStaff() {
+				c.JSON(http.StatusForbidden, gin.H{"error": "only couriers and ops can return a shipment to sender"})
+				return
+			}
+			if len(req.Items) > 0 {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "items can only be selected for customer returns"})
+				return
+			}
+			allowedStatuses = model.RTOStatuses
+		}
+		if !hasStatus(original, allowedStatuses) {
+			c.JSON(http.StatusConflict, gin.H{"error": "shipment can't be returned (" + req.Type + ") in status " + original.Status})
+			return
+		}
+		if req.Type == model.ReturnCustomer && time.Since(service.DeliveredAt(original)) > service.ReturnWindow() {
+			c.JSON(http.StatusConflict, gin.H{"error": "the return window of this shipment has passed"})
+			return
+		}
+
+		returnShipment, err := service.NewReturnShipment(original, model.ShipmentReturn{
+			Type:        req.Type,
+			ReasonCode:  req.ReasonCode,
+			Note:        req.Note,
+			RequestedBy: principal.UserID,
+			Role:        principal.Role,
+			RequestedAt: time.Now(),
+		}, req.Items, req.Note)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		err = startReturn(repo, calendar, ch, hooks, original, returnShipment, allowedStatuses)
+		switch err {
+		case nil:
+		case errReturnActive:
+			last := original.ReturnTrackingNumbers[len(original.ReturnTrackingNumbers)-1]
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error(), "return_tracking_number": last})
+			return
+		case repository.ErrVersionConflict, repository.ErrStatusConflict:
+			c.JSON(http.StatusConflict, gin.H{"error": "shipment was modified by someone else, retry"})
+			return
+		default:
+			log.Printf("[CreateReturn] startReturn error: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create return shipment"})
+			return
+		}
+
+		c.JSON(http.StatusCreated, returnShipment)
+	}
+}
+
+// errReturnActive is returned by startReturn when the latest return shipment isn't cancelled
+var errReturnActive = errors.New("shipment already has a return shipment")
+
+// startReturn links returnShipment (see service.NewReturnShipment) to original and creates it.
+// Only one return can be active at a time; a cancelled return can be replaced. RTO moves the
+// original to return_to_sender. The original must still be at the version it was read at and
+// in one of allowedStatuses, otherwise a repository conflict error is returned.
+func startReturn(repo *repository.ShipmentRepository, calendar *service.DeliveryCalendar, ch *amqp.Channel, hooks *service.WebhookDispatcher, original, returnShipment *model.Shipment, allowedStatuses []string) error {
+	if n := len(original.ReturnTrackingNumbers); n > 0 {
+		last, err := repo.FindByTrackingNumber(original.ReturnTrackingNumbers[n-1])
+		if err != nil {
+			return err
+		}
+		if last != nil && last.Status != model.StatusCancelled {
+			return errReturnActive
+		}
+	}
+
+	// Claim the return on the original first so concurrent requests can't both create one
+	ret := returnShipment.Return
+	var event *model.TrackingEvent
+	if ret.Type == model.ReturnRTO {
+		event = &model.TrackingEvent{
+			Status:      model.StatusReturnToSender,
+			Description: "returning to sender as " + returnShipment.TrackingNumber + ": " + ret.ReasonCode,
+			Actor:       ret.RequestedBy,
+			Timestamp:   ret.RequestedAt,
+		}
+	}
+	err := repo.LinkReturn(original.TrackingNumber, original.Version, allowedStatuses, returnShipment.TrackingNumber, event)
+	if err != nil {
+		return err
+	}
+
+	if err := createShipment(repo, calendar, ch, hooks, returnShipment, original.UserID); err != nil {
+		if err := repo.UnlinkReturn(original.TrackingNumber, returnShipment.TrackingNumber); err != nil {
+			log.Printf("[startReturn] UnlinkReturn error: %v", err)
+		}
+		return err
+	}
+
+	if result, err := repo.FindByTrackingNumber(original.TrackingNumber); err == nil && result != nil {
+		publishShipmentUpdated(ch, result)
+		hooks.Enqueue(result.UserID, model.EventShipmentUpdated, result)
+	}
+	return nil
+}
+
+// completeReturn moves the original of a delivered RTO return shipment to returned
+func completeReturn(repo *repository.ShipmentRepository, ch *amqp.Channel, hooks *service.WebhookDispatcher, returnShipment *model.Shipment, actor string) {
+	if returnShipment.Return == nil || returnShipment.Return.Type != model.ReturnRTO {
+		return
+	}
+	originalTrackingNumber := returnShipment.Return.OriginalTrackingNumber
+	err := repo.UpdateStatus(originalTrackingNumber, model.TrackingEvent{
+		Status:      model.StatusReturned,
+		Description: "returned to sender by " + returnShipment.TrackingNumber,
+		Actor:       actor,
+	})
+	if err != nil {
+		log.Printf("[completeReturn] UpdateStatus error for %s: %v", originalTrackingNumber, err)
+		return
+	}
+	if original, err := repo.FindByTrackingNumber(originalTrackingNumber); err == nil && original != nil {
+		publishShipmentUpdated(ch, original)
+		hooks.Enqueue(original.UserID, model.EventShipmentUpdated, original)
+	}
+}
+
+// shipmentWithReturns is the TrackShipment response: the shipment plus its linked shipments
+// and the progress of its parcels
+type shipmentWithReturns struct {
+	*model.Shipment
+	Original       *model.Shipment       `json:"original,omitempty"`        // Set on return shipments
+	Returns        []*model.Shipment     `json:"returns,omitempty"`         // Return shipments of this shipment
+	ParcelProgress *model.ParcelProgress `json:"parcel_progress,omitempty"` // Set on multi-parcel shipments
+}
+
+// withReturns loads the shipments linked to shipment for TrackShipment
+func withReturns(repo *repository.ShipmentRepository, shipment *model.Shipment) (*shipmentWithReturns, error) {
+	resp := &shipmentWithReturns{Shipment: shipment, ParcelProgress: service.CountParcels(shipment)}
+	if shipment.Return != nil {
+		original, err := repo.FindByTrackingNumber(shipment.Return.OriginalTrackingNumber)
+		if err != nil {
+			return nil, err
+		}
+		resp.Original = original
+	}
+	if len(shipment.ReturnTrackingNumbers) > 0 {
+		returns, err := repo.FindReturns(shipment.TrackingNumber)
+		if err != nil {
+			return nil, err
+		}
+		resp.Returns = returns
+	}
+	return resp, nil
+}
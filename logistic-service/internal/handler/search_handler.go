@@ -0,0 +1,59 @@
+package handler
+
+import (
+	"log"
+	"logistic-service/internal/repository"
+	"logistic-service/internal/service"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SearchShipments handles GET /search/shipments (ops only), the customer service lookup.
+// q searches names, addresses and tracking numbers, name matches sender and recipient names by
+// sound and phone any part of their phone numbers. Accepts the filters of GET /shipments plus
+// user_id, limit (default 20, max 100) and cursor. Results are ranked by relevance.
+func SearchShipments(searcher service.ShipmentSearcher) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if _, ok := requireOps(c, "only ops can search shipments"); !ok {
+			return
+		}
+		filter, err := parseShipmentFilter(c)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		filter.UserID = c.Query("user_id")
+
+		limit, err := strconv.ParseInt(c.DefaultQuery("limit", "20"), 10, 64)
+		if err != nil || limit < 1 || limit > 100 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be between 1 and 100"})
+			return
+		}
+		q := service.ShipmentSearchQuery{
+			Text:   c.Query("q"),
+			Name:   c.Query("name"),
+			Phone:  c.Query("phone"),
+			Filter: filter,
+			Limit:  limit,
+			Cursor: c.Query("cursor"),
+		}
+		if err := service.ValidateShipmentSearch(q); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		page, err := searcher.Search(q)
+		if err == repository.ErrInvalidCursor {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if err != nil {
+			log.Printf("[SearchShipments] Search error: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to search shipments"})
+			return
+		}
+		c.JSON(http.StatusOK, page)
+	}
+}
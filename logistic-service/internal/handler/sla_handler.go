@@ -0,0 +1,53 @@
+package handler
+
+import (
+	"logistic-service/internal/repository"
+	"logistic-service/internal/service"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetServiceLevels handles GET /service-levels and returns the service level catalog
+func GetServiceLevels() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.JSON(http.StatusOK, service.ServiceLevels)
+	}
+}
+
+// ListSLABreaches handles GET /sla/breaches?logistic_name=&open=true&since=&limit= (ops only).
+// Lists the shipments flagged for missing their promised delivery date, most recent first.
+// open=true leaves out the ones delivered or closed since.
+func ListSLABreaches(repo *repository.ShipmentRepository) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if _, ok := requireOps(c, "only ops can list SLA breaches"); !ok {
+			return
+		}
+		limit, err := strconv.ParseInt(c.DefaultQuery("limit", "50"), 10, 64)
+		if err != nil || limit < 1 || limit > 200 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be between 1 and 200"})
+			return
+		}
+		filter := repository.SLABreachFilter{
+			LogisticName: c.Query("logistic_name"),
+			OpenOnly:     c.Query("open") == "true",
+		}
+		if v := c.Query("since"); v != "" {
+			since, err := time.Parse(time.RFC3339, v)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "since must be an RFC 3339 timestamp"})
+				return
+			}
+			filter.Since = since
+		}
+
+		results, err := repo.ListSLABreached(filter, limit)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch SLA breaches"})
+			return
+		}
+		c.JSON(http.StatusOK, results)
+	}
+}
@@ -0,0 +1,297 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"logistic-service/internal/middleware"
+	"logistic-service/internal/model"
+	"logistic-service/internal/repository"
+	"logistic-service/internal/service"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+)
+
+// Interval between keep-alive messages on idle streams
+const streamHeartbeat = 20 * time.Second
+
+// StreamShipment handles GET /shipments/:trackingNumber/stream (Server-Sent Events).
+// Every event carries the full shipment with the shipment version as event ID.
+// On reconnect the browser sends Last-Event-ID; the current state is sent right away
+// if it is newer than that version, so no update is lost across reconnects.
+func StreamShipment(repo *repository.ShipmentRepository, hub *service.TrackingHub) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		trackingNumber := c.Param("trackingNumber")
+
+		principal, ok := currentPrincipal(c)
+		if !ok {
+			return
+		}
+		shipment, err := repo.FindByTrackingNumber(trackingNumber)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch shipment"})
+			return
+		}
+		if !principal.CanAccessShipment(shipment) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "shipment not found"})
+			return
+		}
+
+		// Subscribe before sending the snapshot so no update slips in between
+		updates, unsubscribe := hub.Subscribe(trackingNumber)
+		defer unsubscribe()
+
+		lastVersion := -1
+		lastEventID := c.GetHeader("Last-Event-ID")
+		if lastEventID == "" {
+			lastEventID = c.Query("last_event_id")
+		}
+		if v, err := strconv.Atoi(lastEventID); err == nil {
+			lastVersion = v
+		}
+
+		w := c.Writer
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Cache-Control", "no-cache")
+		w.Header().Set("Connection", "keep-alive")
+		w.Header().Set("X-Accel-Buffering", "no") // disable proxy buffering (nginx)
+		w.WriteHeader(http.StatusOK)
+		fmt.Fprint(w, "retry: 3000\n\n")
+
+		send := func(s *model.Shipment) bool {
+			if s.Version <= lastVersion {
+				return true
+			}
+			data, err := json.Marshal(s)
+			if err != nil {
+				log.Printf("[StreamShipment] Marshal shipment error: %v", err)
+				return true
+			}
+			if _, err := fmt.Fprintf(w, "id: %d\nevent: shipment\ndata: %s\n\n", s.Version, data); err != nil {
+				return false
+			}
+			lastVersion = s.Version
+			w.Flush()
+			return true
+		}
+		if !send(shipment) {
+			return
+		}
+		w.Flush()
+
+		heartbeat := time.NewTicker(streamHeartbeat)
+		defer heartbeat.Stop()
+		for {
+			select {
+			case <-c.Request.Context().Done():
+				return
+			case s := <-updates:
+				if !send(s) {
+					return
+				}
+			case <-heartbeat.C:
+				if _, err := fmt.Fprint(w, ": ping\n\n"); err != nil {
+					return
+				}
+				w.Flush()
+			}
+		}
+	}
+}
+
+// newWSUpgrader returns an upgrader accepting the handshakes allowed by wsOriginAllowed. It selects
+// middleware.WebSocketTokenProtocol when the client offered it, which browsers require to
+// complete a handshake that carried the JWT as a subprotocol.
+func newWSUpgrader(allowedOrigins []string) *websocket.Upgrader {
+	return &websocket.Upgrader{
+		ReadBufferSize:  1024,
+		WriteBufferSize: 1024,
+		Subprotocols:    []string{middleware.WebSocketTokenProtocol},
+		CheckOrigin: func(r *http.Request) bool {
+			return wsOriginAllowed(r, allowedOrigins)
+		},
+	}
+}
+
+// wsOriginAllowed accepts handshakes without an Origin (non-browser clients), from the origin of
+// the service itself or from one of allowedOrigins (e.g. "https://dashboard.example.com"). Other
+// origins are refused so a page elsewhere can't open a stream with a token it got hold of.
+func wsOriginAllowed(r *http.Request, allowedOrigins []string) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	if u, err := url.Parse(origin); err == nil && strings.EqualFold(u.Host, r.Host) {
+		return true
+	}
+	for _, allowed := range allowedOrigins {
+		if strings.EqualFold(strings.TrimSuffix(allowed, "/"), origin) {
+			return true
+		}
+	}
+	return false
+}
+
+// wsClientMessage is sent by WebSocket clients to manage subscriptions, e.g.
+// {"action":"subscribe","tracking_number":"JNE123","last_version":3}
+type wsClientMessage struct {
+	Action         string `json:"action"` // subscribe or unsubscribe
+	TrackingNumber string `json:"tracking_number"`
+	LastVersion    *int   `json:"last_version"` // skip the snapshot if the client already has this version
+}
+
+// wsServerMessage is sent to WebSocket clients.
+type wsServerMessage struct {
+	Type           string          `json:"type"` // shipment, subscribed, unsubscribed or error
+	TrackingNumber string          `json:"tracking_number,omitempty"`
+	Version        int             `json:"version,omitempty"`
+	Data           *model.Shipment `json:"data,omitempty"`
+	Error          string          `json:"error,omitempty"`
+}
+
+// StreamShipmentsWS handles GET /ws/shipments (WebSocket).
+// One connection can follow many shipments, which suits dashboards. Clients subscribe and
+// unsubscribe with wsClientMessage; the server pings every streamHeartbeat.
+// Browser handshakes are only accepted from allowedOrigins (see wsOriginAllowed).
+func StreamShipmentsWS(repo *repository.ShipmentRepository, hub *service.TrackingHub, allowedOrigins []string) gin.HandlerFunc {
+	wsUpgrader := newWSUpgrader(allowedOrigins)
+	return func(c *gin.Context) {
+		principal, ok := currentPrincipal(c)
+		if !ok {
+			return
+		}
+		conn, err := wsUpgrader.Upgrade(c.Writer, c.Request, nil)
+		if err != nil {
+			log.Printf("[StreamShipmentsWS] Upgrade error: %v", err)
+			return
+		}
+		defer conn.Close()
+
+		// ctx ends when either the reader (this goroutine) or the writer stops
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		out := make(chan wsServerMessage, 16)
+		send := func(msg wsServerMessage) bool {
+			select {
+			case out <- msg:
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		}
+
+		// Writer: the only goroutine writing to the connection
+		go func() {
+			defer cancel()
+			defer conn.Close()
+			heartbeat := time.NewTicker(streamHeartbeat)
+			defer heartbeat.Stop()
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case msg := <-out:
+					conn.SetWriteDeadline(time.Now().Add(10 * time.Second))
+					if err := conn.WriteJSON(msg); err != nil {
+						return
+					}
+				case <-heartbeat.C:
+					deadline := time.Now().Add(10 * time.Second)
+					if err := conn.WriteControl(websocket.PingMessage, nil, deadline); err != nil {
+						return
+					}
+				}
+			}
+		}()
+
+		// Clients must answer pings (browsers do automatically)
+		conn.SetReadDeadline(time.Now().Add(3 * streamHeartbeat))
+		conn.SetPongHandler(func(string) error {
+			return conn.SetReadDeadline(time.Now().Add(3 * streamHeartbeat))
+		})
+
+		subscriptions := make(map[string]func())
+		defer func() {
+			for _, stop := range subscriptions {
+				stop()
+			}
+		}()
+
+		for {
+			var msg wsClientMessage
+			if err := conn.ReadJSON(&msg); err != nil {
+				return
+			}
+			switch msg.Action {
+			case "subscribe":
+				if _, exists := subscriptions[msg.TrackingNumber]; exists {
+					continue
+				}
+				shipment, err := repo.FindByTrackingNumber(msg.TrackingNumber)
+				if err != nil || !principal.CanAccessShipment(shipment) {
+					send(wsServerMessage{Type: "error", TrackingNumber: msg.TrackingNumber, Error: "shipment not found"})
+					continue
+				}
+
+				updates, unsubscribe := hub.Subscribe(msg.TrackingNumber)
+				subCtx, subCancel := context.WithCancel(ctx)
+				subscriptions[msg.TrackingNumber] = func() {
+					unsubscribe()
+					subCancel()
+				}
+				send(wsServerMessage{Type: "subscribed", TrackingNumber: msg.TrackingNumber})
+
+				lastVersion := -1
+				if msg.LastVersion != nil {
+					lastVersion = *msg.LastVersion
+				}
+				go forwardUpdates(subCtx, shipment, lastVersion, updates, out)
+			case "unsubscribe":
+				if stop, exists := subscriptions[msg.TrackingNumber]; exists {
+					stop()
+					delete(subscriptions, msg.TrackingNumber)
+					send(wsServerMessage{Type: "unsubscribed", TrackingNumber: msg.TrackingNumber})
+				}
+			default:
+				send(wsServerMessage{Type: "error", Error: "unknown action"})
+			}
+		}
+	}
+}
+
+// forwardUpdates sends the snapshot (if newer than lastVersion) and then every newer update
+// of one subscription until ctx is cancelled.
+func forwardUpdates(ctx context.Context, snapshot *model.Shipment, lastVersion int, updates <-chan *model.Shipment, out chan<- wsServerMessage) {
+	forward := func(s *model.Shipment) bool {
+		if s.Version <= lastVersion {
+			return true
+		}
+		lastVersion = s.Version
+		select {
+		case out <- wsServerMessage{Type: "shipment", TrackingNumber: s.TrackingNumber, Version: s.Version, Data: s}:
+			return true
+		case <-ctx.Done():
+			return false
+		}
+	}
+	if !forward(snapshot) {
+		return
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case s := <-updates:
+			if !forward(s) {
+				return
+			}
+		}
+	}
+}
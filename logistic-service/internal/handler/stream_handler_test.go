@@ -0,0 +1,33 @@
+package handler
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+func TestWSOriginAllowed(t *testing.T) {
+	allowed := []string{"https://dashboard.example.com/", "http://localhost:3000"}
+	tests := []struct {
+		origin string
+		want   bool
+	}{
+		{"", true},
+		{"http://api.example.com", true},
+		{"https://dashboard.example.com", true},
+		{"HTTPS://Dashboard.Example.com", true},
+		{"http://localhost:3000", true},
+		{"http://localhost:3001", false},
+		{"https://evil.example.com", false},
+		{"https://dashboard.example.com.evil.com", false},
+		{"null", false},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest("GET", "http://api.example.com/ws/shipments", nil)
+		if tt.origin != "" {
+			r.Header.Set("Origin", tt.origin)
+		}
+		if got := wsOriginAllowed(r, allowed); got != tt.want {
+			t.Errorf("wsOriginAllowed(%q) = %v, want %v", tt.origin, got, tt.want)
+		}
+	}
+}
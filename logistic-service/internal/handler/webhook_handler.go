@@ -0,0 +1,272 @@
+package handler
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"log"
+	"logistic-service/internal/model"
+	"logistic-service/internal/repository"
+	"logistic-service/internal/service"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// WebhookRequest is the body of POST /webhooks and PATCH /webhooks/:id
+type WebhookRequest struct {
+	URL    *string   `json:"url"`
+	Events *[]string `json:"events"`
+	Active *bool     `json:"active"`
+}
+
+// CreateWebhook handles POST /webhooks.
+// The signing secret is returned only in this response.
+func CreateWebhook(repo *repository.WebhookRepository) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		principal, ok := currentPrincipal(c)
+		if !ok {
+			return
+		}
+
+		var req WebhookRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if req.URL == nil || req.Events == nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "url and events are required"})
+			return
+		}
+		if msg := validateWebhook(*req.URL, *req.Events); msg != "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+			return
+		}
+
+		secret, err := newWebhookSecret()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to generate secret"})
+			return
+		}
+		now := time.Now()
+		hook := &model.Webhook{
+			ID:        uuid.New().String(),
+			UserID:    principal.UserID,
+			URL:       *req.URL,
+			Secret:    secret,
+			Events:    *req.Events,
+			Active:    true,
+			CreatedAt: now,
+			UpdatedAt: now,
+		}
+		if err := repo.Insert(hook); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create webhook"})
+			return
+		}
+
+		c.JSON(http.StatusCreated, struct {
+			*model.Webhook
+			Secret string `json:"secret"`
+		}{hook, secret})
+	}
+}
+
+// ListWebhooks handles GET /webhooks and returns the caller's webhooks
+func ListWebhooks(repo *repository.WebhookRepository) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		principal, ok := currentPrincipal(c)
+		if !ok {
+			return
+		}
+		hooks, err := repo.FindByUserID(principal.UserID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch webhooks"})
+			return
+		}
+		c.JSON(http.StatusOK, hooks)
+	}
+}
+
+// GetWebhook handles GET /webhooks/:id
+func GetWebhook(repo *repository.WebhookRepository) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		hook, ok := ownedWebhook(c, repo)
+		if !ok {
+			return
+		}
+		c.JSON(http.StatusOK, hook)
+	}
+}
+
+// UpdateWebhook handles PATCH /webhooks/:id.
+// Setting active=true re-enables an auto-disabled webhook.
+func UpdateWebhook(repo *repository.WebhookRepository) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		hook, ok := ownedWebhook(c, repo)
+		if !ok {
+			return
+		}
+
+		var req WebhookRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if req.URL != nil {
+			hook.URL = *req.URL
+		}
+		if req.Events != nil {
+			hook.Events = *req.Events
+		}
+		if req.Active != nil {
+			hook.Active = *req.Active
+		}
+		if msg := validateWebhook(hook.URL, hook.Events); msg != "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+			return
+		}
+
+		if err := repo.Update(hook); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update webhook"})
+			return
+		}
+		updated, err := repo.FindByID(hook.ID)
+		if err != nil || updated == nil {
+			c.JSON(http.StatusOK, gin.H{"message": "webhook updated"})
+			return
+		}
+		c.JSON(http.StatusOK, updated)
+	}
+}
+
+// DeleteWebhook handles DELETE /webhooks/:id
+func DeleteWebhook(repo *repository.WebhookRepository) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		hook, ok := ownedWebhook(c, repo)
+		if !ok {
+			return
+		}
+		if err := repo.Delete(hook.ID); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to delete webhook"})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"message": "webhook deleted"})
+	}
+}
+
+// ListWebhookDeliveries handles GET /webhooks/:id/deliveries?status=failed&limit=50
+func ListWebhookDeliveries(repo *repository.WebhookRepository) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		hook, ok := ownedWebhook(c, repo)
+		if !ok {
+			return
+		}
+		limit, err := strconv.ParseInt(c.DefaultQuery("limit", "50"), 10, 64)
+		if err != nil || limit < 1 || limit > 200 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be between 1 and 200"})
+			return
+		}
+		deliveries, err := repo.FindDeliveries(hook.ID, c.Query("status"), limit)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch deliveries"})
+			return
+		}
+		c.JSON(http.StatusOK, deliveries)
+	}
+}
+
+// RedeliverWebhook handles POST /webhooks/:id/deliveries/:deliveryId/redeliver.
+// Queues a new delivery with the same payload; the original stays in the log.
+func RedeliverWebhook(repo *repository.WebhookRepository, hooks *service.WebhookDispatcher) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		hook, ok := ownedWebhook(c, repo)
+		if !ok {
+			return
+		}
+		original, err := repo.FindDeliveryByID(c.Param("deliveryId"))
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch delivery"})
+			return
+		}
+		if original == nil || original.WebhookID != hook.ID {
+			c.JSON(http.StatusNotFound, gin.H{"error": "delivery not found"})
+			return
+		}
+		if !hook.Active {
+			c.JSON(http.StatusConflict, gin.H{"error": "webhook is disabled, re-enable it first"})
+			return
+		}
+
+		delivery, err := hooks.Redeliver(original)
+		if err != nil {
+			log.Printf("[RedeliverWebhook] Redeliver error: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to queue redelivery"})
+			return
+		}
+		c.JSON(http.StatusAccepted, delivery)
+	}
+}
+
+// ownedWebhook loads the :id webhook and checks it belongs to the caller.
+// Writes the error response and returns false otherwise.
+func ownedWebhook(c *gin.Context, repo *repository.WebhookRepository) (*model.Webhook, bool) {
+	principal, ok := currentPrincipal(c)
+	if !ok {
+		return nil, false
+	}
+	hook, err := repo.FindByID(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch webhook"})
+		return nil, false
+	}
+	if hook == nil || hook.UserID != principal.UserID {
+		c.JSON(http.StatusNotFound, gin.H{"error": "webhook not found"})
+		return nil, false
+	}
+	return hook, true
+}
+
+// validateWebhook returns an error message, or "" when url and events are valid
+func validateWebhook(rawURL string, events []string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+		return "url must be an absolute http(s) URL"
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := service.CheckWebhookHost(ctx, u.Hostname()); err != nil {
+		if errors.Is(err, service.ErrPrivateWebhookTarget) {
+			return "url must point to a public host"
+		}
+		return "url host could not be resolved"
+	}
+	if len(events) == 0 {
+		return "events must not be empty"
+	}
+	for _, e := range events {
+		known := false
+		for _, w := range model.WebhookEvents {
+			if e == w {
+				known = true
+				break
+			}
+		}
+		if !known {
+			return "unknown event: " + e
+		}
+	}
+	return ""
+}
+
+func newWebhookSecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return "whsec_" + hex.EncodeToString(b), nil
+}
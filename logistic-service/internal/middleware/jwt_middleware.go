@@ -2,11 +2,16 @@ package middleware
 
 import (
     "github.com/gin-gonic/gin"
+    "github.com/gorilla/websocket"
     "logistic-service/internal/service"
     "net/http"
     "strings"
 )
 
+// WebSocketTokenProtocol is the subprotocol browsers offer, followed by the JWT, to authenticate
+// a WebSocket handshake: new WebSocket(url, ["access_token", jwt])
+const WebSocketTokenProtocol = "access_token"
+
 // JWTAuthMiddleware validates the JWT token from the Authorization header.
 // It expects the header to be in the format: "Bearer <token>".
 // If valid, it stores the claims in the context for downstream handlers.
@@ -33,3 +38,42 @@ func JWTAuthMiddleware() gin.HandlerFunc {
         c.Next()
     }
 }
+
+// StreamAuthMiddleware is JWTAuthMiddleware for streaming endpoints (SSE, WebSocket).
+// Browsers can't set headers on a WebSocket handshake, so there the token may also be sent in
+// Sec-WebSocket-Protocol (see WebSocketTokenProtocol). Tokens in the query string are not
+// accepted: URLs end up in access logs and browser history.
+func StreamAuthMiddleware() gin.HandlerFunc {
+    return func(c *gin.Context) {
+        tokenStr := strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer ")
+        if tokenStr == "" {
+            tokenStr = webSocketToken(c.Request)
+        }
+        if tokenStr == "" {
+            c.JSON(http.StatusUnauthorized, gin.H{"error": "missing JWT"})
+            c.Abort()
+            return
+        }
+
+        claims, err := service.ParseJWT(tokenStr)
+        if err != nil {
+            c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid or expired JWT"})
+            c.Abort()
+            return
+        }
+
+        c.Set("claims", claims)
+        c.Next()
+    }
+}
+
+// webSocketToken returns the JWT offered as the subprotocol after WebSocketTokenProtocol, if any
+func webSocketToken(r *http.Request) string {
+    protocols := websocket.Subprotocols(r)
+    for i := 0; i+1 < len(protocols); i++ {
+        if protocols[i] == WebSocketTokenProtocol {
+            return protocols[i+1]
+        }
+    }
+    return ""
+}
@@ -0,0 +1,48 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func TestStreamAuthMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	t.Setenv("JWT_SECRET", "test-secret")
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{"user_id": "u1"}).SignedString([]byte("test-secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		target string
+		header map[string]string
+		want   int
+	}{
+		{"authorization header", "/", map[string]string{"Authorization": "Bearer " + token}, http.StatusOK},
+		{"websocket subprotocol", "/", map[string]string{"Sec-WebSocket-Protocol": "access_token, " + token}, http.StatusOK},
+		{"subprotocol without the marker", "/", map[string]string{"Sec-WebSocket-Protocol": token}, http.StatusUnauthorized},
+		{"query string", "/?access_token=" + token, nil, http.StatusUnauthorized},
+		{"invalid token", "/", map[string]string{"Authorization": "Bearer x.y.z"}, http.StatusUnauthorized},
+		{"no token", "/", nil, http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := gin.New()
+			r.GET("/", StreamAuthMiddleware(), func(c *gin.Context) { c.Status(http.StatusOK) })
+			req := httptest.NewRequest(http.MethodGet, tt.target, nil)
+			for k, v := range tt.header {
+				req.Header.Set(k, v)
+			}
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+			if w.Code != tt.want {
+				t.Errorf("status = %d, want %d", w.Code, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,62 @@
+package middleware
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// rateWindow counts requests of one client within the current window.
+type rateWindow struct {
+	start time.Time
+	count int
+}
+
+// RateLimitMiddleware limits every client IP to `limit` requests per `window`.
+// Counters are kept in memory, so each service instance enforces its own limit.
+func RateLimitMiddleware(limit int, window time.Duration) gin.HandlerFunc {
+	var (
+		mu        sync.Mutex
+		clients   = make(map[string]*rateWindow)
+		lastSweep = time.Now()
+	)
+
+	return func(c *gin.Context) {
+		now := time.Now()
+		key := c.ClientIP()
+
+		mu.Lock()
+		// Drop expired windows from time to time so the map doesn't grow forever
+		if now.Sub(lastSweep) > window {
+			for k, w := range clients {
+				if now.Sub(w.start) >= window {
+					delete(clients, k)
+				}
+			}
+			lastSweep = now
+		}
+
+		w, ok := clients[key]
+		if !ok || now.Sub(w.start) >= window {
+			w = &rateWindow{start: now}
+			clients[key] = w
+		}
+		w.count++
+		count, resetIn := w.count, window-now.Sub(w.start)
+		mu.Unlock()
+
+		c.Header("X-RateLimit-Limit", strconv.Itoa(limit))
+		c.Header("X-RateLimit-Remaining", strconv.Itoa(max(limit-count, 0)))
+		if count > limit {
+			c.Header("Retry-After", strconv.Itoa(int(math.Ceil(resetIn.Seconds()))))
+			c.JSON(http.StatusTooManyRequests, gin.H{"error": "too many requests"})
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
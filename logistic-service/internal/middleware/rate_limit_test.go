@@ -0,0 +1,47 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestRateLimitIgnoresForwardedForFromUntrustedClients(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	tests := []struct {
+		name     string
+		trusted  []string
+		wantLast int
+	}{
+		{"no trusted proxies", nil, http.StatusTooManyRequests},
+		{"request from a trusted proxy", []string{"10.0.0.1"}, http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := gin.New()
+			if err := r.SetTrustedProxies(tt.trusted); err != nil {
+				t.Fatal(err)
+			}
+			r.Use(RateLimitMiddleware(2, time.Minute))
+			r.GET("/", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+			var code int
+			for i, ip := range []string{"1.1.1.1", "2.2.2.2", "3.3.3.3"} {
+				req := httptest.NewRequest(http.MethodGet, "/", nil)
+				req.RemoteAddr = "10.0.0.1:4000"
+				req.Header.Set("X-Forwarded-For", ip)
+				w := httptest.NewRecorder()
+				r.ServeHTTP(w, req)
+				if code = w.Code; i < 2 && code != http.StatusOK {
+					t.Fatalf("request %d: status %d", i+1, code)
+				}
+			}
+			if code != tt.wantLast {
+				t.Errorf("third request with a new X-Forwarded-For: status %d, want %d", code, tt.wantLast)
+			}
+		})
+	}
+}
@@ -0,0 +1,39 @@
+package model
+
+import "time"
+
+// Bulk job statuses
+const (
+	BulkJobQueued     = "queued"
+	BulkJobProcessing = "processing"
+	BulkJobCompleted  = "completed"
+	BulkJobFailed     = "failed"
+)
+
+// BulkJob tracks the asynchronous creation of shipments from one uploaded file.
+// Jobs are identified per user by the SHA-256 of the file, so uploading the same
+// file again returns the existing job instead of creating shipments twice.
+type BulkJob struct {
+	ID         string         `bson:"_id" json:"id"`
+	UserID     string         `bson:"user_id" json:"user_id"`
+	FileHash   string         `bson:"file_hash" json:"file_hash"`
+	FileName   string         `bson:"file_name" json:"file_name"`
+	Format     string         `bson:"format" json:"format"` // csv, xlsx or json
+	Status     string         `bson:"status" json:"status"`
+	Total      int            `bson:"total" json:"total"` // Number of shipments in the file
+	Processed  int            `bson:"processed" json:"processed"`
+	Succeeded  int            `bson:"succeeded" json:"succeeded"`
+	Failed     int            `bson:"failed" json:"failed"`
+	Errors     []BulkRowError `bson:"errors" json:"errors"`
+	Error      string         `bson:"error,omitempty" json:"error,omitempty"` // Job level failure
+	CreatedAt  time.Time      `bson:"created_at" json:"created_at"`
+	StartedAt  *time.Time     `bson:"started_at,omitempty" json:"started_at,omitempty"`
+	FinishedAt *time.Time     `bson:"finished_at,omitempty" json:"finished_at,omitempty"`
+}
+
+// BulkRowError describes why one shipment of a bulk upload was not created.
+type BulkRowError struct {
+	Row            int    `bson:"row" json:"row"` // 1-based row (CSV/XLSX, header is row 1) or array index + 1 (JSON)
+	TrackingNumber string `bson:"tracking_number" json:"tracking_number"`
+	Error          string `bson:"error" json:"error"`
+}
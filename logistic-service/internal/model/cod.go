@@ -0,0 +1,65 @@
+package model
+
+import "time"
+
+// DefaultCODCurrency is used when a shipment's COD has no currency
+const DefaultCODCurrency = "IDR"
+
+// CashOnDelivery is the amount the courier collects from the recipient on delivery.
+// Amounts are integers in the smallest unit of the currency (rupiah for IDR).
+type CashOnDelivery struct {
+	Amount          int64      `bson:"amount" json:"amount"`
+	Currency        string     `bson:"currency" json:"currency"` // ISO 4217 code
+	CollectedAmount int64      `bson:"collected_amount,omitempty" json:"collected_amount,omitempty"`
+	CollectedBy     string     `bson:"collected_by,omitempty" json:"collected_by,omitempty"` // User ID of the courier
+	CollectedAt     *time.Time `bson:"collected_at,omitempty" json:"collected_at,omitempty"`
+	LedgeredAt      *time.Time `bson:"ledgered_at,omitempty" json:"-"` // When the collection was added to the COD ledger
+}
+
+// COD ledger entry statuses
+const (
+	RemittancePending = "pending"
+	RemittanceSettled = "settled"
+)
+
+// RemittanceSettling is the status of a remittance while its entries are being claimed
+const RemittanceSettling = "settling"
+
+// CODLedgerEntry is the COD collected for one delivered shipment, owed to the merchant
+// (the shipment owner) minus the COD fee until it is settled in a remittance.
+type CODLedgerEntry struct {
+	ID             string     `bson:"_id" json:"id"`
+	MerchantID     string     `bson:"merchant_id" json:"merchant_id"`
+	TrackingNumber string     `bson:"tracking_number" json:"tracking_number"`
+	Currency       string     `bson:"currency" json:"currency"`
+	Amount         int64      `bson:"amount" json:"amount"` // Collected amount
+	Fee            int64      `bson:"fee" json:"fee"`
+	NetAmount      int64      `bson:"net_amount" json:"net_amount"` // Amount - Fee
+	Status         string     `bson:"status" json:"status"`
+	RemittanceID   string     `bson:"remittance_id,omitempty" json:"remittance_id,omitempty"`
+	CollectedBy    string     `bson:"collected_by" json:"collected_by"`
+	CollectedAt    time.Time  `bson:"collected_at" json:"collected_at"`
+	SettledAt      *time.Time `bson:"settled_at,omitempty" json:"settled_at,omitempty"`
+}
+
+// CODRemittance is a payout to a merchant covering all its pending ledger entries in one currency.
+// Pending summaries returned by the API use the same shape with Status pending and no ID.
+type CODRemittance struct {
+	ID          string     `bson:"_id,omitempty" json:"id,omitempty"`
+	MerchantID  string     `bson:"merchant_id" json:"merchant_id"`
+	Currency    string     `bson:"currency" json:"currency"`
+	Status      string     `bson:"status" json:"status"`
+	EntryCount  int        `bson:"entry_count" json:"entry_count"`
+	GrossAmount int64      `bson:"gross_amount" json:"gross_amount"`
+	FeeAmount   int64      `bson:"fee_amount" json:"fee_amount"`
+	NetAmount   int64      `bson:"net_amount" json:"net_amount"`
+	Reference   string     `bson:"reference,omitempty" json:"reference,omitempty"` // e.g. bank transfer reference
+	SettledBy   string     `bson:"settled_by,omitempty" json:"settled_by,omitempty"`
+	SettledAt   *time.Time `bson:"settled_at,omitempty" json:"settled_at,omitempty"`
+}
+
+// CODRemittedEvent is published to the cod.remitted queue when pending entries are settled.
+type CODRemittedEvent struct {
+	Remittance CODRemittance `json:"remittance"`
+	EntryIDs   []string      `json:"entry_ids"`
+}
@@ -0,0 +1,29 @@
+package model
+
+import "time"
+
+// Contact is a saved sender or recipient in a user's address book.
+// Shipments copy the contact into their embedded ShipmentPerson, so editing or deleting
+// a contact never changes existing shipments.
+type Contact struct {
+	ID              string             `bson:"_id" json:"id"`
+	UserID          string             `bson:"user_id" json:"user_id"`
+	Label           string             `bson:"label,omitempty" json:"label,omitempty"` // Optional, e.g. "Warehouse" or "Mum"
+	Name            string             `bson:"name" json:"name"`
+	Phone           string             `bson:"phone" json:"phone"`
+	Address         string             `bson:"address" json:"address"`
+	Structured      *StructuredAddress `bson:"structured_address,omitempty" json:"structured_address,omitempty"`
+	IsDefaultSender bool               `bson:"is_default_sender" json:"is_default_sender"` // At most one per user
+	CreatedAt       time.Time          `bson:"created_at" json:"created_at"`
+	UpdatedAt       time.Time          `bson:"updated_at" json:"updated_at"`
+}
+
+// Person returns the contact as the snapshot embedded in a shipment.
+func (c *Contact) Person() ShipmentPerson {
+	p := ShipmentPerson{Name: c.Name, Phone: c.Phone, Address: c.Address}
+	if c.Structured != nil {
+		structured := *c.Structured
+		p.Structured = &structured
+	}
+	return p
+}
@@ -0,0 +1,9 @@
+package model
+
+// CourierRate is a courier service available between an origin and a destination, as returned
+// by GET /courier-rates. Derived from the shipments booked on the route, it carries none of
+// their data besides the courier and service level.
+type CourierRate struct {
+	LogisticName string `bson:"logistic_name" json:"logistic_name"`
+	ServiceLevel string `bson:"service_level" json:"service_level"`
+}
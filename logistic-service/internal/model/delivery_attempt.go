@@ -0,0 +1,44 @@
+package model
+
+import "time"
+
+// Delivery time slots a recipient can choose when rescheduling
+const (
+	SlotMorning   = "morning"   // 08:00-12:00
+	SlotAfternoon = "afternoon" // 12:00-17:00
+	SlotEvening   = "evening"   // 17:00-21:00
+)
+
+// DeliverySlots lists the accepted DeliverySchedule.TimeSlot values
+var DeliverySlots = []string{SlotMorning, SlotAfternoon, SlotEvening}
+
+// DoorstepAttempt records a failed doorstep delivery attempt.
+type DoorstepAttempt struct {
+	Number      int       `bson:"number" json:"number"`                 // 1 for the first attempt
+	ReasonCode  string    `bson:"reason_code" json:"reason_code"`       // Code from the attempt failure reason catalog
+	Note        string    `bson:"note,omitempty" json:"note,omitempty"` // Free text, required for reason "other"
+	CourierID   string    `bson:"courier_id" json:"courier_id"`         // User ID of the courier
+	Latitude    *float64  `bson:"latitude,omitempty" json:"latitude,omitempty"`
+	Longitude   *float64  `bson:"longitude,omitempty" json:"longitude,omitempty"`
+	AttemptedAt time.Time `bson:"attempted_at" json:"attempted_at"`
+}
+
+// DeliverySchedule is the recipient's preferred day and time slot for the next attempt.
+type DeliverySchedule struct {
+	Date        string    `bson:"date" json:"date"` // YYYY-MM-DD, local date of the destination
+	TimeSlot    string    `bson:"time_slot" json:"time_slot"`
+	RequestedAt time.Time `bson:"requested_at" json:"requested_at"`
+}
+
+// DeliveryPreferencesChangedEvent is published to the delivery.preferences_changed queue when
+// the recipient reschedules or changes the instructions on the public endpoint, so the recipient
+// can be told about it (and spot a change they didn't make).
+type DeliveryPreferencesChangedEvent struct {
+	TrackingNumber      string            `json:"tracking_number"`
+	UserID              string            `json:"user_id"`         // Owner of the shipment
+	RecipientPhone      string            `json:"recipient_phone"` // Where to send the notification
+	NextAttempt         *DeliverySchedule `json:"next_attempt,omitempty"`
+	InstructionsChanged bool              `json:"instructions_changed"`
+	ClientIP            string            `json:"client_ip"`
+	ChangedAt           time.Time         `json:"changed_at"`
+}
@@ -0,0 +1,71 @@
+package model
+
+import "time"
+
+// Driver is the profile of a courier: an auth-service user with role courier.
+// Couriers fill in their own profile; ops decide the areas they serve and can deactivate them.
+type Driver struct {
+	UserID       string    `bson:"_id" json:"user_id"`
+	Name         string    `bson:"name" json:"name"`
+	Phone        string    `bson:"phone" json:"phone"`
+	LogisticName string    `bson:"logistic_name,omitempty" json:"logistic_name,omitempty"` // Courier company, empty serves all
+	VehicleType  string    `bson:"vehicle_type,omitempty" json:"vehicle_type,omitempty"`   // e.g. motorcycle, car, van
+	PlateNumber  string    `bson:"plate_number,omitempty" json:"plate_number,omitempty"`
+	AreaCodes    []string  `bson:"area_codes" json:"area_codes"`         // Region codes served, at any level
+	Active       bool      `bson:"active" json:"active"`                 // Inactive drivers get no new assignments
+	Base         *GeoPoint `bson:"base,omitempty" json:"base,omitempty"` // Where the daily run starts, e.g. the hub
+	CreatedAt    time.Time `bson:"created_at" json:"created_at"`
+	UpdatedAt    time.Time `bson:"updated_at" json:"updated_at"`
+}
+
+// DeliveryAssignableStatuses are the statuses in which a shipment can be assigned to a driver
+var DeliveryAssignableStatuses = []string{StatusPickedUp, StatusInTransit}
+
+// Driver task types
+const (
+	TaskPickup   = "pickup"
+	TaskDelivery = "delivery"
+)
+
+// DriverTask is one stop on a driver's task list: a pickup or a delivery.
+type DriverTask struct {
+	Sequence       int             `json:"sequence"` // 1 for the first stop
+	Type           string          `json:"type"`
+	TimeSlot       string          `json:"time_slot,omitempty"`
+	PickupID       string          `json:"pickup_id,omitempty"`
+	TrackingNumber string          `json:"tracking_number,omitempty"`
+	Status         string          `json:"status"`
+	Contact        ShipmentPerson  `json:"contact"` // Sender for pickups, recipient for deliveries
+	AreaCode       string          `json:"area_code,omitempty"`
+	ShipmentCount  int             `json:"shipment_count"`
+	COD            *CashOnDelivery `json:"cod,omitempty"`
+	Instructions   string          `json:"instructions,omitempty"`
+	Location       *GeoPoint       `json:"location,omitempty"` // From the structured address, nil if not geocoded
+	LegKm          float64         `json:"leg_km,omitempty"`   // Straight-line distance from the previous stop
+}
+
+// DriverRoute is the planned visiting order of a driver's stops on a date. It is kept until the
+// set of stops changes, so the order doesn't shuffle while the driver works through it.
+type DriverRoute struct {
+	ID         string      `bson:"_id" json:"-"` // driver ID|date
+	DriverID   string      `bson:"driver_id" json:"driver_id"`
+	Date       string      `bson:"date" json:"date"`
+	Start      *GeoPoint   `bson:"start,omitempty" json:"start,omitempty"`
+	Stops      []RouteStop `bson:"stops" json:"stops"` // In visiting order
+	DistanceKm float64     `bson:"distance_km" json:"distance_km"`
+	ComputedAt time.Time   `bson:"computed_at" json:"computed_at"`
+}
+
+// RouteStop is one stop of a DriverRoute
+type RouteStop struct {
+	Key      string    `bson:"key" json:"key"` // See DriverTask.Key
+	Location *GeoPoint `bson:"location,omitempty" json:"location,omitempty"`
+}
+
+// Key identifies the stop across route computations: "pickup:<id>" or "delivery:<tracking number>"
+func (t DriverTask) Key() string {
+	if t.Type == TaskPickup {
+		return TaskPickup + ":" + t.PickupID
+	}
+	return TaskDelivery + ":" + t.TrackingNumber
+}
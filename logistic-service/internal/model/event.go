@@ -0,0 +1,14 @@
+package model
+
+import "time"
+
+// ShipmentCancelledEvent is published to the shipment.cancelled queue.
+type ShipmentCancelledEvent struct {
+	TrackingNumber string    `json:"tracking_number"`
+	UserID         string    `json:"user_id"` // Owner of the shipment
+	ReasonCode     string    `json:"reason_code"`
+	Note           string    `json:"note,omitempty"`
+	CancelledBy    string    `json:"cancelled_by"`
+	Role           string    `json:"role"`
+	CancelledAt    time.Time `json:"cancelled_at"`
+}
@@ -0,0 +1,55 @@
+package model
+
+import "time"
+
+// Hub is a sorting hub or warehouse parcels pass through between pickup and delivery.
+type Hub struct {
+	Code       string    `bson:"_id" json:"code"` // Short unique code, e.g. "BDO01"
+	Name       string    `bson:"name" json:"name"`
+	Address    string    `bson:"address" json:"address"`
+	RegionCode string    `bson:"region_code,omitempty" json:"region_code,omitempty"` // Region dataset code of its city or district
+	Active     bool      `bson:"active" json:"active"`                               // Inactive hubs can't scan or receive manifests
+	CreatedAt  time.Time `bson:"created_at" json:"created_at"`
+	UpdatedAt  time.Time `bson:"updated_at" json:"updated_at"`
+}
+
+// Hub scan types
+const (
+	ScanInbound  = "inbound"  // parcel arrived at the hub
+	ScanOutbound = "outbound" // parcel left the hub
+)
+
+// HubScanStatuses are the statuses in which a shipment can be scanned at a hub
+var HubScanStatuses = []string{StatusPickedUp, StatusInTransit}
+
+// Manifest statuses
+const (
+	ManifestOpen       = "open"       // shipments can be added and removed
+	ManifestClosed     = "closed"     // bag sealed, waiting for dispatch
+	ManifestDispatched = "dispatched" // left the origin hub
+	ManifestReceived   = "received"   // arrived and checked at the destination hub
+)
+
+// Manifest is a bag of shipments moving from one hub to another.
+// Shipments in an open, closed or dispatched manifest reference it through Shipment.ManifestID.
+type Manifest struct {
+	ID              string               `bson:"_id" json:"id"`
+	OriginHub       string               `bson:"origin_hub" json:"origin_hub"`
+	DestinationHub  string               `bson:"destination_hub" json:"destination_hub"`
+	TrackingNumbers []string             `bson:"tracking_numbers" json:"tracking_numbers"`
+	Status          string               `bson:"status" json:"status"`
+	CreatedBy       string               `bson:"created_by" json:"created_by"`
+	CreatedAt       time.Time            `bson:"created_at" json:"created_at"`
+	UpdatedAt       time.Time            `bson:"updated_at" json:"updated_at"`
+	ClosedAt        *time.Time           `bson:"closed_at,omitempty" json:"closed_at,omitempty"`
+	DispatchedAt    *time.Time           `bson:"dispatched_at,omitempty" json:"dispatched_at,omitempty"`
+	ReceivedAt      *time.Time           `bson:"received_at,omitempty" json:"received_at,omitempty"`
+	ReceivedBy      string               `bson:"received_by,omitempty" json:"received_by,omitempty"`
+	Discrepancy     *ManifestDiscrepancy `bson:"discrepancy,omitempty" json:"discrepancy,omitempty"` // Set on receive when the contents don't match
+}
+
+// ManifestDiscrepancy lists the differences found when a manifest is received.
+type ManifestDiscrepancy struct {
+	Missing []string `bson:"missing" json:"missing"` // In the manifest but not scanned at the destination
+	Extra   []string `bson:"extra" json:"extra"`     // Scanned at the destination but not in the manifest
+}
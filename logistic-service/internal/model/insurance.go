@@ -0,0 +1,111 @@
+package model
+
+import "time"
+
+// InsuranceCurrency is the currency of declared values, insurance premiums and claim payouts
+const InsuranceCurrency = "IDR"
+
+// ShipmentInsurance covers the contents of a shipment up to InsuredValue against loss and damage.
+// Clients only send insured_value (defaults to the declared value); the rest is computed.
+// Amounts are integers in rupiah.
+type ShipmentInsurance struct {
+	InsuredValue int64  `bson:"insured_value" json:"insured_value"`
+	Currency     string `bson:"currency" json:"currency"`
+	RateBps      int64  `bson:"rate_bps" json:"rate_bps"` // Premium rate in basis points of the insured value
+	Premium      int64  `bson:"premium" json:"premium"`
+}
+
+// InsuranceRule sets the premium of insured shipments. Empty LogisticName and ServiceLevel
+// match every courier and service level.
+type InsuranceRule struct {
+	LogisticName string `json:"logistic_name,omitempty"`
+	ServiceLevel string `json:"service_level,omitempty"`
+	MinValue     int64  `json:"min_value,omitempty"` // Applies to insured values from MinValue up
+	RateBps      int64  `json:"rate_bps"`
+	MinPremium   int64  `json:"min_premium"`
+}
+
+// InsurancePolicy holds the premium rules and the limits of insurance and claims.
+// The first rule matching a shipment sets its premium.
+type InsurancePolicy struct {
+	Currency           string          `json:"currency"`
+	MaxInsuredValue    int64           `json:"max_insured_value"`
+	UninsuredLiability int64           `json:"uninsured_liability"` // Payout limit of claims on shipments without insurance
+	ClaimWindowDays    int             `json:"claim_window_days"`   // Days after delivery in which damage can be claimed
+	Rules              []InsuranceRule `json:"rules"`
+}
+
+// Claim types
+const (
+	ClaimDamaged      = "damaged"       // contents arrived damaged
+	ClaimMissingItems = "missing_items" // parcel arrived with items missing
+	ClaimLost         = "lost"          // parcel never arrived
+)
+
+// ClaimTypes lists every accepted Claim.Type
+var ClaimTypes = []string{ClaimDamaged, ClaimMissingItems, ClaimLost}
+
+// Claim statuses
+const (
+	ClaimSubmitted = "submitted" // filed, waiting for ops
+	ClaimInReview  = "in_review"
+	ClaimApproved  = "approved" // payout amount set, waiting for payment
+	ClaimRejected  = "rejected"
+	ClaimPaid      = "paid"
+)
+
+// ClaimTransitions lists the statuses a claim can move to from each status
+var ClaimTransitions = map[string][]string{
+	ClaimSubmitted: {ClaimInReview, ClaimApproved, ClaimRejected},
+	ClaimInReview:  {ClaimApproved, ClaimRejected},
+	ClaimApproved:  {ClaimPaid},
+}
+
+// ClaimEvidenceStatuses are the statuses in which evidence can still be added to a claim
+var ClaimEvidenceStatuses = []string{ClaimSubmitted, ClaimInReview}
+
+// Claim is a request of the shipment owner to be compensated for a lost or damaged parcel.
+// A shipment has at most one claim. Amounts are integers in rupiah.
+type Claim struct {
+	ID              string          `bson:"_id" json:"id"`
+	TrackingNumber  string          `bson:"tracking_number" json:"tracking_number"`
+	MerchantID      string          `bson:"merchant_id" json:"merchant_id"` // Owner of the shipment
+	LogisticName    string          `bson:"logistic_name" json:"logistic_name"`
+	Type            string          `bson:"type" json:"type"`
+	Description     string          `bson:"description" json:"description"`
+	Currency        string          `bson:"currency" json:"currency"`
+	ClaimedAmount   int64           `bson:"claimed_amount" json:"claimed_amount"`
+	Insured         bool            `bson:"insured" json:"insured"`
+	PayoutLimit     int64           `bson:"payout_limit" json:"payout_limit"` // Insured value, or the uninsured liability
+	PayoutAmount    int64           `bson:"payout_amount,omitempty" json:"payout_amount,omitempty"`
+	PayoutReference string          `bson:"payout_reference,omitempty" json:"payout_reference,omitempty"` // e.g. bank transfer reference
+	Status          string          `bson:"status" json:"status"`
+	ReviewerID      string          `bson:"reviewer_id,omitempty" json:"reviewer_id,omitempty"` // User ID of the ops reviewing it
+	Resolution      string          `bson:"resolution,omitempty" json:"resolution,omitempty"`   // Reason of the approval or rejection
+	Evidence        []ClaimEvidence `bson:"evidence" json:"evidence"`
+	Events          []ClaimEvent    `bson:"events" json:"events"` // Status history, oldest first
+	FiledBy         string          `bson:"filed_by" json:"filed_by"`
+	CreatedAt       time.Time       `bson:"created_at" json:"created_at"`
+	UpdatedAt       time.Time       `bson:"updated_at" json:"updated_at"`
+	ResolvedAt      *time.Time      `bson:"resolved_at,omitempty" json:"resolved_at,omitempty"` // Approved or rejected
+	PaidAt          *time.Time      `bson:"paid_at,omitempty" json:"paid_at,omitempty"`
+}
+
+// ClaimEvidence is a photo or document supporting a claim, kept in blob storage.
+type ClaimEvidence struct {
+	ID          string    `bson:"id" json:"id"`
+	FileName    string    `bson:"file_name" json:"file_name"`
+	ContentType string    `bson:"content_type" json:"content_type"`
+	Size        int64     `bson:"size" json:"size"`
+	Key         string    `bson:"key" json:"-"`
+	UploadedBy  string    `bson:"uploaded_by" json:"uploaded_by"`
+	UploadedAt  time.Time `bson:"uploaded_at" json:"uploaded_at"`
+}
+
+// ClaimEvent is a single entry in the claim status history.
+type ClaimEvent struct {
+	Status    string    `bson:"status" json:"status"`
+	Note      string    `bson:"note,omitempty" json:"note,omitempty"`
+	Actor     string    `bson:"actor" json:"actor"` // User ID that triggered the event
+	Timestamp time.Time `bson:"timestamp" json:"timestamp"`
+}
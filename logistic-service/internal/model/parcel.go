@@ -0,0 +1,54 @@
+package model
+
+import "time"
+
+// MaxParcels is the maximum number of parcels (boxes) in one shipment
+const MaxParcels = 50
+
+// ParcelStatuses are the statuses a parcel goes through, in delivery order. A multi-parcel
+// shipment is as far as its least advanced parcel.
+var ParcelStatuses = []string{StatusOnProcess, StatusPickedUp, StatusInTransit, StatusDelivered}
+
+// ParcelStatusesAtOrPast returns the statuses in which a parcel stays put when its whole shipment
+// moves to status: status and the later ParcelStatuses. When the shipment leaves the delivery
+// flow (cancelled, returned to sender) only delivered parcels stay put.
+func ParcelStatusesAtOrPast(status string) []string {
+	for i, s := range ParcelStatuses {
+		if s == status {
+			return ParcelStatuses[i:]
+		}
+	}
+	return []string{status, StatusDelivered}
+}
+
+// ParcelScanStatuses are the statuses a parcel can be scanned to, see
+// PATCH /shipments/:trackingNumber/parcels/:parcelTrackingNumber/status
+var ParcelScanStatuses = []string{StatusPickedUp, StatusInTransit, StatusDelivered}
+
+// ParcelScanShipmentStatuses are the shipment statuses in which its parcels can be scanned.
+// Parcels are picked up together with their shipment, by completing its pickup.
+var ParcelScanShipmentStatuses = []string{StatusPickedUp, StatusInTransit}
+
+// Parcel is one box of a shipment sent in several boxes. Each parcel has its own label and
+// piece tracking number (the shipment tracking number plus "-P" and the piece number) and is
+// scanned on its own, so part of an order can be delivered before the rest. Clients only send
+// weight, dimensions and description; the rest is set by the service.
+type Parcel struct {
+	PieceNumber    int             `bson:"piece_number" json:"piece_number"` // 1-based
+	TrackingNumber string          `bson:"tracking_number" json:"tracking_number"`
+	Description    string          `bson:"description,omitempty" json:"description,omitempty"`
+	Weight         float64         `bson:"weight" json:"weight"`                     // Kilograms
+	Length         float64         `bson:"length,omitempty" json:"length,omitempty"` // Centimetres
+	Width          float64         `bson:"width,omitempty" json:"width,omitempty"`
+	Height         float64         `bson:"height,omitempty" json:"height,omitempty"`
+	Status         string          `bson:"status" json:"status"`
+	Events         []TrackingEvent `bson:"events" json:"events,omitempty"` // Parcel timeline, oldest first
+	UpdatedAt      time.Time       `bson:"updated_at" json:"updated_at"`
+}
+
+// ParcelProgress counts the parcels of a shipment by status
+type ParcelProgress struct {
+	Total     int            `json:"total"`
+	Delivered int            `json:"delivered"`
+	ByStatus  map[string]int `json:"by_status"`
+}
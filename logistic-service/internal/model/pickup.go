@@ -0,0 +1,66 @@
+package model
+
+import "time"
+
+// Pickup statuses
+const (
+	PickupScheduled = "scheduled" // booked, waiting for a courier
+	PickupAssigned  = "assigned"  // a courier is on the way
+	PickupCompleted = "completed" // parcels collected, shipments moved to picked_up
+	PickupFailed    = "failed"    // courier could not collect the parcels
+	PickupCancelled = "cancelled"
+)
+
+// PickupTransitions lists the statuses a pickup can move to from each status
+var PickupTransitions = map[string][]string{
+	PickupScheduled: {PickupAssigned, PickupCompleted, PickupFailed, PickupCancelled},
+	PickupAssigned:  {PickupCompleted, PickupFailed, PickupCancelled},
+}
+
+// Pickup is a courier pickup of one or more on_process shipments at the sender address
+// in a time slot. Shipments in a pickup reference it through Shipment.PickupID.
+type Pickup struct {
+	ID              string         `bson:"_id" json:"id"`
+	UserID          string         `bson:"user_id" json:"user_id"`             // Owner of the shipments
+	LogisticName    string         `bson:"logistic_name" json:"logistic_name"` // Courier doing the pickup
+	AreaCode        string         `bson:"area_code" json:"area_code"`         // Origin district code, or origin text
+	Address         ShipmentPerson `bson:"address" json:"address"`             // Sender of the shipments
+	TrackingNumbers []string       `bson:"tracking_numbers" json:"tracking_numbers"`
+	Date            string         `bson:"date" json:"date"` // YYYY-MM-DD, WIB
+	TimeSlot        string         `bson:"time_slot" json:"time_slot"`
+	Notes           string         `bson:"notes,omitempty" json:"notes,omitempty"`
+	Status          string         `bson:"status" json:"status"`
+	CourierID       string         `bson:"courier_id,omitempty" json:"courier_id,omitempty"` // User ID of the assigned courier
+	Events          []PickupEvent  `bson:"events" json:"events"`                             // Status history, oldest first
+	CreatedAt       time.Time      `bson:"created_at" json:"created_at"`
+	UpdatedAt       time.Time      `bson:"updated_at" json:"updated_at"`
+}
+
+// PickupEvent is a single entry in the pickup status history.
+type PickupEvent struct {
+	Status    string    `bson:"status" json:"status"`
+	Note      string    `bson:"note,omitempty" json:"note,omitempty"`
+	Actor     string    `bson:"actor" json:"actor"` // User ID that triggered the event
+	Timestamp time.Time `bson:"timestamp" json:"timestamp"`
+}
+
+// PickupSlot is the booking state of one time slot of a courier in an area on a date.
+type PickupSlot struct {
+	LogisticName string `bson:"logistic_name" json:"logistic_name"`
+	AreaCode     string `bson:"area_code" json:"area_code"`
+	Date         string `bson:"date" json:"date"`
+	TimeSlot     string `bson:"time_slot" json:"time_slot"`
+	Booked       int    `bson:"booked" json:"booked"`
+	Capacity     int    `bson:"-" json:"capacity"`
+	Available    int    `bson:"-" json:"available"`
+}
+
+// PickupCapacity overrides the default slot capacity of a courier in an area.
+type PickupCapacity struct {
+	ID           string    `bson:"_id" json:"-"`
+	LogisticName string    `bson:"logistic_name" json:"logistic_name"`
+	AreaCode     string    `bson:"area_code" json:"area_code"`
+	Capacity     int       `bson:"capacity" json:"capacity"` // Pickups per time slot
+	UpdatedBy    string    `bson:"updated_by" json:"updated_by"`
+	UpdatedAt    time.Time `bson:"updated_at" json:"updated_at"`
+}
@@ -0,0 +1,21 @@
+package model
+
+import "time"
+
+// Return shipment types
+const (
+	ReturnRTO      = "rto"             // Return to sender after delivery failed
+	ReturnCustomer = "customer_return" // Recipient sends a delivered parcel back
+)
+
+// ShipmentReturn links a return shipment to the original shipment it sends back.
+// The return shipment swaps sender and recipient and has its own tracking number and lifecycle.
+type ShipmentReturn struct {
+	Type                   string    `bson:"type" json:"type"`
+	OriginalTrackingNumber string    `bson:"original_tracking_number" json:"original_tracking_number"`
+	ReasonCode             string    `bson:"reason_code" json:"reason_code"`       // Code from the return reason catalog
+	Note                   string    `bson:"note,omitempty" json:"note,omitempty"` // Free text, required for reason "other"
+	RequestedBy            string    `bson:"requested_by" json:"requested_by"`     // User ID of the caller
+	Role                   string    `bson:"role" json:"role"`
+	RequestedAt            time.Time `bson:"requested_at" json:"requested_at"`
+}
@@ -5,12 +5,58 @@ import (
 	"time"
 	"gorm.io/gorm"
 )
+
+// Shipment statuses
+const (
+	StatusOnProcess = "on_process" // created, waiting for pickup
+	StatusPickedUp  = "picked_up"
+	StatusInTransit = "in_transit"
+	StatusDelivered = "delivered"
+	StatusCancelled = "cancelled"
+
+	StatusReturnToSender = "return_to_sender" // RTO requested, the parcel travels back on a return shipment
+	StatusReturned       = "returned"         // RTO return shipment delivered to the sender
+)
+
+// PrePickupStatuses are the statuses in which sender, recipient and items can still be edited
+var PrePickupStatuses = []string{StatusOnProcess}
+
+// PODCaptureStatuses are the statuses in which proof of delivery can be captured
+var PODCaptureStatuses = []string{StatusPickedUp, StatusInTransit}
+
+// RTOStatuses are the statuses in which a shipment can be returned to sender.
+// return_to_sender is included so a cancelled RTO can be requested again.
+var RTOStatuses = []string{StatusPickedUp, StatusInTransit, StatusReturnToSender}
+
+// DeliveryAttemptStatuses are the statuses in which a failed delivery attempt can be recorded
+var DeliveryAttemptStatuses = []string{StatusPickedUp, StatusInTransit}
+
+// ReschedulableStatuses are the statuses in which the recipient can reschedule the next attempt
+// or change the delivery instructions
+var ReschedulableStatuses = []string{StatusOnProcess, StatusPickedUp, StatusInTransit}
+
+// PickupStatuses are the statuses in which a shipment can be booked in a pickup
+var PickupStatuses = []string{StatusOnProcess}
+
+// CustomerReturnStatuses are the statuses in which the recipient can send a shipment back
+var CustomerReturnStatuses = []string{StatusDelivered}
+
+// StatusUpdateFrom lists by target status the statuses couriers and ops can move a shipment from
+// with PATCH /shipments/:trackingNumber/status. Other changes have their own flows: pickups set
+// picked_up, the cancel and RTO endpoints cancelled and return_to_sender, and delivering a
+// return shipment sets returned on the original.
+var StatusUpdateFrom = map[string][]string{
+	StatusInTransit: {StatusPickedUp},
+	StatusDelivered: {StatusPickedUp, StatusInTransit},
+}
+
 // ShipmentItem represents a single item in a shipment order.
-// Contains the item name, quantity, and weight (in kg).
+// Contains the item name, quantity, weight (in kg) and declared value.
 type ShipmentItem struct {
-	Name   string  `bson:"name" json:"name"`     // Name of the item
-	Qty    int     `bson:"qty" json:"qty"`       // Quantity of the item
-	Weight float64 `bson:"weight" json:"weight"` // Weight per item in kilograms
+	Name          string  `bson:"name" json:"name"`                                         // Name of the item
+	Qty           int     `bson:"qty" json:"qty"`                                           // Quantity of the item
+	Weight        float64 `bson:"weight" json:"weight"`                                     // Weight per item in kilograms
+	DeclaredValue int64   `bson:"declared_value,omitempty" json:"declared_value,omitempty"` // Value per item in rupiah
 }
 
 // Shipment represents the main shipment order data.
@@ -22,6 +68,12 @@ type Shipment struct {
 	Status         string         `json:"status"`
 	Origin         string         `json:"origin"`
 	Destination    string         `json:"destination"`
+
+	// District (kecamatan) region codes of sender and recipient, used for rating and routing.
+	// Set from the structured addresses; empty for shipments with free text addresses only.
+	OriginCode      string `gorm:"column:origin_code" json:"origin_code,omitempty"`
+	DestinationCode string `gorm:"column:destination_code" json:"destination_code,omitempty"`
+
 	Notes          string         `json:"notes,omitempty"`
 	UserID         string         `gorm:"index;column:user_id" json:"user_id"`
 	CreatedAt      time.Time      `json:"created_at"`
@@ -40,6 +92,68 @@ type Shipment struct {
 	Recipient ShipmentPerson `gorm:"-" json:"recipient"` // ignore by GORM
 
 	Items []ShipmentItem `json:"items"`
+
+	// Boxes of an order shipped in several parcels, empty for single parcel shipments.
+	// The shipment status follows its least advanced parcel.
+	Parcels []Parcel `gorm:"-" json:"parcels,omitempty"`
+
+	// Tracking timeline, oldest first. Appended on every status change.
+	Events []TrackingEvent `gorm:"-" json:"events,omitempty"`
+
+	// Set when the shipment was cancelled through the cancel endpoint
+	Cancellation *ShipmentCancellation `gorm:"-" json:"cancellation,omitempty"`
+
+	// Cash on delivery, nil for prepaid shipments
+	COD *CashOnDelivery `gorm:"-" json:"cod,omitempty"`
+
+	// Value of the contents in rupiah, at least the total declared value of the items.
+	// Insurance is optional and covers up to the declared value.
+	DeclaredValue int64              `gorm:"column:declared_value" json:"declared_value,omitempty"`
+	Insurance     *ShipmentInsurance `gorm:"-" json:"insurance,omitempty"`
+
+	// Service level booked, see GET /service-levels. PromisedDate is the delivery date committed
+	// to at creation and ETA the current estimate, updated on every tracking event (YYYY-MM-DD, WIB).
+	ServiceLevel  string     `gorm:"column:service_level" json:"service_level"`
+	PromisedDate  string     `gorm:"column:promised_date" json:"promised_date,omitempty"`
+	ETA           string     `gorm:"column:eta" json:"eta,omitempty"`
+	SLABreachedAt *time.Time `gorm:"column:sla_breached_at" json:"sla_breached_at,omitempty"` // Set once the promised date passed undelivered
+
+	// Pickup the shipment is booked in (see POST /pickups), cleared if the pickup fails or is cancelled
+	PickupID string `gorm:"-" json:"pickup_id,omitempty"`
+
+	// Hub the parcel was last scanned in at, empty once it left. ManifestID is the manifest
+	// (bag) it is in until the manifest is received.
+	CurrentHub string `gorm:"-" json:"current_hub,omitempty"`
+	ManifestID string `gorm:"-" json:"manifest_id,omitempty"`
+
+	// Courier assigned to deliver the shipment, see POST /shipments/:trackingNumber/driver
+	DriverID string `gorm:"-" json:"driver_id,omitempty"`
+
+	// Failed delivery attempts, oldest first. Reaching the maximum starts an RTO.
+	DeliveryAttempts []DoorstepAttempt `gorm:"-" json:"delivery_attempts,omitempty"`
+
+	// Set by the recipient through the public reschedule endpoint, cleared by the next attempt
+	NextAttempt          *DeliverySchedule `gorm:"-" json:"next_attempt,omitempty"`
+	DeliveryInstructions string            `gorm:"-" json:"delivery_instructions,omitempty"`
+
+	// Evidence captured by the courier at delivery, required before status delivered
+	ProofOfDelivery *ProofOfDelivery `gorm:"-" json:"proof_of_delivery,omitempty"`
+
+	// Phonetic keys of the sender and recipient names for the customer service search, kept
+	// up to date on creation and edits
+	SearchKeys []string `gorm:"-" json:"-"`
+
+	// Incremented on every change, used for optimistic concurrency on edits
+	Version int `gorm:"-" json:"version"`
+
+	// Edit history of sender, recipient, items and notes, oldest first
+	Revisions []ShipmentRevision `gorm:"-" json:"revisions,omitempty"`
+
+	// Set on return shipments: the shipment being sent back and why
+	Return *ShipmentReturn `gorm:"-" json:"return,omitempty"`
+
+	// Return shipments created for this shipment, oldest first
+	ReturnTrackingNumbers []string `gorm:"-" json:"return_tracking_numbers,omitempty"`
 }
 
 func (s *Shipment) BeforeSave(tx *gorm.DB) (err error) {
@@ -60,4 +174,80 @@ type ShipmentPerson struct {
 	Name    string `bson:"name" json:"name"`       // Person's full name
 	Phone   string `bson:"phone" json:"phone"`     // Person's phone number
 	Address string `bson:"address" json:"address"` // Person's address
+
+	// Optional structured address, validated against the region dataset.
+	// When set, Address is composed from it if left empty.
+	Structured *StructuredAddress `bson:"structured_address,omitempty" json:"structured_address,omitempty"`
+}
+
+// StructuredAddress is an Indonesian address down to the sub-district (kelurahan/desa).
+// Clients send street and subdistrict_code; the other codes, names and the postal code are
+// filled in from the region dataset.
+type StructuredAddress struct {
+	Street          string    `bson:"street" json:"street"` // Street, house number, RT/RW
+	ProvinceCode    string    `bson:"province_code" json:"province_code"`
+	Province        string    `bson:"province" json:"province"`
+	CityCode        string    `bson:"city_code" json:"city_code"` // City (kota) or regency (kabupaten)
+	City            string    `bson:"city" json:"city"`
+	DistrictCode    string    `bson:"district_code" json:"district_code"` // Kecamatan
+	District        string    `bson:"district" json:"district"`
+	SubdistrictCode string    `bson:"subdistrict_code" json:"subdistrict_code"` // Kelurahan or desa
+	Subdistrict     string    `bson:"subdistrict" json:"subdistrict"`
+	PostalCode      string    `bson:"postal_code" json:"postal_code"`
+	Location        *GeoPoint `bson:"location,omitempty" json:"location,omitempty"` // Geocoded by the client, used for route planning
+}
+
+// GeoPoint is a position in decimal degrees (WGS84).
+type GeoPoint struct {
+	Latitude  float64 `bson:"latitude" json:"latitude"`
+	Longitude float64 `bson:"longitude" json:"longitude"`
+}
+
+// TrackingEvent represents a single entry in the shipment tracking timeline.
+type TrackingEvent struct {
+	Status      string    `bson:"status" json:"status"`                               // Shipment status after this event
+	Description string    `bson:"description,omitempty" json:"description,omitempty"` // Human readable description, shown publicly
+	Note        string    `bson:"note,omitempty" json:"note,omitempty"`               // Free text of the courier or scanner, never shown publicly
+	Location    string    `bson:"location,omitempty" json:"location,omitempty"`       // Where the event happened, if known
+	HubCode     string    `bson:"hub_code,omitempty" json:"hub_code,omitempty"`       // Hub of scan events
+	Actor       string    `bson:"actor,omitempty" json:"actor,omitempty"`             // User ID that triggered the event
+	Timestamp   time.Time `bson:"timestamp" json:"timestamp"`
+}
+
+// ShipmentCancellation records who cancelled a shipment and why.
+type ShipmentCancellation struct {
+	ReasonCode  string    `bson:"reason_code" json:"reason_code"`       // Code from the cancellation reason catalog
+	Note        string    `bson:"note,omitempty" json:"note,omitempty"` // Free text, required for reason "other"
+	CancelledBy string    `bson:"cancelled_by" json:"cancelled_by"`     // User ID of the caller
+	Role        string    `bson:"role" json:"role"`                     // Role of the caller at cancellation time
+	CancelledAt time.Time `bson:"cancelled_at" json:"cancelled_at"`
+}
+
+// ProofOfDelivery is the evidence captured when a shipment is handed over.
+// Photo and signature are stored in blob storage; only their keys are kept here.
+type ProofOfDelivery struct {
+	ReceiverName         string    `bson:"receiver_name" json:"receiver_name"` // Name of the person who received the parcel
+	Latitude             float64   `bson:"latitude" json:"latitude"`
+	Longitude            float64   `bson:"longitude" json:"longitude"`
+	PhotoKey             string    `bson:"photo_key" json:"-"`
+	PhotoContentType     string    `bson:"photo_content_type" json:"-"`
+	SignatureKey         string    `bson:"signature_key" json:"-"`
+	SignatureContentType string    `bson:"signature_content_type" json:"-"`
+	CapturedBy           string    `bson:"captured_by" json:"captured_by"` // User ID of the courier
+	CapturedAt           time.Time `bson:"captured_at" json:"captured_at"`
+}
+
+// ShipmentRevision records a single edit of a shipment.
+type ShipmentRevision struct {
+	Version  int           `bson:"version" json:"version"`     // Shipment version produced by this edit
+	EditedBy string        `bson:"edited_by" json:"edited_by"` // User ID of the editor
+	EditedAt time.Time     `bson:"edited_at" json:"edited_at"`
+	Changes  []FieldChange `bson:"changes" json:"changes"`
+}
+
+// FieldChange is the old and new value of a single edited field, e.g. "recipient.address".
+type FieldChange struct {
+	Field string      `bson:"field" json:"field"`
+	Old   interface{} `bson:"old" json:"old"`
+	New   interface{} `bson:"new" json:"new"`
 }
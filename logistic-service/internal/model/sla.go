@@ -0,0 +1,27 @@
+package model
+
+// Service levels a shipment can be booked with, see ServiceLevel
+const (
+	ServiceSameDay = "sameday"
+	ServiceExpress = "express"
+	ServiceRegular = "regular" // default
+	ServiceEconomy = "economy"
+)
+
+// Route zones, from the origin and destination region codes
+const (
+	ZoneCity     = "city"     // same city or regency
+	ZoneProvince = "province" // same province
+	ZoneNational = "national"
+)
+
+// SLAOpenStatuses are the statuses in which a shipment can still breach its promised date
+var SLAOpenStatuses = []string{StatusOnProcess, StatusPickedUp, StatusInTransit}
+
+// ServiceLevel is an entry of the service level catalog.
+type ServiceLevel struct {
+	Code        string `json:"code"`
+	Description string `json:"description"`
+	// Working days from handover to delivery per route zone; zones not listed are not served
+	TransitDays map[string]int `json:"transit_days"`
+}
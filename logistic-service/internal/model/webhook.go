@@ -0,0 +1,82 @@
+package model
+
+import "time"
+
+// Webhook events a merchant can subscribe to
+const (
+	EventShipmentCreated   = "shipment.created"
+	EventShipmentUpdated   = "shipment.updated"
+	EventShipmentDelivered = "shipment.delivered"
+	EventShipmentCancelled = "shipment.cancelled"
+
+	EventShipmentDeliveryFailed = "shipment.delivery_failed"
+	EventShipmentSLABreached    = "shipment.sla_breached"
+
+	EventClaimUpdated = "claim.updated" // claim filed or moved to another status
+)
+
+// WebhookEvents lists every event accepted in Webhook.Events
+var WebhookEvents = []string{
+	EventShipmentCreated,
+	EventShipmentUpdated,
+	EventShipmentDelivered,
+	EventShipmentCancelled,
+	EventShipmentDeliveryFailed,
+	EventShipmentSLABreached,
+	EventClaimUpdated,
+}
+
+// Webhook delivery statuses
+const (
+	DeliveryPending   = "pending"
+	DeliverySucceeded = "succeeded"
+	DeliveryFailed    = "failed"
+)
+
+// Webhook is an endpoint registered by a user to receive shipment lifecycle events.
+type Webhook struct {
+	ID             string     `bson:"_id" json:"id"`
+	UserID         string     `bson:"user_id" json:"user_id"`
+	URL            string     `bson:"url" json:"url"`
+	Secret         string     `bson:"secret" json:"-"`      // HMAC-SHA256 signing key, only returned on create
+	Events         []string   `bson:"events" json:"events"` // Subscribed events, see WebhookEvents
+	Active         bool       `bson:"active" json:"active"`
+	FailureCount   int        `bson:"failure_count" json:"failure_count"` // Consecutive failed attempts
+	DisabledAt     *time.Time `bson:"disabled_at,omitempty" json:"disabled_at,omitempty"`
+	DisabledReason string     `bson:"disabled_reason,omitempty" json:"disabled_reason,omitempty"`
+	CreatedAt      time.Time  `bson:"created_at" json:"created_at"`
+	UpdatedAt      time.Time  `bson:"updated_at" json:"updated_at"`
+}
+
+// Subscribed reports whether the webhook wants the given event.
+func (w *Webhook) Subscribed(event string) bool {
+	for _, e := range w.Events {
+		if e == event {
+			return true
+		}
+	}
+	return false
+}
+
+// WebhookDelivery is a single event payload queued for a webhook, with its attempt log.
+type WebhookDelivery struct {
+	ID            string            `bson:"_id" json:"id"`
+	WebhookID     string            `bson:"webhook_id" json:"webhook_id"`
+	UserID        string            `bson:"user_id" json:"user_id"`
+	Event         string            `bson:"event" json:"event"`
+	Payload       string            `bson:"payload" json:"payload"` // Exact JSON body sent to the endpoint
+	Status        string            `bson:"status" json:"status"`
+	Attempts      []DeliveryAttempt `bson:"attempts" json:"attempts"`
+	NextAttemptAt time.Time         `bson:"next_attempt_at" json:"next_attempt_at"`
+	RedeliveryOf  string            `bson:"redelivery_of,omitempty" json:"redelivery_of,omitempty"`
+	CreatedAt     time.Time         `bson:"created_at" json:"created_at"`
+	CompletedAt   *time.Time        `bson:"completed_at,omitempty" json:"completed_at,omitempty"`
+}
+
+// DeliveryAttempt is the outcome of one HTTP request to a webhook endpoint.
+type DeliveryAttempt struct {
+	At         time.Time `bson:"at" json:"at"`
+	StatusCode int       `bson:"status_code,omitempty" json:"status_code,omitempty"`
+	Error      string    `bson:"error,omitempty" json:"error,omitempty"`
+	DurationMs int64     `bson:"duration_ms" json:"duration_ms"`
+}
@@ -0,0 +1,137 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"logistic-service/internal/model"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// BulkJobRepository handles the "bulk_jobs" MongoDB collection
+type BulkJobRepository struct {
+	col *mongo.Collection
+}
+
+// NewBulkJobRepository creates a new BulkJobRepository
+func NewBulkJobRepository(db *mongo.Database) *BulkJobRepository {
+	return &BulkJobRepository{col: db.Collection("bulk_jobs")}
+}
+
+// EnsureIndexes creates the unique (user_id, file_hash) index that makes uploads idempotent
+func (r *BulkJobRepository) EnsureIndexes() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	_, err := r.col.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "user_id", Value: 1}, {Key: "file_hash", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	})
+	return err
+}
+
+// Insert stores a new job. Returns a duplicate key error if the user already uploaded the same file.
+func (r *BulkJobRepository) Insert(job *model.BulkJob) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	_, err := r.col.InsertOne(ctx, job)
+	return err
+}
+
+// Replace overwrites a job, used to restart a failed job on resubmission
+func (r *BulkJobRepository) Replace(job *model.BulkJob) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	_, err := r.col.ReplaceOne(ctx, bson.M{"_id": job.ID}, job)
+	return err
+}
+
+// FindByID returns a job, or (nil, nil) if it doesn't exist
+func (r *BulkJobRepository) FindByID(id string) (*model.BulkJob, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var job model.BulkJob
+	err := r.col.FindOne(ctx, bson.M{"_id": id}).Decode(&job)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	return &job, err
+}
+
+// FindByHash returns the job of a user for a file hash, or (nil, nil) if none
+func (r *BulkJobRepository) FindByHash(userID, fileHash string) (*model.BulkJob, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var job model.BulkJob
+	err := r.col.FindOne(ctx, bson.M{"user_id": userID, "file_hash": fileHash}).Decode(&job)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	return &job, err
+}
+
+// MarkStarted sets the job status to processing
+func (r *BulkJobRepository) MarkStarted(id string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	_, err := r.col.UpdateOne(ctx, bson.M{"_id": id}, bson.M{"$set": bson.M{
+		"status":     model.BulkJobProcessing,
+		"started_at": time.Now(),
+	}})
+	return err
+}
+
+// RecordProgress adds processed rows to the counters and appends their errors
+func (r *BulkJobRepository) RecordProgress(id string, succeeded, failed int, rowErrors []model.BulkRowError) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	update := bson.M{"$inc": bson.M{
+		"processed": succeeded + failed,
+		"succeeded": succeeded,
+		"failed":    failed,
+	}}
+	if len(rowErrors) > 0 {
+		update["$push"] = bson.M{"errors": bson.M{"$each": rowErrors}}
+	}
+	_, err := r.col.UpdateOne(ctx, bson.M{"_id": id}, update)
+	return err
+}
+
+// MarkFinished sets the final job status; jobErr is stored for failed jobs
+func (r *BulkJobRepository) MarkFinished(id, status, jobErr string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	set := bson.M{"status": status, "finished_at": time.Now()}
+	if jobErr != "" {
+		set["error"] = jobErr
+	}
+	_, err := r.col.UpdateOne(ctx, bson.M{"_id": id}, bson.M{"$set": set})
+	return err
+}
+
+// FailInterrupted marks jobs left queued or processing by a previous run as failed,
+// so they can be restarted by uploading the file again
+func (r *BulkJobRepository) FailInterrupted() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	_, err := r.col.UpdateMany(ctx,
+		bson.M{"status": bson.M{"$in": bson.A{model.BulkJobQueued, model.BulkJobProcessing}}},
+		bson.M{"$set": bson.M{
+			"status":      model.BulkJobFailed,
+			"error":       "interrupted by service restart, upload the file again to resume",
+			"finished_at": time.Now(),
+		}},
+	)
+	return err
+}
@@ -0,0 +1,146 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"logistic-service/internal/model"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// ClaimRepository handles the "claims" MongoDB collection
+type ClaimRepository struct {
+	claims *mongo.Collection
+}
+
+// NewClaimRepository creates a new ClaimRepository
+func NewClaimRepository(db *mongo.Database) *ClaimRepository {
+	return &ClaimRepository{claims: db.Collection("claims")}
+}
+
+// EnsureIndexes creates the indexes used by claim listing. The unique tracking number index
+// guarantees a shipment has at most one claim.
+func (r *ClaimRepository) EnsureIndexes() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	_, err := r.claims.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "tracking_number", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "merchant_id", Value: 1}, {Key: "created_at", Value: -1}}},
+		{Keys: bson.D{{Key: "status", Value: 1}, {Key: "created_at", Value: -1}}},
+	})
+	return err
+}
+
+// Insert stores a new claim. Returns a duplicate key error if the shipment already has a claim.
+func (r *ClaimRepository) Insert(claim *model.Claim) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	_, err := r.claims.InsertOne(ctx, claim)
+	return err
+}
+
+// FindByID returns a claim, or (nil, nil) if it doesn't exist
+func (r *ClaimRepository) FindByID(id string) (*model.Claim, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var claim model.Claim
+	err := r.claims.FindOne(ctx, bson.M{"_id": id}).Decode(&claim)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	return &claim, err
+}
+
+// ClaimFilter narrows List results. Empty fields match everything.
+type ClaimFilter struct {
+	MerchantID     string
+	TrackingNumber string
+	LogisticName   string
+	Status         string
+	Type           string
+}
+
+// List returns the claims matching filter, newest first
+func (r *ClaimRepository) List(f ClaimFilter, limit int64) ([]*model.Claim, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	filter := bson.M{}
+	for key, value := range map[string]string{
+		"merchant_id":     f.MerchantID,
+		"tracking_number": f.TrackingNumber,
+		"logistic_name":   f.LogisticName,
+		"status":          f.Status,
+		"type":            f.Type,
+	} {
+		if value != "" {
+			filter[key] = value
+		}
+	}
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}}).SetLimit(limit)
+	cursor, err := r.claims.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	results := []*model.Claim{}
+	err = cursor.All(ctx, &results)
+	return results, err
+}
+
+// AddEvidence appends evidence to a claim while its status is one of statuses, else ErrStatusConflict
+func (r *ClaimRepository) AddEvidence(id string, statuses []string, evidence []model.ClaimEvidence) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	res, err := r.claims.UpdateOne(ctx,
+		bson.M{"_id": id, "status": bson.M{"$in": statuses}},
+		bson.M{
+			"$push": bson.M{"evidence": bson.M{"$each": evidence}},
+			"$set":  bson.M{"updated_at": time.Now()},
+		},
+	)
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return ErrStatusConflict
+	}
+	return nil
+}
+
+// UpdateStatus saves the review fields of claim and moves it to event.Status, but only while
+// its stored status is still fromStatus, else ErrStatusConflict.
+func (r *ClaimRepository) UpdateStatus(claim *model.Claim, fromStatus string, event model.ClaimEvent) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	res, err := r.claims.UpdateOne(ctx,
+		bson.M{"_id": claim.ID, "status": fromStatus},
+		bson.M{
+			"$set": bson.M{
+				"status":           event.Status,
+				"reviewer_id":      claim.ReviewerID,
+				"resolution":       claim.Resolution,
+				"payout_amount":    claim.PayoutAmount,
+				"payout_reference": claim.PayoutReference,
+				"resolved_at":      claim.ResolvedAt,
+				"paid_at":          claim.PaidAt,
+				"updated_at":       event.Timestamp,
+			},
+			"$push": bson.M{"events": event},
+		},
+	)
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return ErrStatusConflict
+	}
+	return nil
+}
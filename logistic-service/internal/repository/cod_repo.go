@@ -0,0 +1,273 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"logistic-service/internal/model"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// CODRepository handles the "cod_ledger" and "cod_remittances" MongoDB collections
+type CODRepository struct {
+	ledger      *mongo.Collection
+	remittances *mongo.Collection
+}
+
+// NewCODRepository creates a new CODRepository
+func NewCODRepository(db *mongo.Database) *CODRepository {
+	return &CODRepository{
+		ledger:      db.Collection("cod_ledger"),
+		remittances: db.Collection("cod_remittances"),
+	}
+}
+
+// EnsureIndexes creates the indexes used by the ledger. The unique tracking number index
+// guarantees a shipment is recorded in the ledger only once.
+func (r *CODRepository) EnsureIndexes() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	_, err := r.ledger.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "tracking_number", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "merchant_id", Value: 1}, {Key: "status", Value: 1}, {Key: "collected_at", Value: -1}}},
+		{Keys: bson.D{{Key: "remittance_id", Value: 1}}},
+	})
+	if err != nil {
+		return err
+	}
+	_, err = r.remittances.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "merchant_id", Value: 1}, {Key: "settled_at", Value: -1}},
+	})
+	return err
+}
+
+// InsertEntry stores a ledger entry. Returns a duplicate key error if the shipment is already recorded.
+func (r *CODRepository) InsertEntry(entry *model.CODLedgerEntry) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	_, err := r.ledger.InsertOne(ctx, entry)
+	return err
+}
+
+// ListEntries returns ledger entries, newest first. Empty merchantID or status match everything.
+func (r *CODRepository) ListEntries(merchantID, status string, limit int64) ([]*model.CODLedgerEntry, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	filter := bson.M{}
+	if merchantID != "" {
+		filter["merchant_id"] = merchantID
+	}
+	if status != "" {
+		filter["status"] = status
+	}
+	opts := options.Find().SetSort(bson.D{{Key: "collected_at", Value: -1}}).SetLimit(limit)
+	cursor, err := r.ledger.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	results := []*model.CODLedgerEntry{}
+	err = cursor.All(ctx, &results)
+	return results, err
+}
+
+// EntriesByRemittance returns the ledger entries settled in a remittance
+func (r *CODRepository) EntriesByRemittance(remittanceID string) ([]*model.CODLedgerEntry, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	cursor, err := r.ledger.Find(ctx, bson.M{"remittance_id": remittanceID}, options.Find().SetSort(bson.M{"collected_at": 1}))
+	if err != nil {
+		return nil, err
+	}
+	results := []*model.CODLedgerEntry{}
+	err = cursor.All(ctx, &results)
+	return results, err
+}
+
+// PendingRemittances sums the pending entries per merchant and currency.
+// Empty merchantID returns the totals of every merchant.
+func (r *CODRepository) PendingRemittances(merchantID string) ([]*model.CODRemittance, error) {
+	match := bson.M{"status": model.RemittancePending}
+	if merchantID != "" {
+		match["merchant_id"] = merchantID
+	}
+	return r.sumEntries(match)
+}
+
+// sumEntries groups the entries matching filter per merchant and currency
+func (r *CODRepository) sumEntries(match bson.M) ([]*model.CODRemittance, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: match}},
+		{{Key: "$group", Value: bson.M{
+			"_id":          bson.M{"merchant_id": "$merchant_id", "currency": "$currency"},
+			"entry_count":  bson.M{"$sum": 1},
+			"gross_amount": bson.M{"$sum": "$amount"},
+			"fee_amount":   bson.M{"$sum": "$fee"},
+			"net_amount":   bson.M{"$sum": "$net_amount"},
+		}}},
+		{{Key: "$sort", Value: bson.D{{Key: "_id.merchant_id", Value: 1}, {Key: "_id.currency", Value: 1}}}},
+	}
+	cursor, err := r.ledger.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	var rows []struct {
+		Key struct {
+			MerchantID string `bson:"merchant_id"`
+			Currency   string `bson:"currency"`
+		} `bson:"_id"`
+		EntryCount  int   `bson:"entry_count"`
+		GrossAmount int64 `bson:"gross_amount"`
+		FeeAmount   int64 `bson:"fee_amount"`
+		NetAmount   int64 `bson:"net_amount"`
+	}
+	if err := cursor.All(ctx, &rows); err != nil {
+		return nil, err
+	}
+
+	results := []*model.CODRemittance{}
+	for _, row := range rows {
+		results = append(results, &model.CODRemittance{
+			MerchantID:  row.Key.MerchantID,
+			Currency:    row.Key.Currency,
+			Status:      model.RemittancePending,
+			EntryCount:  row.EntryCount,
+			GrossAmount: row.GrossAmount,
+			FeeAmount:   row.FeeAmount,
+			NetAmount:   row.NetAmount,
+		})
+	}
+	return results, nil
+}
+
+// staleSettlingAfter is how long a remittance may stay settling before it is taken for the
+// leftover of an interrupted Settle, well past the Settle timeout
+const staleSettlingAfter = time.Minute
+
+// Settle moves every pending entry of a merchant in one currency into a new remittance. The steps
+// are ordered so an interruption never leaves settled entries without their remittance: the
+// remittance is stored first as settling, then the entries are claimed with a single update (an
+// entry recorded concurrently is either part of it or stays pending for the next one), then the
+// totals are written and it becomes settled. Remittances left settling are finished by the next
+// Settle of the merchant and currency. Returns (nil, nil) when there is nothing to settle.
+func (r *CODRepository) Settle(remittance *model.CODRemittance) (*model.CODRemittance, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if err := r.finishSettling(ctx, remittance.MerchantID, remittance.Currency); err != nil {
+		return nil, err
+	}
+
+	remittance.Status = model.RemittanceSettling
+	if _, err := r.remittances.InsertOne(ctx, remittance); err != nil {
+		return nil, err
+	}
+	_, err := r.ledger.UpdateMany(ctx, bson.M{
+		"merchant_id": remittance.MerchantID,
+		"currency":    remittance.Currency,
+		"status":      model.RemittancePending,
+	}, bson.M{"$set": bson.M{
+		"status":        model.RemittanceSettled,
+		"remittance_id": remittance.ID,
+		"settled_at":    remittance.SettledAt,
+	}})
+	if err != nil {
+		return nil, err
+	}
+	return r.completeRemittance(ctx, remittance)
+}
+
+// completeRemittance writes the totals of the entries claimed by a settling remittance and marks
+// it settled, or deletes it when it claimed none and returns nil
+func (r *CODRepository) completeRemittance(ctx context.Context, remittance *model.CODRemittance) (*model.CODRemittance, error) {
+	totals, err := r.sumEntries(bson.M{"remittance_id": remittance.ID})
+	if err != nil {
+		return nil, err
+	}
+	if len(totals) == 0 {
+		_, err := r.remittances.DeleteOne(ctx, bson.M{"_id": remittance.ID, "status": model.RemittanceSettling})
+		return nil, err
+	}
+
+	remittance.EntryCount = totals[0].EntryCount
+	remittance.GrossAmount = totals[0].GrossAmount
+	remittance.FeeAmount = totals[0].FeeAmount
+	remittance.NetAmount = totals[0].NetAmount
+	remittance.Status = model.RemittanceSettled
+	_, err = r.remittances.UpdateOne(ctx, bson.M{"_id": remittance.ID}, bson.M{"$set": bson.M{
+		"status":       remittance.Status,
+		"entry_count":  remittance.EntryCount,
+		"gross_amount": remittance.GrossAmount,
+		"fee_amount":   remittance.FeeAmount,
+		"net_amount":   remittance.NetAmount,
+	}})
+	if err != nil {
+		return nil, err
+	}
+	return remittance, nil
+}
+
+// finishSettling completes the remittances of a merchant and currency left settling by an
+// interrupted Settle. Recent ones are skipped, they may belong to a Settle still running.
+func (r *CODRepository) finishSettling(ctx context.Context, merchantID, currency string) error {
+	cursor, err := r.remittances.Find(ctx, bson.M{
+		"merchant_id": merchantID,
+		"currency":    currency,
+		"status":      model.RemittanceSettling,
+		"settled_at":  bson.M{"$lt": time.Now().Add(-staleSettlingAfter)},
+	})
+	if err != nil {
+		return err
+	}
+	var stale []*model.CODRemittance
+	if err := cursor.All(ctx, &stale); err != nil {
+		return err
+	}
+	for _, remittance := range stale {
+		if _, err := r.completeRemittance(ctx, remittance); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ListRemittances returns settled remittances, newest first. Empty merchantID matches everything.
+func (r *CODRepository) ListRemittances(merchantID string, limit int64) ([]*model.CODRemittance, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	filter := bson.M{"status": model.RemittanceSettled}
+	if merchantID != "" {
+		filter["merchant_id"] = merchantID
+	}
+	opts := options.Find().SetSort(bson.D{{Key: "settled_at", Value: -1}}).SetLimit(limit)
+	cursor, err := r.remittances.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	results := []*model.CODRemittance{}
+	err = cursor.All(ctx, &results)
+	return results, err
+}
+
+// FindRemittance returns the remittance with the given ID, or (nil, nil) if it doesn't exist
+func (r *CODRepository) FindRemittance(id string) (*model.CODRemittance, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var remittance model.CODRemittance
+	err := r.remittances.FindOne(ctx, bson.M{"_id": id}).Decode(&remittance)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	return &remittance, err
+}
@@ -0,0 +1,73 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"logistic-service/internal/model"
+
+	"github.com/google/uuid"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestSettle(t *testing.T) {
+	repo := NewCODRepository(testDB(t))
+	for _, amount := range []int64{10000, 25000} {
+		entry := &model.CODLedgerEntry{ID: uuid.NewString(), MerchantID: "m1", TrackingNumber: "TN-" + uuid.NewString()[:8],
+			Currency: "IDR", Amount: amount, Fee: amount / 40, NetAmount: amount - amount/40, Status: model.RemittancePending}
+		if err := repo.InsertEntry(entry); err != nil {
+			t.Fatalf("InsertEntry: %v", err)
+		}
+	}
+
+	now := time.Now()
+	remittance, err := repo.Settle(&model.CODRemittance{ID: uuid.NewString(), MerchantID: "m1", Currency: "IDR", SettledAt: &now})
+	if err != nil || remittance == nil {
+		t.Fatalf("Settle: %v, %v", remittance, err)
+	}
+	stored, err := repo.FindRemittance(remittance.ID)
+	if err != nil || stored == nil {
+		t.Fatalf("FindRemittance: %v", err)
+	}
+	if stored.Status != model.RemittanceSettled || stored.EntryCount != 2 || stored.GrossAmount != 35000 {
+		t.Errorf("got %s remittance of %d entries, %d gross; want settled, 2, 35000", stored.Status, stored.EntryCount, stored.GrossAmount)
+	}
+
+	again, err := repo.Settle(&model.CODRemittance{ID: uuid.NewString(), MerchantID: "m1", Currency: "IDR", SettledAt: &now})
+	if err != nil || again != nil {
+		t.Errorf("Settle with nothing pending = %v, %v; want nil, nil", again, err)
+	}
+}
+
+func TestSettleFinishesInterruptedRemittance(t *testing.T) {
+	repo := NewCODRepository(testDB(t))
+	// A Settle interrupted after claiming its entries left this behind
+	settledAt := time.Now().Add(-time.Hour)
+	interrupted := &model.CODRemittance{ID: uuid.NewString(), MerchantID: "m1", Currency: "IDR",
+		Status: model.RemittanceSettling, SettledAt: &settledAt}
+	if _, err := repo.remittances.InsertOne(context.Background(), interrupted); err != nil {
+		t.Fatalf("insert remittance: %v", err)
+	}
+	entry := &model.CODLedgerEntry{ID: uuid.NewString(), MerchantID: "m1", TrackingNumber: "TN-1", Currency: "IDR",
+		Amount: 10000, NetAmount: 10000, Status: model.RemittanceSettled, RemittanceID: interrupted.ID, SettledAt: &settledAt}
+	if err := repo.InsertEntry(entry); err != nil {
+		t.Fatalf("InsertEntry: %v", err)
+	}
+
+	now := time.Now()
+	if _, err := repo.Settle(&model.CODRemittance{ID: uuid.NewString(), MerchantID: "m1", Currency: "IDR", SettledAt: &now}); err != nil {
+		t.Fatalf("Settle: %v", err)
+	}
+	stored, err := repo.FindRemittance(interrupted.ID)
+	if err != nil || stored == nil {
+		t.Fatalf("FindRemittance: %v", err)
+	}
+	if stored.Status != model.RemittanceSettled || stored.EntryCount != 1 {
+		t.Errorf("got %s remittance of %d entries, want settled with 1", stored.Status, stored.EntryCount)
+	}
+	n, err := repo.remittances.CountDocuments(context.Background(), bson.M{"status": model.RemittanceSettling})
+	if err != nil || n != 0 {
+		t.Errorf("%d remittances left settling (%v)", n, err)
+	}
+}
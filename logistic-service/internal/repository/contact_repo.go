@@ -0,0 +1,147 @@
+package repository
+
+import (
+	"context"
+	"regexp"
+	"time"
+
+	"logistic-service/internal/model"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// ContactRepository handles the "contacts" MongoDB collection (address book)
+type ContactRepository struct {
+	col *mongo.Collection
+}
+
+// NewContactRepository creates a new ContactRepository
+func NewContactRepository(db *mongo.Database) *ContactRepository {
+	return &ContactRepository{col: db.Collection("contacts")}
+}
+
+// EnsureIndexes creates the indexes used by address book listing and search.
+// The partial unique index allows only one default sender per user.
+func (r *ContactRepository) EnsureIndexes() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	_, err := r.col.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "name", Value: 1}}},
+		{Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "phone", Value: 1}}},
+		{
+			Keys: bson.D{{Key: "user_id", Value: 1}},
+			Options: options.Index().
+				SetName("user_id_default_sender").
+				SetUnique(true).
+				SetPartialFilterExpression(bson.M{"is_default_sender": true}),
+		},
+	})
+	return err
+}
+
+// Insert stores a new contact. A new default sender replaces the previous one.
+func (r *ContactRepository) Insert(contact *model.Contact) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if contact.IsDefaultSender {
+		if err := r.clearDefaultSender(ctx, contact.UserID, contact.ID); err != nil {
+			return err
+		}
+	}
+	_, err := r.col.InsertOne(ctx, contact)
+	return err
+}
+
+// Update saves label, name, phone, addresses and default flag of a contact.
+// Making it the default sender clears the flag on the user's other contacts.
+func (r *ContactRepository) Update(contact *model.Contact) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if contact.IsDefaultSender {
+		if err := r.clearDefaultSender(ctx, contact.UserID, contact.ID); err != nil {
+			return err
+		}
+	}
+	_, err := r.col.UpdateOne(ctx, bson.M{"_id": contact.ID}, bson.M{"$set": bson.M{
+		"label":              contact.Label,
+		"name":               contact.Name,
+		"phone":              contact.Phone,
+		"address":            contact.Address,
+		"structured_address": contact.Structured,
+		"is_default_sender":  contact.IsDefaultSender,
+		"updated_at":         contact.UpdatedAt,
+	}})
+	return err
+}
+
+func (r *ContactRepository) clearDefaultSender(ctx context.Context, userID, exceptID string) error {
+	_, err := r.col.UpdateMany(ctx,
+		bson.M{"user_id": userID, "is_default_sender": true, "_id": bson.M{"$ne": exceptID}},
+		bson.M{"$set": bson.M{"is_default_sender": false}},
+	)
+	return err
+}
+
+// Delete removes a contact
+func (r *ContactRepository) Delete(id string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	_, err := r.col.DeleteOne(ctx, bson.M{"_id": id})
+	return err
+}
+
+// FindByID returns a contact of the user, or (nil, nil) if it doesn't exist or belongs to someone else
+func (r *ContactRepository) FindByID(userID, id string) (*model.Contact, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var contact model.Contact
+	err := r.col.FindOne(ctx, bson.M{"_id": id, "user_id": userID}).Decode(&contact)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	return &contact, err
+}
+
+// FindDefaultSender returns the user's default sender, or (nil, nil) if none is set
+func (r *ContactRepository) FindDefaultSender(userID string) (*model.Contact, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var contact model.Contact
+	err := r.col.FindOne(ctx, bson.M{"user_id": userID, "is_default_sender": true}).Decode(&contact)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	return &contact, err
+}
+
+// Search returns the user's contacts sorted by name. A non-empty query matches the name
+// (case insensitive, anywhere) or the start of the phone number.
+func (r *ContactRepository) Search(userID, query string, limit int64) ([]*model.Contact, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	filter := bson.M{"user_id": userID}
+	if query != "" {
+		quoted := regexp.QuoteMeta(query)
+		filter["$or"] = bson.A{
+			bson.M{"name": bson.M{"$regex": quoted, "$options": "i"}},
+			bson.M{"phone": bson.M{"$regex": "^" + quoted}},
+		}
+	}
+	opts := options.Find().SetSort(bson.D{{Key: "name", Value: 1}, {Key: "_id", Value: 1}}).SetLimit(limit)
+	cursor, err := r.col.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	results := []*model.Contact{}
+	err = cursor.All(ctx, &results)
+	return results, err
+}
@@ -0,0 +1,130 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"logistic-service/internal/model"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// DriverRepository handles the "drivers" and "driver_routes" MongoDB collections
+type DriverRepository struct {
+	col    *mongo.Collection
+	routes *mongo.Collection
+}
+
+// NewDriverRepository creates a new DriverRepository
+func NewDriverRepository(db *mongo.Database) *DriverRepository {
+	return &DriverRepository{col: db.Collection("drivers"), routes: db.Collection("driver_routes")}
+}
+
+// EnsureIndexes creates the indexes used to find the drivers of an area
+func (r *DriverRepository) EnsureIndexes() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	_, err := r.col.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "active", Value: 1}, {Key: "area_codes", Value: 1}},
+	})
+	return err
+}
+
+// SaveProfile creates the driver or updates the fields a courier maintains themselves
+// (name, phone, vehicle, base). New drivers start active without areas.
+func (r *DriverRepository) SaveProfile(driver *model.Driver) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	_, err := r.col.UpdateOne(ctx, bson.M{"_id": driver.UserID}, bson.M{
+		"$set": bson.M{
+			"name":         driver.Name,
+			"phone":        driver.Phone,
+			"vehicle_type": driver.VehicleType,
+			"plate_number": driver.PlateNumber,
+			"base":         driver.Base,
+			"updated_at":   driver.UpdatedAt,
+		},
+		"$setOnInsert": bson.M{
+			"area_codes": []string{},
+			"active":     true,
+			"created_at": driver.UpdatedAt,
+		},
+	}, options.Update().SetUpsert(true))
+	return err
+}
+
+// UpdateAssignment saves the fields ops maintain: courier company, areas and active flag
+func (r *DriverRepository) UpdateAssignment(driver *model.Driver) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	_, err := r.col.UpdateOne(ctx, bson.M{"_id": driver.UserID}, bson.M{"$set": bson.M{
+		"logistic_name": driver.LogisticName,
+		"area_codes":    driver.AreaCodes,
+		"active":        driver.Active,
+		"updated_at":    driver.UpdatedAt,
+	}})
+	return err
+}
+
+// FindByID returns a driver, or (nil, nil) if the user has no driver profile
+func (r *DriverRepository) FindByID(userID string) (*model.Driver, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var driver model.Driver
+	err := r.col.FindOne(ctx, bson.M{"_id": userID}).Decode(&driver)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	return &driver, err
+}
+
+// List returns drivers sorted by name, only the active ones unless includeInactive is set.
+// A non-empty areaCode only returns drivers serving exactly that region code.
+func (r *DriverRepository) List(areaCode string, includeInactive bool) ([]*model.Driver, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	filter := bson.M{}
+	if !includeInactive {
+		filter["active"] = true
+	}
+	if areaCode != "" {
+		filter["area_codes"] = areaCode
+	}
+	cursor, err := r.col.Find(ctx, filter, options.Find().SetSort(bson.D{{Key: "name", Value: 1}, {Key: "_id", Value: 1}}))
+	if err != nil {
+		return nil, err
+	}
+	results := []*model.Driver{}
+	err = cursor.All(ctx, &results)
+	return results, err
+}
+
+// FindRoute returns the planned route of a driver on a date, or (nil, nil) if none was planned
+func (r *DriverRepository) FindRoute(driverID, date string) (*model.DriverRoute, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var route model.DriverRoute
+	err := r.routes.FindOne(ctx, bson.M{"_id": driverID + "|" + date}).Decode(&route)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	return &route, err
+}
+
+// SaveRoute stores the planned route of a driver on a date, replacing the previous one
+func (r *DriverRepository) SaveRoute(route *model.DriverRoute) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	route.ID = route.DriverID + "|" + route.Date
+	_, err := r.routes.ReplaceOne(ctx, bson.M{"_id": route.ID}, route, options.Replace().SetUpsert(true))
+	return err
+}
@@ -0,0 +1,205 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"logistic-service/internal/model"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// HubRepository handles the "hubs" and "manifests" MongoDB collections
+type HubRepository struct {
+	hubs      *mongo.Collection
+	manifests *mongo.Collection
+}
+
+// NewHubRepository creates a new HubRepository
+func NewHubRepository(db *mongo.Database) *HubRepository {
+	return &HubRepository{
+		hubs:      db.Collection("hubs"),
+		manifests: db.Collection("manifests"),
+	}
+}
+
+// EnsureIndexes creates the indexes used by manifest listing
+func (r *HubRepository) EnsureIndexes() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	_, err := r.manifests.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "origin_hub", Value: 1}, {Key: "status", Value: 1}, {Key: "created_at", Value: -1}}},
+		{Keys: bson.D{{Key: "destination_hub", Value: 1}, {Key: "status", Value: 1}, {Key: "created_at", Value: -1}}},
+	})
+	return err
+}
+
+// InsertHub stores a new hub. Returns a duplicate key error if the code is taken.
+func (r *HubRepository) InsertHub(hub *model.Hub) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	_, err := r.hubs.InsertOne(ctx, hub)
+	return err
+}
+
+// UpdateHub saves name, address, region code and active flag of a hub
+func (r *HubRepository) UpdateHub(hub *model.Hub) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	_, err := r.hubs.UpdateOne(ctx, bson.M{"_id": hub.Code}, bson.M{"$set": bson.M{
+		"name":        hub.Name,
+		"address":     hub.Address,
+		"region_code": hub.RegionCode,
+		"active":      hub.Active,
+		"updated_at":  hub.UpdatedAt,
+	}})
+	return err
+}
+
+// FindHub returns the hub with the given code, or (nil, nil) if it doesn't exist
+func (r *HubRepository) FindHub(code string) (*model.Hub, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var hub model.Hub
+	err := r.hubs.FindOne(ctx, bson.M{"_id": code}).Decode(&hub)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	return &hub, err
+}
+
+// ListHubs returns the hubs sorted by code, only the active ones unless includeInactive is set
+func (r *HubRepository) ListHubs(includeInactive bool) ([]*model.Hub, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	filter := bson.M{}
+	if !includeInactive {
+		filter["active"] = true
+	}
+	cursor, err := r.hubs.Find(ctx, filter, options.Find().SetSort(bson.M{"_id": 1}))
+	if err != nil {
+		return nil, err
+	}
+	results := []*model.Hub{}
+	err = cursor.All(ctx, &results)
+	return results, err
+}
+
+// InsertManifest stores a new manifest
+func (r *HubRepository) InsertManifest(manifest *model.Manifest) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	_, err := r.manifests.InsertOne(ctx, manifest)
+	return err
+}
+
+// FindManifest returns the manifest with the given ID, or (nil, nil) if it doesn't exist
+func (r *HubRepository) FindManifest(id string) (*model.Manifest, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var manifest model.Manifest
+	err := r.manifests.FindOne(ctx, bson.M{"_id": id}).Decode(&manifest)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	return &manifest, err
+}
+
+// ManifestFilter narrows ListManifests results. Empty fields match everything.
+type ManifestFilter struct {
+	Hub            string // Origin or destination hub
+	Status         string
+	HasDiscrepancy bool
+}
+
+// ListManifests returns the manifests matching filter, newest first
+func (r *HubRepository) ListManifests(f ManifestFilter, limit int64) ([]*model.Manifest, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	filter := bson.M{}
+	if f.Hub != "" {
+		filter["$or"] = bson.A{bson.M{"origin_hub": f.Hub}, bson.M{"destination_hub": f.Hub}}
+	}
+	if f.Status != "" {
+		filter["status"] = f.Status
+	}
+	if f.HasDiscrepancy {
+		filter["discrepancy"] = bson.M{"$exists": true}
+	}
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}}).SetLimit(limit)
+	cursor, err := r.manifests.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	results := []*model.Manifest{}
+	err = cursor.All(ctx, &results)
+	return results, err
+}
+
+// AddToManifest adds shipments to a manifest while it is open, else ErrStatusConflict
+func (r *HubRepository) AddToManifest(id string, trackingNumbers []string) error {
+	return r.updateOpenManifest(id, bson.M{"$addToSet": bson.M{"tracking_numbers": bson.M{"$each": trackingNumbers}}})
+}
+
+// RemoveFromManifest removes a shipment from a manifest while it is open, else ErrStatusConflict
+func (r *HubRepository) RemoveFromManifest(id, trackingNumber string) error {
+	return r.updateOpenManifest(id, bson.M{"$pull": bson.M{"tracking_numbers": trackingNumber}})
+}
+
+func (r *HubRepository) updateOpenManifest(id string, update bson.M) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	update["$set"] = bson.M{"updated_at": time.Now()}
+	res, err := r.manifests.UpdateOne(ctx, bson.M{"_id": id, "status": model.ManifestOpen}, update)
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return ErrStatusConflict
+	}
+	return nil
+}
+
+// manifestTimestamps maps each manifest status to the field recording when it was reached
+var manifestTimestamps = map[string]string{
+	model.ManifestClosed:     "closed_at",
+	model.ManifestDispatched: "dispatched_at",
+	model.ManifestReceived:   "received_at",
+}
+
+// SetManifestStatus moves a manifest from status from to status to at the given time, else
+// ErrStatusConflict. receivedBy and discrepancy are only stored when not empty.
+func (r *HubRepository) SetManifestStatus(id, from, to string, at time.Time, receivedBy string, discrepancy *model.ManifestDiscrepancy) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	set := bson.M{"status": to, "updated_at": at}
+	if field, ok := manifestTimestamps[to]; ok {
+		set[field] = at
+	}
+	if receivedBy != "" {
+		set["received_by"] = receivedBy
+	}
+	if discrepancy != nil {
+		set["discrepancy"] = discrepancy
+	}
+	res, err := r.manifests.UpdateOne(ctx, bson.M{"_id": id, "status": from}, bson.M{"$set": set})
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return ErrStatusConflict
+	}
+	return nil
+}
@@ -2,12 +2,15 @@ package repository
 
 import (
 	"context"
+	"errors"
+	"regexp"
 	"time"
 
 	"logistic-service/internal/model"
 
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
 // ShipmentRepository handles CRUD operations on the "shipments" MongoDB collection
@@ -17,7 +20,29 @@ type ShipmentRepository struct {
 
 // NewShipmentRepository creates a new ShipmentRepository bound to the "shipments" collection
 func NewShipmentRepository(db *mongo.Database) *ShipmentRepository {
-	return &ShipmentRepository{col: db.Collection("shipments")}
+	// Decode untyped nested documents (e.g. revision old/new values) as maps so they render as JSON objects.
+	// Nil slices are stored as empty arrays, as updates $push to them and Mongo can't push onto null.
+	opts := options.Collection().SetBSONOptions(&options.BSONOptions{DefaultDocumentM: true, NilSliceAsEmpty: true})
+	return &ShipmentRepository{col: db.Collection("shipments", opts)}
+}
+
+// pushedArrays are the shipment arrays that updates $push to besides events. Status updates
+// also write parcels through an array filter, which fails on a null or missing array.
+var pushedArrays = []string{"revisions", "returntrackingnumbers", "deliveryattempts", "parcels"}
+
+// RepairNullArrays replaces the null or missing pushedArrays of shipments stored before nil
+// slices were written as empty arrays, so updating them doesn't fail
+func (r *ShipmentRepository) RepairNullArrays() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	for _, field := range pushedArrays {
+		_, err := r.col.UpdateMany(ctx, bson.M{field: nil}, bson.M{"$set": bson.M{field: bson.A{}}})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
 // Insert inserts a new shipment document into the shipments collection
@@ -29,8 +54,9 @@ func (r *ShipmentRepository) Insert(shipment *model.Shipment) error {
 	return err
 }
 
-// UpdateStatus updates the status and updated_at timestamp of a shipment identified by trackingNumber
-func (r *ShipmentRepository) UpdateStatus(trackingNumber string, status string) error {
+// UpdateStatus sets the shipment status to event.Status, refreshes the updated timestamp
+// and appends the event to the shipment tracking timeline. Parcels follow, see followParcels.
+func (r *ShipmentRepository) UpdateStatus(trackingNumber string, event model.TrackingEvent) error {
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
@@ -41,10 +67,14 @@ func (r *ShipmentRepository) UpdateStatus(trackingNumber string, status string)
 		return err
 	}
 
+	if event.Timestamp.IsZero() {
+		event.Timestamp = time.Now()
+	}
+
 	// Copy nested to flat fields before update
 	updateFields := bson.M{
-		"status":          status,
-		"updated_at":      time.Now().Unix(),
+		"status":          event.Status,
+		"updatedat":       event.Timestamp,
 		"sendername":      shipment.Sender.Name,
 		"senderphone":     shipment.Sender.Phone,
 		"senderaddress":   shipment.Sender.Address,
@@ -53,10 +83,695 @@ func (r *ShipmentRepository) UpdateStatus(trackingNumber string, status string)
 		"recipientaddress": shipment.Recipient.Address,
 	}
 
-	_, err = r.col.UpdateOne(ctx, bson.M{"trackingnumber": trackingNumber}, bson.M{"$set": updateFields})
+	update := bson.M{
+		"$set":  updateFields,
+		"$push": bson.M{"events": event},
+		"$inc":  bson.M{"version": 1},
+	}
+	_, err = r.col.UpdateOne(ctx, bson.M{"trackingnumber": trackingNumber}, update, followParcels(update, event))
+	return err
+}
+
+// UpdateStatusFrom is UpdateStatus for status changes that depend on the current status: it
+// only applies while the status is one of allowedStatuses, else ErrStatusConflict
+func (r *ShipmentRepository) UpdateStatusFrom(trackingNumber string, allowedStatuses []string, event model.TrackingEvent) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if event.Timestamp.IsZero() {
+		event.Timestamp = time.Now()
+	}
+	filter := bson.M{
+		"trackingnumber": trackingNumber,
+		"status":         bson.M{"$in": allowedStatuses},
+	}
+	update := bson.M{
+		"$set":  bson.M{"status": event.Status, "updatedat": event.Timestamp},
+		"$push": bson.M{"events": event},
+		"$inc":  bson.M{"version": 1},
+	}
+	res, err := r.col.UpdateOne(ctx, filter, update, followParcels(update, event))
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return ErrStatusConflict
+	}
+	return nil
+}
+
+// followParcels adds to update, which moves a whole shipment to event.Status, the move of its
+// parcels that are behind event.Status, with event appended to their timelines, and returns the
+// options carrying the array filter. Every update changing the status of a whole shipment uses it,
+// so the shipment and its parcels change in one write; shipments without parcels match no element.
+// update must have $set and $push documents.
+func followParcels(update bson.M, event model.TrackingEvent) *options.UpdateOptions {
+	set := update["$set"].(bson.M)
+	set["parcels.$[p].status"] = event.Status
+	set["parcels.$[p].updated_at"] = event.Timestamp
+	update["$push"].(bson.M)["parcels.$[p].events"] = event
+	return options.Update().SetArrayFilters(options.ArrayFilters{Filters: []interface{}{
+		bson.M{"p.status": bson.M{"$nin": model.ParcelStatusesAtOrPast(event.Status)}},
+	}})
+}
+
+// ErrStatusConflict is returned when a conditional update finds the shipment in a status
+// that doesn't allow the change
+var ErrStatusConflict = errors.New("shipment status does not allow this change")
+
+// Cancel marks a shipment as cancelled, but only while its status is one of allowedStatuses.
+// The check and the update are a single atomic operation.
+func (r *ShipmentRepository) Cancel(trackingNumber string, allowedStatuses []string, cancellation model.ShipmentCancellation) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	event := model.TrackingEvent{
+		Status:      model.StatusCancelled,
+		Description: "shipment cancelled: " + cancellation.ReasonCode,
+		Actor:       cancellation.CancelledBy,
+		Timestamp:   cancellation.CancelledAt,
+	}
+	filter := bson.M{
+		"trackingnumber": trackingNumber,
+		"status":         bson.M{"$in": allowedStatuses},
+	}
+	update := bson.M{
+		"$set": bson.M{
+			"status":       model.StatusCancelled,
+			"updatedat":    cancellation.CancelledAt,
+			"cancellation": cancellation,
+		},
+		"$push": bson.M{"events": event},
+		"$inc":  bson.M{"version": 1},
+	}
+	res, err := r.col.UpdateOne(ctx, filter, update, followParcels(update, event))
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return ErrStatusConflict
+	}
+	return nil
+}
+
+// SetProofOfDelivery stores the proof of delivery of a shipment, and the COD collection if cod is
+// not nil, but only while its status is one of allowedStatuses. A new capture replaces the previous one.
+func (r *ShipmentRepository) SetProofOfDelivery(trackingNumber string, allowedStatuses []string, pod model.ProofOfDelivery, cod *model.CashOnDelivery) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	filter := bson.M{
+		"trackingnumber": trackingNumber,
+		"status":         bson.M{"$in": allowedStatuses},
+	}
+	set := bson.M{
+		"proofofdelivery": pod,
+		"updatedat":       pod.CapturedAt,
+	}
+	if cod != nil {
+		set["cod"] = cod
+	}
+	update := bson.M{
+		"$set": set,
+		"$inc": bson.M{"version": 1},
+	}
+	res, err := r.col.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return ErrStatusConflict
+	}
+	return nil
+}
+
+// ErrVersionConflict is returned when the shipment was modified since the caller read it
+var ErrVersionConflict = errors.New("shipment was modified by someone else")
+
+// UpdateDetails replaces sender, recipient, items and/or notes of a shipment and records the revision.
+// Origin, destination, their region codes and the promised date and ETA are saved too as they
+// follow the addresses.
+// The update only applies when the stored version equals expectedVersion and the status is one of
+// allowedStatuses; otherwise ErrVersionConflict or ErrStatusConflict is returned.
+func (r *ShipmentRepository) UpdateDetails(trackingNumber string, expectedVersion int, allowedStatuses []string, updated *model.Shipment, revision model.ShipmentRevision) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	filter := versionedFilter(trackingNumber, expectedVersion, allowedStatuses)
+	update := bson.M{
+		"$set": bson.M{
+			"sender":           updated.Sender,
+			"recipient":        updated.Recipient,
+			"items":            updated.Items,
+			"origin":           updated.Origin,
+			"destination":      updated.Destination,
+			"origincode":       updated.OriginCode,
+			"destinationcode":  updated.DestinationCode,
+			"notes":            updated.Notes,
+			"sendername":       updated.Sender.Name,
+			"senderphone":      updated.Sender.Phone,
+			"senderaddress":    updated.Sender.Address,
+			"recipientname":    updated.Recipient.Name,
+			"recipientphone":   updated.Recipient.Phone,
+			"recipientaddress": updated.Recipient.Address,
+			"searchkeys":       updated.SearchKeys,
+			"promiseddate":     updated.PromisedDate,
+			"eta":              updated.ETA,
+			"slabreachedat":    updated.SLABreachedAt,
+			"updatedat":        revision.EditedAt,
+			"version":          expectedVersion + 1,
+		},
+		"$push": bson.M{"revisions": revision},
+	}
+	res, err := r.col.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount > 0 {
+		return nil
+	}
+	return r.versionedConflict(trackingNumber, allowedStatuses)
+}
+
+// LinkReturn records returnTrackingNumber as the latest return shipment of a shipment. For RTO,
+// event moves the original to return_to_sender in the same update; pass nil to keep the status.
+// Like UpdateDetails it only applies at expectedVersion and while the status is one of allowedStatuses.
+func (r *ShipmentRepository) LinkReturn(trackingNumber string, expectedVersion int, allowedStatuses []string, returnTrackingNumber string, event *model.TrackingEvent) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	push := bson.M{"returntrackingnumbers": returnTrackingNumber}
+	set := bson.M{"updatedat": time.Now(), "version": expectedVersion + 1}
+	update := bson.M{"$set": set, "$push": push}
+	opts := options.Update()
+	if event != nil {
+		set["status"] = event.Status
+		set["updatedat"] = event.Timestamp
+		push["events"] = event
+		opts = followParcels(update, *event)
+	}
+	res, err := r.col.UpdateOne(ctx, versionedFilter(trackingNumber, expectedVersion, allowedStatuses), update, opts)
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount > 0 {
+		return nil
+	}
+	return r.versionedConflict(trackingNumber, allowedStatuses)
+}
+
+// AddDeliveryAttempt appends a failed delivery attempt and its tracking event and clears the
+// rescheduled next attempt, which the attempt used up. Like UpdateDetails it only applies at
+// expectedVersion and while the status is one of allowedStatuses, so attempt numbers are unique.
+func (r *ShipmentRepository) AddDeliveryAttempt(trackingNumber string, expectedVersion int, allowedStatuses []string, attempt model.DoorstepAttempt, event model.TrackingEvent) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	update := bson.M{
+		"$set":   bson.M{"updatedat": attempt.AttemptedAt, "version": expectedVersion + 1},
+		"$unset": bson.M{"nextattempt": ""},
+		"$push":  bson.M{"deliveryattempts": attempt, "events": event},
+	}
+	res, err := r.col.UpdateOne(ctx, versionedFilter(trackingNumber, expectedVersion, allowedStatuses), update)
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount > 0 {
+		return nil
+	}
+	return r.versionedConflict(trackingNumber, allowedStatuses)
+}
+
+// SetDeliveryPreferences stores the recipient's next attempt schedule and/or delivery instructions
+// (nil leaves a field unchanged) while the status is one of allowedStatuses, else ErrStatusConflict.
+// event, if not nil, is appended to the tracking timeline.
+func (r *ShipmentRepository) SetDeliveryPreferences(trackingNumber string, allowedStatuses []string, schedule *model.DeliverySchedule, instructions *string, event *model.TrackingEvent) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	set := bson.M{"updatedat": time.Now()}
+	if schedule != nil {
+		set["nextattempt"] = schedule
+	}
+	if instructions != nil {
+		set["deliveryinstructions"] = *instructions
+	}
+	update := bson.M{"$set": set, "$inc": bson.M{"version": 1}}
+	if event != nil {
+		update["$push"] = bson.M{"events": event}
+	}
+	filter := bson.M{
+		"trackingnumber": trackingNumber,
+		"status":         bson.M{"$in": allowedStatuses},
+	}
+	res, err := r.col.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return ErrStatusConflict
+	}
+	return nil
+}
+
+// UnlinkReturn removes a return shipment from the original, used when creating it failed
+func (r *ShipmentRepository) UnlinkReturn(trackingNumber, returnTrackingNumber string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	_, err := r.col.UpdateOne(ctx, bson.M{"trackingnumber": trackingNumber}, bson.M{
+		"$pull": bson.M{"returntrackingnumbers": returnTrackingNumber},
+		"$inc":  bson.M{"version": 1},
+	})
+	return err
+}
+
+// FindReturns returns the return shipments of a shipment, oldest first
+func (r *ShipmentRepository) FindReturns(trackingNumber string) ([]*model.Shipment, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	opts := options.Find().SetSort(bson.D{{Key: "createdat", Value: 1}})
+	cursor, err := r.col.Find(ctx, bson.M{"return.original_tracking_number": trackingNumber}, opts)
+	if err != nil {
+		return nil, err
+	}
+	results := []*model.Shipment{}
+	err = cursor.All(ctx, &results)
+	return results, err
+}
+
+// ClaimForPickup books a shipment in a pickup while its status is one of allowedStatuses and
+// it isn't booked in another pickup, else ErrStatusConflict.
+func (r *ShipmentRepository) ClaimForPickup(trackingNumber, pickupID string, allowedStatuses []string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	filter := bson.M{
+		"trackingnumber": trackingNumber,
+		"status":         bson.M{"$in": allowedStatuses},
+		"pickupid":       bson.M{"$in": bson.A{nil, ""}},
+	}
+	update := bson.M{
+		"$set": bson.M{"pickupid": pickupID, "updatedat": time.Now()},
+		"$inc": bson.M{"version": 1},
+	}
+	res, err := r.col.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return ErrStatusConflict
+	}
+	return nil
+}
+
+// ReleaseFromPickup removes a shipment from a pickup that failed or was cancelled.
+// Shipments already picked up keep the reference.
+func (r *ShipmentRepository) ReleaseFromPickup(trackingNumber, pickupID string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	filter := bson.M{
+		"trackingnumber": trackingNumber,
+		"pickupid":       pickupID,
+		"status":         bson.M{"$in": model.PickupStatuses},
+	}
+	update := bson.M{
+		"$unset": bson.M{"pickupid": ""},
+		"$set":   bson.M{"updatedat": time.Now()},
+		"$inc":   bson.M{"version": 1},
+	}
+	_, err := r.col.UpdateOne(ctx, filter, update)
+	return err
+}
+
+// MarkPickedUp moves a shipment booked in pickupID to event.Status (picked_up) while it is still
+// waiting for pickup, else ErrStatusConflict (e.g. it was cancelled in the meantime).
+func (r *ShipmentRepository) MarkPickedUp(trackingNumber, pickupID string, event model.TrackingEvent) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	filter := bson.M{
+		"trackingnumber": trackingNumber,
+		"pickupid":       pickupID,
+		"status":         bson.M{"$in": model.PickupStatuses},
+	}
+	update := bson.M{
+		"$set":  bson.M{"status": event.Status, "updatedat": event.Timestamp},
+		"$push": bson.M{"events": event},
+		"$inc":  bson.M{"version": 1},
+	}
+	res, err := r.col.UpdateOne(ctx, filter, update, followParcels(update, event))
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return ErrStatusConflict
+	}
+	return nil
+}
+
+// RecordHubScan applies an inbound or outbound scan at event.HubCode: the shipment moves to
+// event.Status and the event is appended. Inbound scans make it the current hub; outbound scans
+// only match a shipment currently at that hub and clear it. Returns ErrStatusConflict when the
+// shipment isn't in one of model.HubScanStatuses or, for outbound scans, not at the hub.
+func (r *ShipmentRepository) RecordHubScan(trackingNumber, scanType string, event model.TrackingEvent) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	filter := bson.M{
+		"trackingnumber": trackingNumber,
+		"status":         bson.M{"$in": model.HubScanStatuses},
+	}
+	update := bson.M{
+		"$set":  bson.M{"status": event.Status, "updatedat": event.Timestamp, "currenthub": event.HubCode},
+		"$push": bson.M{"events": event},
+		"$inc":  bson.M{"version": 1},
+	}
+	if scanType == model.ScanOutbound {
+		filter["currenthub"] = event.HubCode
+		update["$set"] = bson.M{"status": event.Status, "updatedat": event.Timestamp}
+		update["$unset"] = bson.M{"currenthub": ""}
+	}
+	res, err := r.col.UpdateOne(ctx, filter, update, followParcels(update, event))
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return ErrStatusConflict
+	}
+	return nil
+}
+
+// ClaimForManifest puts a shipment in a manifest while it is at hubCode in one of
+// model.HubScanStatuses and not in another manifest, else ErrStatusConflict.
+func (r *ShipmentRepository) ClaimForManifest(trackingNumber, manifestID, hubCode string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	filter := bson.M{
+		"trackingnumber": trackingNumber,
+		"status":         bson.M{"$in": model.HubScanStatuses},
+		"currenthub":     hubCode,
+		"manifestid":     bson.M{"$in": bson.A{nil, ""}},
+	}
+	update := bson.M{
+		"$set": bson.M{"manifestid": manifestID, "updatedat": time.Now()},
+		"$inc": bson.M{"version": 1},
+	}
+	res, err := r.col.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return ErrStatusConflict
+	}
+	return nil
+}
+
+// ReleaseFromManifest takes a shipment out of a manifest, used when it is removed from the
+// manifest or the manifest was received
+func (r *ShipmentRepository) ReleaseFromManifest(trackingNumber, manifestID string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	_, err := r.col.UpdateOne(ctx, bson.M{"trackingnumber": trackingNumber, "manifestid": manifestID}, bson.M{
+		"$unset": bson.M{"manifestid": ""},
+		"$set":   bson.M{"updatedat": time.Now()},
+		"$inc":   bson.M{"version": 1},
+	})
 	return err
 }
 
+// AssignDriver sets the courier delivering a shipment (empty driverID unassigns it) while its
+// status is one of allowedStatuses, else ErrStatusConflict
+func (r *ShipmentRepository) AssignDriver(trackingNumber, driverID string, allowedStatuses []string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	update := bson.M{
+		"$set": bson.M{"driverid": driverID, "updatedat": time.Now()},
+		"$inc": bson.M{"version": 1},
+	}
+	if driverID == "" {
+		update["$set"] = bson.M{"updatedat": time.Now()}
+		update["$unset"] = bson.M{"driverid": ""}
+	}
+	filter := bson.M{
+		"trackingnumber": trackingNumber,
+		"status":         bson.M{"$in": allowedStatuses},
+	}
+	res, err := r.col.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return ErrStatusConflict
+	}
+	return nil
+}
+
+// UpdateAssignedStatus is UpdateStatus for drivers: it only applies while the shipment is assigned
+// to driverID and its status is one of allowedStatuses, else ErrStatusConflict
+func (r *ShipmentRepository) UpdateAssignedStatus(trackingNumber, driverID string, allowedStatuses []string, event model.TrackingEvent) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	filter := bson.M{
+		"trackingnumber": trackingNumber,
+		"driverid":       driverID,
+		"status":         bson.M{"$in": allowedStatuses},
+	}
+	update := bson.M{
+		"$set":  bson.M{"status": event.Status, "updatedat": event.Timestamp},
+		"$push": bson.M{"events": event},
+		"$inc":  bson.M{"version": 1},
+	}
+	res, err := r.col.UpdateOne(ctx, filter, update, followParcels(update, event))
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return ErrStatusConflict
+	}
+	return nil
+}
+
+// UpdateParcelStatus records a scan of one parcel of a multi-parcel shipment: parcelEvent moves the
+// parcel to its status and event the shipment to the status derived from all parcels. Like
+// UpdateDetails it only applies at expectedVersion and while the status is one of allowedStatuses.
+func (r *ShipmentRepository) UpdateParcelStatus(trackingNumber string, expectedVersion int, allowedStatuses []string, parcelTrackingNumber string, parcelEvent, event model.TrackingEvent) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	update := bson.M{
+		"$set": bson.M{
+			"status":                  event.Status,
+			"updatedat":               event.Timestamp,
+			"version":                 expectedVersion + 1,
+			"parcels.$[p].status":     parcelEvent.Status,
+			"parcels.$[p].updated_at": parcelEvent.Timestamp,
+		},
+		"$push": bson.M{"events": event, "parcels.$[p].events": parcelEvent},
+	}
+	opts := options.Update().SetArrayFilters(options.ArrayFilters{Filters: []interface{}{
+		bson.M{"p.tracking_number": parcelTrackingNumber},
+	}})
+	res, err := r.col.UpdateOne(ctx, versionedFilter(trackingNumber, expectedVersion, allowedStatuses), update, opts)
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount > 0 {
+		return nil
+	}
+	return r.versionedConflict(trackingNumber, allowedStatuses)
+}
+
+// FindByDriver returns the shipments assigned to a driver in one of statuses, oldest first
+func (r *ShipmentRepository) FindByDriver(driverID string, statuses []string) ([]*model.Shipment, error) {
+	return r.findSorted(bson.M{"driverid": driverID, "status": bson.M{"$in": statuses}}, 0)
+}
+
+// FindUnassigned returns up to limit shipments in one of statuses without a driver, oldest first
+func (r *ShipmentRepository) FindUnassigned(statuses []string, limit int64) ([]*model.Shipment, error) {
+	return r.findSorted(bson.M{
+		"driverid": bson.M{"$in": bson.A{nil, ""}},
+		"status":   bson.M{"$in": statuses},
+	}, limit)
+}
+
+// SetETA stores a new delivery estimate and increments the version, so stream subscribers see
+// it, unless the shipment changed since version was read (ErrVersionConflict).
+func (r *ShipmentRepository) SetETA(trackingNumber string, version int, eta string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	res, err := r.col.UpdateOne(ctx,
+		bson.M{"trackingnumber": trackingNumber, "version": version},
+		bson.M{"$set": bson.M{"eta": eta}, "$inc": bson.M{"version": 1}},
+	)
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return ErrVersionConflict
+	}
+	return nil
+}
+
+// FindSLABreaches returns up to limit open shipments promised before today that are not flagged
+// yet, oldest promise first
+func (r *ShipmentRepository) FindSLABreaches(today string, limit int64) ([]*model.Shipment, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	filter := bson.M{
+		"promiseddate":  bson.M{"$gt": "", "$lt": today},
+		"status":        bson.M{"$in": model.SLAOpenStatuses},
+		"slabreachedat": nil,
+	}
+	opts := options.Find().SetSort(bson.D{{Key: "promiseddate", Value: 1}}).SetLimit(limit)
+	cursor, err := r.col.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	results := []*model.Shipment{}
+	err = cursor.All(ctx, &results)
+	return results, err
+}
+
+// MarkSLABreached flags a shipment as having missed its promised date, increments its version and
+// returns the updated shipment. Returns ErrStatusConflict when it was flagged already, so only one
+// instance reports the breach.
+func (r *ShipmentRepository) MarkSLABreached(trackingNumber string, at time.Time) (*model.Shipment, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var shipment model.Shipment
+	err := r.col.FindOneAndUpdate(ctx,
+		bson.M{"trackingnumber": trackingNumber, "slabreachedat": nil},
+		bson.M{"$set": bson.M{"slabreachedat": at}, "$inc": bson.M{"version": 1}},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&shipment)
+	if err == mongo.ErrNoDocuments {
+		return nil, ErrStatusConflict
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &shipment, nil
+}
+
+// unledgeredCODFilter matches the delivered COD shipments not recorded in the COD ledger yet
+var unledgeredCODFilter = bson.M{
+	"status":           model.StatusDelivered,
+	"cod.collected_at": bson.M{"$exists": true},
+	"cod.ledgered_at":  nil,
+}
+
+// FindUnledgeredCOD returns up to limit delivered COD shipments not recorded in the COD ledger
+// yet, oldest first
+func (r *ShipmentRepository) FindUnledgeredCOD(limit int64) ([]*model.Shipment, error) {
+	return r.findSorted(unledgeredCODFilter, limit)
+}
+
+// MarkCODLedgered records that the COD collection of a shipment is in the COD ledger
+func (r *ShipmentRepository) MarkCODLedgered(trackingNumber string, at time.Time) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	_, err := r.col.UpdateOne(ctx,
+		bson.M{"trackingnumber": trackingNumber, "cod": bson.M{"$ne": nil}},
+		bson.M{"$set": bson.M{"cod.ledgered_at": at}},
+	)
+	return err
+}
+
+// SLABreachFilter narrows ListSLABreached results. Empty fields match everything.
+type SLABreachFilter struct {
+	LogisticName string
+	OpenOnly     bool // only shipments still not delivered
+	Since        time.Time
+}
+
+// ListSLABreached returns up to limit shipments flagged as SLA breached, most recently flagged first
+func (r *ShipmentRepository) ListSLABreached(f SLABreachFilter, limit int64) ([]*model.Shipment, error) {
+	flagged := bson.M{"$ne": nil}
+	if !f.Since.IsZero() {
+		flagged["$gte"] = f.Since
+	}
+	filter := bson.M{"slabreachedat": flagged}
+	if f.LogisticName != "" {
+		filter["logisticname"] = f.LogisticName
+	}
+	if f.OpenOnly {
+		filter["status"] = bson.M{"$in": model.SLAOpenStatuses}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	opts := options.Find().SetSort(bson.D{{Key: "slabreachedat", Value: -1}}).SetLimit(limit)
+	cursor, err := r.col.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	results := []*model.Shipment{}
+	err = cursor.All(ctx, &results)
+	return results, err
+}
+
+func (r *ShipmentRepository) findSorted(filter bson.M, limit int64) ([]*model.Shipment, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	opts := options.Find().SetSort(bson.D{{Key: "createdat", Value: 1}})
+	if limit > 0 {
+		opts.SetLimit(limit)
+	}
+	cursor, err := r.col.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	results := []*model.Shipment{}
+	err = cursor.All(ctx, &results)
+	return results, err
+}
+
+// versionedFilter matches a shipment at expectedVersion in one of allowedStatuses
+func versionedFilter(trackingNumber string, expectedVersion int, allowedStatuses []string) bson.M {
+	versionFilter := bson.M{"version": expectedVersion}
+	if expectedVersion == 0 {
+		// Shipments created before versioning have no version field
+		versionFilter = bson.M{"$or": bson.A{
+			bson.M{"version": 0},
+			bson.M{"version": bson.M{"$exists": false}},
+		}}
+	}
+	return bson.M{"$and": bson.A{
+		bson.M{"trackingnumber": trackingNumber, "status": bson.M{"$in": allowedStatuses}},
+		versionFilter,
+	}}
+}
+
+// versionedConflict finds out why a versionedFilter update matched nothing:
+// ErrStatusConflict, ErrVersionConflict or mongo.ErrNoDocuments
+func (r *ShipmentRepository) versionedConflict(trackingNumber string, allowedStatuses []string) error {
+	current, err := r.FindByTrackingNumber(trackingNumber)
+	if err != nil {
+		return err
+	}
+	if current == nil {
+		return mongo.ErrNoDocuments
+	}
+	for _, s := range allowedStatuses {
+		if current.Status == s {
+			return ErrVersionConflict
+		}
+	}
+	return ErrStatusConflict
+}
 
 // FindByTrackingNumber retrieves a shipment document by its tracking number.
 // Returns (nil, nil) if shipment not found.
@@ -74,15 +789,37 @@ func (r *ShipmentRepository) FindByTrackingNumber(trackingNumber string) (*model
 
 
 // FindByOriginDestination finds courier rates by origin_name and destination_name
-func (r *ShipmentRepository) FindByOriginDestination(origin, destination string) ([]*model.Shipment, error) {
+func (r *ShipmentRepository) FindByOriginDestination(origin, destination string) ([]*model.CourierRate, error) {
+	return r.findRates(bson.M{"origin_name": origin, "destination_name": destination})
+}
+
+// FindByRegionCodes finds courier rates by origin and destination region codes.
+// A code matches itself and every region below it, e.g. city "32.73" matches district "32.73.02".
+func (r *ShipmentRepository) FindByRegionCodes(originCode, destinationCode string) ([]*model.CourierRate, error) {
+	return r.findRates(bson.M{
+		"origincode":      bson.M{"$regex": "^" + regexp.QuoteMeta(originCode) + `(\.|$)`},
+		"destinationcode": bson.M{"$regex": "^" + regexp.QuoteMeta(destinationCode) + `(\.|$)`},
+	})
+}
+
+// findRates returns the distinct courier and service level pairs of the shipments matching
+// filter. Rates are visible to every user, so nothing else of the shipments may leave here.
+func (r *ShipmentRepository) findRates(filter bson.M) ([]*model.CourierRate, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
-	filter := bson.M{"origin_name": origin, "destination_name": destination}
-	cursor, err := r.col.Find(ctx, filter)
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: filter}},
+		{{Key: "$group", Value: bson.M{
+			"_id": bson.M{"logistic_name": "$logisticname", "service_level": "$servicelevel"},
+		}}},
+		{{Key: "$replaceWith", Value: "$_id"}},
+		{{Key: "$sort", Value: bson.D{{Key: "logistic_name", Value: 1}, {Key: "service_level", Value: 1}}}},
+	}
+	cursor, err := r.col.Aggregate(ctx, pipeline)
 	if err != nil {
 		return nil, err
 	}
-	var results []*model.Shipment
+	results := []*model.CourierRate{}
 	err = cursor.All(ctx, &results)
 	return results, err
 }
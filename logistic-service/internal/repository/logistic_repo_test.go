@@ -0,0 +1,235 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"logistic-service/internal/model"
+
+	"github.com/google/uuid"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// testShipmentRepo returns a repository on testDB
+func testShipmentRepo(t *testing.T) *ShipmentRepository {
+	t.Helper()
+	return NewShipmentRepository(testDB(t))
+}
+
+// testDB returns a throwaway database of the MongoDB at MONGO_URI, dropped when the test ends.
+// Tests using it are skipped without MONGO_URI.
+func testDB(t *testing.T) *mongo.Database {
+	t.Helper()
+	uri := os.Getenv("MONGO_URI")
+	if uri == "" {
+		t.Skip("MONGO_URI not set")
+	}
+	client, err := mongo.Connect(context.Background(), options.Client().ApplyURI(uri))
+	if err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	db := client.Database("logistic_test_" + uuid.NewString()[:8])
+	t.Cleanup(func() {
+		db.Drop(context.Background())
+		client.Disconnect(context.Background())
+	})
+	return db
+}
+
+// newTestShipment returns a shipment as createShipment stores it, with its history slices nil
+func newTestShipment() *model.Shipment {
+	now := time.Now()
+	return &model.Shipment{
+		ID:             uuid.NewString(),
+		TrackingNumber: "TN-" + uuid.NewString()[:8],
+		LogisticName:   "JNE",
+		Status:         model.StatusOnProcess,
+		Sender:         model.ShipmentPerson{Name: "Ahmad", Phone: "081234567890", Address: "Jl. Merdeka 1"},
+		Recipient:      model.ShipmentPerson{Name: "Budi", Phone: "089876543210", Address: "Jl. Sudirman 10"},
+		Items:          []model.ShipmentItem{{Name: "Sepatu", Qty: 1, Weight: 1.2}},
+		Events:         []model.TrackingEvent{{Status: model.StatusOnProcess, Timestamp: now}},
+		CreatedAt:      now,
+		UpdatedAt:      now,
+		Version:        1,
+	}
+}
+
+func TestUpdateDetailsAfterCreate(t *testing.T) {
+	repo := testShipmentRepo(t)
+	shipment := newTestShipment()
+	if err := repo.Insert(shipment); err != nil {
+		t.Fatalf("Insert: %v", err)
+	}
+
+	updated := *shipment
+	updated.Notes = "fragile"
+	revision := model.ShipmentRevision{Version: 2, EditedBy: "u1", EditedAt: time.Now(),
+		Changes: []model.FieldChange{{Field: "notes", Old: "", New: "fragile"}}}
+	err := repo.UpdateDetails(shipment.TrackingNumber, 1, []string{model.StatusOnProcess}, &updated, revision)
+	if err != nil {
+		t.Fatalf("UpdateDetails on a new shipment: %v", err)
+	}
+
+	stored, err := repo.FindByTrackingNumber(shipment.TrackingNumber)
+	if err != nil || stored == nil {
+		t.Fatalf("FindByTrackingNumber: %v", err)
+	}
+	if stored.Version != 2 || len(stored.Revisions) != 1 || stored.Notes != "fragile" {
+		t.Errorf("got version %d, %d revisions, notes %q; want 2, 1, fragile", stored.Version, len(stored.Revisions), stored.Notes)
+	}
+}
+
+func TestRepairNullArrays(t *testing.T) {
+	repo := testShipmentRepo(t)
+	shipment := newTestShipment()
+	if err := repo.Insert(shipment); err != nil {
+		t.Fatalf("Insert: %v", err)
+	}
+	// Shipments stored before NilSliceAsEmpty have null arrays
+	unset := bson.M{}
+	for _, field := range pushedArrays {
+		unset[field] = nil
+	}
+	if _, err := repo.col.UpdateOne(context.Background(), bson.M{"trackingnumber": shipment.TrackingNumber}, bson.M{"$set": unset}); err != nil {
+		t.Fatalf("null arrays: %v", err)
+	}
+
+	if err := repo.RepairNullArrays(); err != nil {
+		t.Fatalf("RepairNullArrays: %v", err)
+	}
+	for _, field := range pushedArrays {
+		n, err := repo.col.CountDocuments(context.Background(), bson.M{field: bson.M{"$type": "null"}})
+		if err != nil || n != 0 {
+			t.Errorf("%s: %d null after repair (%v)", field, n, err)
+		}
+	}
+}
+
+func TestLinkReturnAfterCreate(t *testing.T) {
+	repo := testShipmentRepo(t)
+	shipment := newTestShipment()
+	shipment.Status = model.StatusDelivered
+	if err := repo.Insert(shipment); err != nil {
+		t.Fatalf("Insert: %v", err)
+	}
+
+	err := repo.LinkReturn(shipment.TrackingNumber, 1, []string{model.StatusDelivered}, "RET-1", nil)
+	if err != nil {
+		t.Fatalf("LinkReturn on a new shipment: %v", err)
+	}
+	stored, err := repo.FindByTrackingNumber(shipment.TrackingNumber)
+	if err != nil || stored == nil {
+		t.Fatalf("FindByTrackingNumber: %v", err)
+	}
+	if len(stored.ReturnTrackingNumbers) != 1 || stored.ReturnTrackingNumbers[0] != "RET-1" {
+		t.Errorf("got return tracking numbers %v, want [RET-1]", stored.ReturnTrackingNumbers)
+	}
+}
+
+func TestAddDeliveryAttemptAfterCreate(t *testing.T) {
+	repo := testShipmentRepo(t)
+	shipment := newTestShipment()
+	shipment.Status = model.StatusInTransit
+	if err := repo.Insert(shipment); err != nil {
+		t.Fatalf("Insert: %v", err)
+	}
+
+	now := time.Now()
+	attempt := model.DoorstepAttempt{Number: 1, ReasonCode: "recipient_absent", CourierID: "c1", AttemptedAt: now}
+	event := model.TrackingEvent{Status: model.StatusInTransit, Description: "delivery attempt 1 failed", Timestamp: now}
+	err := repo.AddDeliveryAttempt(shipment.TrackingNumber, 1, []string{model.StatusInTransit}, attempt, event)
+	if err != nil {
+		t.Fatalf("AddDeliveryAttempt on a new shipment: %v", err)
+	}
+	stored, err := repo.FindByTrackingNumber(shipment.TrackingNumber)
+	if err != nil || stored == nil {
+		t.Fatalf("FindByTrackingNumber: %v", err)
+	}
+	if len(stored.DeliveryAttempts) != 1 || stored.DeliveryAttempts[0].Number != 1 {
+		t.Errorf("got delivery attempts %+v, want attempt 1", stored.DeliveryAttempts)
+	}
+}
+
+func TestUpdateStatusFromMovesParcels(t *testing.T) {
+	repo := testShipmentRepo(t)
+	shipment := newTestShipment()
+	shipment.Status = model.StatusPickedUp
+	shipment.Parcels = []model.Parcel{
+		{PieceNumber: 1, TrackingNumber: shipment.TrackingNumber + "-P1", Status: model.StatusPickedUp},
+		{PieceNumber: 2, TrackingNumber: shipment.TrackingNumber + "-P2", Status: model.StatusDelivered},
+	}
+	if err := repo.Insert(shipment); err != nil {
+		t.Fatalf("Insert: %v", err)
+	}
+
+	event := model.TrackingEvent{Status: model.StatusInTransit, Timestamp: time.Now()}
+	if err := repo.UpdateStatusFrom(shipment.TrackingNumber, []string{model.StatusPickedUp}, event); err != nil {
+		t.Fatalf("UpdateStatusFrom: %v", err)
+	}
+	stored, err := repo.FindByTrackingNumber(shipment.TrackingNumber)
+	if err != nil || stored == nil {
+		t.Fatalf("FindByTrackingNumber: %v", err)
+	}
+	if stored.Status != model.StatusInTransit || stored.Parcels[0].Status != model.StatusInTransit || stored.Parcels[1].Status != model.StatusDelivered {
+		t.Errorf("got shipment %s, parcels %s and %s; want in_transit, in_transit and delivered",
+			stored.Status, stored.Parcels[0].Status, stored.Parcels[1].Status)
+	}
+}
+
+func TestUpdateStatusFromWithoutParcels(t *testing.T) {
+	repo := testShipmentRepo(t)
+	shipment := newTestShipment()
+	shipment.Status = model.StatusPickedUp
+	if err := repo.Insert(shipment); err != nil {
+		t.Fatalf("Insert: %v", err)
+	}
+
+	event := model.TrackingEvent{Status: model.StatusInTransit, Timestamp: time.Now()}
+	if err := repo.UpdateStatusFrom(shipment.TrackingNumber, []string{model.StatusPickedUp}, event); err != nil {
+		t.Fatalf("UpdateStatusFrom on a shipment without parcels: %v", err)
+	}
+}
+
+func TestFindByRegionCodesReturnsRatesOnly(t *testing.T) {
+	repo := testShipmentRepo(t)
+	for _, level := range []string{model.ServiceRegular, model.ServiceRegular, model.ServiceExpress} {
+		shipment := newTestShipment()
+		shipment.UserID = "merchant-a"
+		shipment.ServiceLevel = level
+		shipment.OriginCode = "32.73.02"
+		shipment.DestinationCode = "31.71.01"
+		if err := repo.Insert(shipment); err != nil {
+			t.Fatalf("Insert: %v", err)
+		}
+	}
+
+	// Queried by another customer, with a city code above the shipments' districts
+	rates, err := repo.FindByRegionCodes("32.73", "31.71")
+	if err != nil {
+		t.Fatalf("FindByRegionCodes: %v", err)
+	}
+	want := []model.CourierRate{
+		{LogisticName: "JNE", ServiceLevel: model.ServiceExpress},
+		{LogisticName: "JNE", ServiceLevel: model.ServiceRegular},
+	}
+	if len(rates) != len(want) {
+		t.Fatalf("got %d rates, want %d", len(rates), len(want))
+	}
+	for i, rate := range rates {
+		if *rate != want[i] {
+			t.Errorf("rate %d = %+v, want %+v", i, *rate, want[i])
+		}
+	}
+	body, _ := json.Marshal(rates)
+	for _, private := range []string{"merchant-a", "Ahmad", "Budi", "081234567890", "Sudirman", "TN-"} {
+		if strings.Contains(string(body), private) {
+			t.Errorf("rates %s contain shipment data %q", body, private)
+		}
+	}
+}
@@ -0,0 +1,104 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// PhoneCheckRepository handles the "phone_checks" MongoDB collection: failed phone_last4
+// verifications per tracking number. After maxFailures failures within lockout, the tracking
+// number is locked for lockout whatever client the guesses come from, so the 10^4 possible
+// digits can't be tried from rotating IPs.
+type PhoneCheckRepository struct {
+	col         *mongo.Collection
+	maxFailures int
+	lockout     time.Duration
+}
+
+// phoneCheck counts the failed verifications of a tracking number until ExpiresAt. Once
+// Failures reaches maxFailures, the tracking number is locked until ExpiresAt.
+type phoneCheck struct {
+	TrackingNumber string    `bson:"_id"`
+	Failures       int       `bson:"failures"`
+	ExpiresAt      time.Time `bson:"expires_at"`
+}
+
+// NewPhoneCheckRepository creates a new PhoneCheckRepository
+func NewPhoneCheckRepository(db *mongo.Database, maxFailures int, lockout time.Duration) *PhoneCheckRepository {
+	return &PhoneCheckRepository{col: db.Collection("phone_checks"), maxFailures: maxFailures, lockout: lockout}
+}
+
+// EnsureIndexes creates the TTL index removing expired counters
+func (r *PhoneCheckRepository) EnsureIndexes() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	_, err := r.col.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "expires_at", Value: 1}},
+		Options: options.Index().SetExpireAfterSeconds(0),
+	})
+	return err
+}
+
+// LockedUntil returns until when verifications of trackingNumber are locked, or the zero time
+// when they aren't
+func (r *PhoneCheckRepository) LockedUntil(trackingNumber string) (time.Time, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var check phoneCheck
+	err := r.col.FindOne(ctx, bson.M{
+		"_id":        trackingNumber,
+		"failures":   bson.M{"$gte": r.maxFailures},
+		"expires_at": bson.M{"$gt": time.Now()}, // the TTL monitor only runs every minute
+	}).Decode(&check)
+	if err == mongo.ErrNoDocuments {
+		return time.Time{}, nil
+	}
+	if err != nil {
+		return time.Time{}, err
+	}
+	return check.ExpiresAt, nil
+}
+
+// RecordFailure counts a failed verification of trackingNumber. It returns until when the
+// tracking number is locked if this failure locked it, or the zero time.
+func (r *PhoneCheckRepository) RecordFailure(trackingNumber string) (time.Time, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	now := time.Now()
+	current := bson.M{"$gt": bson.A{"$expires_at", now}} // false for a new counter
+	update := mongo.Pipeline{
+		{{Key: "$set", Value: bson.M{
+			"failures":   bson.M{"$cond": bson.A{current, bson.M{"$add": bson.A{"$failures", 1}}, 1}},
+			"expires_at": bson.M{"$cond": bson.A{current, "$expires_at", now.Add(r.lockout)}},
+		}}},
+		{{Key: "$set", Value: bson.M{
+			"expires_at": bson.M{"$cond": bson.A{bson.M{"$gte": bson.A{"$failures", r.maxFailures}}, now.Add(r.lockout), "$expires_at"}},
+		}}},
+	}
+	var check phoneCheck
+	err := r.col.FindOneAndUpdate(ctx, bson.M{"_id": trackingNumber}, update,
+		options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)).Decode(&check)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if check.Failures < r.maxFailures {
+		return time.Time{}, nil
+	}
+	return check.ExpiresAt, nil
+}
+
+// Reset clears the failures of trackingNumber after a successful verification
+func (r *PhoneCheckRepository) Reset(trackingNumber string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	_, err := r.col.DeleteOne(ctx, bson.M{"_id": trackingNumber})
+	return err
+}
@@ -0,0 +1,47 @@
+package repository
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPhoneCheckLockout(t *testing.T) {
+	checks := NewPhoneCheckRepository(testDB(t), 3, time.Hour)
+
+	for i := 1; i <= 3; i++ {
+		until, err := checks.RecordFailure("TN-1")
+		if err != nil {
+			t.Fatalf("RecordFailure %d: %v", i, err)
+		}
+		if locked := !until.IsZero(); locked != (i == 3) {
+			t.Errorf("failure %d: locked %v, want %v", i, locked, i == 3)
+		}
+	}
+	until, err := checks.LockedUntil("TN-1")
+	if err != nil || time.Until(until) < 59*time.Minute {
+		t.Errorf("LockedUntil after 3 failures = %v, %v; want an hour from now", until, err)
+	}
+	if until, _ := checks.LockedUntil("TN-2"); !until.IsZero() {
+		t.Errorf("another tracking number is locked until %v", until)
+	}
+
+	if err := checks.Reset("TN-1"); err != nil {
+		t.Fatalf("Reset: %v", err)
+	}
+	if until, _ := checks.LockedUntil("TN-1"); !until.IsZero() {
+		t.Errorf("locked until %v after a reset", until)
+	}
+}
+
+func TestPhoneCheckFailuresExpire(t *testing.T) {
+	checks := NewPhoneCheckRepository(testDB(t), 2, 50*time.Millisecond)
+
+	if _, err := checks.RecordFailure("TN-1"); err != nil {
+		t.Fatalf("RecordFailure: %v", err)
+	}
+	time.Sleep(100 * time.Millisecond)
+	until, err := checks.RecordFailure("TN-1")
+	if err != nil || !until.IsZero() {
+		t.Errorf("second failure after the window = %v, %v; want a new count, not locked", until, err)
+	}
+}
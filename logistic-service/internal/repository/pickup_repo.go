@@ -0,0 +1,237 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"logistic-service/internal/model"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// ErrSlotFull is returned by BookSlot when the time slot has no capacity left
+var ErrSlotFull = errors.New("pickup time slot is fully booked")
+
+// PickupRepository handles the "pickups", "pickup_slots" and "pickup_capacities" MongoDB collections
+type PickupRepository struct {
+	pickups    *mongo.Collection
+	slots      *mongo.Collection
+	capacities *mongo.Collection
+}
+
+// NewPickupRepository creates a new PickupRepository
+func NewPickupRepository(db *mongo.Database) *PickupRepository {
+	return &PickupRepository{
+		pickups:    db.Collection("pickups"),
+		slots:      db.Collection("pickup_slots"),
+		capacities: db.Collection("pickup_capacities"),
+	}
+}
+
+// EnsureIndexes creates the indexes used by pickup listing
+func (r *PickupRepository) EnsureIndexes() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	_, err := r.pickups.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "created_at", Value: -1}}},
+		{Keys: bson.D{{Key: "date", Value: 1}, {Key: "logistic_name", Value: 1}, {Key: "area_code", Value: 1}}},
+		{Keys: bson.D{{Key: "courier_id", Value: 1}, {Key: "date", Value: 1}}},
+	})
+	if err != nil {
+		return err
+	}
+	_, err = r.slots.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "logistic_name", Value: 1}, {Key: "area_code", Value: 1}, {Key: "date", Value: 1}},
+	})
+	return err
+}
+
+// Insert stores a new pickup
+func (r *PickupRepository) Insert(pickup *model.Pickup) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	_, err := r.pickups.InsertOne(ctx, pickup)
+	return err
+}
+
+// FindByID returns a pickup, or (nil, nil) if it doesn't exist
+func (r *PickupRepository) FindByID(id string) (*model.Pickup, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var pickup model.Pickup
+	err := r.pickups.FindOne(ctx, bson.M{"_id": id}).Decode(&pickup)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	return &pickup, err
+}
+
+// PickupFilter narrows List results. Empty fields match everything.
+type PickupFilter struct {
+	UserID       string
+	CourierID    string
+	LogisticName string
+	AreaCode     string
+	Status       string
+	Date         string
+}
+
+// List returns the pickups matching filter, by date and time slot, newest first
+func (r *PickupRepository) List(f PickupFilter, limit int64) ([]*model.Pickup, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	filter := bson.M{}
+	for key, value := range map[string]string{
+		"user_id":       f.UserID,
+		"courier_id":    f.CourierID,
+		"logistic_name": f.LogisticName,
+		"area_code":     f.AreaCode,
+		"status":        f.Status,
+		"date":          f.Date,
+	} {
+		if value != "" {
+			filter[key] = value
+		}
+	}
+	opts := options.Find().SetSort(bson.D{{Key: "date", Value: -1}, {Key: "created_at", Value: -1}}).SetLimit(limit)
+	cursor, err := r.pickups.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	results := []*model.Pickup{}
+	err = cursor.All(ctx, &results)
+	return results, err
+}
+
+// UpdateStatus moves a pickup to event.Status, but only while its status is one of fromStatuses,
+// else ErrStatusConflict. A non-empty courierID assigns the courier.
+func (r *PickupRepository) UpdateStatus(id string, fromStatuses []string, courierID string, event model.PickupEvent) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	set := bson.M{"status": event.Status, "updated_at": event.Timestamp}
+	if courierID != "" {
+		set["courier_id"] = courierID
+	}
+	res, err := r.pickups.UpdateOne(ctx,
+		bson.M{"_id": id, "status": bson.M{"$in": fromStatuses}},
+		bson.M{"$set": set, "$push": bson.M{"events": event}},
+	)
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return ErrStatusConflict
+	}
+	return nil
+}
+
+// SetTrackingNumbers replaces the shipments of a pickup, used when some could not be booked
+func (r *PickupRepository) SetTrackingNumbers(id string, trackingNumbers []string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	_, err := r.pickups.UpdateOne(ctx, bson.M{"_id": id}, bson.M{"$set": bson.M{"tracking_numbers": trackingNumbers}})
+	return err
+}
+
+// slotID is the _id of the pickup_slots document counting the bookings of a time slot
+func slotID(logisticName, areaCode, date, timeSlot string) string {
+	return strings.Join([]string{logisticName, areaCode, date, timeSlot}, "|")
+}
+
+// BookSlot takes one pickup from the time slot of a courier in an area, or returns ErrSlotFull
+// when capacity pickups are already booked. The check and the increment are a single atomic update.
+func (r *PickupRepository) BookSlot(logisticName, areaCode, date, timeSlot string, capacity int) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	id := slotID(logisticName, areaCode, date, timeSlot)
+	_, err := r.slots.UpdateOne(ctx, bson.M{"_id": id}, bson.M{"$setOnInsert": model.PickupSlot{
+		LogisticName: logisticName,
+		AreaCode:     areaCode,
+		Date:         date,
+		TimeSlot:     timeSlot,
+	}}, options.Update().SetUpsert(true))
+	if err != nil && !mongo.IsDuplicateKeyError(err) { // lost a race to create it, which is fine
+		return err
+	}
+
+	res, err := r.slots.UpdateOne(ctx,
+		bson.M{"_id": id, "booked": bson.M{"$lt": capacity}},
+		bson.M{"$inc": bson.M{"booked": 1}},
+	)
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return ErrSlotFull
+	}
+	return nil
+}
+
+// ReleaseSlot gives back a pickup booked with BookSlot
+func (r *PickupRepository) ReleaseSlot(logisticName, areaCode, date, timeSlot string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	_, err := r.slots.UpdateOne(ctx,
+		bson.M{"_id": slotID(logisticName, areaCode, date, timeSlot), "booked": bson.M{"$gt": 0}},
+		bson.M{"$inc": bson.M{"booked": -1}},
+	)
+	return err
+}
+
+// BookedSlots returns the number of pickups booked per time slot of a courier in an area on a date
+func (r *PickupRepository) BookedSlots(logisticName, areaCode, date string) (map[string]int, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	cursor, err := r.slots.Find(ctx, bson.M{"logistic_name": logisticName, "area_code": areaCode, "date": date})
+	if err != nil {
+		return nil, err
+	}
+	var slots []model.PickupSlot
+	if err := cursor.All(ctx, &slots); err != nil {
+		return nil, err
+	}
+	booked := make(map[string]int)
+	for _, s := range slots {
+		booked[s.TimeSlot] = s.Booked
+	}
+	return booked, nil
+}
+
+// Capacity returns the slot capacity ops set for a courier in an area, or def when none is set
+func (r *PickupRepository) Capacity(logisticName, areaCode string, def int) (int, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var capacity model.PickupCapacity
+	err := r.capacities.FindOne(ctx, bson.M{"_id": logisticName + "|" + areaCode}).Decode(&capacity)
+	if err == mongo.ErrNoDocuments {
+		return def, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	return capacity.Capacity, nil
+}
+
+// SetCapacity stores the slot capacity of a courier in an area, replacing the previous one
+func (r *PickupRepository) SetCapacity(capacity *model.PickupCapacity) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	capacity.ID = capacity.LogisticName + "|" + capacity.AreaCode
+	_, err := r.capacities.ReplaceOne(ctx, bson.M{"_id": capacity.ID}, capacity, options.Replace().SetUpsert(true))
+	return err
+}
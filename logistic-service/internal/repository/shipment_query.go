@@ -0,0 +1,267 @@
+package repository
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"regexp"
+	"time"
+
+	"logistic-service/internal/model"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Sort fields accepted by List, mapped to their MongoDB keys
+var shipmentSortFields = map[string]string{
+	"created_at":      "createdat",
+	"updated_at":      "updatedat",
+	"tracking_number": "trackingnumber",
+}
+
+// Errors returned by List for bad client input
+var (
+	ErrInvalidCursor = errors.New("invalid cursor")
+	ErrInvalidSort   = errors.New("sort must be created_at, updated_at or tracking_number")
+)
+
+// ShipmentFilter holds the optional filters of a shipment listing.
+// Empty fields are ignored.
+type ShipmentFilter struct {
+	UserID         string
+	Status         string
+	LogisticName   string
+	Origin         string // case-insensitive substring match
+	Destination    string // case-insensitive substring match
+	RecipientPhone string
+	CreatedFrom    *time.Time // inclusive
+	CreatedTo      *time.Time // exclusive
+}
+
+// ShipmentListOptions controls filtering, sorting and cursor pagination of List
+type ShipmentListOptions struct {
+	Filter ShipmentFilter
+	Sort   string // one of created_at, updated_at, tracking_number (default created_at)
+	Desc   bool
+	Limit  int64
+	Cursor string // next_cursor returned by the previous page
+}
+
+// ShipmentPage is a single page of a shipment listing
+type ShipmentPage struct {
+	Data       []*model.Shipment `json:"data"`
+	Total      int64             `json:"total"`
+	Limit      int64             `json:"limit"`
+	NextCursor string            `json:"next_cursor,omitempty"`
+}
+
+// shipmentCursor points right after the last shipment of a page.
+// Value holds the sort key of that shipment, ID breaks ties.
+type shipmentCursor struct {
+	Sort  string    `json:"s"`
+	Time  time.Time `json:"t,omitempty"`
+	Value string    `json:"v,omitempty"`
+	ID    string    `json:"id"`
+}
+
+// EnsureIndexes creates the indexes used by shipment lookups and listings
+func (r *ShipmentRepository) EnsureIndexes() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	_, err := r.col.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "trackingnumber", Value: 1}}},
+		{Keys: bson.D{{Key: "userid", Value: 1}, {Key: "createdat", Value: -1}, {Key: "id", Value: -1}}},
+		{Keys: bson.D{{Key: "userid", Value: 1}, {Key: "updatedat", Value: -1}, {Key: "id", Value: -1}}},
+		{Keys: bson.D{{Key: "userid", Value: 1}, {Key: "status", Value: 1}, {Key: "createdat", Value: -1}}},
+		{Keys: bson.D{{Key: "userid", Value: 1}, {Key: "logisticname", Value: 1}, {Key: "createdat", Value: -1}}},
+		{Keys: bson.D{{Key: "recipient.phone", Value: 1}}},
+		{Keys: bson.D{{Key: "driverid", Value: 1}, {Key: "status", Value: 1}}},
+		{Keys: bson.D{{Key: "promiseddate", Value: 1}, {Key: "status", Value: 1}}},
+		{Keys: bson.D{{Key: "slabreachedat", Value: -1}}},
+		{Keys: bson.D{{Key: "searchkeys", Value: 1}}},
+		shipmentTextIndex,
+		{
+			Keys:    bson.D{{Key: "return.original_tracking_number", Value: 1}},
+			Options: options.Index().SetSparse(true),
+		},
+		{
+			Keys:    bson.D{{Key: "status", Value: 1}, {Key: "cod.ledgered_at", Value: 1}},
+			Options: options.Index().SetPartialFilterExpression(bson.M{"cod.collected_at": bson.M{"$exists": true}}),
+		},
+	})
+	return err
+}
+
+// BuildFilter converts a ShipmentFilter into a MongoDB query
+func (f ShipmentFilter) BuildFilter() bson.M {
+	filter := bson.M{}
+	if f.UserID != "" {
+		filter["userid"] = f.UserID
+	}
+	if f.Status != "" {
+		filter["status"] = f.Status
+	}
+	if f.LogisticName != "" {
+		filter["logisticname"] = f.LogisticName
+	}
+	if f.Origin != "" {
+		filter["origin"] = bson.M{"$regex": regexp.QuoteMeta(f.Origin), "$options": "i"}
+	}
+	if f.Destination != "" {
+		filter["destination"] = bson.M{"$regex": regexp.QuoteMeta(f.Destination), "$options": "i"}
+	}
+	if f.RecipientPhone != "" {
+		filter["recipient.phone"] = f.RecipientPhone
+	}
+	if f.CreatedFrom != nil || f.CreatedTo != nil {
+		created := bson.M{}
+		if f.CreatedFrom != nil {
+			created["$gte"] = *f.CreatedFrom
+		}
+		if f.CreatedTo != nil {
+			created["$lt"] = *f.CreatedTo
+		}
+		filter["createdat"] = created
+	}
+	return filter
+}
+
+// List returns one page of shipments matching opts together with the total match count
+func (r *ShipmentRepository) List(opts ShipmentListOptions) (*ShipmentPage, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if opts.Sort == "" {
+		opts.Sort = "created_at"
+	}
+	sortKey, ok := shipmentSortFields[opts.Sort]
+	if !ok {
+		return nil, ErrInvalidSort
+	}
+
+	var cur *shipmentCursor
+	if opts.Cursor != "" {
+		var err error
+		cur, err = decodeShipmentCursor(opts.Cursor)
+		if err != nil || cur.Sort != opts.Sort {
+			return nil, ErrInvalidCursor
+		}
+	}
+
+	filter := opts.Filter.BuildFilter()
+	total, err := r.col.CountDocuments(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	// Keyset pagination: continue strictly after the cursor position
+	query := filter
+	if cur != nil {
+		var value interface{} = cur.Value
+		if sortKey != "trackingnumber" {
+			value = cur.Time
+		}
+		op := "$gt"
+		if opts.Desc {
+			op = "$lt"
+		}
+		query = bson.M{"$and": bson.A{filter, bson.M{"$or": bson.A{
+			bson.M{sortKey: bson.M{op: value}},
+			bson.M{sortKey: value, "id": bson.M{op: cur.ID}},
+		}}}}
+	}
+
+	direction := 1
+	if opts.Desc {
+		direction = -1
+	}
+	findOpts := options.Find().
+		SetSort(bson.D{{Key: sortKey, Value: direction}, {Key: "id", Value: direction}}).
+		SetLimit(opts.Limit + 1) // one extra to know whether a next page exists
+
+	cursor, err := r.col.Find(ctx, query, findOpts)
+	if err != nil {
+		return nil, err
+	}
+	results := []*model.Shipment{}
+	if err := cursor.All(ctx, &results); err != nil {
+		return nil, err
+	}
+
+	page := &ShipmentPage{Total: total, Limit: opts.Limit}
+	if int64(len(results)) > opts.Limit {
+		results = results[:opts.Limit]
+		page.NextCursor = encodeShipmentCursor(opts.Sort, results[len(results)-1])
+	}
+	page.Data = results
+	return page, nil
+}
+
+func encodeShipmentCursor(sort string, last *model.Shipment) string {
+	cur := shipmentCursor{Sort: sort, ID: last.ID}
+	switch sort {
+	case "created_at":
+		cur.Time = last.CreatedAt
+	case "updated_at":
+		cur.Time = last.UpdatedAt
+	case "tracking_number":
+		cur.Value = last.TrackingNumber
+	}
+	raw, _ := json.Marshal(cur)
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
+
+func decodeShipmentCursor(s string) (*shipmentCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+	var cur shipmentCursor
+	if err := json.Unmarshal(raw, &cur); err != nil {
+		return nil, err
+	}
+	return &cur, nil
+}
+
+// CountUpTo counts the shipments matching filter, stopping at limit
+func (r *ShipmentRepository) CountUpTo(filter ShipmentFilter, limit int64) (int64, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	return r.col.CountDocuments(ctx, filter.BuildFilter(), options.Count().SetLimit(limit))
+}
+
+// exportTimeout bounds a shipment export, which streams for as long as the client keeps reading
+const exportTimeout = 10 * time.Minute
+
+// Export calls fn for every shipment matching filter, oldest first. Shipments are read from a
+// cursor in batches, so large exports never sit in memory. It stops at the first error of fn.
+func (r *ShipmentRepository) Export(filter ShipmentFilter, fn func(*model.Shipment) error) error {
+	ctx, cancel := context.WithTimeout(context.Background(), exportTimeout)
+	defer cancel()
+
+	opts := options.Find().
+		SetSort(bson.D{{Key: "createdat", Value: 1}, {Key: "id", Value: 1}}).
+		SetProjection(bson.M{"revisions": 0, "searchkeys": 0}).
+		SetBatchSize(500)
+	cursor, err := r.col.Find(ctx, filter.BuildFilter(), opts)
+	if err != nil {
+		return err
+	}
+	defer cursor.Close(ctx)
+
+	for cursor.Next(ctx) {
+		var shipment model.Shipment
+		if err := cursor.Decode(&shipment); err != nil {
+			return err
+		}
+		if err := fn(&shipment); err != nil {
+			return err
+		}
+	}
+	return cursor.Err()
+}
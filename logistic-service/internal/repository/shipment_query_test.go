@@ -0,0 +1,87 @@
+package repository
+
+import (
+	"encoding/base64"
+	"reflect"
+	"testing"
+	"time"
+
+	"logistic-service/internal/model"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestShipmentCursorRoundTrip(t *testing.T) {
+	created := time.Date(2026, time.August, 14, 10, 0, 0, 0, time.UTC)
+	s := &model.Shipment{ID: "id-1", TrackingNumber: "TN-1", CreatedAt: created, UpdatedAt: created.Add(time.Hour)}
+	tests := []struct {
+		sort string
+		want shipmentCursor
+	}{
+		{"created_at", shipmentCursor{Sort: "created_at", Time: created, ID: "id-1"}},
+		{"updated_at", shipmentCursor{Sort: "updated_at", Time: created.Add(time.Hour), ID: "id-1"}},
+		{"tracking_number", shipmentCursor{Sort: "tracking_number", Value: "TN-1", ID: "id-1"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.sort, func(t *testing.T) {
+			cur, err := decodeShipmentCursor(encodeShipmentCursor(tt.sort, s))
+			if err != nil {
+				t.Fatalf("decodeShipmentCursor: %v", err)
+			}
+			if !cur.Time.Equal(tt.want.Time) || cur.Sort != tt.want.Sort || cur.Value != tt.want.Value || cur.ID != tt.want.ID {
+				t.Errorf("got %+v, want %+v", *cur, tt.want)
+			}
+		})
+	}
+}
+
+func TestListRejectsBadInput(t *testing.T) {
+	byCreated := encodeShipmentCursor("created_at", &model.Shipment{ID: "id-1", CreatedAt: time.Now()})
+	tests := []struct {
+		name string
+		opts ShipmentListOptions
+		want error
+	}{
+		{"unknown sort", ShipmentListOptions{Sort: "status"}, ErrInvalidSort},
+		{"created_at cursor with tracking_number", ShipmentListOptions{Sort: "tracking_number", Cursor: byCreated}, ErrInvalidCursor},
+		{"created_at cursor with updated_at", ShipmentListOptions{Sort: "updated_at", Cursor: byCreated}, ErrInvalidCursor},
+		{"not base64", ShipmentListOptions{Cursor: "not a cursor!"}, ErrInvalidCursor},
+		{"not JSON", ShipmentListOptions{Cursor: base64.RawURLEncoding.EncodeToString([]byte("created_at"))}, ErrInvalidCursor},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Rejected before the database is queried
+			if _, err := (&ShipmentRepository{}).List(tt.opts); err != tt.want {
+				t.Errorf("List error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestBuildFilter(t *testing.T) {
+	from := time.Date(2026, time.August, 1, 0, 0, 0, 0, time.UTC)
+	to := from.AddDate(0, 1, 0)
+	tests := []struct {
+		name   string
+		filter ShipmentFilter
+		want   bson.M
+	}{
+		{"empty", ShipmentFilter{}, bson.M{}},
+		{"owner and status", ShipmentFilter{UserID: "u1", Status: model.StatusInTransit},
+			bson.M{"userid": "u1", "status": model.StatusInTransit}},
+		{"origin is a literal substring", ShipmentFilter{Origin: "Kab. (Bandung)"},
+			bson.M{"origin": bson.M{"$regex": `Kab\. \(Bandung\)`, "$options": "i"}}},
+		{"destination and phone", ShipmentFilter{Destination: "Jakarta", RecipientPhone: "0812"},
+			bson.M{"destination": bson.M{"$regex": "Jakarta", "$options": "i"}, "recipient.phone": "0812"}},
+		{"created from", ShipmentFilter{CreatedFrom: &from}, bson.M{"createdat": bson.M{"$gte": from}}},
+		{"created range", ShipmentFilter{LogisticName: "JNE", CreatedFrom: &from, CreatedTo: &to},
+			bson.M{"logisticname": "JNE", "createdat": bson.M{"$gte": from, "$lt": to}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.filter.BuildFilter(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("BuildFilter = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,163 @@
+package repository
+
+import (
+	"context"
+	"encoding/base64"
+	"strconv"
+	"strings"
+	"time"
+
+	"logistic-service/internal/model"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// shipmentTextIndex is the text index searched by Search. A collection has at most one text index.
+var shipmentTextIndex = mongo.IndexModel{
+	Keys: bson.D{
+		{Key: "trackingnumber", Value: "text"},
+		{Key: "recipient.name", Value: "text"},
+		{Key: "sender.name", Value: "text"},
+		{Key: "recipient.address", Value: "text"},
+		{Key: "sender.address", Value: "text"},
+	},
+	Options: options.Index().
+		SetName("shipment_search").
+		SetDefaultLanguage("none"). // names and Indonesian addresses, no English stemming
+		SetWeights(bson.D{
+			{Key: "trackingnumber", Value: 10},
+			{Key: "recipient.name", Value: 5},
+			{Key: "sender.name", Value: 3},
+			{Key: "recipient.address", Value: 2},
+			{Key: "sender.address", Value: 1},
+		}),
+}
+
+// ShipmentSearch is a ranked search of shipments. At least one of Text, PhoneDigits and NameKeys
+// is set; they and Filter must all match.
+type ShipmentSearch struct {
+	Filter      ShipmentFilter
+	Text        string   // words of names, addresses or tracking numbers, see shipmentTextIndex
+	PhoneDigits string   // consecutive digits of the sender or recipient phone
+	NameKeys    []string // phonetic keys, a shipment matches when it has one of them
+	Limit       int64
+	Cursor      string // next_cursor returned by the previous page
+}
+
+// ShipmentSearchHit is a shipment found by Search and its relevance, higher first
+type ShipmentSearchHit struct {
+	*model.Shipment
+	Score float64 `json:"score"`
+}
+
+// ShipmentSearchPage is a single page of search results
+type ShipmentSearchPage struct {
+	Data       []ShipmentSearchHit `json:"data"`
+	Total      int64               `json:"total"`
+	Limit      int64               `json:"limit"`
+	NextCursor string              `json:"next_cursor,omitempty"`
+}
+
+// Search returns one page of shipments matching q, most relevant first, newest first among equals.
+// The score adds up the text score, 2 for every matching name key and 1 when the phone digits
+// are in the recipient's phone rather than the sender's. Pages are offsets as scores don't
+// make a stable keyset.
+func (r *ShipmentRepository) Search(q ShipmentSearch) (*ShipmentSearchPage, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var offset int64
+	if q.Cursor != "" {
+		raw, err := base64.RawURLEncoding.DecodeString(q.Cursor)
+		if err != nil {
+			return nil, ErrInvalidCursor
+		}
+		if offset, err = strconv.ParseInt(string(raw), 10, 64); err != nil || offset < 0 {
+			return nil, ErrInvalidCursor
+		}
+	}
+
+	filter := q.Filter.BuildFilter()
+	score := bson.A{}
+	if q.Text != "" {
+		filter["$text"] = bson.M{"$search": q.Text}
+		score = append(score, bson.M{"$meta": "textScore"})
+	}
+	if q.PhoneDigits != "" {
+		// Digits may be separated by spaces or dashes in stored phone numbers
+		pattern := strings.Join(strings.Split(q.PhoneDigits, ""), `\D*`)
+		filter["$or"] = bson.A{
+			bson.M{"recipient.phone": bson.M{"$regex": pattern}},
+			bson.M{"sender.phone": bson.M{"$regex": pattern}},
+		}
+		score = append(score, bson.M{"$cond": bson.A{
+			bson.M{"$regexMatch": bson.M{"input": bson.M{"$ifNull": bson.A{"$recipient.phone", ""}}, "regex": pattern}}, 1, 0,
+		}})
+	}
+	if len(q.NameKeys) > 0 {
+		filter["searchkeys"] = bson.M{"$in": q.NameKeys}
+		score = append(score, bson.M{"$multiply": bson.A{2, bson.M{"$size": bson.M{
+			"$setIntersection": bson.A{bson.M{"$ifNull": bson.A{"$searchkeys", bson.A{}}}, q.NameKeys},
+		}}}})
+	}
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: filter}},
+		{{Key: "$addFields", Value: bson.M{"searchscore": bson.M{"$add": score}}}},
+		{{Key: "$sort", Value: bson.D{{Key: "searchscore", Value: -1}, {Key: "createdat", Value: -1}, {Key: "id", Value: -1}}}},
+		{{Key: "$facet", Value: bson.M{
+			"data":  bson.A{bson.M{"$skip": offset}, bson.M{"$limit": q.Limit + 1}},
+			"total": bson.A{bson.M{"$count": "n"}},
+		}}},
+	}
+	cursor, err := r.col.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	var results []struct {
+		Data []struct {
+			model.Shipment `bson:",inline"`
+			Score          float64 `bson:"searchscore"`
+		} `bson:"data"`
+		Total []struct {
+			N int64 `bson:"n"`
+		} `bson:"total"`
+	}
+	if err := cursor.All(ctx, &results); err != nil {
+		return nil, err
+	}
+
+	page := &ShipmentSearchPage{Data: []ShipmentSearchHit{}, Limit: q.Limit}
+	if len(results) == 0 {
+		return page, nil
+	}
+	if len(results[0].Total) > 0 {
+		page.Total = results[0].Total[0].N
+	}
+	data := results[0].Data
+	if int64(len(data)) > q.Limit {
+		data = data[:q.Limit]
+		page.NextCursor = base64.RawURLEncoding.EncodeToString([]byte(strconv.FormatInt(offset+q.Limit, 10)))
+	}
+	for i := range data {
+		page.Data = append(page.Data, ShipmentSearchHit{Shipment: &data[i].Shipment, Score: data[i].Score})
+	}
+	return page, nil
+}
+
+// FindMissingSearchKeys returns up to limit shipments created before Shipment.SearchKeys existed
+func (r *ShipmentRepository) FindMissingSearchKeys(limit int64) ([]*model.Shipment, error) {
+	return r.findSorted(bson.M{"searchkeys": bson.M{"$exists": false}}, limit)
+}
+
+// SetSearchKeys stores the phonetic name keys of a shipment. It doesn't count as a change,
+// so the version stays the same.
+func (r *ShipmentRepository) SetSearchKeys(trackingNumber string, keys []string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	_, err := r.col.UpdateOne(ctx, bson.M{"trackingnumber": trackingNumber}, bson.M{"$set": bson.M{"searchkeys": keys}})
+	return err
+}
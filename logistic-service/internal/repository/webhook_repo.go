@@ -0,0 +1,241 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"logistic-service/internal/model"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// WebhookRepository handles the "webhooks" and "webhook_deliveries" MongoDB collections
+type WebhookRepository struct {
+	hooks      *mongo.Collection
+	deliveries *mongo.Collection
+}
+
+// NewWebhookRepository creates a new WebhookRepository
+func NewWebhookRepository(db *mongo.Database) *WebhookRepository {
+	return &WebhookRepository{
+		hooks:      db.Collection("webhooks"),
+		deliveries: db.Collection("webhook_deliveries"),
+	}
+}
+
+// EnsureIndexes creates the indexes used by webhook lookups and the delivery queue
+func (r *WebhookRepository) EnsureIndexes() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	if _, err := r.hooks.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "user_id", Value: 1}},
+	}); err != nil {
+		return err
+	}
+	_, err := r.deliveries.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "status", Value: 1}, {Key: "next_attempt_at", Value: 1}}},
+		{Keys: bson.D{{Key: "webhook_id", Value: 1}, {Key: "created_at", Value: -1}}},
+	})
+	return err
+}
+
+// Insert stores a new webhook
+func (r *WebhookRepository) Insert(hook *model.Webhook) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	_, err := r.hooks.InsertOne(ctx, hook)
+	return err
+}
+
+// FindByID returns the webhook with the given ID, or (nil, nil) if it doesn't exist
+func (r *WebhookRepository) FindByID(id string) (*model.Webhook, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var hook model.Webhook
+	err := r.hooks.FindOne(ctx, bson.M{"_id": id}).Decode(&hook)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	return &hook, err
+}
+
+// FindByUserID returns all webhooks registered by a user
+func (r *WebhookRepository) FindByUserID(userID string) ([]*model.Webhook, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	cursor, err := r.hooks.Find(ctx, bson.M{"user_id": userID}, options.Find().SetSort(bson.M{"created_at": 1}))
+	if err != nil {
+		return nil, err
+	}
+	results := []*model.Webhook{}
+	err = cursor.All(ctx, &results)
+	return results, err
+}
+
+// FindActiveForEvent returns the active webhooks of a user subscribed to event
+func (r *WebhookRepository) FindActiveForEvent(userID, event string) ([]*model.Webhook, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	cursor, err := r.hooks.Find(ctx, bson.M{"user_id": userID, "active": true, "events": event})
+	if err != nil {
+		return nil, err
+	}
+	var results []*model.Webhook
+	err = cursor.All(ctx, &results)
+	return results, err
+}
+
+// Update saves url, events and active flag of a webhook.
+// Re-activating a webhook clears its failure counter and disabled reason.
+func (r *WebhookRepository) Update(hook *model.Webhook) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	set := bson.M{
+		"url":        hook.URL,
+		"events":     hook.Events,
+		"active":     hook.Active,
+		"updated_at": time.Now(),
+	}
+	update := bson.M{"$set": set}
+	if hook.Active {
+		set["failure_count"] = 0
+		update["$unset"] = bson.M{"disabled_at": "", "disabled_reason": ""}
+	}
+	_, err := r.hooks.UpdateOne(ctx, bson.M{"_id": hook.ID}, update)
+	return err
+}
+
+// Delete removes a webhook and its delivery log
+func (r *WebhookRepository) Delete(id string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if _, err := r.hooks.DeleteOne(ctx, bson.M{"_id": id}); err != nil {
+		return err
+	}
+	_, err := r.deliveries.DeleteMany(ctx, bson.M{"webhook_id": id})
+	return err
+}
+
+// RecordSuccess resets the consecutive failure counter of a webhook
+func (r *WebhookRepository) RecordSuccess(id string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	_, err := r.hooks.UpdateOne(ctx, bson.M{"_id": id}, bson.M{"$set": bson.M{"failure_count": 0}})
+	return err
+}
+
+// RecordFailure increments the consecutive failure counter of a webhook and
+// disables it once the counter reaches disableAfter. Returns true if it was disabled.
+func (r *WebhookRepository) RecordFailure(id string, disableAfter int) (bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var hook model.Webhook
+	err := r.hooks.FindOneAndUpdate(ctx,
+		bson.M{"_id": id},
+		bson.M{"$inc": bson.M{"failure_count": 1}},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&hook)
+	if err != nil {
+		return false, err
+	}
+	if !hook.Active || hook.FailureCount < disableAfter {
+		return false, nil
+	}
+
+	now := time.Now()
+	_, err = r.hooks.UpdateOne(ctx, bson.M{"_id": id}, bson.M{"$set": bson.M{
+		"active":          false,
+		"disabled_at":     now,
+		"disabled_reason": "too many consecutive failed deliveries",
+		"updated_at":      now,
+	}})
+	return err == nil, err
+}
+
+// InsertDelivery queues a new delivery
+func (r *WebhookRepository) InsertDelivery(delivery *model.WebhookDelivery) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	_, err := r.deliveries.InsertOne(ctx, delivery)
+	return err
+}
+
+// FindDeliveryByID returns a delivery, or (nil, nil) if it doesn't exist
+func (r *WebhookRepository) FindDeliveryByID(id string) (*model.WebhookDelivery, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var delivery model.WebhookDelivery
+	err := r.deliveries.FindOne(ctx, bson.M{"_id": id}).Decode(&delivery)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	return &delivery, err
+}
+
+// FindDeliveries returns the most recent deliveries of a webhook, newest first
+func (r *WebhookRepository) FindDeliveries(webhookID string, status string, limit int64) ([]*model.WebhookDelivery, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	filter := bson.M{"webhook_id": webhookID}
+	if status != "" {
+		filter["status"] = status
+	}
+	opts := options.Find().SetSort(bson.M{"created_at": -1}).SetLimit(limit)
+	cursor, err := r.deliveries.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	results := []*model.WebhookDelivery{}
+	err = cursor.All(ctx, &results)
+	return results, err
+}
+
+// ClaimDueDelivery picks one pending delivery whose next attempt is due and leases it
+// for the given duration so that concurrent dispatchers don't send it twice.
+// Returns (nil, nil) when nothing is due.
+func (r *WebhookRepository) ClaimDueDelivery(lease time.Duration) (*model.WebhookDelivery, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	now := time.Now()
+	var delivery model.WebhookDelivery
+	err := r.deliveries.FindOneAndUpdate(ctx,
+		bson.M{"status": model.DeliveryPending, "next_attempt_at": bson.M{"$lte": now}},
+		bson.M{"$set": bson.M{"next_attempt_at": now.Add(lease)}},
+		options.FindOneAndUpdate().SetSort(bson.M{"next_attempt_at": 1}).SetReturnDocument(options.After),
+	).Decode(&delivery)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	return &delivery, err
+}
+
+// RecordAttempt appends an attempt to a delivery and sets its status and next attempt time
+func (r *WebhookRepository) RecordAttempt(id string, attempt model.DeliveryAttempt, status string, nextAttemptAt time.Time) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	set := bson.M{"status": status, "next_attempt_at": nextAttemptAt}
+	if status != model.DeliveryPending {
+		set["completed_at"] = attempt.At
+	}
+	_, err := r.deliveries.UpdateOne(ctx, bson.M{"_id": id}, bson.M{
+		"$set":  set,
+		"$push": bson.M{"attempts": attempt},
+	})
+	return err
+}
@@ -0,0 +1,52 @@
+package service
+
+import (
+	"errors"
+	"logistic-service/internal/model"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Roles issued by auth-service in the "role" claim.
+// Tokens without a role claim are treated as customers.
+const (
+	RoleCustomer = "customer"
+	RoleCourier  = "courier"
+	RoleOps      = "ops"
+)
+
+// Principal is the authenticated caller extracted from the JWT claims.
+type Principal struct {
+	UserID   string
+	Username string
+	Role     string
+}
+
+// PrincipalFromClaims builds a Principal from the claims stored by JWTAuthMiddleware.
+func PrincipalFromClaims(claims jwt.MapClaims) (*Principal, error) {
+	userID, ok := claims["user_id"].(string)
+	if !ok || userID == "" {
+		return nil, errors.New("invalid user_id in claims")
+	}
+	username, _ := claims["username"].(string)
+	role, _ := claims["role"].(string)
+	if role == "" {
+		role = RoleCustomer
+	}
+	return &Principal{UserID: userID, Username: username, Role: role}, nil
+}
+
+// IsStaff reports whether the principal acts on behalf of the logistics operator
+// (couriers and ops) rather than as a shipment owner.
+func (p *Principal) IsStaff() bool {
+	return p.Role == RoleCourier || p.Role == RoleOps
+}
+
+// CanAccessShipment reports whether the principal may read or modify the shipment.
+// Owners can always access their own shipments; couriers and ops can access all of them.
+func (p *Principal) CanAccessShipment(s *model.Shipment) bool {
+	if s == nil {
+		return false
+	}
+	return s.UserID == p.UserID || p.IsStaff()
+}
@@ -0,0 +1,67 @@
+package service
+
+import (
+	"testing"
+
+	"logistic-service/internal/model"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func TestPrincipalFromClaims(t *testing.T) {
+	tests := []struct {
+		name     string
+		claims   jwt.MapClaims
+		wantRole string
+		wantErr  bool
+	}{
+		{"customer", jwt.MapClaims{"user_id": "u1", "role": "customer"}, RoleCustomer, false},
+		{"courier", jwt.MapClaims{"user_id": "u1", "role": "courier"}, RoleCourier, false},
+		{"ops", jwt.MapClaims{"user_id": "u1", "role": "ops"}, RoleOps, false},
+		{"missing role claim", jwt.MapClaims{"user_id": "u1"}, RoleCustomer, false},
+		{"empty role claim", jwt.MapClaims{"user_id": "u1", "role": ""}, RoleCustomer, false},
+		{"role claim not a string", jwt.MapClaims{"user_id": "u1", "role": 1}, RoleCustomer, false},
+		{"missing user_id", jwt.MapClaims{"role": "ops"}, "", true},
+		{"empty user_id", jwt.MapClaims{"user_id": "", "role": "ops"}, "", true},
+		{"user_id not a string", jwt.MapClaims{"user_id": 42}, "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := PrincipalFromClaims(tt.claims)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("PrincipalFromClaims error = %v, want error %v", err, tt.wantErr)
+			}
+			if err == nil && (p.UserID != "u1" || p.Role != tt.wantRole) {
+				t.Errorf("got %+v, want user u1 with role %s", p, tt.wantRole)
+			}
+		})
+	}
+}
+
+func TestCanAccessShipment(t *testing.T) {
+	shipment := &model.Shipment{TrackingNumber: "TN-1", UserID: "owner"}
+	tests := []struct {
+		name      string
+		principal Principal
+		shipment  *model.Shipment
+		wantStaff bool
+		want      bool
+	}{
+		{"owner", Principal{UserID: "owner", Role: RoleCustomer}, shipment, false, true},
+		{"other customer", Principal{UserID: "other", Role: RoleCustomer}, shipment, false, false},
+		{"courier", Principal{UserID: "courier", Role: RoleCourier}, shipment, true, true},
+		{"ops", Principal{UserID: "ops", Role: RoleOps}, shipment, true, true},
+		{"unknown role", Principal{UserID: "other", Role: "admin"}, shipment, false, false},
+		{"missing shipment", Principal{UserID: "ops", Role: RoleOps}, nil, true, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.principal.IsStaff(); got != tt.wantStaff {
+				t.Errorf("IsStaff = %v, want %v", got, tt.wantStaff)
+			}
+			if got := tt.principal.CanAccessShipment(tt.shipment); got != tt.want {
+				t.Errorf("CanAccessShipment = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
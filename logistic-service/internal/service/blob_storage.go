@@ -0,0 +1,82 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ErrBlobNotFound is returned by BlobStorage.Get when the key doesn't exist
+var ErrBlobNotFound = errors.New("blob not found")
+
+// BlobStorage stores binary files such as proof of delivery photos.
+// Keys are slash separated paths, e.g. "pod/JNE123/photo-<uuid>.jpg".
+// LocalBlobStorage is meant for development; production deployments can plug in
+// an object store (S3, GCS, ...) behind the same interface.
+type BlobStorage interface {
+	Put(ctx context.Context, key string, r io.Reader) error
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+}
+
+// LocalBlobStorage stores blobs as files below a root directory.
+type LocalBlobStorage struct {
+	root string
+}
+
+// NewLocalBlobStorage creates the root directory if needed and returns a storage rooted there.
+func NewLocalBlobStorage(root string) (*LocalBlobStorage, error) {
+	if err := os.MkdirAll(root, 0o750); err != nil {
+		return nil, err
+	}
+	return &LocalBlobStorage{root: root}, nil
+}
+
+// Put writes the blob to a temporary file first, so readers never see a partial file.
+func (s *LocalBlobStorage) Put(ctx context.Context, key string, r io.Reader) error {
+	path, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o750); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".upload-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name()) // no-op after a successful rename
+
+	if _, err := io.Copy(tmp, r); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// Get opens the blob for reading. The caller must close it.
+func (s *LocalBlobStorage) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	path, err := s.path(key)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrBlobNotFound
+	}
+	return f, err
+}
+
+// path maps a key to a file below root, rejecting keys that would escape it
+func (s *LocalBlobStorage) path(key string) (string, error) {
+	clean := filepath.Clean("/" + key)
+	if key == "" || strings.Contains(key, "..") || clean == "/" {
+		return "", errors.New("invalid blob key")
+	}
+	return filepath.Join(s.root, filepath.FromSlash(clean)), nil
+}
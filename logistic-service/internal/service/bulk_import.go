@@ -0,0 +1,326 @@
+package service
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"logistic-service/internal/model"
+	"strconv"
+	"strings"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// Limits of a single bulk upload
+const (
+	MaxBulkFileSize  = 10 << 20 // 10 MB
+	MaxBulkShipments = 5000
+)
+
+// BulkColumns are the CSV/XLSX header names, in template order.
+// A shipment with several items spans several rows sharing the same tracking_number;
+// shipment fields are taken from its first row.
+var BulkColumns = []string{
+	"tracking_number", "logistic_name", "status", "origin", "destination",
+	"sender_name", "sender_phone", "sender_address",
+	"recipient_name", "recipient_phone", "recipient_address",
+	"item_name", "item_qty", "item_weight", "notes",
+	"cod_amount", "cod_currency",
+	"sender_subdistrict_code", "recipient_subdistrict_code", "service_level",
+	"item_declared_value", "declared_value", "insured",
+}
+
+var requiredBulkColumns = []string{
+	"tracking_number", "logistic_name", "origin", "destination",
+	"sender_name", "sender_phone", "sender_address",
+	"recipient_name", "recipient_phone", "recipient_address",
+	"item_name",
+}
+
+// BulkRow is one shipment parsed from a bulk upload.
+// Err is set when the row could not be turned into a valid shipment.
+type BulkRow struct {
+	Row      int
+	Shipment model.Shipment
+	Err      string
+}
+
+// ParseBulkFile parses an uploaded CSV, XLSX or JSON array into shipments.
+// Row level problems are reported in BulkRow.Err; the error is only returned
+// when the file as a whole can't be read (bad format, missing columns, too many rows).
+// Structured addresses are resolved against regions.
+func ParseBulkFile(format string, data []byte, regions *RegionIndex) ([]BulkRow, error) {
+	var (
+		rows []BulkRow
+		err  error
+	)
+	switch format {
+	case "csv":
+		var records [][]string
+		r := csv.NewReader(bytes.NewReader(data))
+		r.FieldsPerRecord = -1
+		r.TrimLeadingSpace = true
+		records, err = r.ReadAll()
+		if err != nil {
+			return nil, fmt.Errorf("invalid CSV: %v", err)
+		}
+		rows, err = parseBulkRecords(records, regions)
+	case "xlsx":
+		var records [][]string
+		records, err = readXLSX(data)
+		if err != nil {
+			return nil, fmt.Errorf("invalid XLSX: %v", err)
+		}
+		rows, err = parseBulkRecords(records, regions)
+	case "json":
+		rows, err = parseBulkJSON(data, regions)
+	default:
+		return nil, errors.New("unsupported format, use csv, xlsx or json")
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(rows) == 0 {
+		return nil, errors.New("file contains no shipments")
+	}
+	if len(rows) > MaxBulkShipments {
+		return nil, fmt.Errorf("file contains %d shipments, the limit is %d", len(rows), MaxBulkShipments)
+	}
+	return rows, nil
+}
+
+func readXLSX(data []byte) ([][]string, error) {
+	f, err := excelize.OpenReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return f.GetRows(f.GetSheetName(0))
+}
+
+// parseBulkRecords converts CSV/XLSX records (header first) into shipments
+// A sender/recipient_subdistrict_code turns the address column into the street of a structured address.
+func parseBulkRecords(records [][]string, regions *RegionIndex) ([]BulkRow, error) {
+	if len(records) == 0 {
+		return nil, errors.New("file is empty")
+	}
+	columns := make(map[string]int)
+	for i, name := range records[0] {
+		columns[strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))] = i
+	}
+	for _, name := range requiredBulkColumns {
+		if _, ok := columns[name]; !ok {
+			return nil, fmt.Errorf("missing column %q", name)
+		}
+	}
+
+	var rows []BulkRow
+	index := make(map[string]int) // tracking number -> position in rows
+	for i, record := range records[1:] {
+		rowNo := i + 2 // header is row 1
+		get := func(name string) string {
+			if idx, ok := columns[name]; ok && idx < len(record) {
+				return strings.TrimSpace(record[idx])
+			}
+			return ""
+		}
+		if isBlankRecord(record) {
+			continue
+		}
+
+		item, itemErr := parseBulkItem(get("item_name"), get("item_qty"), get("item_weight"), get("item_declared_value"))
+		trackingNumber := get("tracking_number")
+		if pos, seen := index[trackingNumber]; seen && trackingNumber != "" {
+			// Additional item row of a shipment already started
+			if itemErr != nil && rows[pos].Err == "" {
+				rows[pos].Err = fmt.Sprintf("row %d: %v", rowNo, itemErr)
+			}
+			rows[pos].Shipment.Items = append(rows[pos].Shipment.Items, item)
+			continue
+		}
+
+		row := BulkRow{Row: rowNo, Shipment: model.Shipment{
+			TrackingNumber: trackingNumber,
+			LogisticName:   get("logistic_name"),
+			Status:         get("status"),
+			Origin:         get("origin"),
+			Destination:    get("destination"),
+			Notes:          get("notes"),
+			ServiceLevel:   get("service_level"),
+			Sender: model.ShipmentPerson{
+				Name:    get("sender_name"),
+				Phone:   get("sender_phone"),
+				Address: get("sender_address"),
+			},
+			Recipient: model.ShipmentPerson{
+				Name:    get("recipient_name"),
+				Phone:   get("recipient_phone"),
+				Address: get("recipient_address"),
+			},
+			Items: []model.ShipmentItem{item},
+		}}
+		if itemErr != nil {
+			row.Err = itemErr.Error()
+		}
+		for _, p := range []struct {
+			person *model.ShipmentPerson
+			code   string
+		}{
+			{&row.Shipment.Sender, get("sender_subdistrict_code")},
+			{&row.Shipment.Recipient, get("recipient_subdistrict_code")},
+		} {
+			if p.code != "" {
+				p.person.Structured = &model.StructuredAddress{Street: p.person.Address, SubdistrictCode: p.code}
+				p.person.Address = ""
+			}
+		}
+		if value := get("declared_value"); value != "" {
+			n, err := strconv.ParseInt(value, 10, 64)
+			if err != nil && row.Err == "" {
+				row.Err = fmt.Sprintf("invalid declared_value %q", value)
+			}
+			row.Shipment.DeclaredValue = n
+		}
+		if insured, err := strconv.ParseBool(get("insured")); err == nil && insured {
+			row.Shipment.Insurance = &model.ShipmentInsurance{}
+		}
+		if amount := get("cod_amount"); amount != "" {
+			n, err := strconv.ParseInt(amount, 10, 64)
+			if err != nil && row.Err == "" {
+				row.Err = fmt.Sprintf("invalid cod_amount %q", amount)
+			}
+			row.Shipment.COD = &model.CashOnDelivery{Amount: n, Currency: get("cod_currency")}
+		}
+		index[trackingNumber] = len(rows)
+		rows = append(rows, row)
+	}
+
+	for i := range rows {
+		if rows[i].Err == "" {
+			if err := ValidateShipment(regions, &rows[i].Shipment); err != nil {
+				rows[i].Err = err.Error()
+			}
+		}
+	}
+	return rows, nil
+}
+
+func parseBulkItem(name, qty, weight, value string) (model.ShipmentItem, error) {
+	item := model.ShipmentItem{Name: name, Qty: 1}
+	if qty != "" {
+		n, err := strconv.Atoi(qty)
+		if err != nil {
+			return item, fmt.Errorf("invalid item_qty %q", qty)
+		}
+		item.Qty = n
+	}
+	if weight != "" {
+		// Accept both 1.5 and the Indonesian decimal comma 1,5
+		w, err := strconv.ParseFloat(strings.Replace(weight, ",", ".", 1), 64)
+		if err != nil {
+			return item, fmt.Errorf("invalid item_weight %q", weight)
+		}
+		item.Weight = w
+	}
+	if value != "" {
+		v, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return item, fmt.Errorf("invalid item_declared_value %q", value)
+		}
+		item.DeclaredValue = v
+	}
+	return item, nil
+}
+
+func parseBulkJSON(data []byte, regions *RegionIndex) ([]BulkRow, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	var raw []json.RawMessage
+	if err := dec.Decode(&raw); err != nil {
+		return nil, fmt.Errorf("body must be a JSON array of shipments: %v", err)
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		return nil, errors.New("unexpected data after JSON array")
+	}
+
+	rows := make([]BulkRow, 0, len(raw))
+	for i, item := range raw {
+		row := BulkRow{Row: i + 1}
+		if err := json.Unmarshal(item, &row.Shipment); err != nil {
+			row.Err = "invalid shipment: " + err.Error()
+		} else if err := ValidateShipment(regions, &row.Shipment); err != nil {
+			row.Err = err.Error()
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+// ValidateShipment checks the fields required to create a shipment and fills defaults.
+// Structured addresses are resolved first, so they can supply address, origin and destination.
+func ValidateShipment(regions *RegionIndex, s *model.Shipment) error {
+	if err := regions.ApplyRegions(s); err != nil {
+		return err
+	}
+	required := []struct{ name, value string }{
+		{"tracking_number", s.TrackingNumber},
+		{"logistic_name", s.LogisticName},
+		{"origin", s.Origin},
+		{"destination", s.Destination},
+		{"sender.name", s.Sender.Name},
+		{"sender.phone", s.Sender.Phone},
+		{"sender.address", s.Sender.Address},
+		{"recipient.name", s.Recipient.Name},
+		{"recipient.phone", s.Recipient.Phone},
+		{"recipient.address", s.Recipient.Address},
+	}
+	for _, f := range required {
+		if strings.TrimSpace(f.value) == "" {
+			return fmt.Errorf("%s is required", f.name)
+		}
+	}
+	if len(s.Items) == 0 {
+		return errors.New("at least one item is required")
+	}
+	for _, item := range s.Items {
+		if strings.TrimSpace(item.Name) == "" {
+			return errors.New("item name is required")
+		}
+		if item.Qty <= 0 {
+			return fmt.Errorf("item %q: qty must be greater than 0", item.Name)
+		}
+		if item.Weight < 0 {
+			return fmt.Errorf("item %q: weight must not be negative", item.Name)
+		}
+	}
+	if err := ValidateCOD(s.COD); err != nil {
+		return err
+	}
+	if err := ValidateDeclaredValue(s); err != nil {
+		return err
+	}
+	if err := ApplyServiceLevel(s); err != nil {
+		return err
+	}
+	if err := ValidateParcels(s.Parcels); err != nil {
+		return err
+	}
+	// Kept in the template for older files; shipments always start on_process
+	if s.Status != "" && s.Status != model.StatusOnProcess {
+		return fmt.Errorf("status must be empty or %s", model.StatusOnProcess)
+	}
+	s.Status = model.StatusOnProcess
+	s.Return = nil
+	return nil
+}
+
+func isBlankRecord(record []string) bool {
+	for _, v := range record {
+		if strings.TrimSpace(v) != "" {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,171 @@
+package service
+
+import (
+	"strings"
+	"testing"
+
+	"logistic-service/internal/model"
+)
+
+const bulkHeader = "tracking_number,logistic_name,origin,destination,sender_name,sender_phone,sender_address," +
+	"recipient_name,recipient_phone,recipient_address,item_name,item_qty,item_weight,sender_subdistrict_code,status\n"
+
+func TestParseBulkCSV(t *testing.T) {
+	csv := "\ufeff" + bulkHeader +
+		"TN1,JNE,Bandung,Jakarta,Ani,0811,Jl. A 1,Budi,0812,Jl. B 2,Shoes,2,\"1,5\",,\n" +
+		"TN1,,,,,,,,,,Socks,3,0.2,,\n" +
+		",,,,,,,,,,,,,,\n" +
+		"TN2,JNE,Bandung,Jakarta,Ani,0811,Jl. A 1,Budi,0812,Jl. B 2,Book,x,,,\n" +
+		"TN3,JNE,,Jakarta,Ani,0811,Jl. Gambir 2,Budi,0812,Jl. B 2,Book,1,,31.71.01.1001,\n" +
+		"TN4,JNE,Bandung,Jakarta,Ani,0811,Jl. A 1,Budi,0812,Jl. B 2,Book,1,,,delivered\n" +
+		"TN5,JNE,Bandung,Jakarta,Ani,0811,Jl. A 1,,0812,Jl. B 2,Book,1,,,\n" +
+		"TN2,,,,,,,,,,Pen,1,,,\n"
+	rows, err := ParseBulkFile("csv", []byte(csv), testRegions(t))
+	if err != nil {
+		t.Fatalf("ParseBulkFile: %v", err)
+	}
+
+	tests := []struct {
+		row     int
+		tn      string
+		wantErr string
+	}{
+		{2, "TN1", ""},
+		{5, "TN2", `invalid item_qty "x"`},
+		{6, "TN3", ""},
+		{7, "TN4", "status must be empty or on_process"},
+		{8, "TN5", "recipient.name is required"},
+	}
+	if len(rows) != len(tests) {
+		t.Fatalf("got %d rows, want %d", len(rows), len(tests))
+	}
+	for i, tt := range tests {
+		r := rows[i]
+		if r.Row != tt.row || r.Shipment.TrackingNumber != tt.tn || r.Err != tt.wantErr {
+			t.Errorf("rows[%d] = row %d %s %q, want row %d %s %q", i, r.Row, r.Shipment.TrackingNumber, r.Err, tt.row, tt.tn, tt.wantErr)
+		}
+	}
+
+	tn1 := rows[0].Shipment
+	wantItems := []model.ShipmentItem{{Name: "Shoes", Qty: 2, Weight: 1.5}, {Name: "Socks", Qty: 3, Weight: 0.2}}
+	if len(tn1.Items) != 2 || tn1.Items[0] != wantItems[0] || tn1.Items[1] != wantItems[1] {
+		t.Errorf("TN1 items = %+v, want %+v", tn1.Items, wantItems)
+	}
+	if tn1.Status != model.StatusOnProcess || tn1.ServiceLevel != model.ServiceRegular {
+		t.Errorf("TN1 status %q, service level %q, want the defaults", tn1.Status, tn1.ServiceLevel)
+	}
+	if len(rows[1].Shipment.Items) != 2 {
+		t.Errorf("TN2 has %d items, want the later item row added too", len(rows[1].Shipment.Items))
+	}
+
+	tn3 := rows[2].Shipment
+	if tn3.Sender.Structured == nil || tn3.Sender.Structured.Street != "Jl. Gambir 2" || tn3.OriginCode != "31.71.01" || tn3.Origin == "" {
+		t.Errorf("TN3 sender %+v, origin %q (%s), want a structured address in Gambir", tn3.Sender, tn3.Origin, tn3.OriginCode)
+	}
+}
+
+func TestParseBulkFileRejected(t *testing.T) {
+	tests := []struct {
+		name, format, data, wantErr string
+	}{
+		{"unknown format", "txt", "x", "unsupported format"},
+		{"empty CSV", "csv", "", "file is empty"},
+		{"header only", "csv", bulkHeader, "no shipments"},
+		{"missing column", "csv", "tracking_number,logistic_name\nTN1,JNE\n", `missing column "origin"`},
+		{"JSON object", "json", `{"tracking_number":"TN1"}`, "JSON array"},
+		{"trailing JSON", "json", `[] []`, "unexpected data"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseBulkFile(tt.format, []byte(tt.data), testRegions(t))
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("ParseBulkFile error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestParseBulkJSON(t *testing.T) {
+	data := `[
+		{"tracking_number":"TN1","logistic_name":"JNE","origin":"Bandung","destination":"Jakarta",
+		 "sender":{"name":"Ani","phone":"0811","address":"Jl. A 1"},
+		 "recipient":{"name":"Budi","phone":"0812","address":"Jl. B 2"},
+		 "items":[{"name":"Shoes","qty":1}]},
+		{"tracking_number":"TN2","items":"none"},
+		{"tracking_number":"TN3"}
+	]`
+	rows, err := ParseBulkFile("json", []byte(data), testRegions(t))
+	if err != nil {
+		t.Fatalf("ParseBulkFile: %v", err)
+	}
+	want := []struct {
+		row     int
+		wantErr string
+	}{
+		{1, ""},
+		{2, "invalid shipment: "},
+		{3, "logistic_name is required"},
+	}
+	if len(rows) != len(want) {
+		t.Fatalf("got %d rows, want %d", len(rows), len(want))
+	}
+	for i, w := range want {
+		if rows[i].Row != w.row || (w.wantErr == "") != (rows[i].Err == "") || !strings.HasPrefix(rows[i].Err, w.wantErr) {
+			t.Errorf("rows[%d] = row %d %q, want row %d %q", i, rows[i].Row, rows[i].Err, w.row, w.wantErr)
+		}
+	}
+}
+
+func TestValidateShipment(t *testing.T) {
+	valid := func() *model.Shipment {
+		return &model.Shipment{
+			TrackingNumber: "TN1", LogisticName: "JNE", Origin: "Bandung", Destination: "Jakarta",
+			Sender:    model.ShipmentPerson{Name: "Ani", Phone: "0811", Address: "Jl. A 1"},
+			Recipient: model.ShipmentPerson{Name: "Budi", Phone: "0812", Address: "Jl. B 2"},
+			Items:     []model.ShipmentItem{{Name: "Shoes", Qty: 1, DeclaredValue: 100000}},
+			Return:    &model.ShipmentReturn{},
+		}
+	}
+	tests := []struct {
+		name    string
+		edit    func(s *model.Shipment)
+		wantErr string
+	}{
+		{"valid", func(s *model.Shipment) {}, ""},
+		{"on_process status", func(s *model.Shipment) { s.Status = model.StatusOnProcess }, ""},
+		{"no tracking number", func(s *model.Shipment) { s.TrackingNumber = " " }, "tracking_number is required"},
+		{"no sender phone", func(s *model.Shipment) { s.Sender.Phone = "" }, "sender.phone is required"},
+		{"no recipient address", func(s *model.Shipment) { s.Recipient.Address = "" }, "recipient.address is required"},
+		{"no items", func(s *model.Shipment) { s.Items = nil }, "at least one item is required"},
+		{"unnamed item", func(s *model.Shipment) { s.Items[0].Name = "" }, "item name is required"},
+		{"zero qty", func(s *model.Shipment) { s.Items[0].Qty = 0 }, "qty must be greater than 0"},
+		{"negative weight", func(s *model.Shipment) { s.Items[0].Weight = -1 }, "weight must not be negative"},
+		{"bad COD", func(s *model.Shipment) { s.COD = &model.CashOnDelivery{Amount: 0} }, "cod.amount"},
+		{"declared value below items", func(s *model.Shipment) { s.DeclaredValue = 1 }, "declared_value must be at least"},
+		{"unknown service level", func(s *model.Shipment) { s.ServiceLevel = "overnight" }, "service"},
+		{"other status", func(s *model.Shipment) { s.Status = "delivered" }, "status must be empty or on_process"},
+		{"bad structured address", func(s *model.Shipment) {
+			s.Sender.Structured = &model.StructuredAddress{Street: "Jl. A 1", SubdistrictCode: "99"}
+		}, "sender.structured_address:"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := valid()
+			tt.edit(s)
+			err := ValidateShipment(testRegions(t), s)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("ValidateShipment error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ValidateShipment: %v", err)
+			}
+			if s.Status != model.StatusOnProcess || s.ServiceLevel != model.ServiceRegular || s.DeclaredValue != 100000 || s.Return != nil {
+				t.Errorf("defaults not applied: status %q, service level %q, declared value %d, return %+v",
+					s.Status, s.ServiceLevel, s.DeclaredValue, s.Return)
+			}
+		})
+	}
+}
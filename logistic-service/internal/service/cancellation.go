@@ -0,0 +1,60 @@
+package service
+
+import (
+	"errors"
+	"logistic-service/internal/model"
+	"os"
+	"strings"
+)
+
+// CancelReason is an entry of the cancellation reason catalog.
+type CancelReason struct {
+	Code         string `json:"code"`
+	Description  string `json:"description"`
+	NoteRequired bool   `json:"note_required"`
+}
+
+// CancelReasons is the catalog of accepted cancellation reason codes.
+var CancelReasons = []CancelReason{
+	{Code: "changed_mind", Description: "Customer changed their mind"},
+	{Code: "wrong_address", Description: "Wrong sender or recipient address"},
+	{Code: "duplicate_order", Description: "Shipment was created twice"},
+	{Code: "item_unavailable", Description: "Item is out of stock or unavailable"},
+	{Code: "pickup_delayed", Description: "Courier pickup took too long"},
+	{Code: "other", Description: "Other reason, see note", NoteRequired: true},
+}
+
+// Errors returned by ValidateCancelReason
+var (
+	ErrUnknownCancelReason = errors.New("unknown reason_code")
+	ErrCancelNoteRequired  = errors.New("note is required for this reason_code")
+)
+
+// ValidateCancelReason checks the reason code against the catalog.
+func ValidateCancelReason(code, note string) error {
+	for _, r := range CancelReasons {
+		if r.Code == code {
+			if r.NoteRequired && strings.TrimSpace(note) == "" {
+				return ErrCancelNoteRequired
+			}
+			return nil
+		}
+	}
+	return ErrUnknownCancelReason
+}
+
+// CancellableStatuses returns the statuses a shipment may be cancelled from.
+// Defaults to pre-pickup only; override with CANCELLABLE_STATUSES (comma separated).
+func CancellableStatuses() []string {
+	v := os.Getenv("CANCELLABLE_STATUSES")
+	if v == "" {
+		return []string{model.StatusOnProcess}
+	}
+	var statuses []string
+	for _, s := range strings.Split(v, ",") {
+		if s = strings.TrimSpace(s); s != "" && s != model.StatusCancelled {
+			statuses = append(statuses, s)
+		}
+	}
+	return statuses
+}
@@ -0,0 +1,142 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log"
+	"logistic-service/internal/model"
+	"logistic-service/internal/repository"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	amqp "github.com/rabbitmq/amqp091-go"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// Default COD fee in basis points (1/100 of a percent) of the collected amount
+const defaultCODFeeBasisPoints = 250 // 2.5%
+
+// ValidateCOD checks the COD requested on a new shipment and normalizes its currency.
+// Collection fields are cleared: they are only set by the courier on delivery.
+func ValidateCOD(cod *model.CashOnDelivery) error {
+	if cod == nil {
+		return nil
+	}
+	if cod.Amount <= 0 {
+		return errors.New("cod.amount must be greater than 0")
+	}
+	cod.Currency = strings.ToUpper(strings.TrimSpace(cod.Currency))
+	if cod.Currency == "" {
+		cod.Currency = model.DefaultCODCurrency
+	}
+	if len(cod.Currency) != 3 || strings.Trim(cod.Currency, "ABCDEFGHIJKLMNOPQRSTUVWXYZ") != "" {
+		return errors.New("cod.currency must be a 3 letter ISO 4217 code")
+	}
+	cod.CollectedAmount = 0
+	cod.CollectedBy = ""
+	cod.CollectedAt = nil
+	cod.LedgeredAt = nil
+	return nil
+}
+
+// CODFeeBasisPoints returns the COD fee charged to merchants, in basis points of the collected amount.
+// Defaults to 250 (2.5%); override with COD_FEE_BPS.
+func CODFeeBasisPoints() int64 {
+	v, err := strconv.ParseInt(os.Getenv("COD_FEE_BPS"), 10, 64)
+	if err != nil || v < 0 || v > 10000 {
+		return defaultCODFeeBasisPoints
+	}
+	return v
+}
+
+// NewCODLedgerEntry builds the pending ledger entry for a delivered COD shipment.
+// The fee is rounded half up to the smallest currency unit.
+func NewCODLedgerEntry(s *model.Shipment) *model.CODLedgerEntry {
+	amount := s.COD.CollectedAmount
+	fee := (amount*CODFeeBasisPoints() + 5000) / 10000
+	collectedAt := time.Now()
+	if s.COD.CollectedAt != nil {
+		collectedAt = *s.COD.CollectedAt
+	}
+	return &model.CODLedgerEntry{
+		ID:             uuid.New().String(),
+		MerchantID:     s.UserID,
+		TrackingNumber: s.TrackingNumber,
+		Currency:       s.COD.Currency,
+		Amount:         amount,
+		Fee:            fee,
+		NetAmount:      amount - fee,
+		Status:         model.RemittancePending,
+		CollectedBy:    s.COD.CollectedBy,
+		CollectedAt:    collectedAt,
+	}
+}
+
+// RecordCODCollection adds the COD of a delivered shipment to the ledger, publishes cod.collected
+// and marks the shipment ledgered. A shipment already in the ledger (delivered twice, or a previous
+// attempt failed after the insert) is only marked. Delivered shipments left unmarked are retried by
+// the CODLedgerSweeper, so a failure here delays the entry instead of losing it.
+func RecordCODCollection(codRepo *repository.CODRepository, repo *repository.ShipmentRepository, ch *amqp.Channel, s *model.Shipment) error {
+	entry := NewCODLedgerEntry(s)
+	err := codRepo.InsertEntry(entry)
+	if err != nil && !mongo.IsDuplicateKeyError(err) {
+		return err
+	}
+	if err == nil {
+		publishJSON(ch, "", "cod.collected", entry)
+	}
+	return repo.MarkCODLedgered(s.TrackingNumber, time.Now())
+}
+
+// CODLedgerSweeper periodically records the COD of delivered shipments missing from the ledger,
+// e.g. because the database write failed right after the delivery.
+type CODLedgerSweeper struct {
+	codRepo  *repository.CODRepository
+	repo     *repository.ShipmentRepository
+	ch       *amqp.Channel
+	interval time.Duration
+}
+
+// NewCODLedgerSweeper creates a sweeper running every interval. Call Run in a goroutine to start it.
+func NewCODLedgerSweeper(codRepo *repository.CODRepository, repo *repository.ShipmentRepository, ch *amqp.Channel, interval time.Duration) *CODLedgerSweeper {
+	return &CODLedgerSweeper{codRepo: codRepo, repo: repo, ch: ch, interval: interval}
+}
+
+// Run sweeps right away and then every interval until ctx is cancelled.
+func (w *CODLedgerSweeper) Run(ctx context.Context) {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+	for {
+		w.sweep()
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// sweep records the unledgered collections found in batches until none are left
+func (w *CODLedgerSweeper) sweep() {
+	for {
+		shipments, err := w.repo.FindUnledgeredCOD(200)
+		if err != nil {
+			log.Printf("[CODLedgerSweeper] FindUnledgeredCOD error: %v", err)
+			return
+		}
+		recorded := 0
+		for _, s := range shipments {
+			if err := RecordCODCollection(w.codRepo, w.repo, w.ch, s); err != nil {
+				log.Printf("[CODLedgerSweeper] RecordCODCollection error for %s: %v", s.TrackingNumber, err)
+				continue
+			}
+			recorded++
+		}
+		if recorded == 0 || len(shipments) < 200 {
+			return
+		}
+	}
+}
@@ -0,0 +1,92 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"logistic-service/internal/model"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// AttemptFailureReason is an entry of the failed delivery attempt reason catalog.
+type AttemptFailureReason struct {
+	Code         string `json:"code"`
+	Description  string `json:"description"`
+	NoteRequired bool   `json:"note_required"`
+	RTOReason    string `json:"-"` // Return reason used when this attempt triggers an RTO
+}
+
+// AttemptFailureReasons is the catalog of accepted failed attempt reason codes.
+var AttemptFailureReasons = []AttemptFailureReason{
+	{Code: "recipient_absent", Description: "Nobody at the address to receive the parcel", RTOReason: "recipient_unavailable"},
+	{Code: "wrong_address", Description: "Address is wrong or could not be found", RTOReason: "address_not_found"},
+	{Code: "refused", Description: "Recipient refused the parcel", RTOReason: "refused"},
+	{Code: "premises_closed", Description: "Office or shop was closed", RTOReason: "recipient_unavailable"},
+	{Code: "cod_not_ready", Description: "Recipient could not pay the COD amount", RTOReason: "cod_not_paid"},
+	{Code: "other", Description: "Other reason, see note", NoteRequired: true, RTOReason: "other"},
+}
+
+// Limits of the delivery preferences a recipient can set
+const (
+	MaxRescheduleDays          = 7
+	MaxDeliveryInstructionsLen = 500
+)
+
+// Errors returned by ValidateAttemptReason and ValidateSchedule
+var (
+	ErrUnknownAttemptReason = errors.New("unknown reason_code")
+	ErrAttemptNoteRequired  = errors.New("note is required for this reason_code")
+	ErrUnknownTimeSlot      = errors.New("time_slot must be morning, afternoon or evening")
+)
+
+// deliveryZone is the time zone delivery dates are interpreted in (WIB)
+var deliveryZone = time.FixedZone("WIB", 7*60*60)
+
+// ValidateAttemptReason checks the reason code against the catalog and returns its entry.
+func ValidateAttemptReason(code, note string) (*AttemptFailureReason, error) {
+	for i, r := range AttemptFailureReasons {
+		if r.Code == code {
+			if r.NoteRequired && strings.TrimSpace(note) == "" {
+				return nil, ErrAttemptNoteRequired
+			}
+			return &AttemptFailureReasons[i], nil
+		}
+	}
+	return nil, ErrUnknownAttemptReason
+}
+
+// MaxDeliveryAttempts returns the number of failed attempts after which a shipment is returned
+// to sender. Defaults to 3; override with MAX_DELIVERY_ATTEMPTS.
+func MaxDeliveryAttempts() int {
+	if v, err := strconv.Atoi(os.Getenv("MAX_DELIVERY_ATTEMPTS")); err == nil && v > 0 {
+		return v
+	}
+	return 3
+}
+
+// ValidateSchedule checks a requested delivery date (YYYY-MM-DD, WIB) and time slot.
+// The date must be between today and MaxRescheduleDays ahead.
+func ValidateSchedule(date, timeSlot string, now time.Time) (*model.DeliverySchedule, error) {
+	day, err := time.ParseInLocation("2006-01-02", date, deliveryZone)
+	if err != nil {
+		return nil, errors.New("date must be formatted as YYYY-MM-DD")
+	}
+	today := now.In(deliveryZone)
+	today = time.Date(today.Year(), today.Month(), today.Day(), 0, 0, 0, 0, deliveryZone)
+	if day.Before(today) || day.After(today.AddDate(0, 0, MaxRescheduleDays)) {
+		return nil, fmt.Errorf("date must be between today and %d days ahead", MaxRescheduleDays)
+	}
+
+	valid := false
+	for _, s := range model.DeliverySlots {
+		if s == timeSlot {
+			valid = true
+		}
+	}
+	if !valid {
+		return nil, ErrUnknownTimeSlot
+	}
+	return &model.DeliverySchedule{Date: date, TimeSlot: timeSlot, RequestedAt: now}, nil
+}
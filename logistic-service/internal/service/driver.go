@@ -0,0 +1,58 @@
+package service
+
+import (
+	"errors"
+	"logistic-service/internal/model"
+	"strings"
+	"time"
+)
+
+// Today returns the current date (YYYY-MM-DD) in WIB, the zone pickup and delivery dates use
+func Today(now time.Time) string {
+	return now.In(deliveryZone).Format("2006-01-02")
+}
+
+// ParseTaskDate validates a task list date (YYYY-MM-DD); empty means today
+func ParseTaskDate(date string, now time.Time) (string, error) {
+	if date == "" {
+		return Today(now), nil
+	}
+	if _, err := time.ParseInLocation("2006-01-02", date, deliveryZone); err != nil {
+		return "", errors.New("date must be formatted as YYYY-MM-DD")
+	}
+	return date, nil
+}
+
+// InArea reports whether a region code lies in one of areaCodes. Area codes can be at any
+// level: a driver serving city "31.71" covers district "31.71.01". Non-code areas (free text
+// origins) must match exactly.
+func InArea(areaCodes []string, code string) bool {
+	if code == "" {
+		return false
+	}
+	for _, area := range areaCodes {
+		if code == area || strings.HasPrefix(code, area+".") {
+			return true
+		}
+	}
+	return false
+}
+
+// MatchDriver picks the driver for a stop in areaCode of a courier company: among the active
+// drivers serving the area and the company, the one with the lowest load. Ties go to the first
+// driver in the list. Returns nil when no driver serves the area.
+func MatchDriver(drivers []*model.Driver, areaCode, logisticName string, load map[string]int) *model.Driver {
+	var best *model.Driver
+	for _, d := range drivers {
+		if !d.Active || !InArea(d.AreaCodes, areaCode) {
+			continue
+		}
+		if d.LogisticName != "" && !strings.EqualFold(d.LogisticName, logisticName) {
+			continue
+		}
+		if best == nil || load[d.UserID] < load[best.UserID] {
+			best = d
+		}
+	}
+	return best
+}
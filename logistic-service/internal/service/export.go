@@ -0,0 +1,300 @@
+package service
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"logistic-service/internal/model"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// MaxXLSXExportRows is the most shipments in one XLSX export; a sheet holds 1,048,576 rows
+// including the header. CSV exports are unlimited.
+const MaxXLSXExportRows = 1048575
+
+// ErrExportTooLarge is returned by ExportWriter.WriteRow once an XLSX sheet is full
+var ErrExportTooLarge = errors.New("too many shipments for one XLSX sheet, narrow the filters or export CSV")
+
+// Shipment export column groups, see ExportColumns
+const (
+	ExportGroupShipment   = "shipment"
+	ExportGroupSender     = "sender"
+	ExportGroupRecipient  = "recipient"
+	ExportGroupItems      = "items"
+	ExportGroupCost       = "cost"
+	ExportGroupTimestamps = "timestamps"
+)
+
+// ExportColumn is a column of the shipment export. Value returns a string, int64 or float64.
+type ExportColumn struct {
+	Name  string
+	Group string
+	Value func(s *model.Shipment) interface{}
+}
+
+// ExportColumns lists every export column in file order. Timestamps are formatted in WIB.
+// Amounts are in rupiah; shipments don't store a shipping fee, so cost covers the declared
+// value, insurance and COD.
+var ExportColumns = []ExportColumn{
+	{"tracking_number", ExportGroupShipment, func(s *model.Shipment) interface{} { return s.TrackingNumber }},
+	{"logistic_name", ExportGroupShipment, func(s *model.Shipment) interface{} { return s.LogisticName }},
+	{"service_level", ExportGroupShipment, func(s *model.Shipment) interface{} { return s.ServiceLevel }},
+	{"status", ExportGroupShipment, func(s *model.Shipment) interface{} { return s.Status }},
+	{"origin", ExportGroupShipment, func(s *model.Shipment) interface{} { return s.Origin }},
+	{"destination", ExportGroupShipment, func(s *model.Shipment) interface{} { return s.Destination }},
+	{"parcels", ExportGroupShipment, func(s *model.Shipment) interface{} { return int64(len(s.Parcels)) }},
+	{"notes", ExportGroupShipment, func(s *model.Shipment) interface{} { return s.Notes }},
+
+	{"sender_name", ExportGroupSender, func(s *model.Shipment) interface{} { return s.Sender.Name }},
+	{"sender_phone", ExportGroupSender, func(s *model.Shipment) interface{} { return s.Sender.Phone }},
+	{"sender_address", ExportGroupSender, func(s *model.Shipment) interface{} { return s.Sender.Address }},
+
+	{"recipient_name", ExportGroupRecipient, func(s *model.Shipment) interface{} { return s.Recipient.Name }},
+	{"recipient_phone", ExportGroupRecipient, func(s *model.Shipment) interface{} { return s.Recipient.Phone }},
+	{"recipient_address", ExportGroupRecipient, func(s *model.Shipment) interface{} { return s.Recipient.Address }},
+
+	{"items", ExportGroupItems, exportItems},
+	{"item_qty", ExportGroupItems, func(s *model.Shipment) interface{} {
+		var qty int64
+		for _, item := range s.Items {
+			qty += int64(item.Qty)
+		}
+		return qty
+	}},
+	{"weight_kg", ExportGroupItems, func(s *model.Shipment) interface{} {
+		weight := 0.0
+		for _, item := range s.Items {
+			weight += item.Weight * float64(item.Qty)
+		}
+		return weight
+	}},
+
+	{"declared_value", ExportGroupCost, func(s *model.Shipment) interface{} { return s.DeclaredValue }},
+	{"insured_value", ExportGroupCost, func(s *model.Shipment) interface{} {
+		if s.Insurance == nil {
+			return int64(0)
+		}
+		return s.Insurance.InsuredValue
+	}},
+	{"insurance_premium", ExportGroupCost, func(s *model.Shipment) interface{} {
+		if s.Insurance == nil {
+			return int64(0)
+		}
+		return s.Insurance.Premium
+	}},
+	{"cod_amount", ExportGroupCost, func(s *model.Shipment) interface{} {
+		if s.COD == nil {
+			return int64(0)
+		}
+		return s.COD.Amount
+	}},
+	{"cod_collected_amount", ExportGroupCost, func(s *model.Shipment) interface{} {
+		if s.COD == nil {
+			return int64(0)
+		}
+		return s.COD.CollectedAmount
+	}},
+
+	{"created_at", ExportGroupTimestamps, func(s *model.Shipment) interface{} { return exportTime(s.CreatedAt) }},
+	{"picked_up_at", ExportGroupTimestamps, firstEventAt(model.StatusPickedUp)},
+	{"in_transit_at", ExportGroupTimestamps, firstEventAt(model.StatusInTransit)},
+	{"delivered_at", ExportGroupTimestamps, func(s *model.Shipment) interface{} {
+		if s.Status != model.StatusDelivered {
+			return ""
+		}
+		return exportTime(DeliveredAt(s))
+	}},
+	{"cancelled_at", ExportGroupTimestamps, firstEventAt(model.StatusCancelled)},
+	{"return_to_sender_at", ExportGroupTimestamps, firstEventAt(model.StatusReturnToSender)},
+	{"promised_date", ExportGroupTimestamps, func(s *model.Shipment) interface{} { return s.PromisedDate }},
+	{"updated_at", ExportGroupTimestamps, func(s *model.Shipment) interface{} { return exportTime(s.UpdatedAt) }},
+}
+
+// ParseExportColumns resolves a comma separated list of column groups and column names, in
+// ExportColumns order. Empty selects every column. tracking_number is always included.
+func ParseExportColumns(spec string) ([]ExportColumn, error) {
+	selected := map[string]bool{"tracking_number": true}
+	all := true
+	for _, name := range strings.Split(spec, ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+		known := false
+		for _, col := range ExportColumns {
+			if col.Name == name || col.Group == name {
+				selected[col.Name] = true
+				known = true
+			}
+		}
+		if !known {
+			return nil, fmt.Errorf("unknown export column %q", name)
+		}
+		all = false
+	}
+	columns := []ExportColumn{}
+	for _, col := range ExportColumns {
+		if all || selected[col.Name] {
+			columns = append(columns, col)
+		}
+	}
+	return columns, nil
+}
+
+// exportItems lists the items of s, e.g. "2x Kaos (0.25 kg); 1x Sepatu (1.00 kg)"
+func exportItems(s *model.Shipment) interface{} {
+	items := make([]string, 0, len(s.Items))
+	for _, item := range s.Items {
+		items = append(items, fmt.Sprintf("%dx %s (%.2f kg)", item.Qty, item.Name, item.Weight))
+	}
+	return strings.Join(items, "; ")
+}
+
+// firstEventAt returns a column value with the time s first reached status, or ""
+func firstEventAt(status string) func(s *model.Shipment) interface{} {
+	return func(s *model.Shipment) interface{} {
+		for _, e := range s.Events {
+			if e.Status == status {
+				return exportTime(e.Timestamp)
+			}
+		}
+		return ""
+	}
+}
+
+func exportTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.In(deliveryZone).Format("2006-01-02 15:04:05")
+}
+
+// ExportRow returns the values of columns for s
+func ExportRow(columns []ExportColumn, s *model.Shipment) []interface{} {
+	row := make([]interface{}, len(columns))
+	for i, col := range columns {
+		row[i] = col.Value(s)
+	}
+	return row
+}
+
+// ExportHeader returns the header row of columns
+func ExportHeader(columns []ExportColumn) []interface{} {
+	row := make([]interface{}, len(columns))
+	for i, col := range columns {
+		row[i] = col.Name
+	}
+	return row
+}
+
+// ExportWriter writes the rows of an export in one file format. Close must be called to
+// finish the file; Discard releases what the writer holds (e.g. temporary files) whether the
+// file was finished or not, so it is safe to defer right after NewExportWriter.
+type ExportWriter interface {
+	WriteRow(values []interface{}) error
+	Close() error
+	Discard()
+}
+
+// Export file formats and their content types
+var ExportContentTypes = map[string]string{
+	"csv":  "text/csv; charset=utf-8",
+	"xlsx": "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+}
+
+// NewExportWriter returns a writer of format (csv or xlsx) to w. CSV rows are written as they
+// come; XLSX rows are buffered by the excelize stream writer, which spills to a temporary file,
+// and the workbook is written to w on Close.
+func NewExportWriter(format string, w io.Writer) (ExportWriter, error) {
+	switch format {
+	case "csv":
+		return &csvExportWriter{w: csv.NewWriter(w)}, nil
+	case "xlsx":
+		f := excelize.NewFile()
+		if err := f.SetSheetName("Sheet1", "Shipments"); err != nil {
+			return nil, err
+		}
+		sw, err := f.NewStreamWriter("Shipments")
+		if err != nil {
+			return nil, err
+		}
+		return &xlsxExportWriter{f: f, sw: sw, w: w}, nil
+	}
+	return nil, errors.New("format must be csv or xlsx")
+}
+
+type csvExportWriter struct {
+	w *csv.Writer
+}
+
+func (e *csvExportWriter) WriteRow(values []interface{}) error {
+	record := make([]string, len(values))
+	for i, v := range values {
+		switch v := v.(type) {
+		case string:
+			record[i] = escapeCSVFormula(v)
+		case int64:
+			record[i] = strconv.FormatInt(v, 10)
+		case float64:
+			record[i] = strconv.FormatFloat(v, 'f', -1, 64)
+		default:
+			record[i] = fmt.Sprint(v)
+		}
+	}
+	return e.w.Write(record)
+}
+
+// escapeCSVFormula prefixes text a spreadsheet would run as a formula with a quote, so
+// merchant-supplied fields like names and notes open as plain text
+func escapeCSVFormula(v string) string {
+	if v != "" && strings.ContainsRune("=+-@\t\r", rune(v[0])) {
+		return "'" + v
+	}
+	return v
+}
+
+func (e *csvExportWriter) Close() error {
+	e.w.Flush()
+	return e.w.Error()
+}
+
+func (e *csvExportWriter) Discard() {}
+
+type xlsxExportWriter struct {
+	f    *excelize.File
+	sw   *excelize.StreamWriter
+	w    io.Writer
+	rows int
+}
+
+func (e *xlsxExportWriter) WriteRow(values []interface{}) error {
+	if e.rows > MaxXLSXExportRows {
+		return ErrExportTooLarge
+	}
+	e.rows++
+	cell, err := excelize.CoordinatesToCellName(1, e.rows)
+	if err != nil {
+		return err
+	}
+	return e.sw.SetRow(cell, values)
+}
+
+func (e *xlsxExportWriter) Close() error {
+	defer e.f.Close()
+	if err := e.sw.Flush(); err != nil {
+		return err
+	}
+	return e.f.Write(e.w)
+}
+
+func (e *xlsxExportWriter) Discard() {
+	if err := e.f.Close(); err != nil {
+		log.Printf("[xlsxExportWriter] Close error: %v", err)
+	}
+}
@@ -0,0 +1,40 @@
+package service
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestCSVExportEscapesFormulas(t *testing.T) {
+	tests := []struct {
+		value interface{}
+		want  string
+	}{
+		{"Budi", "Budi\n"},
+		{"=HYPERLINK(\"http://evil\")", "\"'=HYPERLINK(\"\"http://evil\"\")\"\n"},
+		{"+6281234567890", "'+6281234567890\n"},
+		{"-1", "'-1\n"},
+		{"@SUM(A1)", "'@SUM(A1)\n"},
+		{"\tcmd", "'\tcmd\n"},
+		{"a=b", "a=b\n"},
+		{"", "\n"},
+		{int64(-5), "-5\n"},
+		{-1.5, "-1.5\n"},
+	}
+	for _, tt := range tests {
+		var buf bytes.Buffer
+		w, err := NewExportWriter("csv", &buf)
+		if err != nil {
+			t.Fatalf("NewExportWriter: %v", err)
+		}
+		if err := w.WriteRow([]interface{}{tt.value}); err != nil {
+			t.Fatalf("WriteRow: %v", err)
+		}
+		if err := w.Close(); err != nil {
+			t.Fatalf("Close: %v", err)
+		}
+		if got := buf.String(); got != tt.want {
+			t.Errorf("WriteRow(%q) wrote %q, want %q", tt.value, got, tt.want)
+		}
+	}
+}
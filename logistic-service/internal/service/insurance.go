@@ -0,0 +1,199 @@
+package service
+
+import (
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"logistic-service/internal/model"
+	"os"
+	"strings"
+	"time"
+)
+
+// bundledInsurancePolicy is the default premium rules; override with INSURANCE_POLICY_FILE.
+//
+//go:embed data/insurance.json
+var bundledInsurancePolicy []byte
+
+// ErrInsuranceUnavailable is returned when no insurance rule matches a shipment
+var ErrInsuranceUnavailable = errors.New("insurance is not available for this courier and service level")
+
+// LoadInsurancePolicy reads the insurance policy JSON at path, or the bundled policy when path is empty.
+func LoadInsurancePolicy(path string) (*model.InsurancePolicy, error) {
+	data := bundledInsurancePolicy
+	if path != "" {
+		var err error
+		if data, err = os.ReadFile(path); err != nil {
+			return nil, err
+		}
+	}
+	var policy model.InsurancePolicy
+	if err := json.Unmarshal(data, &policy); err != nil {
+		return nil, fmt.Errorf("insurance policy: %v", err)
+	}
+	if policy.Currency == "" {
+		policy.Currency = model.InsuranceCurrency
+	}
+	if policy.Currency != model.InsuranceCurrency {
+		return nil, fmt.Errorf("insurance policy: currency must be %s", model.InsuranceCurrency)
+	}
+	if policy.MaxInsuredValue <= 0 || policy.UninsuredLiability < 0 || policy.ClaimWindowDays <= 0 {
+		return nil, errors.New("insurance policy: max_insured_value and claim_window_days must be greater than 0")
+	}
+	for i, rule := range policy.Rules {
+		if rule.RateBps < 0 || rule.RateBps > 10000 || rule.MinPremium < 0 || rule.MinValue < 0 {
+			return nil, fmt.Errorf("insurance policy: rule %d: rate_bps must be between 0 and 10000, amounts must not be negative", i+1)
+		}
+	}
+	return &policy, nil
+}
+
+// ItemsDeclaredValue returns the total declared value of items (value per item times quantity)
+func ItemsDeclaredValue(items []model.ShipmentItem) int64 {
+	var total int64
+	for _, item := range items {
+		total += item.DeclaredValue * int64(item.Qty)
+	}
+	return total
+}
+
+// ValidateDeclaredValue checks the declared values of a new shipment. The shipment value defaults
+// to the total of its items and can't be lower.
+func ValidateDeclaredValue(s *model.Shipment) error {
+	for _, item := range s.Items {
+		if item.DeclaredValue < 0 {
+			return fmt.Errorf("item %q: declared_value must not be negative", item.Name)
+		}
+	}
+	total := ItemsDeclaredValue(s.Items)
+	switch {
+	case s.DeclaredValue < 0:
+		return errors.New("declared_value must not be negative")
+	case s.DeclaredValue == 0:
+		s.DeclaredValue = total
+	case s.DeclaredValue < total:
+		return fmt.Errorf("declared_value must be at least the total declared value of the items (%d)", total)
+	}
+	return nil
+}
+
+// ApplyInsurance checks the insurance requested on a new shipment and sets its premium. The insured
+// value defaults to the declared value and can't exceed it or the policy maximum.
+func ApplyInsurance(policy *model.InsurancePolicy, s *model.Shipment) error {
+	if s.Insurance == nil {
+		return nil
+	}
+	if s.DeclaredValue <= 0 {
+		return errors.New("declared_value is required for insured shipments")
+	}
+	insured := s.Insurance.InsuredValue
+	if insured == 0 {
+		insured = s.DeclaredValue
+	}
+	switch {
+	case insured < 0 || insured > s.DeclaredValue:
+		return errors.New("insurance.insured_value must be between 1 and the declared_value")
+	case insured > policy.MaxInsuredValue:
+		return fmt.Errorf("insurance.insured_value must not exceed %d", policy.MaxInsuredValue)
+	}
+	quote, err := QuoteInsurance(policy, s.LogisticName, s.ServiceLevel, insured)
+	if err != nil {
+		return err
+	}
+	s.Insurance = quote
+	return nil
+}
+
+// QuoteInsurance computes the premium of insuring value with the first matching rule. The premium
+// is rounded half up to the rupiah and is at least the minimum premium of the rule.
+func QuoteInsurance(policy *model.InsurancePolicy, logisticName, serviceLevel string, value int64) (*model.ShipmentInsurance, error) {
+	for _, rule := range policy.Rules {
+		if rule.LogisticName != "" && !strings.EqualFold(rule.LogisticName, logisticName) {
+			continue
+		}
+		if rule.ServiceLevel != "" && rule.ServiceLevel != serviceLevel {
+			continue
+		}
+		if value < rule.MinValue {
+			continue
+		}
+		premium := (value*rule.RateBps + 5000) / 10000
+		if premium < rule.MinPremium {
+			premium = rule.MinPremium
+		}
+		return &model.ShipmentInsurance{
+			InsuredValue: value,
+			Currency:     policy.Currency,
+			RateBps:      rule.RateBps,
+			Premium:      premium,
+		}, nil
+	}
+	return nil, ErrInsuranceUnavailable
+}
+
+// ClaimPayoutLimit returns the most a claim on s can pay out: the insured value, or for shipments
+// without insurance the uninsured liability, capped by the declared value when there is one.
+func ClaimPayoutLimit(policy *model.InsurancePolicy, s *model.Shipment) int64 {
+	if s.Insurance != nil {
+		return s.Insurance.InsuredValue
+	}
+	if s.DeclaredValue > 0 && s.DeclaredValue < policy.UninsuredLiability {
+		return s.DeclaredValue
+	}
+	return policy.UninsuredLiability
+}
+
+// MaxClaimPayout returns the most an approved claim can pay out: the claimed amount, capped by the
+// payout limit set when it was filed
+func MaxClaimPayout(claim *model.Claim) int64 {
+	if claim.ClaimedAmount < claim.PayoutLimit {
+		return claim.ClaimedAmount
+	}
+	return claim.PayoutLimit
+}
+
+// ValidateClaimType checks claimType against model.ClaimTypes
+func ValidateClaimType(claimType string) error {
+	for _, t := range model.ClaimTypes {
+		if t == claimType {
+			return nil
+		}
+	}
+	return fmt.Errorf("type must be one of %s", strings.Join(model.ClaimTypes, ", "))
+}
+
+// CheckClaimable reports why a claim of claimType can't be filed for s at now, or nil.
+// Damage and missing items are claimed within the claim window after delivery; a parcel is
+// considered lost once it is still on its way after its promised delivery date.
+func CheckClaimable(policy *model.InsurancePolicy, s *model.Shipment, claimType string, now time.Time) error {
+	switch claimType {
+	case model.ClaimDamaged, model.ClaimMissingItems:
+		if s.Status != model.StatusDelivered {
+			return errors.New("damaged and missing_items claims can only be filed for delivered shipments")
+		}
+		if now.Sub(DeliveredAt(s)) > time.Duration(policy.ClaimWindowDays)*24*time.Hour {
+			return fmt.Errorf("claims must be filed within %d days after delivery", policy.ClaimWindowDays)
+		}
+	case model.ClaimLost:
+		if s.Status != model.StatusPickedUp && s.Status != model.StatusInTransit {
+			return errors.New("lost claims can only be filed for shipments picked up and not delivered")
+		}
+		if s.SLABreachedAt == nil {
+			return errors.New("lost claims can only be filed once the shipment missed its promised delivery date")
+		}
+	default:
+		return ValidateClaimType(claimType)
+	}
+	return nil
+}
+
+// CanTransitionClaim reports whether a claim in status from can move to status to
+func CanTransitionClaim(from, to string) bool {
+	for _, s := range model.ClaimTransitions[from] {
+		if s == to {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,175 @@
+package service
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"logistic-service/internal/model"
+)
+
+func testInsurancePolicy(t *testing.T) *model.InsurancePolicy {
+	t.Helper()
+	policy, err := LoadInsurancePolicy("")
+	if err != nil {
+		t.Fatalf("LoadInsurancePolicy: %v", err)
+	}
+	return policy
+}
+
+func TestQuoteInsurance(t *testing.T) {
+	policy := testInsurancePolicy(t)
+	tests := []struct {
+		name, serviceLevel string
+		value              int64
+		wantRate           int64
+		wantPremium        int64
+	}{
+		{"economy minimum premium", model.ServiceEconomy, 1000000, 30, 5000},
+		{"economy rate", model.ServiceEconomy, 50000000, 30, 150000},
+		{"regular minimum premium", model.ServiceRegular, 1000000, 20, 2500},
+		{"regular rate", model.ServiceRegular, 10000000, 20, 20000},
+		{"rounded half up", model.ServiceRegular, 2000250, 20, 4001},
+		{"rounded down", model.ServiceRegular, 2000240, 20, 4000},
+		{"high value from its minimum", model.ServiceExpress, 20000000, 25, 50000},
+		{"high value rate", model.ServiceExpress, 30000000, 25, 75000},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			quote, err := QuoteInsurance(policy, "JNE", tt.serviceLevel, tt.value)
+			if err != nil {
+				t.Fatalf("QuoteInsurance: %v", err)
+			}
+			want := model.ShipmentInsurance{InsuredValue: tt.value, Currency: "IDR", RateBps: tt.wantRate, Premium: tt.wantPremium}
+			if *quote != want {
+				t.Errorf("QuoteInsurance = %+v, want %+v", *quote, want)
+			}
+		})
+	}
+
+	courier := &model.InsurancePolicy{Currency: "IDR", Rules: []model.InsuranceRule{{LogisticName: "JNE", RateBps: 10}}}
+	if quote, err := QuoteInsurance(courier, "jne", model.ServiceRegular, 1000000); err != nil || quote.Premium != 1000 {
+		t.Errorf("courier rule: %+v, %v, want a premium of 1000", quote, err)
+	}
+	if _, err := QuoteInsurance(courier, "SiCepat", model.ServiceRegular, 1000000); err != ErrInsuranceUnavailable {
+		t.Errorf("no matching rule: error = %v, want ErrInsuranceUnavailable", err)
+	}
+}
+
+func TestApplyInsurance(t *testing.T) {
+	policy := testInsurancePolicy(t)
+	tests := []struct {
+		name          string
+		declaredValue int64
+		insuredValue  int64
+		wantInsured   int64
+		wantPremium   int64
+		wantErr       string
+	}{
+		{"defaults to the declared value", 5000000, 0, 5000000, 10000, ""},
+		{"part of the declared value", 5000000, 2000000, 2000000, 4000, ""},
+		{"no declared value", 0, 0, 0, 0, "declared_value is required"},
+		{"above the declared value", 5000000, 6000000, 0, 0, "between 1 and the declared_value"},
+		{"negative", 5000000, -1, 0, 0, "between 1 and the declared_value"},
+		{"above the policy maximum", 200000000, 0, 0, 0, "must not exceed 100000000"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &model.Shipment{
+				LogisticName:  "JNE",
+				ServiceLevel:  model.ServiceRegular,
+				DeclaredValue: tt.declaredValue,
+				Insurance:     &model.ShipmentInsurance{InsuredValue: tt.insuredValue, Premium: 1},
+			}
+			err := ApplyInsurance(policy, s)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("ApplyInsurance error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ApplyInsurance: %v", err)
+			}
+			if s.Insurance.InsuredValue != tt.wantInsured || s.Insurance.Premium != tt.wantPremium {
+				t.Errorf("insurance = %+v, want %d insured for a premium of %d", *s.Insurance, tt.wantInsured, tt.wantPremium)
+			}
+		})
+	}
+
+	s := &model.Shipment{}
+	if err := ApplyInsurance(policy, s); err != nil || s.Insurance != nil {
+		t.Errorf("uninsured shipment: %+v, %v", s.Insurance, err)
+	}
+}
+
+func TestClaimPayout(t *testing.T) {
+	policy := testInsurancePolicy(t)
+	limits := []struct {
+		name  string
+		s     model.Shipment
+		limit int64
+	}{
+		{"insured", model.Shipment{DeclaredValue: 5000000, Insurance: &model.ShipmentInsurance{InsuredValue: 3000000}}, 3000000},
+		{"uninsured below the liability", model.Shipment{DeclaredValue: 400000}, 400000},
+		{"uninsured above the liability", model.Shipment{DeclaredValue: 5000000}, 1000000},
+		{"uninsured without declared value", model.Shipment{}, 1000000},
+	}
+	for _, tt := range limits {
+		if got := ClaimPayoutLimit(policy, &tt.s); got != tt.limit {
+			t.Errorf("%s: ClaimPayoutLimit = %d, want %d", tt.name, got, tt.limit)
+		}
+	}
+
+	payouts := []struct {
+		claimed, limit, want int64
+	}{
+		{300000, 1000000, 300000},
+		{1000000, 1000000, 1000000},
+		{2500000, 1000000, 1000000},
+	}
+	for _, tt := range payouts {
+		if got := MaxClaimPayout(&model.Claim{ClaimedAmount: tt.claimed, PayoutLimit: tt.limit}); got != tt.want {
+			t.Errorf("MaxClaimPayout(claimed %d, limit %d) = %d, want %d", tt.claimed, tt.limit, got, tt.want)
+		}
+	}
+}
+
+func TestCheckClaimable(t *testing.T) {
+	policy := testInsurancePolicy(t)
+	now := time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC)
+	delivered := func(ago time.Duration) *model.Shipment {
+		return &model.Shipment{Status: model.StatusDelivered, Events: []model.TrackingEvent{
+			{Status: model.StatusDelivered, Timestamp: now.Add(-ago)},
+		}}
+	}
+	breached := now.Add(-24 * time.Hour)
+	tests := []struct {
+		name      string
+		s         *model.Shipment
+		claimType string
+		wantErr   string
+	}{
+		{"damaged within the window", delivered(6 * 24 * time.Hour), model.ClaimDamaged, ""},
+		{"missing items after the window", delivered(8 * 24 * time.Hour), model.ClaimMissingItems, "within 7 days"},
+		{"damaged before delivery", &model.Shipment{Status: model.StatusInTransit}, model.ClaimDamaged, "delivered shipments"},
+		{"lost after the promised date", &model.Shipment{Status: model.StatusInTransit, SLABreachedAt: &breached}, model.ClaimLost, ""},
+		{"lost before the promised date", &model.Shipment{Status: model.StatusInTransit}, model.ClaimLost, "missed its promised delivery date"},
+		{"lost once delivered", delivered(time.Hour), model.ClaimLost, "picked up and not delivered"},
+		{"unknown type", delivered(time.Hour), "stolen", "type must be one of"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := CheckClaimable(policy, tt.s, tt.claimType, now)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("CheckClaimable: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("CheckClaimable error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,218 @@
+package service
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/draw"
+	"image/png"
+	"logistic-service/internal/model"
+	"strings"
+
+	"github.com/boombuler/barcode"
+	"github.com/boombuler/barcode/code128"
+	"github.com/boombuler/barcode/qr"
+	"github.com/jung-kurt/gofpdf"
+)
+
+// MaxBatchLabels is the maximum number of shipments in one batch label request
+const MaxBatchLabels = 100
+
+// LabelFormat is a printable label page size in millimetres.
+type LabelFormat struct {
+	Name   string
+	Width  float64
+	Height float64
+}
+
+// Supported label formats: A6 paper and 4x6 inch thermal labels
+var LabelFormats = map[string]LabelFormat{
+	"a6":  {Name: "a6", Width: 105, Height: 148},
+	"4x6": {Name: "4x6", Width: 101.6, Height: 152.4},
+}
+
+// DefaultLabelFormat is used when the request doesn't specify one
+const DefaultLabelFormat = "a6"
+
+// ParseLabelFormat looks up a label format by name (case insensitive, empty means default).
+func ParseLabelFormat(name string) (LabelFormat, error) {
+	if name == "" {
+		name = DefaultLabelFormat
+	}
+	format, ok := LabelFormats[strings.ToLower(name)]
+	if !ok {
+		return LabelFormat{}, fmt.Errorf("unsupported label format %q, use a6 or 4x6", name)
+	}
+	return format, nil
+}
+
+// RenderLabels renders one label page per shipment into a single PDF. Multi-parcel shipments get
+// a label per parcel, with the piece tracking number in the barcodes.
+func RenderLabels(format LabelFormat, shipments []*model.Shipment) ([]byte, error) {
+	pdf := gofpdf.NewCustom(&gofpdf.InitType{
+		UnitStr: "mm",
+		Size:    gofpdf.SizeType{Wd: format.Width, Ht: format.Height},
+	})
+	pdf.SetMargins(4, 4, 4)
+	pdf.SetAutoPageBreak(false, 0)
+	// Core fonts are cp1252; translate so names and addresses with accents still render
+	tr := pdf.UnicodeTranslatorFromDescriptor("")
+
+	for _, s := range shipments {
+		if len(s.Parcels) == 0 {
+			pdf.AddPage()
+			if err := drawLabel(pdf, tr, format, s, nil); err != nil {
+				return nil, fmt.Errorf("label %s: %v", s.TrackingNumber, err)
+			}
+			continue
+		}
+		for i := range s.Parcels {
+			pdf.AddPage()
+			if err := drawLabel(pdf, tr, format, s, &s.Parcels[i]); err != nil {
+				return nil, fmt.Errorf("label %s: %v", s.Parcels[i].TrackingNumber, err)
+			}
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := pdf.Output(&buf); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// drawLabel draws a single label: courier header, Code128 barcode, addresses, items and QR code.
+// Labels of a parcel carry its piece tracking number and piece count.
+func drawLabel(pdf *gofpdf.Fpdf, tr func(string) string, format LabelFormat, s *model.Shipment, parcel *model.Parcel) error {
+	const margin = 4.0
+	width := format.Width - 2*margin
+	y := margin
+	code := s.TrackingNumber
+	if parcel != nil {
+		code = parcel.TrackingNumber
+	}
+
+	// Header: courier and route
+	pdf.SetFont("Helvetica", "B", 16)
+	pdf.SetXY(margin, y)
+	pdf.CellFormat(width, 8, tr(strings.ToUpper(s.LogisticName)), "", 0, "L", false, 0, "")
+	pdf.SetFont("Helvetica", "", 9)
+	pdf.SetXY(margin, y)
+	pdf.CellFormat(width, 8, tr(s.Origin+" > "+s.Destination), "", 0, "R", false, 0, "")
+	y += 9
+	pdf.Line(margin, y, margin+width, y)
+	y += 2
+
+	// Code128 barcode of the tracking number. Code128 only encodes ASCII: tracking numbers it
+	// can't encode get a note instead, and the QR code below still carries them.
+	if bar, err := code128.Encode(code); err == nil {
+		if err := drawBarcode(pdf, "code128-"+code, bar, margin+4, y, width-8, 18); err != nil {
+			return err
+		}
+	} else {
+		pdf.SetFont("Helvetica", "I", 9)
+		pdf.SetXY(margin, y+6)
+		pdf.CellFormat(width, 6, "No barcode for this tracking number, scan the QR code", "1", 0, "C", false, 0, "")
+	}
+	y += 19
+	pdf.SetFont("Courier", "B", 12)
+	pdf.SetXY(margin, y)
+	pdf.CellFormat(width, 5, tr(code), "", 0, "C", false, 0, "")
+	y += 6
+	if parcel != nil {
+		pdf.SetFont("Helvetica", "B", 9)
+		pdf.SetXY(margin, y)
+		pdf.CellFormat(width, 4, fmt.Sprintf("PARCEL %d/%d", parcel.PieceNumber, len(s.Parcels))+labelWeight(" - %s", parcel.Weight), "", 0, "C", false, 0, "")
+		y += 5
+	}
+	y++
+	pdf.Line(margin, y, margin+width, y)
+	y += 2
+
+	// Recipient gets the most room, it's what the courier reads
+	y = drawParty(pdf, tr, "TO", s.Recipient, margin, y, width, 11)
+	pdf.Line(margin, y, margin+width, y)
+	y += 2
+	y = drawParty(pdf, tr, "FROM", s.Sender, margin, y, width, 8)
+	pdf.Line(margin, y, margin+width, y)
+	y += 2
+
+	// Items next to the QR code in the bottom part of the label
+	qrSize := 28.0
+	qrTop := format.Height - margin - qrSize
+	itemsWidth := width - qrSize - 3
+
+	totalWeight := 0.0
+	totalQty := 0
+	for _, item := range s.Items {
+		totalWeight += item.Weight * float64(item.Qty)
+		totalQty += item.Qty
+	}
+	pdf.SetFont("Helvetica", "B", 8)
+	pdf.SetXY(margin, y)
+	pdf.CellFormat(itemsWidth, 4, fmt.Sprintf("ITEMS (%d pcs%s)", totalQty, labelWeight(", %s", totalWeight)), "", 2, "L", false, 0, "")
+	pdf.SetFont("Helvetica", "", 8)
+	for i, item := range s.Items {
+		// Keep the list above the bottom margin; the total above still covers everything
+		if pdf.GetY()+4 > format.Height-margin-4 {
+			pdf.CellFormat(itemsWidth, 4, fmt.Sprintf("+ %d more", len(s.Items)-i), "", 2, "L", false, 0, "")
+			break
+		}
+		pdf.CellFormat(itemsWidth, 4, tr(fmt.Sprintf("%dx %s", item.Qty, item.Name)+labelWeight(" (%s)", item.Weight)), "", 2, "L", false, 0, "")
+	}
+	if s.Notes != "" && pdf.GetY()+8 <= format.Height-margin {
+		pdf.SetFont("Helvetica", "I", 7)
+		pdf.MultiCell(itemsWidth, 3.5, tr("Note: "+s.Notes), "", "L", false)
+	}
+
+	qrCode, err := qr.Encode(code, qr.M, qr.Auto)
+	if err != nil {
+		return err
+	}
+	return drawBarcode(pdf, "qr-"+code, qrCode, margin+width-qrSize, qrTop, qrSize, qrSize)
+}
+
+// drawBarcode embeds a barcode as PNG image. It is scaled up front so the PDF viewer
+// doesn't blur the bars when stretching a 1 pixel per module image.
+func drawBarcode(pdf *gofpdf.Fpdf, name string, code barcode.Barcode, x, y, w, h float64) error {
+	bounds := code.Bounds()
+	scale := 4
+	if bounds.Dx() < 100 {
+		scale = 8
+	}
+	scaled, err := barcode.Scale(code, bounds.Dx()*scale, bounds.Dy()*scale)
+	if err != nil {
+		return err
+	}
+	// Barcodes are 16-bit gray, which gofpdf can't embed; 8-bit is plenty for black and white
+	gray := image.NewGray(scaled.Bounds())
+	draw.Draw(gray, gray.Bounds(), scaled, scaled.Bounds().Min, draw.Src)
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, gray); err != nil {
+		return err
+	}
+	pdf.RegisterImageOptionsReader(name, gofpdf.ImageOptions{ImageType: "PNG"}, &buf)
+	pdf.ImageOptions(name, x, y, w, h, false, gofpdf.ImageOptions{ImageType: "PNG"}, 0, "")
+	return pdf.Error()
+}
+
+// labelWeight formats weight in kilograms into format, or returns "" when no weight was given
+func labelWeight(format string, weight float64) string {
+	if weight <= 0 {
+		return ""
+	}
+	return fmt.Sprintf(format, fmt.Sprintf("%.2f kg", weight))
+}
+
+// drawParty draws a sender or recipient block and returns the y position below it
+func drawParty(pdf *gofpdf.Fpdf, tr func(string) string, title string, p model.ShipmentPerson, x, y, width, fontSize float64) float64 {
+	lineHeight := fontSize * 0.45
+	pdf.SetXY(x, y)
+	pdf.SetFont("Helvetica", "B", 7)
+	pdf.CellFormat(width, 3.5, title, "", 2, "L", false, 0, "")
+	pdf.SetFont("Helvetica", "B", fontSize)
+	pdf.CellFormat(width, lineHeight, tr(p.Name+"  "+p.Phone), "", 2, "L", false, 0, "")
+	pdf.SetFont("Helvetica", "", fontSize-1)
+	pdf.MultiCell(width, lineHeight, tr(p.Address), "", "L", false)
+	return pdf.GetY() + 1.5
+}
@@ -0,0 +1,42 @@
+package service
+
+import (
+	"bytes"
+	"testing"
+
+	"logistic-service/internal/model"
+)
+
+func TestRenderLabels(t *testing.T) {
+	format, err := ParseLabelFormat("a6")
+	if err != nil {
+		t.Fatalf("ParseLabelFormat: %v", err)
+	}
+	shipment := func(trackingNumber string) *model.Shipment {
+		return &model.Shipment{
+			TrackingNumber: trackingNumber, LogisticName: "JNE", Origin: "Bandung", Destination: "Jakarta",
+			Sender:    model.ShipmentPerson{Name: "Ani", Phone: "0811", Address: "Jl. A 1"},
+			Recipient: model.ShipmentPerson{Name: "Budi", Phone: "0812", Address: "Jl. B 2"},
+			Items:     []model.ShipmentItem{{Name: "Shoes", Qty: 1, Weight: 1.5}},
+		}
+	}
+	tests := []struct {
+		name           string
+		trackingNumber string
+	}{
+		{"ascii", "JNE123456789"},
+		{"non-ascii falls back to the QR code", "JNÉ-12345"},
+		{"non-latin", "追跡123"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pdf, err := RenderLabels(format, []*model.Shipment{shipment(tt.trackingNumber)})
+			if err != nil {
+				t.Fatalf("RenderLabels: %v", err)
+			}
+			if !bytes.HasPrefix(pdf, []byte("%PDF-")) {
+				t.Errorf("RenderLabels did not return a PDF")
+			}
+		})
+	}
+}
@@ -0,0 +1,134 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"logistic-service/internal/model"
+	"sync"
+	"time"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+// ShipmentUpdatesExchange is the fanout exchange shipment.updated messages are published to.
+// The worker's durable "shipment.updated" queue is bound to it, and every logistic-service
+// instance binds its own exclusive queue to feed live tracking streams.
+const ShipmentUpdatesExchange = "shipment.updated"
+
+// DeclareShipmentUpdates declares the shipment.updated exchange and binds the worker queue to it.
+// The queue arguments must match the declaration in the worker.
+func DeclareShipmentUpdates(ch *amqp.Channel) error {
+	if err := ch.ExchangeDeclare(ShipmentUpdatesExchange, "fanout", true, false, false, false, nil); err != nil {
+		return err
+	}
+	if _, err := ch.QueueDeclare("shipment.updated", true, false, false, false, nil); err != nil {
+		return err
+	}
+	return ch.QueueBind("shipment.updated", "", ShipmentUpdatesExchange, false, nil)
+}
+
+// TrackingHub fans shipment updates out to live tracking subscribers (SSE and WebSocket).
+// Every update carries the full shipment, so a subscriber that misses one catches up with the next.
+type TrackingHub struct {
+	mu   sync.RWMutex
+	subs map[string]map[chan *model.Shipment]struct{} // tracking number -> subscribers
+}
+
+// NewTrackingHub creates an empty hub.
+func NewTrackingHub() *TrackingHub {
+	return &TrackingHub{subs: make(map[string]map[chan *model.Shipment]struct{})}
+}
+
+// Subscribe registers for updates of one shipment. Call the returned func to unsubscribe.
+func (h *TrackingHub) Subscribe(trackingNumber string) (<-chan *model.Shipment, func()) {
+	ch := make(chan *model.Shipment, 8)
+
+	h.mu.Lock()
+	if h.subs[trackingNumber] == nil {
+		h.subs[trackingNumber] = make(map[chan *model.Shipment]struct{})
+	}
+	h.subs[trackingNumber][ch] = struct{}{}
+	h.mu.Unlock()
+
+	var once sync.Once
+	return ch, func() {
+		once.Do(func() {
+			h.mu.Lock()
+			delete(h.subs[trackingNumber], ch)
+			if len(h.subs[trackingNumber]) == 0 {
+				delete(h.subs, trackingNumber)
+			}
+			h.mu.Unlock()
+		})
+	}
+}
+
+// Publish delivers an update to the subscribers of that shipment without blocking.
+// Slow subscribers skip the update.
+func (h *TrackingHub) Publish(s *model.Shipment) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	for ch := range h.subs[s.TrackingNumber] {
+		select {
+		case ch <- s:
+		default:
+		}
+	}
+}
+
+// Run consumes shipment.updated messages through an exclusive queue bound to
+// ShipmentUpdatesExchange and publishes them to subscribers. It reopens the channel
+// if it gets closed and returns when ctx is cancelled or the connection is closed.
+func (h *TrackingHub) Run(ctx context.Context, conn *amqp.Connection) {
+	for {
+		if err := h.consume(ctx, conn); err != nil {
+			log.Printf("[TrackingHub] consume error: %v", err)
+		}
+		if conn.IsClosed() {
+			return
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(5 * time.Second):
+		}
+	}
+}
+
+func (h *TrackingHub) consume(ctx context.Context, conn *amqp.Connection) error {
+	ch, err := conn.Channel()
+	if err != nil {
+		return err
+	}
+	defer ch.Close()
+
+	q, err := ch.QueueDeclare("", false, true, true, false, nil)
+	if err != nil {
+		return err
+	}
+	if err := ch.QueueBind(q.Name, "", ShipmentUpdatesExchange, false, nil); err != nil {
+		return err
+	}
+	msgs, err := ch.Consume(q.Name, "", true, true, false, false, nil)
+	if err != nil {
+		return err
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case msg, ok := <-msgs:
+			if !ok {
+				return nil
+			}
+			var shipment model.Shipment
+			if err := json.Unmarshal(msg.Body, &shipment); err != nil {
+				log.Printf("[TrackingHub] Unmarshal shipment.updated error: %v", err)
+				continue
+			}
+			h.Publish(&shipment)
+		}
+	}
+}
@@ -0,0 +1,32 @@
+package service
+
+import "logistic-service/internal/model"
+
+// CompareManifest compares the shipments listed in a manifest with the ones scanned when it was
+// received. Returns nil when they match.
+func CompareManifest(listed, scanned []string) *model.ManifestDiscrepancy {
+	inManifest := make(map[string]bool, len(listed))
+	for _, tn := range listed {
+		inManifest[tn] = true
+	}
+	wasScanned := make(map[string]bool, len(scanned))
+	for _, tn := range scanned {
+		wasScanned[tn] = true
+	}
+
+	d := &model.ManifestDiscrepancy{Missing: []string{}, Extra: []string{}}
+	for _, tn := range listed {
+		if !wasScanned[tn] {
+			d.Missing = append(d.Missing, tn)
+		}
+	}
+	for _, tn := range scanned {
+		if !inManifest[tn] {
+			d.Extra = append(d.Extra, tn)
+		}
+	}
+	if len(d.Missing) == 0 && len(d.Extra) == 0 {
+		return nil
+	}
+	return d
+}
@@ -0,0 +1,35 @@
+package service
+
+import (
+	"reflect"
+	"testing"
+
+	"logistic-service/internal/model"
+)
+
+func TestCompareManifest(t *testing.T) {
+	tests := []struct {
+		name            string
+		listed, scanned []string
+		want            *model.ManifestDiscrepancy
+	}{
+		{"all received", []string{"A", "B"}, []string{"B", "A"}, nil},
+		{"scanned twice", []string{"A", "B"}, []string{"A", "B", "A"}, nil},
+		{"empty manifest", nil, nil, nil},
+		{"missing", []string{"A", "B", "C"}, []string{"A"},
+			&model.ManifestDiscrepancy{Missing: []string{"B", "C"}, Extra: []string{}}},
+		{"extra", []string{"A"}, []string{"A", "X"},
+			&model.ManifestDiscrepancy{Missing: []string{}, Extra: []string{"X"}}},
+		{"missing and extra", []string{"A", "B"}, []string{"B", "X", "Y"},
+			&model.ManifestDiscrepancy{Missing: []string{"A"}, Extra: []string{"X", "Y"}}},
+		{"nothing scanned", []string{"A"}, nil,
+			&model.ManifestDiscrepancy{Missing: []string{"A"}, Extra: []string{}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := CompareManifest(tt.listed, tt.scanned); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("CompareManifest = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,128 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"logistic-service/internal/model"
+	"strings"
+	"time"
+)
+
+// ParcelTrackingNumber returns the piece tracking number of parcel piece of a shipment
+func ParcelTrackingNumber(trackingNumber string, piece int) string {
+	return fmt.Sprintf("%s-P%d", trackingNumber, piece)
+}
+
+// ValidateParcels checks the parcels of a new shipment. An order in one box has no parcels,
+// so a split shipment has at least two.
+func ValidateParcels(parcels []model.Parcel) error {
+	if len(parcels) == 0 {
+		return nil
+	}
+	if len(parcels) < 2 {
+		return errors.New("parcels must list at least 2 parcels, leave it empty for a shipment in one box")
+	}
+	if len(parcels) > model.MaxParcels {
+		return fmt.Errorf("a shipment can have at most %d parcels", model.MaxParcels)
+	}
+	for i, p := range parcels {
+		if p.Weight <= 0 {
+			return fmt.Errorf("parcel %d: weight must be greater than 0", i+1)
+		}
+		if p.Length < 0 || p.Width < 0 || p.Height < 0 {
+			return fmt.Errorf("parcel %d: dimensions must not be negative", i+1)
+		}
+	}
+	return nil
+}
+
+// NumberParcels assigns the piece numbers and tracking numbers of the parcels of a new shipment
+// and starts their timelines in the shipment status.
+func NumberParcels(s *model.Shipment, actor string, at time.Time) {
+	for i := range s.Parcels {
+		p := &s.Parcels[i]
+		p.PieceNumber = i + 1
+		p.TrackingNumber = ParcelTrackingNumber(s.TrackingNumber, p.PieceNumber)
+		p.Description = strings.TrimSpace(p.Description)
+		p.Status = s.Status
+		p.Events = []model.TrackingEvent{{
+			Status:      s.Status,
+			Description: fmt.Sprintf("parcel %d of %d created", p.PieceNumber, len(s.Parcels)),
+			Actor:       actor,
+			Timestamp:   at,
+		}}
+		p.UpdatedAt = at
+	}
+}
+
+// FindParcel returns the parcel of s with the piece tracking number, or nil
+func FindParcel(s *model.Shipment, parcelTrackingNumber string) *model.Parcel {
+	for i := range s.Parcels {
+		if strings.EqualFold(s.Parcels[i].TrackingNumber, parcelTrackingNumber) {
+			return &s.Parcels[i]
+		}
+	}
+	return nil
+}
+
+// parcelRank returns the position of status in model.ParcelStatuses, or -1
+func parcelRank(status string) int {
+	for i, s := range model.ParcelStatuses {
+		if s == status {
+			return i
+		}
+	}
+	return -1
+}
+
+// CheckParcelScan reports why parcel p can't be scanned to status, or nil. Parcels only move
+// forward; repeated in_transit scans are fine (e.g. at every hub).
+func CheckParcelScan(p *model.Parcel, status string) error {
+	if parcelRank(status) < 1 {
+		return fmt.Errorf("status must be one of %s", strings.Join(model.ParcelScanStatuses, ", "))
+	}
+	if p.Status == model.StatusDelivered {
+		return errors.New("parcel is already delivered")
+	}
+	if parcelRank(p.Status) >= 0 && parcelRank(status) < parcelRank(p.Status) {
+		return fmt.Errorf("parcel is already %s", p.Status)
+	}
+	return nil
+}
+
+// CheckShipmentScan reports why shipment s can't be moved to status as a whole, or nil. The
+// parcels of a multi-parcel shipment follow whole-shipment scans up to in_transit, but are
+// delivered one by one: the shipment is delivered with its last parcel, see ParcelShipmentStatus.
+func CheckShipmentScan(s *model.Shipment, status string) error {
+	if len(s.Parcels) > 0 && parcelRank(status) > parcelRank(model.StatusInTransit) {
+		return fmt.Errorf("shipment has %d parcels, scan each parcel to %s with PATCH /shipments/:trackingNumber/parcels/:parcelTrackingNumber/status", len(s.Parcels), status)
+	}
+	return nil
+}
+
+// ParcelShipmentStatus returns the status of a multi-parcel shipment derived from its parcels:
+// the status of the least advanced one. The shipment is delivered once every parcel is.
+func ParcelShipmentStatus(parcels []model.Parcel) string {
+	status := model.StatusDelivered
+	for _, p := range parcels {
+		if parcelRank(p.Status) < parcelRank(status) {
+			status = p.Status
+		}
+	}
+	return status
+}
+
+// CountParcels returns the per-status progress of the parcels of s, nil for single parcel shipments
+func CountParcels(s *model.Shipment) *model.ParcelProgress {
+	if len(s.Parcels) == 0 {
+		return nil
+	}
+	progress := &model.ParcelProgress{Total: len(s.Parcels), ByStatus: make(map[string]int)}
+	for _, p := range s.Parcels {
+		progress.ByStatus[p.Status]++
+		if p.Status == model.StatusDelivered {
+			progress.Delivered++
+		}
+	}
+	return progress
+}
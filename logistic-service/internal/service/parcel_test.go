@@ -0,0 +1,79 @@
+package service
+
+import (
+	"testing"
+
+	"logistic-service/internal/model"
+)
+
+func parcelsIn(statuses ...string) []model.Parcel {
+	parcels := make([]model.Parcel, len(statuses))
+	for i, s := range statuses {
+		parcels[i] = model.Parcel{PieceNumber: i + 1, Status: s}
+	}
+	return parcels
+}
+
+func TestParcelShipmentStatus(t *testing.T) {
+	tests := []struct {
+		name     string
+		statuses []string
+		want     string
+	}{
+		{"all delivered", []string{model.StatusDelivered, model.StatusDelivered}, model.StatusDelivered},
+		{"one left in transit", []string{model.StatusDelivered, model.StatusInTransit}, model.StatusInTransit},
+		{"least advanced wins", []string{model.StatusInTransit, model.StatusPickedUp, model.StatusDelivered}, model.StatusPickedUp},
+		{"none picked up", []string{model.StatusOnProcess, model.StatusOnProcess}, model.StatusOnProcess},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ParcelShipmentStatus(parcelsIn(tt.statuses...)); got != tt.want {
+				t.Errorf("ParcelShipmentStatus(%v) = %s, want %s", tt.statuses, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckParcelScan(t *testing.T) {
+	tests := []struct {
+		from, to string
+		wantErr  bool
+	}{
+		{model.StatusPickedUp, model.StatusInTransit, false},
+		{model.StatusInTransit, model.StatusInTransit, false},
+		{model.StatusInTransit, model.StatusDelivered, false},
+		{model.StatusOnProcess, model.StatusDelivered, false},
+		{model.StatusInTransit, model.StatusPickedUp, true},
+		{model.StatusDelivered, model.StatusDelivered, true},
+		{model.StatusPickedUp, model.StatusOnProcess, true},
+		{model.StatusPickedUp, model.StatusCancelled, true},
+		{model.StatusPickedUp, "lost", true},
+	}
+	for _, tt := range tests {
+		p := &model.Parcel{Status: tt.from}
+		if err := CheckParcelScan(p, tt.to); (err != nil) != tt.wantErr {
+			t.Errorf("CheckParcelScan(%s -> %s) = %v, want error %v", tt.from, tt.to, err, tt.wantErr)
+		}
+	}
+}
+
+func TestCheckShipmentScan(t *testing.T) {
+	tests := []struct {
+		name    string
+		parcels []model.Parcel
+		status  string
+		wantErr bool
+	}{
+		{"single parcel delivered", nil, model.StatusDelivered, false},
+		{"parcels follow to in_transit", parcelsIn(model.StatusPickedUp, model.StatusInTransit), model.StatusInTransit, false},
+		{"parcels delivered as a whole", parcelsIn(model.StatusInTransit, model.StatusInTransit), model.StatusDelivered, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &model.Shipment{Parcels: tt.parcels}
+			if err := CheckShipmentScan(s, tt.status); (err != nil) != tt.wantErr {
+				t.Errorf("CheckShipmentScan(%s) = %v, want error %v", tt.status, err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,88 @@
+package service
+
+import (
+	"logistic-service/internal/model"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// spellingVariants folds the Indonesian spelling before the 1972 reform (EYD) and common variants
+// into one form, so "Djoko" and "Joko", "Soekarno" and "Sukarno" or "Jusuf" and "Yusuf" sound alike.
+// Earlier pairs win, e.g. "dj" is folded before "j".
+var spellingVariants = strings.NewReplacer(
+	"oe", "u", "dj", "y", "tj", "c", "sj", "sy", "nj", "ny", "ch", "kh", "ph", "f",
+	"j", "y", "q", "k", "x", "ks", "z", "s", "v", "f",
+)
+
+// soundexCodes are the Soundex digits of consonants; vowels, h, w and y have none
+var soundexCodes = map[rune]byte{
+	'b': '1', 'f': '1', 'p': '1', 'v': '1',
+	'c': '2', 'g': '2', 'j': '2', 'k': '2', 'q': '2', 's': '2', 'x': '2', 'z': '2',
+	'd': '3', 't': '3',
+	'l': '4',
+	'm': '5', 'n': '5',
+	'r': '6',
+}
+
+// PhoneticKey returns the Soundex code of a word after folding Indonesian spelling variants,
+// e.g. "Muhammad", "Mohamad" and "Muhamad" are all "M530". Returns "" for words without letters.
+func PhoneticKey(word string) string {
+	var letters strings.Builder
+	for _, r := range strings.ToLower(word) {
+		if r >= 'a' && r <= 'z' {
+			letters.WriteRune(r)
+		}
+	}
+	folded := spellingVariants.Replace(letters.String())
+	if folded == "" {
+		return ""
+	}
+
+	key := []byte{byte(unicode.ToUpper(rune(folded[0])))}
+	last := soundexCodes[rune(folded[0])]
+	for _, r := range folded[1:] {
+		code, ok := soundexCodes[r]
+		switch {
+		case !ok && r != 'h' && r != 'w':
+			last = 0 // vowels separate repeated codes, h and w don't
+		case ok && code != last:
+			key = append(key, code)
+			last = code
+		}
+		if len(key) == 4 {
+			break
+		}
+	}
+	for len(key) < 4 {
+		key = append(key, '0')
+	}
+	return string(key)
+}
+
+// NameKeys returns the sorted, unique phonetic keys of the words of names. Single letters
+// (initials) are left out.
+func NameKeys(names ...string) []string {
+	seen := make(map[string]bool)
+	keys := []string{}
+	for _, name := range names {
+		words := strings.FieldsFunc(name, func(r rune) bool { return !unicode.IsLetter(r) })
+		for _, word := range words {
+			if len([]rune(word)) < 2 {
+				continue
+			}
+			if key := PhoneticKey(word); key != "" && !seen[key] {
+				seen[key] = true
+				keys = append(keys, key)
+			}
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// ShipmentSearchKeys returns the phonetic keys of the sender and recipient names of s,
+// stored in Shipment.SearchKeys for fuzzy name search
+func ShipmentSearchKeys(s *model.Shipment) []string {
+	return NameKeys(s.Sender.Name, s.Recipient.Name)
+}
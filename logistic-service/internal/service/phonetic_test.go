@@ -0,0 +1,71 @@
+package service
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestPhoneticKey(t *testing.T) {
+	tests := []struct {
+		word, want string
+	}{
+		// Plain Soundex
+		{"Robert", "R163"},
+		{"Rupert", "R163"},
+		{"Ashcraft", "A261"},
+		{"Tymczak", "T522"},
+		{"Pfister", "P236"},
+		{"Lee", "L000"},
+		// Indonesian spelling variants fold together
+		{"Muhammad", "M530"},
+		{"Mohamad", "M530"},
+		{"Muhamad", "M530"},
+		{"Djoko", "Y200"},
+		{"Joko", "Y200"},
+		{"Soekarno", "S265"},
+		{"Sukarno", "S265"},
+		{"Jusuf", "Y210"},
+		{"Yusuf", "Y210"},
+		{"Tjahjo", "C000"},
+		{"Cahyo", "C000"},
+		{"Njoman", "N550"},
+		{"Nyoman", "N550"},
+		// Case, accents and punctuation
+		{"O'BRIEN", "O165"},
+		{"123", ""},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := PhoneticKey(tt.word); got != tt.want {
+			t.Errorf("PhoneticKey(%q) = %q, want %q", tt.word, got, tt.want)
+		}
+	}
+}
+
+func TestPhoneticKeyDistinguishes(t *testing.T) {
+	pairs := [][2]string{{"Budi", "Sari"}, {"Joko", "Koko"}, {"Dewi", "Desi"}}
+	for _, p := range pairs {
+		if PhoneticKey(p[0]) == PhoneticKey(p[1]) {
+			t.Errorf("PhoneticKey(%q) and PhoneticKey(%q) are both %q", p[0], p[1], PhoneticKey(p[0]))
+		}
+	}
+}
+
+func TestNameKeys(t *testing.T) {
+	tests := []struct {
+		names []string
+		want  []string
+	}{
+		{[]string{"Joko Widodo"}, []string{PhoneticKey("Widodo"), PhoneticKey("Joko")}},
+		{[]string{"Djoko", "Joko S."}, []string{PhoneticKey("Joko")}},
+		{[]string{"A. B."}, []string{}},
+		{nil, []string{}},
+	}
+	for _, tt := range tests {
+		sort.Strings(tt.want)
+		if got := NameKeys(tt.names...); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("NameKeys(%q) = %v, want %v", tt.names, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,79 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"logistic-service/internal/model"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// MaxPickupShipments is the largest number of shipments one pickup can include
+const MaxPickupShipments = 100
+
+// PickupSlotCapacity returns how many pickups a courier takes per time slot in an area when
+// ops haven't set a capacity for it. Defaults to 20; override with PICKUP_SLOT_CAPACITY.
+func PickupSlotCapacity() int {
+	if v, err := strconv.Atoi(os.Getenv("PICKUP_SLOT_CAPACITY")); err == nil && v > 0 {
+		return v
+	}
+	return 20
+}
+
+// PickupArea returns the area a shipment is picked up in: its origin district code, or the
+// normalized origin text for shipments without a structured sender address.
+func PickupArea(s *model.Shipment) string {
+	if s.OriginCode != "" {
+		return s.OriginCode
+	}
+	return strings.ToLower(strings.TrimSpace(s.Origin))
+}
+
+// NewPickup groups shipments into a pickup in the given time slot. All shipments must use the
+// same courier and sender address, since one courier collects them in a single visit.
+func NewPickup(shipments []*model.Shipment, schedule *model.DeliverySchedule) (*model.Pickup, error) {
+	if len(shipments) == 0 {
+		return nil, errors.New("tracking_numbers is required")
+	}
+	if len(shipments) > MaxPickupShipments {
+		return nil, fmt.Errorf("a pickup can include at most %d shipments", MaxPickupShipments)
+	}
+
+	first := shipments[0]
+	pickup := &model.Pickup{
+		UserID:       first.UserID,
+		LogisticName: first.LogisticName,
+		AreaCode:     PickupArea(first),
+		Address:      first.Sender,
+		Date:         schedule.Date,
+		TimeSlot:     schedule.TimeSlot,
+		Status:       model.PickupScheduled,
+	}
+	if pickup.AreaCode == "" {
+		return nil, fmt.Errorf("shipment %s has no origin", first.TrackingNumber)
+	}
+	for _, s := range shipments {
+		switch {
+		case s.UserID != pickup.UserID:
+			return nil, errors.New("all shipments must belong to the same user")
+		case s.LogisticName != pickup.LogisticName:
+			return nil, fmt.Errorf("shipment %s uses courier %s, expected %s", s.TrackingNumber, s.LogisticName, pickup.LogisticName)
+		case PickupArea(s) != pickup.AreaCode ||
+			s.Sender.Name != pickup.Address.Name || s.Sender.Phone != pickup.Address.Phone || s.Sender.Address != pickup.Address.Address:
+			return nil, fmt.Errorf("shipment %s has a different sender address", s.TrackingNumber)
+		}
+		pickup.TrackingNumbers = append(pickup.TrackingNumbers, s.TrackingNumber)
+	}
+	return pickup, nil
+}
+
+// CanTransitionPickup reports whether a pickup in status from can move to status to
+func CanTransitionPickup(from, to string) bool {
+	for _, s := range model.PickupTransitions[from] {
+		if s == to {
+			return true
+		}
+	}
+	return false
+}
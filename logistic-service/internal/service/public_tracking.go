@@ -0,0 +1,168 @@
+package service
+
+import (
+	"crypto/subtle"
+	"logistic-service/internal/model"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// PublicEvent is a tracking event as shown to unauthenticated visitors.
+// The actor is intentionally left out.
+type PublicEvent struct {
+	Status      string    `json:"status"`
+	Description string    `json:"description,omitempty"`
+	Location    string    `json:"location,omitempty"`
+	Timestamp   time.Time `json:"timestamp"`
+}
+
+// PublicDeliveryAttempt is a failed delivery attempt as shown to unauthenticated visitors.
+type PublicDeliveryAttempt struct {
+	Number      int       `json:"number"`
+	ReasonCode  string    `json:"reason_code"`
+	AttemptedAt time.Time `json:"attempted_at"`
+}
+
+// PublicParcel is a parcel of a multi-parcel shipment as shown to unauthenticated visitors.
+type PublicParcel struct {
+	PieceNumber    int       `json:"piece_number"`
+	TrackingNumber string    `json:"tracking_number"`
+	Status         string    `json:"status"`
+	UpdatedAt      time.Time `json:"updated_at"`
+}
+
+// PublicTracking is the response of the public tracking endpoint.
+// Sender and recipient details and delivery instructions are masked unless Verified is true.
+type PublicTracking struct {
+	TrackingNumber string               `json:"tracking_number"`
+	LogisticName   string               `json:"logistic_name"`
+	Status         string               `json:"status"`
+	Origin         string               `json:"origin"`
+	Destination    string               `json:"destination"`
+	Sender         model.ShipmentPerson `json:"sender"`
+	Recipient      model.ShipmentPerson `json:"recipient"`
+	Events         []PublicEvent        `json:"events"`
+	Verified       bool                 `json:"verified"`
+	UpdatedAt      time.Time            `json:"updated_at"`
+
+	DeliveryAttempts     []PublicDeliveryAttempt `json:"delivery_attempts"`
+	NextAttempt          *model.DeliverySchedule `json:"next_attempt,omitempty"`
+	DeliveryInstructions string                  `json:"delivery_instructions,omitempty"` // Verified only
+
+	Parcels        []PublicParcel        `json:"parcels,omitempty"`
+	ParcelProgress *model.ParcelProgress `json:"parcel_progress,omitempty"`
+}
+
+// NewPublicTracking builds the public view of a shipment.
+// Personal data is masked unless verified is true.
+func NewPublicTracking(s *model.Shipment, verified bool) PublicTracking {
+	view := PublicTracking{
+		TrackingNumber: s.TrackingNumber,
+		LogisticName:   s.LogisticName,
+		Status:         s.Status,
+		Origin:         s.Origin,
+		Destination:    s.Destination,
+		Sender:         s.Sender,
+		Recipient:      s.Recipient,
+		Events:         make([]PublicEvent, 0, len(s.Events)),
+		Verified:       verified,
+		UpdatedAt:      s.UpdatedAt,
+
+		DeliveryAttempts: make([]PublicDeliveryAttempt, 0, len(s.DeliveryAttempts)),
+		NextAttempt:      s.NextAttempt,
+
+		ParcelProgress: CountParcels(s),
+	}
+	for _, p := range s.Parcels {
+		view.Parcels = append(view.Parcels, PublicParcel{
+			PieceNumber:    p.PieceNumber,
+			TrackingNumber: p.TrackingNumber,
+			Status:         p.Status,
+			UpdatedAt:      p.UpdatedAt,
+		})
+	}
+	for _, a := range s.DeliveryAttempts {
+		view.DeliveryAttempts = append(view.DeliveryAttempts, PublicDeliveryAttempt{
+			Number:      a.Number,
+			ReasonCode:  a.ReasonCode,
+			AttemptedAt: a.AttemptedAt,
+		})
+	}
+	for _, e := range s.Events {
+		view.Events = append(view.Events, PublicEvent{
+			Status:      e.Status,
+			Description: e.Description,
+			Location:    e.Location,
+			Timestamp:   e.Timestamp,
+		})
+	}
+	if verified {
+		view.DeliveryInstructions = s.DeliveryInstructions
+	} else {
+		view.Sender = MaskPerson(s.Sender)
+		view.Recipient = MaskPerson(s.Recipient)
+	}
+	return view
+}
+
+// VerifyPhoneLast4 checks the supplied digits against the last 4 digits of phone.
+func VerifyPhoneLast4(phone, last4 string) bool {
+	digits := onlyDigits(phone)
+	if len(last4) != 4 || len(digits) < 4 {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(digits[len(digits)-4:]), []byte(last4)) == 1
+}
+
+// MaskPerson masks name, phone and address of a sender or recipient.
+func MaskPerson(p model.ShipmentPerson) model.ShipmentPerson {
+	return model.ShipmentPerson{
+		Name:    MaskName(p.Name),
+		Phone:   MaskPhone(p.Phone),
+		Address: MaskAddress(p.Address),
+	}
+}
+
+// MaskName keeps the first letter of every word, e.g. "Budi Santoso" -> "B*** S******".
+func MaskName(name string) string {
+	words := strings.Fields(name)
+	for i, w := range words {
+		r := []rune(w)
+		words[i] = string(r[0]) + strings.Repeat("*", len(r)-1)
+	}
+	return strings.Join(words, " ")
+}
+
+// MaskPhone keeps only the first 4 digits, e.g. "6285212345678" -> "6285*********".
+// The last digits stay hidden because they are used to unlock the full details.
+func MaskPhone(phone string) string {
+	digits := onlyDigits(phone)
+	if len(digits) <= 4 {
+		return strings.Repeat("*", len(digits))
+	}
+	return digits[:4] + strings.Repeat("*", len(digits)-4)
+}
+
+// MaskAddress hides everything but the last comma separated part (usually the city),
+// e.g. "Jl. Sudirman No.10, Bandung" -> "***, Bandung".
+func MaskAddress(address string) string {
+	address = strings.TrimSpace(address)
+	if address == "" {
+		return ""
+	}
+	idx := strings.LastIndex(address, ",")
+	if idx < 0 {
+		return "***"
+	}
+	return "***, " + strings.TrimSpace(address[idx+1:])
+}
+
+func onlyDigits(s string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsDigit(r) {
+			return r
+		}
+		return -1
+	}, s)
+}
@@ -0,0 +1,126 @@
+package service
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"logistic-service/internal/model"
+)
+
+func TestVerifyPhoneLast4(t *testing.T) {
+	tests := []struct {
+		phone, last4 string
+		want         bool
+	}{
+		{"081234567890", "7890", true},
+		{"+62 812-3456-7890", "7890", true},
+		{"081234567890", "7891", false},
+		{"081234567890", "890", false},
+		{"081234567890", "67890", false},
+		{"081234567890", "", false},
+		{"123", "0123", false},
+		{"", "0000", false},
+	}
+	for _, tt := range tests {
+		if got := VerifyPhoneLast4(tt.phone, tt.last4); got != tt.want {
+			t.Errorf("VerifyPhoneLast4(%q, %q) = %v, want %v", tt.phone, tt.last4, got, tt.want)
+		}
+	}
+}
+
+func TestMaskName(t *testing.T) {
+	tests := []struct{ name, want string }{
+		{"Budi Santoso", "B*** S******"},
+		{"  Siti   Aminah ", "S*** A*****"},
+		{"Ö", "Ö"},
+		{"Ömer", "Ö***"},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := MaskName(tt.name); got != tt.want {
+			t.Errorf("MaskName(%q) = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestMaskPhone(t *testing.T) {
+	tests := []struct{ phone, want string }{
+		{"6285212345678", "6285*********"},
+		{"+62 852-1234-5678", "6285*********"},
+		{"1234", "****"},
+		{"12", "**"},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := MaskPhone(tt.phone); got != tt.want {
+			t.Errorf("MaskPhone(%q) = %q, want %q", tt.phone, got, tt.want)
+		}
+	}
+}
+
+func TestMaskAddress(t *testing.T) {
+	tests := []struct{ address, want string }{
+		{"Jl. Sudirman No.10, Bandung", "***, Bandung"},
+		{"Jl. Merdeka 1, Kel. Braga, Kota Bandung ", "***, Kota Bandung"},
+		{"Jl. Merdeka 1", "***"},
+		{"   ", ""},
+	}
+	for _, tt := range tests {
+		if got := MaskAddress(tt.address); got != tt.want {
+			t.Errorf("MaskAddress(%q) = %q, want %q", tt.address, got, tt.want)
+		}
+	}
+}
+
+func TestNewPublicTracking(t *testing.T) {
+	s := &model.Shipment{
+		TrackingNumber: "TN-1",
+		Status:         model.StatusInTransit,
+		Sender:         model.ShipmentPerson{Name: "Ahmad Yani", Phone: "081234567890", Address: "Jl. Merdeka 1, Jakarta"},
+		Recipient:      model.ShipmentPerson{Name: "Budi", Phone: "089876543210", Address: "Jl. Sudirman 10, Bandung"},
+		Events:         []model.TrackingEvent{{Status: model.StatusInTransit, Description: "departed hub", Actor: "courier-1", Timestamp: time.Now()}},
+	}
+	masked := model.ShipmentPerson{Name: "B***", Phone: "0898********", Address: "***, Bandung"}
+	tests := []struct {
+		name          string
+		verified      bool
+		wantRecipient model.ShipmentPerson
+	}{
+		{"masked", false, masked},
+		{"verified", true, s.Recipient},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			view := NewPublicTracking(s, tt.verified)
+			if view.Recipient != tt.wantRecipient || view.Verified != tt.verified {
+				t.Errorf("recipient %+v, verified %v; want %+v, %v", view.Recipient, view.Verified, tt.wantRecipient, tt.verified)
+			}
+			if !tt.verified && view.Sender.Phone != "0812********" {
+				t.Errorf("sender phone %q not masked", view.Sender.Phone)
+			}
+			if len(view.Events) != 1 || view.Events[0].Description != "departed hub" {
+				t.Errorf("events %+v, want the one event", view.Events)
+			}
+		})
+	}
+}
+
+func TestNewPublicTrackingLeavesOutNotes(t *testing.T) {
+	s := &model.Shipment{Events: []model.TrackingEvent{{
+		Status:      model.StatusDelivered,
+		Description: "delivered by the courier",
+		Note:        "left with neighbour Pak Joko, 0812 3456 7890",
+		Timestamp:   time.Now(),
+	}}}
+	for _, verified := range []bool{false, true} {
+		body, err := json.Marshal(NewPublicTracking(s, verified))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if strings.Contains(string(body), "Joko") {
+			t.Errorf("verified %v: public view %s contains the courier note", verified, body)
+		}
+	}
+}
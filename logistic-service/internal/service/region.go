@@ -0,0 +1,230 @@
+package service
+
+import (
+	"bytes"
+	_ "embed"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"logistic-service/internal/model"
+	"os"
+	"sort"
+	"strings"
+)
+
+// Region levels, from the top of the hierarchy down
+const (
+	RegionProvince    = "province"
+	RegionCity        = "city" // kota or kabupaten
+	RegionDistrict    = "district"
+	RegionSubdistrict = "subdistrict"
+)
+
+// Region levels by number of dot separated code segments, e.g. "32.73.02" is a district
+var regionLevels = []string{RegionProvince, RegionCity, RegionDistrict, RegionSubdistrict}
+
+// bundledRegions is a small subset of the Kemendagri region codes for development.
+// Load the full dataset with REGION_DATA_FILE (same CSV format: code,name,postal_code).
+//
+//go:embed data/regions.csv
+var bundledRegions []byte
+
+// Region is a province, city/regency, district or sub-district.
+type Region struct {
+	Code       string `json:"code"`
+	Name       string `json:"name"`
+	Level      string `json:"level"`
+	ParentCode string `json:"parent_code,omitempty"`
+	PostalCode string `json:"postal_code,omitempty"` // Sub-districts only
+}
+
+// RegionIndex is the in-memory region dataset used for validation and autocomplete.
+type RegionIndex struct {
+	byCode   map[string]*Region
+	children map[string][]*Region // parent code ("" for provinces) -> regions sorted by name
+}
+
+// LoadRegionIndex reads the region CSV at path, or the bundled dataset when path is empty.
+func LoadRegionIndex(path string) (*RegionIndex, error) {
+	var r io.Reader = bytes.NewReader(bundledRegions)
+	if path != "" {
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		r = f
+	}
+	return parseRegions(r)
+}
+
+func parseRegions(r io.Reader) (*RegionIndex, error) {
+	records, err := csv.NewReader(r).ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	ix := &RegionIndex{
+		byCode:   make(map[string]*Region),
+		children: make(map[string][]*Region),
+	}
+	for i, record := range records {
+		if i == 0 && len(record) > 0 && record[0] == "code" {
+			continue // header
+		}
+		if len(record) < 2 {
+			return nil, fmt.Errorf("region dataset line %d: expected code,name[,postal_code]", i+1)
+		}
+		code := strings.TrimSpace(record[0])
+		segments := strings.Split(code, ".")
+		if len(segments) > len(regionLevels) {
+			return nil, fmt.Errorf("region dataset line %d: invalid code %q", i+1, code)
+		}
+		region := &Region{
+			Code:  code,
+			Name:  strings.TrimSpace(record[1]),
+			Level: regionLevels[len(segments)-1],
+		}
+		if len(segments) > 1 {
+			region.ParentCode = strings.Join(segments[:len(segments)-1], ".")
+		}
+		if len(record) > 2 {
+			region.PostalCode = strings.TrimSpace(record[2])
+		}
+		ix.byCode[code] = region
+		ix.children[region.ParentCode] = append(ix.children[region.ParentCode], region)
+	}
+	for parent, regions := range ix.children {
+		if _, ok := ix.byCode[parent]; parent != "" && !ok {
+			return nil, fmt.Errorf("region dataset: parent %q of %q is missing", parent, regions[0].Code)
+		}
+		sort.Slice(regions, func(i, j int) bool { return regions[i].Name < regions[j].Name })
+	}
+	return ix, nil
+}
+
+// Len returns the number of regions in the index
+func (ix *RegionIndex) Len() int {
+	return len(ix.byCode)
+}
+
+// Find returns the region with the given code, or nil
+func (ix *RegionIndex) Find(code string) *Region {
+	return ix.byCode[code]
+}
+
+// Children returns the regions below parentCode ("" for provinces) whose name contains query
+// (case insensitive), sorted by name. Sub-districts can also be matched by postal code prefix.
+func (ix *RegionIndex) Children(parentCode, query string, limit int) []*Region {
+	query = strings.ToLower(strings.TrimSpace(query))
+	results := []*Region{}
+	for _, r := range ix.children[parentCode] {
+		if len(results) == limit {
+			break
+		}
+		if query == "" || strings.Contains(strings.ToLower(r.Name), query) ||
+			(r.PostalCode != "" && strings.HasPrefix(r.PostalCode, query)) {
+			results = append(results, r)
+		}
+	}
+	return results
+}
+
+// ResolveAddress validates a structured address against the dataset and fills in the codes and
+// names of every level and the postal code from its subdistrict_code. Codes of higher levels sent
+// by the client must agree with the sub-district.
+func (ix *RegionIndex) ResolveAddress(a *model.StructuredAddress) error {
+	a.Street = strings.TrimSpace(a.Street)
+	if a.Street == "" {
+		return errors.New("street is required")
+	}
+	if a.SubdistrictCode == "" {
+		return errors.New("subdistrict_code is required")
+	}
+	sub := ix.Find(a.SubdistrictCode)
+	if sub == nil || sub.Level != RegionSubdistrict {
+		return fmt.Errorf("unknown subdistrict_code %q", a.SubdistrictCode)
+	}
+	district := ix.Find(sub.ParentCode)
+	city := ix.Find(district.ParentCode)
+	province := ix.Find(city.ParentCode)
+
+	for _, check := range []struct {
+		field, given, want string
+	}{
+		{"district_code", a.DistrictCode, district.Code},
+		{"city_code", a.CityCode, city.Code},
+		{"province_code", a.ProvinceCode, province.Code},
+	} {
+		if check.given != "" && check.given != check.want {
+			return fmt.Errorf("%s %q does not match subdistrict_code %q", check.field, check.given, a.SubdistrictCode)
+		}
+	}
+	a.PostalCode = strings.TrimSpace(a.PostalCode)
+	if sub.PostalCode != "" {
+		if a.PostalCode != "" && a.PostalCode != sub.PostalCode {
+			return fmt.Errorf("postal_code %q does not match %s (%s)", a.PostalCode, sub.Name, sub.PostalCode)
+		}
+		a.PostalCode = sub.PostalCode
+	}
+
+	if err := ValidateGeoPoint(a.Location); err != nil {
+		return err
+	}
+
+	a.Subdistrict = sub.Name
+	a.DistrictCode, a.District = district.Code, district.Name
+	a.CityCode, a.City = city.Code, city.Name
+	a.ProvinceCode, a.Province = province.Code, province.Name
+	return nil
+}
+
+// FormatAddress renders a structured address as a single line, e.g. for labels.
+func FormatAddress(a *model.StructuredAddress) string {
+	line := strings.Join([]string{a.Street, a.Subdistrict, a.District, a.City, a.Province}, ", ")
+	if a.PostalCode != "" {
+		line += " " + a.PostalCode
+	}
+	return line
+}
+
+// ResolvePerson resolves the structured address of a sender or recipient, if any, and composes
+// the free text address from it when that is empty. field prefixes error messages ("sender").
+func (ix *RegionIndex) ResolvePerson(field string, p *model.ShipmentPerson) error {
+	if p.Structured == nil {
+		return nil
+	}
+	if err := ix.ResolveAddress(p.Structured); err != nil {
+		return fmt.Errorf("%s.structured_address: %v", field, err)
+	}
+	if strings.TrimSpace(p.Address) == "" {
+		p.Address = FormatAddress(p.Structured)
+	}
+	return nil
+}
+
+// ApplyRegions resolves the structured addresses of a shipment and derives the origin and
+// destination region codes, and the origin/destination text when empty, from them.
+func (ix *RegionIndex) ApplyRegions(s *model.Shipment) error {
+	if err := ix.ResolvePerson("sender", &s.Sender); err != nil {
+		return err
+	}
+	if err := ix.ResolvePerson("recipient", &s.Recipient); err != nil {
+		return err
+	}
+
+	s.OriginCode, s.DestinationCode = "", ""
+	if a := s.Sender.Structured; a != nil {
+		s.OriginCode = a.DistrictCode
+		if s.Origin == "" {
+			s.Origin = a.District + ", " + a.City
+		}
+	}
+	if a := s.Recipient.Structured; a != nil {
+		s.DestinationCode = a.DistrictCode
+		if s.Destination == "" {
+			s.Destination = a.District + ", " + a.City
+		}
+	}
+	return nil
+}
@@ -0,0 +1,179 @@
+package service
+
+import (
+	"strings"
+	"testing"
+
+	"logistic-service/internal/model"
+)
+
+// testRegions is a small dataset: two districts of Kota Bandung and one of Jakarta Pusat
+func testRegions(t *testing.T) *RegionIndex {
+	t.Helper()
+	ix, err := parseRegions(strings.NewReader(`code,name,postal_code
+31,DKI Jakarta,
+31.71,Kota Administrasi Jakarta Pusat,
+31.71.01,Gambir,
+31.71.01.1001,Gambir,10110
+32,Jawa Barat,
+32.73,Kota Bandung,
+32.73.02,Sukasari,
+32.73.02.1001,Gegerkalong,40153
+32.73.02.1002,Isola,40154
+32.73.06,Cidadap,
+32.73.06.1001,Ciumbuleuit,
+`))
+	if err != nil {
+		t.Fatalf("parseRegions: %v", err)
+	}
+	return ix
+}
+
+func TestParseRegions(t *testing.T) {
+	tests := []struct {
+		name, csv string
+		wantErr   bool
+	}{
+		{"valid", "31,DKI Jakarta\n31.71,Jakarta Pusat\n", false},
+		{"missing parent", "31,DKI Jakarta\n31.71.01,Gambir\n", true},
+		{"too many levels", "31,DKI Jakarta\n31.71.01.1001.1,Gambir\n", true},
+		{"no name", "31\n", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := parseRegions(strings.NewReader(tt.csv)); (err != nil) != tt.wantErr {
+				t.Errorf("parseRegions error = %v, want error %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestRegionFind(t *testing.T) {
+	ix := testRegions(t)
+	tests := []struct {
+		code, wantLevel, wantParent string
+	}{
+		{"32", RegionProvince, ""},
+		{"32.73", RegionCity, "32"},
+		{"32.73.02", RegionDistrict, "32.73"},
+		{"32.73.02.1001", RegionSubdistrict, "32.73.02"},
+		{"32.74", "", ""},
+		{"", "", ""},
+	}
+	for _, tt := range tests {
+		r := ix.Find(tt.code)
+		if tt.wantLevel == "" {
+			if r != nil {
+				t.Errorf("Find(%q) = %+v, want nil", tt.code, r)
+			}
+			continue
+		}
+		if r == nil || r.Level != tt.wantLevel || r.ParentCode != tt.wantParent {
+			t.Errorf("Find(%q) = %+v, want level %s under %q", tt.code, r, tt.wantLevel, tt.wantParent)
+		}
+	}
+}
+
+func TestRegionChildren(t *testing.T) {
+	ix := testRegions(t)
+	tests := []struct {
+		name, parent, query string
+		limit               int
+		want                []string
+	}{
+		{"provinces by name", "", "", 10, []string{"31", "32"}},
+		{"name contains", "32.73", "SUKA", 10, []string{"32.73.02"}},
+		{"postal code prefix", "32.73.02", "40154", 10, []string{"32.73.02.1002"}},
+		{"limit", "32.73.02", "", 1, []string{"32.73.02.1001"}},
+		{"unknown parent", "33", "", 10, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, r := range ix.Children(tt.parent, tt.query, tt.limit) {
+				got = append(got, r.Code)
+			}
+			if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("Children = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestResolveAddress(t *testing.T) {
+	ix := testRegions(t)
+	tests := []struct {
+		name    string
+		address model.StructuredAddress
+		wantErr string
+	}{
+		{"subdistrict only", model.StructuredAddress{Street: "Jl. Setiabudi 1", SubdistrictCode: "32.73.02.1001"}, ""},
+		{"matching codes and postal code", model.StructuredAddress{Street: "Jl. Setiabudi 1", SubdistrictCode: "32.73.02.1001",
+			DistrictCode: "32.73.02", CityCode: "32.73", ProvinceCode: "32", PostalCode: "40153"}, ""},
+		{"no street", model.StructuredAddress{Street: " ", SubdistrictCode: "32.73.02.1001"}, "street is required"},
+		{"no subdistrict", model.StructuredAddress{Street: "Jl. Setiabudi 1"}, "subdistrict_code is required"},
+		{"unknown subdistrict", model.StructuredAddress{Street: "Jl. Setiabudi 1", SubdistrictCode: "32.73.02.9999"}, "unknown subdistrict_code"},
+		{"district given as subdistrict", model.StructuredAddress{Street: "Jl. Setiabudi 1", SubdistrictCode: "32.73.02"}, "unknown subdistrict_code"},
+		{"other district", model.StructuredAddress{Street: "Jl. Setiabudi 1", SubdistrictCode: "32.73.02.1001", DistrictCode: "32.73.06"}, "district_code"},
+		{"other province", model.StructuredAddress{Street: "Jl. Setiabudi 1", SubdistrictCode: "32.73.02.1001", ProvinceCode: "31"}, "province_code"},
+		{"wrong postal code", model.StructuredAddress{Street: "Jl. Setiabudi 1", SubdistrictCode: "32.73.02.1001", PostalCode: "40154"}, "postal_code"},
+		{"invalid location", model.StructuredAddress{Street: "Jl. Setiabudi 1", SubdistrictCode: "32.73.02.1001",
+			Location: &model.GeoPoint{Latitude: 91, Longitude: 107}}, "latitude"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := tt.address
+			err := ix.ResolveAddress(&a)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("ResolveAddress error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ResolveAddress: %v", err)
+			}
+			want := model.StructuredAddress{
+				Street: "Jl. Setiabudi 1", ProvinceCode: "32", Province: "Jawa Barat", CityCode: "32.73", City: "Kota Bandung",
+				DistrictCode: "32.73.02", District: "Sukasari", SubdistrictCode: "32.73.02.1001", Subdistrict: "Gegerkalong", PostalCode: "40153",
+			}
+			if a != want {
+				t.Errorf("resolved %+v, want %+v", a, want)
+			}
+		})
+	}
+}
+
+func TestApplyRegions(t *testing.T) {
+	ix := testRegions(t)
+	s := &model.Shipment{
+		Origin:      "",
+		Destination: "Jakarta",
+		OriginCode:  "stale",
+		Sender:      model.ShipmentPerson{Structured: &model.StructuredAddress{Street: "Jl. Setiabudi 1", SubdistrictCode: "32.73.02.1001"}},
+		Recipient:   model.ShipmentPerson{Address: "Jl. Merdeka 1", Structured: &model.StructuredAddress{Street: "Jl. Gambir 2", SubdistrictCode: "31.71.01.1001"}},
+	}
+	if err := ix.ApplyRegions(s); err != nil {
+		t.Fatalf("ApplyRegions: %v", err)
+	}
+	if s.OriginCode != "32.73.02" || s.DestinationCode != "31.71.01" {
+		t.Errorf("codes %s -> %s, want 32.73.02 -> 31.71.01", s.OriginCode, s.DestinationCode)
+	}
+	if s.Origin != "Sukasari, Kota Bandung" || s.Destination != "Jakarta" {
+		t.Errorf("origin %q, destination %q; want the district of the sender and the given destination", s.Origin, s.Destination)
+	}
+	if s.Sender.Address != "Jl. Setiabudi 1, Gegerkalong, Sukasari, Kota Bandung, Jawa Barat 40153" || s.Recipient.Address != "Jl. Merdeka 1" {
+		t.Errorf("addresses %q and %q; want the composed sender address and the given recipient address", s.Sender.Address, s.Recipient.Address)
+	}
+
+	// Free text addresses only clear codes left from structured ones
+	s = &model.Shipment{OriginCode: "32.73.02", DestinationCode: "31.71.01", Sender: model.ShipmentPerson{Address: "Bandung"}}
+	if err := ix.ApplyRegions(s); err != nil || s.OriginCode != "" || s.DestinationCode != "" {
+		t.Errorf("free text: codes %q, %q (%v), want none", s.OriginCode, s.DestinationCode, err)
+	}
+
+	s = &model.Shipment{Recipient: model.ShipmentPerson{Structured: &model.StructuredAddress{Street: "x", SubdistrictCode: "99"}}}
+	if err := ix.ApplyRegions(s); err == nil || !strings.HasPrefix(err.Error(), "recipient.structured_address:") {
+		t.Errorf("ApplyRegions error = %v, want a recipient.structured_address error", err)
+	}
+}
@@ -0,0 +1,130 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"logistic-service/internal/model"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ReturnReason is an entry of the return reason catalog.
+type ReturnReason struct {
+	Code         string `json:"code"`
+	Type         string `json:"type"` // Return type the reason applies to
+	Description  string `json:"description"`
+	NoteRequired bool   `json:"note_required"`
+}
+
+// ReturnReasons is the catalog of accepted return reason codes.
+var ReturnReasons = []ReturnReason{
+	{Code: "recipient_unavailable", Type: model.ReturnRTO, Description: "Recipient could not be reached after repeated attempts"},
+	{Code: "address_not_found", Type: model.ReturnRTO, Description: "Recipient address could not be found"},
+	{Code: "refused", Type: model.ReturnRTO, Description: "Recipient refused the parcel"},
+	{Code: "cod_not_paid", Type: model.ReturnRTO, Description: "Recipient did not pay the COD amount"},
+	{Code: "other", Type: model.ReturnRTO, Description: "Other reason, see note", NoteRequired: true},
+	{Code: "damaged", Type: model.ReturnCustomer, Description: "Item arrived damaged"},
+	{Code: "wrong_item", Type: model.ReturnCustomer, Description: "Wrong item was sent"},
+	{Code: "not_as_described", Type: model.ReturnCustomer, Description: "Item does not match the description"},
+	{Code: "changed_mind", Type: model.ReturnCustomer, Description: "Recipient changed their mind"},
+	{Code: "other", Type: model.ReturnCustomer, Description: "Other reason, see note", NoteRequired: true},
+}
+
+// Errors returned by ValidateReturnReason
+var (
+	ErrUnknownReturnType   = errors.New("type must be rto or customer_return")
+	ErrUnknownReturnReason = errors.New("unknown reason_code for this return type")
+	ErrReturnNoteRequired  = errors.New("note is required for this reason_code")
+)
+
+// ValidateReturnReason checks the return type and reason code against the catalog.
+func ValidateReturnReason(returnType, code, note string) error {
+	if returnType != model.ReturnRTO && returnType != model.ReturnCustomer {
+		return ErrUnknownReturnType
+	}
+	for _, r := range ReturnReasons {
+		if r.Type == returnType && r.Code == code {
+			if r.NoteRequired && strings.TrimSpace(note) == "" {
+				return ErrReturnNoteRequired
+			}
+			return nil
+		}
+	}
+	return ErrUnknownReturnReason
+}
+
+// ReturnWindow returns how long after delivery a customer return can be requested.
+// Defaults to 14 days; override with RETURN_WINDOW_DAYS.
+func ReturnWindow() time.Duration {
+	days := 14
+	if v, err := strconv.Atoi(os.Getenv("RETURN_WINDOW_DAYS")); err == nil && v > 0 {
+		days = v
+	}
+	return time.Duration(days) * 24 * time.Hour
+}
+
+// DeliveredAt returns when the shipment was last marked delivered according to its timeline,
+// falling back to the last update for shipments without events.
+func DeliveredAt(s *model.Shipment) time.Time {
+	for i := len(s.Events) - 1; i >= 0; i-- {
+		if s.Events[i].Status == model.StatusDelivered {
+			return s.Events[i].Timestamp
+		}
+	}
+	return s.UpdatedAt
+}
+
+// ReturnTrackingNumber derives the tracking number of the next return shipment of original,
+// e.g. "JNE123-R1", "JNE123-R2" after the first return was cancelled.
+func ReturnTrackingNumber(original *model.Shipment) string {
+	return fmt.Sprintf("%s-R%d", original.TrackingNumber, len(original.ReturnTrackingNumbers)+1)
+}
+
+// NewReturnShipment builds the return shipment of original: sender and recipient, origin and
+// destination are swapped, COD and insurance are dropped. items selects what is sent back (all items when empty);
+// each item must exist in the original with at least the requested quantity.
+func NewReturnShipment(original *model.Shipment, ret model.ShipmentReturn, items []model.ShipmentItem, notes string) (*model.Shipment, error) {
+	returned := original.Items
+	if len(items) > 0 {
+		available := make(map[string]int)
+		weights := make(map[string]float64)
+		for _, item := range original.Items {
+			available[item.Name] += item.Qty
+			weights[item.Name] = item.Weight
+		}
+		items = append([]model.ShipmentItem(nil), items...)
+		for i, item := range items {
+			if item.Qty <= 0 {
+				return nil, fmt.Errorf("item %q: qty must be greater than 0", item.Name)
+			}
+			if available[item.Name] < item.Qty {
+				return nil, fmt.Errorf("item %q: not in the original shipment or qty too high", item.Name)
+			}
+			available[item.Name] -= item.Qty
+			if item.Weight == 0 {
+				items[i].Weight = weights[item.Name]
+			}
+		}
+		returned = items
+	}
+
+	ret.OriginalTrackingNumber = original.TrackingNumber
+	return &model.Shipment{
+		LogisticName:    original.LogisticName,
+		TrackingNumber:  ReturnTrackingNumber(original),
+		Status:          model.StatusOnProcess,
+		Origin:          original.Destination,
+		Destination:     original.Origin,
+		OriginCode:      original.DestinationCode,
+		DestinationCode: original.OriginCode,
+		ServiceLevel:    original.ServiceLevel,
+		DeclaredValue:   ItemsDeclaredValue(returned),
+		Notes:           notes,
+		Sender:          original.Recipient,
+		Recipient:       original.Sender,
+		Items:           append([]model.ShipmentItem(nil), returned...),
+		Return:          &ret,
+	}, nil
+}
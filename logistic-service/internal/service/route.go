@@ -0,0 +1,258 @@
+package service
+
+import (
+	"errors"
+	"logistic-service/internal/model"
+	"math"
+	"sort"
+)
+
+// maxTwoOptPasses bounds the local search of PlanRoute; each pass is O(n²)
+const maxTwoOptPasses = 20
+
+const earthRadiusKm = 6371.0
+
+// ValidateGeoPoint checks the latitude and longitude ranges. nil (not geocoded) is valid.
+func ValidateGeoPoint(p *model.GeoPoint) error {
+	if p == nil {
+		return nil
+	}
+	if p.Latitude < -90 || p.Latitude > 90 || p.Longitude < -180 || p.Longitude > 180 {
+		return errors.New("location latitude or longitude out of range")
+	}
+	return nil
+}
+
+// DistanceKm returns the great-circle distance between two points in kilometres
+func DistanceKm(a, b model.GeoPoint) float64 {
+	rad := math.Pi / 180
+	dLat := (b.Latitude - a.Latitude) * rad
+	dLng := (b.Longitude - a.Longitude) * rad
+	h := math.Sin(dLat/2)*math.Sin(dLat/2) +
+		math.Cos(a.Latitude*rad)*math.Cos(b.Latitude*rad)*math.Sin(dLng/2)*math.Sin(dLng/2)
+	return 2 * earthRadiusKm * math.Asin(math.Sqrt(h))
+}
+
+// slotOrder ranks time slots, stops without a time window rank last
+func slotOrder(slot string) int {
+	for i, s := range model.DeliverySlots {
+		if s == slot {
+			return i
+		}
+	}
+	return len(model.DeliverySlots)
+}
+
+// PlanRoute orders a driver's tasks into a short visiting sequence starting at start (nil when
+// unknown) and numbers them. Time windows are respected: stops are visited slot by slot, and
+// stops without a window join the slot of the nearest stop that has one. Within a slot the
+// geocoded stops are ordered by nearest neighbour and improved with 2-opt; stops without
+// coordinates follow them, pickups first, by area. Returns the total distance in km.
+func PlanRoute(tasks []model.DriverTask, start *model.GeoPoint) float64 {
+	flexible := len(model.DeliverySlots)
+	windows := make([][]model.DriverTask, flexible+1)
+	var unplaced []model.DriverTask
+	for _, t := range tasks {
+		if w := slotOrder(t.TimeSlot); w != flexible {
+			windows[w] = append(windows[w], t)
+		} else {
+			unplaced = append(unplaced, t)
+		}
+	}
+	for _, t := range unplaced {
+		w := nearestWindow(windows[:flexible], t.Location, flexible)
+		windows[w] = append(windows[w], t)
+	}
+
+	ordered := make([]model.DriverTask, 0, len(tasks))
+	pos := start
+	for _, window := range windows {
+		var located, unlocated []model.DriverTask
+		for _, t := range window {
+			if t.Location != nil {
+				located = append(located, t)
+			} else {
+				unlocated = append(unlocated, t)
+			}
+		}
+		sortByArea(located)
+		sortByArea(unlocated)
+		located = nearestNeighbour(pos, located)
+		twoOpt(pos, located)
+		if len(located) > 0 {
+			pos = located[len(located)-1].Location
+		}
+		ordered = append(ordered, located...)
+		ordered = append(ordered, unlocated...)
+	}
+	copy(tasks, ordered)
+	return numberRoute(tasks, start)
+}
+
+// ApplyRoute puts tasks in the order of a previously planned route and numbers them. It returns
+// false, leaving tasks untouched, when the stops differ from the ones the route was planned for.
+func ApplyRoute(tasks []model.DriverTask, route *model.DriverRoute) (float64, bool) {
+	if route == nil || len(route.Stops) != len(tasks) {
+		return 0, false
+	}
+	position := make(map[string]int, len(route.Stops))
+	for i, stop := range route.Stops {
+		position[stop.Key] = i
+	}
+	for _, t := range tasks {
+		if _, ok := position[t.Key()]; !ok {
+			return 0, false
+		}
+	}
+	sort.SliceStable(tasks, func(i, j int) bool {
+		return position[tasks[i].Key()] < position[tasks[j].Key()]
+	})
+	return numberRoute(tasks, route.Start), true
+}
+
+// RouteStops returns the stops of tasks in order, as stored in DriverRoute.Stops
+func RouteStops(tasks []model.DriverTask) []model.RouteStop {
+	stops := make([]model.RouteStop, len(tasks))
+	for i, t := range tasks {
+		stops[i] = model.RouteStop{Key: t.Key(), Location: t.Location}
+	}
+	return stops
+}
+
+// ResumePoint estimates where the driver is when a route is replanned because its stops changed:
+// stops are done in order, so it is the last geocoded stop of the leading run of stops that are
+// no longer on the task list. Falls back to the start of the previous route.
+func ResumePoint(previous *model.DriverRoute, tasks []model.DriverTask) *model.GeoPoint {
+	if previous == nil {
+		return nil
+	}
+	current := make(map[string]bool, len(tasks))
+	for _, t := range tasks {
+		current[t.Key()] = true
+	}
+	pos := previous.Start
+	for _, stop := range previous.Stops {
+		if current[stop.Key] {
+			break
+		}
+		if stop.Location != nil {
+			pos = stop.Location
+		}
+	}
+	return pos
+}
+
+// numberRoute sets the sequence and leg distance of each task and returns the total distance
+func numberRoute(tasks []model.DriverTask, start *model.GeoPoint) float64 {
+	total := 0.0
+	prev := start
+	for i := range tasks {
+		tasks[i].Sequence = i + 1
+		tasks[i].LegKm = 0
+		if tasks[i].Location == nil {
+			continue
+		}
+		if prev != nil {
+			tasks[i].LegKm = math.Round(DistanceKm(*prev, *tasks[i].Location)*100) / 100
+			total += tasks[i].LegKm
+		}
+		prev = tasks[i].Location
+	}
+	return math.Round(total*100) / 100
+}
+
+// nearestWindow returns the window holding the stop closest to p, or def when p is nil or no
+// window has a geocoded stop
+func nearestWindow(windows [][]model.DriverTask, p *model.GeoPoint, def int) int {
+	best, bestKm := def, math.Inf(1)
+	if p == nil {
+		return best
+	}
+	for w, window := range windows {
+		for _, t := range window {
+			if t.Location == nil {
+				continue
+			}
+			if km := DistanceKm(*p, *t.Location); km < bestKm {
+				best, bestKm = w, km
+			}
+		}
+	}
+	return best
+}
+
+// sortByArea orders stops pickups first, then by area, as a deterministic base order
+func sortByArea(tasks []model.DriverTask) {
+	sort.SliceStable(tasks, func(i, j int) bool {
+		if tasks[i].Type != tasks[j].Type {
+			return tasks[i].Type == model.TaskPickup
+		}
+		return tasks[i].AreaCode < tasks[j].AreaCode
+	})
+}
+
+// nearestNeighbour builds a path through geocoded stops by always going to the closest unvisited
+// one. Without a start the path begins at the first stop.
+func nearestNeighbour(start *model.GeoPoint, stops []model.DriverTask) []model.DriverTask {
+	if len(stops) == 0 {
+		return stops
+	}
+	path := make([]model.DriverTask, 0, len(stops))
+	visited := make([]bool, len(stops))
+	pos := start
+	if pos == nil {
+		path = append(path, stops[0])
+		visited[0] = true
+		pos = stops[0].Location
+	}
+	for len(path) < len(stops) {
+		next, nextKm := -1, math.Inf(1)
+		for i, t := range stops {
+			if visited[i] {
+				continue
+			}
+			if km := DistanceKm(*pos, *t.Location); km < nextKm {
+				next, nextKm = i, km
+			}
+		}
+		visited[next] = true
+		path = append(path, stops[next])
+		pos = stops[next].Location
+	}
+	return path
+}
+
+// twoOpt improves an open path from start (fixed, may be nil) by reversing segments as long as
+// that makes it shorter
+func twoOpt(start *model.GeoPoint, path []model.DriverTask) {
+	dist := func(a, b *model.GeoPoint) float64 {
+		if a == nil || b == nil {
+			return 0
+		}
+		return DistanceKm(*a, *b)
+	}
+	improved := true
+	for pass := 0; improved && pass < maxTwoOptPasses; pass++ {
+		improved = false
+		for i := 0; i < len(path)-1; i++ {
+			prev := start
+			if i > 0 {
+				prev = path[i-1].Location
+			}
+			for j := i + 1; j < len(path); j++ {
+				var next *model.GeoPoint
+				if j+1 < len(path) {
+					next = path[j+1].Location
+				}
+				delta := dist(prev, path[j].Location) + dist(path[i].Location, next) -
+					dist(prev, path[i].Location) - dist(path[j].Location, next)
+				if delta < -1e-9 {
+					for a, b := i, j; a < b; a, b = a+1, b-1 {
+						path[a], path[b] = path[b], path[a]
+					}
+					improved = true
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,148 @@
+package service
+
+import (
+	"math"
+	"strings"
+	"testing"
+
+	"logistic-service/internal/model"
+)
+
+// at returns the point x hundredths of a degree east of 100°E and y north of the equator; a
+// hundredth of a degree is about 1.1 km, so short distances are close to planar
+func at(x, y float64) *model.GeoPoint {
+	return &model.GeoPoint{Latitude: y / 100, Longitude: 100 + x/100}
+}
+
+func delivery(tn, slot string, location *model.GeoPoint) model.DriverTask {
+	return model.DriverTask{Type: model.TaskDelivery, TrackingNumber: tn, TimeSlot: slot, Location: location}
+}
+
+func routeOrder(tasks []model.DriverTask) string {
+	keys := make([]string, len(tasks))
+	for i, t := range tasks {
+		keys[i] = t.TrackingNumber + t.PickupID
+	}
+	return strings.Join(keys, ",")
+}
+
+func TestDistanceKm(t *testing.T) {
+	monas := model.GeoPoint{Latitude: -6.1754, Longitude: 106.8272}
+	gedungSate := model.GeoPoint{Latitude: -6.9025, Longitude: 107.6188}
+	tests := []struct {
+		name     string
+		a, b     model.GeoPoint
+		min, max float64
+	}{
+		{"same point", monas, monas, 0, 0},
+		{"Jakarta to Bandung", monas, gedungSate, 115, 120},
+		{"one hundredth of a degree on the equator", *at(0, 0), *at(1, 0), 1.11, 1.12},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := DistanceKm(tt.a, tt.b); got < tt.min || got > tt.max {
+				t.Errorf("DistanceKm = %.3f, want between %g and %g", got, tt.min, tt.max)
+			}
+		})
+	}
+}
+
+func TestPlanRoute(t *testing.T) {
+	tests := []struct {
+		name  string
+		start *model.GeoPoint
+		tasks []model.DriverTask
+		want  string
+	}{
+		{
+			name:  "nearest first along a street",
+			start: at(0, 0),
+			tasks: []model.DriverTask{delivery("C", "", at(3, 0)), delivery("A", "", at(1, 0)), delivery("B", "", at(2, 0))},
+			want:  "A,B,C",
+		},
+		{
+			name:  "2-opt fixes the nearest neighbour detour",
+			start: at(0, 0),
+			// nearest neighbour goes D, B, A and back east to C (9.5 units); C, D, B, A is 7.65
+			tasks: []model.DriverTask{
+				delivery("A", "", at(-3, 2)), delivery("B", "", at(-1, 2)), delivery("C", "", at(2, 1)), delivery("D", "", at(0, 1)),
+			},
+			want: "C,D,B,A",
+		},
+		{
+			name:  "slots before distance",
+			start: at(0, 0),
+			tasks: []model.DriverTask{
+				delivery("NEAR", model.SlotAfternoon, at(1, 0)),
+				delivery("FAR", model.SlotMorning, at(10, 0)),
+				delivery("EVENING", model.SlotEvening, at(2, 0)),
+			},
+			want: "FAR,NEAR,EVENING",
+		},
+		{
+			name:  "stops without a slot join the nearest slotted stop",
+			start: at(0, 0),
+			tasks: []model.DriverTask{
+				delivery("AFTERNOON", model.SlotAfternoon, at(1, 0)),
+				delivery("MORNING", model.SlotMorning, at(10, 0)),
+				delivery("ANY", "", at(11, 0)),
+			},
+			want: "MORNING,ANY,AFTERNOON",
+		},
+		{
+			name:  "ungeocoded stops last in their window, pickups first",
+			start: at(0, 0),
+			tasks: []model.DriverTask{
+				delivery("NOWHERE", "", nil),
+				{Type: model.TaskPickup, PickupID: "PICKUP"},
+				delivery("HERE", "", at(1, 0)),
+			},
+			want: "HERE,PICKUP,NOWHERE",
+		},
+		{
+			name:  "no start",
+			start: nil,
+			tasks: []model.DriverTask{delivery("A", "", at(1, 0)), delivery("C", "", at(3, 0)), delivery("B", "", at(2, 0))},
+			want:  "A,B,C",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			PlanRoute(tt.tasks, tt.start)
+			if got := routeOrder(tt.tasks); got != tt.want {
+				t.Errorf("PlanRoute order = %s, want %s", got, tt.want)
+			}
+			for i, task := range tt.tasks {
+				if task.Sequence != i+1 {
+					t.Errorf("stop %d has sequence %d", i+1, task.Sequence)
+				}
+			}
+		})
+	}
+}
+
+func TestPlanRouteDistance(t *testing.T) {
+	tasks := []model.DriverTask{delivery("A", "", at(0, 3)), delivery("B", "", at(4, 3))}
+	// 3 units north to A, then 4 east to B
+	total := PlanRoute(tasks, at(0, 0))
+	unit := DistanceKm(*at(0, 0), *at(1, 0))
+	if math.Abs(total-7*unit) > 0.05 {
+		t.Errorf("PlanRoute distance = %.2f km, want %.2f", total, 7*unit)
+	}
+	if math.Abs(tasks[0].LegKm-3*unit) > 0.01 || math.Abs(tasks[1].LegKm-4*unit) > 0.01 {
+		t.Errorf("legs = %.2f and %.2f km, want %.2f and %.2f", tasks[0].LegKm, tasks[1].LegKm, 3*unit, 4*unit)
+	}
+}
+
+func TestApplyRoute(t *testing.T) {
+	tasks := []model.DriverTask{delivery("A", "", at(1, 0)), delivery("B", "", at(2, 0))}
+	route := &model.DriverRoute{Start: at(0, 0), Stops: []model.RouteStop{{Key: "delivery:B"}, {Key: "delivery:A"}}}
+	if _, ok := ApplyRoute(tasks, route); !ok || routeOrder(tasks) != "B,A" {
+		t.Errorf("ApplyRoute = %s, %v; want B,A, true", routeOrder(tasks), ok)
+	}
+
+	changed := []model.DriverTask{delivery("A", "", at(1, 0)), delivery("C", "", at(3, 0))}
+	if _, ok := ApplyRoute(changed, route); ok || routeOrder(changed) != "A,C" {
+		t.Errorf("ApplyRoute with other stops = %s, %v; want A,C untouched, false", routeOrder(changed), ok)
+	}
+}
@@ -0,0 +1,111 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"logistic-service/internal/repository"
+	"strings"
+)
+
+// Limits of the input of a shipment search
+const (
+	MinSearchPhoneDigits = 4
+	MaxSearchTextLength  = 200
+)
+
+// ShipmentSearchQuery is a customer service search. At least one of Text, Name and Phone is
+// required; all given criteria and the filter must match.
+type ShipmentSearchQuery struct {
+	Text   string // words of names, addresses or the tracking number
+	Name   string // sender or recipient name, matched by sound so misspellings are found
+	Phone  string // part of the sender or recipient phone, at least MinSearchPhoneDigits digits
+	Filter repository.ShipmentFilter
+	Limit  int64
+	Cursor string
+}
+
+// ShipmentSearcher finds shipments for customer service, ranked by relevance.
+// MongoShipmentSearch uses the text index and phonetic name keys of the shipments collection;
+// a dedicated search engine (Elasticsearch, Meilisearch, ...) can be plugged in behind the
+// same interface.
+type ShipmentSearcher interface {
+	Search(q ShipmentSearchQuery) (*repository.ShipmentSearchPage, error)
+}
+
+// ValidateShipmentSearch checks q before it is handed to a ShipmentSearcher
+func ValidateShipmentSearch(q ShipmentSearchQuery) error {
+	text, name, phone := strings.TrimSpace(q.Text), strings.TrimSpace(q.Name), strings.TrimSpace(q.Phone)
+	switch {
+	case text == "" && name == "" && phone == "":
+		return errors.New("q, name or phone is required")
+	case len(text) > MaxSearchTextLength || len(name) > MaxSearchTextLength:
+		return fmt.Errorf("q and name must be at most %d characters", MaxSearchTextLength)
+	case phone != "" && len(onlyDigits(phone)) < MinSearchPhoneDigits:
+		return fmt.Errorf("phone must have at least %d digits", MinSearchPhoneDigits)
+	case name != "" && len(NameKeys(name)) == 0:
+		return errors.New("name must have a word of at least 2 letters")
+	}
+	return nil
+}
+
+// MongoShipmentSearch searches the shipments collection
+type MongoShipmentSearch struct {
+	repo *repository.ShipmentRepository
+}
+
+// NewMongoShipmentSearch creates a searcher over repo
+func NewMongoShipmentSearch(repo *repository.ShipmentRepository) *MongoShipmentSearch {
+	return &MongoShipmentSearch{repo: repo}
+}
+
+// Search implements ShipmentSearcher. Phones match on their digits, with a leading 0 or 62
+// of the query dropped so local and international formats find each other.
+func (m *MongoShipmentSearch) Search(q ShipmentSearchQuery) (*repository.ShipmentSearchPage, error) {
+	if err := ValidateShipmentSearch(q); err != nil {
+		return nil, err
+	}
+	search := repository.ShipmentSearch{
+		Filter: q.Filter,
+		Text:   strings.TrimSpace(q.Text),
+		Limit:  q.Limit,
+		Cursor: q.Cursor,
+	}
+	if name := strings.TrimSpace(q.Name); name != "" {
+		search.NameKeys = NameKeys(name)
+	}
+	if digits := onlyDigits(q.Phone); digits != "" {
+		trimmed := strings.TrimPrefix(strings.TrimPrefix(digits, "62"), "0")
+		if len(trimmed) >= MinSearchPhoneDigits {
+			digits = trimmed
+		}
+		search.PhoneDigits = digits
+	}
+	return m.repo.Search(search)
+}
+
+// BackfillSearchKeys stores the phonetic name keys of shipments created before fuzzy name search
+// existed, in batches until none is left. Meant to run once in the background at startup.
+func BackfillSearchKeys(repo *repository.ShipmentRepository) {
+	total := 0
+	for {
+		shipments, err := repo.FindMissingSearchKeys(500)
+		if err != nil {
+			log.Printf("[BackfillSearchKeys] FindMissingSearchKeys error: %v", err)
+			return
+		}
+		if len(shipments) == 0 {
+			break
+		}
+		for _, s := range shipments {
+			if err := repo.SetSearchKeys(s.TrackingNumber, ShipmentSearchKeys(s)); err != nil {
+				log.Printf("[BackfillSearchKeys] SetSearchKeys error for %s: %v", s.TrackingNumber, err)
+				return
+			}
+		}
+		total += len(shipments)
+	}
+	if total > 0 {
+		log.Printf("[BackfillSearchKeys] Indexed the names of %d shipments", total)
+	}
+}
@@ -0,0 +1,43 @@
+package service
+
+import (
+	"logistic-service/internal/model"
+	"reflect"
+)
+
+// DiffShipment lists the editable fields that differ between before and after.
+// Sender and recipient are compared field by field, items as a whole list.
+func DiffShipment(before, after *model.Shipment) []model.FieldChange {
+	var changes []model.FieldChange
+	add := func(field string, old, new interface{}) {
+		if !reflect.DeepEqual(old, new) {
+			changes = append(changes, model.FieldChange{Field: field, Old: old, New: new})
+		}
+	}
+
+	add("sender.name", before.Sender.Name, after.Sender.Name)
+	add("sender.phone", before.Sender.Phone, after.Sender.Phone)
+	add("sender.address", before.Sender.Address, after.Sender.Address)
+	add("sender.structured_address", before.Sender.Structured, after.Sender.Structured)
+	add("recipient.name", before.Recipient.Name, after.Recipient.Name)
+	add("recipient.phone", before.Recipient.Phone, after.Recipient.Phone)
+	add("recipient.address", before.Recipient.Address, after.Recipient.Address)
+	add("recipient.structured_address", before.Recipient.Structured, after.Recipient.Structured)
+	add("notes", before.Notes, after.Notes)
+	if !itemsEqual(before.Items, after.Items) {
+		changes = append(changes, model.FieldChange{Field: "items", Old: before.Items, New: after.Items})
+	}
+	return changes
+}
+
+func itemsEqual(a, b []model.ShipmentItem) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,276 @@
+package service
+
+import (
+	"bytes"
+	_ "embed"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"logistic-service/internal/model"
+	"os"
+	"strings"
+	"time"
+)
+
+// ServiceLevels is the catalog of accepted Shipment.ServiceLevel values.
+var ServiceLevels = []model.ServiceLevel{
+	{Code: model.ServiceSameDay, Description: "Delivered the same working day, within a city",
+		TransitDays: map[string]int{model.ZoneCity: 0}},
+	{Code: model.ServiceExpress, Description: "Next working day",
+		TransitDays: map[string]int{model.ZoneCity: 1, model.ZoneProvince: 1, model.ZoneNational: 2}},
+	{Code: model.ServiceRegular, Description: "Standard delivery",
+		TransitDays: map[string]int{model.ZoneCity: 1, model.ZoneProvince: 2, model.ZoneNational: 4}},
+	{Code: model.ServiceEconomy, Description: "Cheapest, by land and sea freight",
+		TransitDays: map[string]int{model.ZoneCity: 2, model.ZoneProvince: 4, model.ZoneNational: 7}},
+}
+
+// ErrUnknownServiceLevel is returned for a service_level missing from ServiceLevels
+var ErrUnknownServiceLevel = errors.New("unknown service_level")
+
+// FindServiceLevel returns the catalog entry of code, or nil
+func FindServiceLevel(code string) *model.ServiceLevel {
+	for i := range ServiceLevels {
+		if ServiceLevels[i].Code == code {
+			return &ServiceLevels[i]
+		}
+	}
+	return nil
+}
+
+// ApplyServiceLevel defaults the service level of a new shipment to regular and checks that it
+// serves the shipment's route.
+func ApplyServiceLevel(s *model.Shipment) error {
+	if s.ServiceLevel == "" {
+		s.ServiceLevel = model.ServiceRegular
+	}
+	_, err := transitDays(s)
+	return err
+}
+
+// RouteZone classifies the route of a shipment by its origin and destination district codes.
+// Shipments with free text addresses only are in the city zone when origin and destination
+// name the same place, else national.
+func RouteZone(s *model.Shipment) string {
+	if s.OriginCode == "" || s.DestinationCode == "" {
+		if strings.EqualFold(strings.TrimSpace(s.Origin), strings.TrimSpace(s.Destination)) {
+			return model.ZoneCity
+		}
+		return model.ZoneNational
+	}
+	origin := strings.Split(s.OriginCode, ".")
+	destination := strings.Split(s.DestinationCode, ".")
+	switch {
+	case len(origin) >= 2 && len(destination) >= 2 && origin[0] == destination[0] && origin[1] == destination[1]:
+		return model.ZoneCity
+	case origin[0] == destination[0]:
+		return model.ZoneProvince
+	}
+	return model.ZoneNational
+}
+
+// bundledHolidays lists the Indonesian national holidays known at release.
+// Load the official calendar (cuti bersama included) with HOLIDAY_CALENDAR_FILE (CSV: date,name).
+//
+//go:embed data/holidays.csv
+var bundledHolidays []byte
+
+// DeliveryCalendar knows the working days couriers deliver on (every day except Sundays and
+// holidays) and turns service levels into dates. Dates are YYYY-MM-DD in WIB.
+type DeliveryCalendar struct {
+	holidays   map[string]string // date -> name
+	cutoffHour int
+}
+
+// LoadDeliveryCalendar reads the holiday CSV at path, or the bundled calendar when path is empty.
+// Parcels handed over at or after cutoffHour (WIB) count from the next working day.
+func LoadDeliveryCalendar(path string, cutoffHour int) (*DeliveryCalendar, error) {
+	var r io.Reader = bytes.NewReader(bundledHolidays)
+	if path != "" {
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		r = f
+	}
+	records, err := csv.NewReader(r).ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	cal := &DeliveryCalendar{holidays: make(map[string]string), cutoffHour: cutoffHour}
+	for i, record := range records {
+		if i == 0 && len(record) > 0 && record[0] == "date" {
+			continue // header
+		}
+		if len(record) < 1 {
+			return nil, fmt.Errorf("holiday calendar line %d: missing date", i+1)
+		}
+		date := strings.TrimSpace(record[0])
+		if _, err := time.Parse("2006-01-02", date); err != nil {
+			return nil, fmt.Errorf("holiday calendar line %d: date must be formatted as YYYY-MM-DD", i+1)
+		}
+		name := ""
+		if len(record) > 1 {
+			name = strings.TrimSpace(record[1])
+		}
+		cal.holidays[date] = name
+	}
+	return cal, nil
+}
+
+// Len returns the number of holidays in the calendar
+func (cal *DeliveryCalendar) Len() int {
+	return len(cal.holidays)
+}
+
+// IsWorkingDay reports whether couriers deliver on the date of day (WIB)
+func (cal *DeliveryCalendar) IsWorkingDay(day time.Time) bool {
+	day = day.In(deliveryZone)
+	if day.Weekday() == time.Sunday {
+		return false
+	}
+	_, holiday := cal.holidays[day.Format("2006-01-02")]
+	return !holiday
+}
+
+// dayOf returns midnight (WIB) of the date of t
+func dayOf(t time.Time) time.Time {
+	t = t.In(deliveryZone)
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, deliveryZone)
+}
+
+// onOrAfter returns the first working day on or after the date of t
+func (cal *DeliveryCalendar) onOrAfter(t time.Time) time.Time {
+	day := dayOf(t)
+	for !cal.IsWorkingDay(day) {
+		day = day.AddDate(0, 0, 1)
+	}
+	return day
+}
+
+// handoverDay returns the working day transit counts from for a parcel handed over at t:
+// the same day before the cutoff hour, else the next working day
+func (cal *DeliveryCalendar) handoverDay(t time.Time) time.Time {
+	day := cal.onOrAfter(t)
+	if day.Equal(dayOf(t)) && t.In(deliveryZone).Hour() >= cal.cutoffHour {
+		day = cal.onOrAfter(day.AddDate(0, 0, 1))
+	}
+	return day
+}
+
+// addWorkingDays returns the working day n working days after day
+func (cal *DeliveryCalendar) addWorkingDays(day time.Time, n int) time.Time {
+	for ; n > 0; n-- {
+		day = cal.onOrAfter(day.AddDate(0, 0, 1))
+	}
+	return day
+}
+
+// transitDays returns the working days the service level of s needs for its route
+func transitDays(s *model.Shipment) (int, error) {
+	level := FindServiceLevel(s.ServiceLevel)
+	if level == nil {
+		return 0, ErrUnknownServiceLevel
+	}
+	zone := RouteZone(s)
+	days, ok := level.TransitDays[zone]
+	if !ok {
+		return 0, fmt.Errorf("service_level %s is not available for %s routes", level.Code, zone)
+	}
+	return days, nil
+}
+
+// PromisedDate returns the delivery date committed to for a shipment created at created,
+// from its service level, route zone and the holiday calendar.
+func (cal *DeliveryCalendar) PromisedDate(s *model.Shipment, created time.Time) (string, error) {
+	days, err := transitDays(s)
+	if err != nil {
+		return "", err
+	}
+	return cal.addWorkingDays(cal.handoverDay(created), days).Format("2006-01-02"), nil
+}
+
+// UpdatePromise recomputes the promised date and ETA of after, an edit of before, when the edit
+// moved it to another route zone: the promise becomes the one it would have had if created on
+// the new route, and a breach flagged against the old promise is cleared. Reports whether the
+// promised date changed. Shipments from before promised dates are left alone.
+func (cal *DeliveryCalendar) UpdatePromise(before, after *model.Shipment, now time.Time) (bool, error) {
+	if before.PromisedDate == "" || RouteZone(before) == RouteZone(after) {
+		return false, nil
+	}
+	promised, err := cal.PromisedDate(after, after.CreatedAt)
+	if err != nil {
+		return false, err
+	}
+	after.PromisedDate = promised
+	after.ETA = cal.EstimateArrival(after, now)
+	if promised == before.PromisedDate {
+		return false, nil
+	}
+	after.SLABreachedAt = nil
+	return true, nil
+}
+
+// EstimateArrival returns the current delivery date estimate of a shipment, or "" when it won't
+// be delivered (cancelled, returning to sender). Transit counts from pickup, or from now while
+// the parcel waits for pickup. A requested reschedule sets the date; after a failed attempt it's
+// the next working day at the earliest. Late shipments are expected today (or the next working
+// day), never in the past. Delivered shipments return their delivery date.
+func (cal *DeliveryCalendar) EstimateArrival(s *model.Shipment, now time.Time) string {
+	switch s.Status {
+	case model.StatusDelivered, model.StatusReturned:
+		at := s.UpdatedAt
+		if n := len(s.Events); n > 0 {
+			at = s.Events[n-1].Timestamp
+		}
+		return dayOf(at).Format("2006-01-02")
+	case model.StatusCancelled, model.StatusReturnToSender:
+		return ""
+	}
+	days, err := transitDays(s)
+	if err != nil {
+		return s.PromisedDate
+	}
+
+	handover := now
+	if s.Status != model.StatusOnProcess {
+		handover = s.CreatedAt
+		for _, e := range s.Events {
+			if e.Status != model.StatusOnProcess {
+				handover = e.Timestamp
+				break
+			}
+		}
+	}
+	eta := cal.addWorkingDays(cal.handoverDay(handover), days)
+
+	if s.NextAttempt != nil {
+		if day, err := time.ParseInLocation("2006-01-02", s.NextAttempt.Date, deliveryZone); err == nil {
+			eta = day
+		}
+	} else if n := len(s.DeliveryAttempts); n > 0 {
+		retry := cal.onOrAfter(dayOf(s.DeliveryAttempts[n-1].AttemptedAt).AddDate(0, 0, 1))
+		if eta.Before(retry) {
+			eta = retry
+		}
+	}
+	if earliest := cal.onOrAfter(now); eta.Before(earliest) {
+		eta = earliest
+	}
+	return eta.Format("2006-01-02")
+}
+
+// Breached reports whether a shipment still open on the date of now missed its promised date
+func (cal *DeliveryCalendar) Breached(s *model.Shipment, now time.Time) bool {
+	if s.PromisedDate == "" || s.SLABreachedAt != nil {
+		return false
+	}
+	open := false
+	for _, status := range model.SLAOpenStatuses {
+		if s.Status == status {
+			open = true
+		}
+	}
+	return open && Today(now) > s.PromisedDate
+}
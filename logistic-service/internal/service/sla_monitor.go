@@ -0,0 +1,184 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"logistic-service/internal/model"
+	"logistic-service/internal/repository"
+	"time"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+// etaQueue is the durable queue the ETA tracker consumes from ShipmentUpdatesExchange. It is
+// shared by all logistic-service instances so every update is handled once.
+const etaQueue = "shipment.eta"
+
+// SLABreachedQueue receives a shipment every time it is flagged as SLA breached
+const SLABreachedQueue = "shipment.sla_breached"
+
+// ETATracker keeps Shipment.ETA current: it recomputes the estimate from every shipment.updated
+// message and republishes the shipment when it changed.
+type ETATracker struct {
+	repo     *repository.ShipmentRepository
+	calendar *DeliveryCalendar
+}
+
+// NewETATracker creates a tracker. Call Run in a goroutine to start it.
+func NewETATracker(repo *repository.ShipmentRepository, calendar *DeliveryCalendar) *ETATracker {
+	return &ETATracker{repo: repo, calendar: calendar}
+}
+
+// Run consumes shipment.updated messages through the shared etaQueue. It reopens the channel if
+// it gets closed and returns when ctx is cancelled or the connection is closed.
+func (t *ETATracker) Run(ctx context.Context, conn *amqp.Connection) {
+	for {
+		if err := t.consume(ctx, conn); err != nil {
+			log.Printf("[ETATracker] consume error: %v", err)
+		}
+		if conn.IsClosed() {
+			return
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(5 * time.Second):
+		}
+	}
+}
+
+func (t *ETATracker) consume(ctx context.Context, conn *amqp.Connection) error {
+	ch, err := conn.Channel()
+	if err != nil {
+		return err
+	}
+	defer ch.Close()
+
+	if _, err := ch.QueueDeclare(etaQueue, true, false, false, false, nil); err != nil {
+		return err
+	}
+	if err := ch.QueueBind(etaQueue, "", ShipmentUpdatesExchange, false, nil); err != nil {
+		return err
+	}
+	msgs, err := ch.Consume(etaQueue, "", true, false, false, false, nil)
+	if err != nil {
+		return err
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case msg, ok := <-msgs:
+			if !ok {
+				return nil
+			}
+			var shipment model.Shipment
+			if err := json.Unmarshal(msg.Body, &shipment); err != nil {
+				log.Printf("[ETATracker] Unmarshal shipment.updated error: %v", err)
+				continue
+			}
+			t.update(ch, &shipment)
+		}
+	}
+}
+
+// update stores the new estimate of s and republishes it. Stale messages (the shipment moved on
+// since) are skipped; the newer message brings its own update.
+func (t *ETATracker) update(ch *amqp.Channel, s *model.Shipment) {
+	eta := t.calendar.EstimateArrival(s, time.Now())
+	if eta == s.ETA {
+		return
+	}
+	err := t.repo.SetETA(s.TrackingNumber, s.Version, eta)
+	if err == repository.ErrVersionConflict {
+		return
+	}
+	if err != nil {
+		log.Printf("[ETATracker] SetETA error for %s: %v", s.TrackingNumber, err)
+		return
+	}
+	s.ETA = eta
+	s.Version++
+	publishJSON(ch, ShipmentUpdatesExchange, "shipment.updated", s)
+}
+
+// SLAMonitor periodically flags open shipments whose promised date passed. Each breach is
+// published to SLABreachedQueue and shipment.updated and sent to the shipment.sla_breached webhook.
+type SLAMonitor struct {
+	repo     *repository.ShipmentRepository
+	calendar *DeliveryCalendar
+	ch       *amqp.Channel
+	hooks    *WebhookDispatcher
+	interval time.Duration
+}
+
+// NewSLAMonitor creates a monitor checking every interval. Call Run in a goroutine to start it.
+func NewSLAMonitor(repo *repository.ShipmentRepository, calendar *DeliveryCalendar, ch *amqp.Channel, hooks *WebhookDispatcher, interval time.Duration) *SLAMonitor {
+	return &SLAMonitor{repo: repo, calendar: calendar, ch: ch, hooks: hooks, interval: interval}
+}
+
+// Run checks for breaches right away and then every interval until ctx is cancelled.
+func (m *SLAMonitor) Run(ctx context.Context) {
+	ticker := time.NewTicker(m.interval)
+	defer ticker.Stop()
+	for {
+		m.check()
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// check flags the breaches found in batches until none are left
+func (m *SLAMonitor) check() {
+	for {
+		now := time.Now()
+		candidates, err := m.repo.FindSLABreaches(Today(now), 200)
+		if err != nil {
+			log.Printf("[SLAMonitor] FindSLABreaches error: %v", err)
+			return
+		}
+		flagged := 0
+		for _, s := range candidates {
+			if !m.calendar.Breached(s, now) {
+				continue
+			}
+			s, err := m.repo.MarkSLABreached(s.TrackingNumber, now)
+			if err == repository.ErrStatusConflict {
+				continue // flagged by another instance
+			}
+			if err != nil {
+				log.Printf("[SLAMonitor] MarkSLABreached error for %s: %v", s.TrackingNumber, err)
+				continue
+			}
+			flagged++
+			log.Printf("[SLAMonitor] %s breached its promised date %s", s.TrackingNumber, s.PromisedDate)
+			publishJSON(m.ch, "", SLABreachedQueue, s)
+			publishJSON(m.ch, ShipmentUpdatesExchange, "shipment.updated", s)
+			m.hooks.Enqueue(s.UserID, model.EventShipmentSLABreached, s)
+		}
+		if flagged == 0 || len(candidates) < 200 {
+			return
+		}
+	}
+}
+
+// publishJSON publishes payload as JSON. Failures are logged: the next change republishes.
+func publishJSON(ch *amqp.Channel, exchange, key string, payload interface{}) {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		log.Printf("[publishJSON] Marshal %s payload error: %v", key, err)
+		return
+	}
+	err = ch.Publish(exchange, key, false, false, amqp.Publishing{
+		ContentType: "application/json",
+		Body:        body,
+	})
+	if err != nil {
+		log.Printf("[publishJSON] RabbitMQ publish %s error: %v", key, err)
+	}
+}
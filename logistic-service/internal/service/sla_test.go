@@ -0,0 +1,174 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"logistic-service/internal/model"
+)
+
+// testCalendar has Independence Day 2026 (Monday 17 August) as its only holiday and a 15:00 cutoff
+func testCalendar() *DeliveryCalendar {
+	return &DeliveryCalendar{holidays: map[string]string{"2026-08-17": "Hari Kemerdekaan"}, cutoffHour: 15}
+}
+
+func wib(day, hour, minute int) time.Time {
+	return time.Date(2026, time.August, day, hour, minute, 0, 0, deliveryZone)
+}
+
+func TestRouteZone(t *testing.T) {
+	tests := []struct {
+		name                      string
+		originCode, destCode      string
+		origin, destination, want string
+	}{
+		{"same city", "31.71.01", "31.71.06", "", "", model.ZoneCity},
+		{"same province", "31.71.01", "31.74.01", "", "", model.ZoneProvince},
+		{"other province", "31.71.01", "32.73.02", "", "", model.ZoneNational},
+		{"free text, same place", "", "", "Bandung", " bandung", model.ZoneCity},
+		{"free text, other place", "", "", "Bandung", "Jakarta", model.ZoneNational},
+		{"one side coded", "31.71.01", "", "Jakarta", "Jakarta", model.ZoneCity},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &model.Shipment{OriginCode: tt.originCode, DestinationCode: tt.destCode, Origin: tt.origin, Destination: tt.destination}
+			if got := RouteZone(s); got != tt.want {
+				t.Errorf("RouteZone = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIsWorkingDay(t *testing.T) {
+	cal := testCalendar()
+	tests := []struct {
+		at   time.Time
+		want bool
+	}{
+		{wib(15, 12, 0), true},  // Saturday
+		{wib(16, 12, 0), false}, // Sunday
+		{wib(17, 12, 0), false}, // holiday
+		{wib(18, 12, 0), true},
+		{time.Date(2026, time.August, 16, 18, 0, 0, 0, time.UTC), false}, // Monday 01:00 WIB, the holiday
+	}
+	for _, tt := range tests {
+		if got := cal.IsWorkingDay(tt.at); got != tt.want {
+			t.Errorf("IsWorkingDay(%s) = %v, want %v", tt.at, got, tt.want)
+		}
+	}
+}
+
+func TestPromisedDate(t *testing.T) {
+	cal := testCalendar()
+	city := func(level string) *model.Shipment {
+		return &model.Shipment{ServiceLevel: level, OriginCode: "31.71.01", DestinationCode: "31.71.06"}
+	}
+	national := func(level string) *model.Shipment {
+		return &model.Shipment{ServiceLevel: level, OriginCode: "31.71.01", DestinationCode: "51.71.01"}
+	}
+	tests := []struct {
+		name     string
+		shipment *model.Shipment
+		created  time.Time
+		want     string
+		wantErr  bool
+	}{
+		{"regular city, Saturday counts", city(model.ServiceRegular), wib(14, 10, 0), "2026-08-15", false},
+		{"skips Sunday and the holiday", city(model.ServiceRegular), wib(15, 10, 0), "2026-08-18", false},
+		{"after the cutoff counts from the next working day", city(model.ServiceRegular), wib(14, 16, 0), "2026-08-18", false},
+		{"at the cutoff counts from the next working day", city(model.ServiceRegular), wib(14, 15, 0), "2026-08-18", false},
+		{"handed over on a Sunday", city(model.ServiceRegular), wib(16, 9, 0), "2026-08-19", false},
+		{"same day", city(model.ServiceSameDay), wib(18, 9, 0), "2026-08-18", false},
+		{"same day after the cutoff", city(model.ServiceSameDay), wib(18, 15, 30), "2026-08-19", false},
+		{"regular national", national(model.ServiceRegular), wib(18, 9, 0), "2026-08-22", false},
+		{"express national", national(model.ServiceExpress), wib(14, 9, 0), "2026-08-18", false},
+		{"created in UTC, before the cutoff in WIB", city(model.ServiceRegular), time.Date(2026, time.August, 14, 7, 0, 0, 0, time.UTC), "2026-08-15", false},
+		{"same day isn't national", national(model.ServiceSameDay), wib(18, 9, 0), "", true},
+		{"unknown service level", city("teleport"), wib(18, 9, 0), "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := cal.PromisedDate(tt.shipment, tt.created)
+			if (err != nil) != tt.wantErr || got != tt.want {
+				t.Errorf("PromisedDate = %q, %v; want %q, error %v", got, err, tt.want, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestBreached(t *testing.T) {
+	cal := testCalendar()
+	flagged := wib(19, 8, 0)
+	tests := []struct {
+		name    string
+		status  string
+		flagged *time.Time
+		now     time.Time
+		want    bool
+	}{
+		{"open on the promised date", model.StatusInTransit, nil, wib(18, 23, 0), false},
+		{"open the day after", model.StatusInTransit, nil, wib(19, 0, 30), true},
+		{"waiting for pickup the day after", model.StatusOnProcess, nil, wib(19, 9, 0), true},
+		{"delivered late", model.StatusDelivered, nil, wib(19, 9, 0), false},
+		{"already flagged", model.StatusInTransit, &flagged, wib(20, 9, 0), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &model.Shipment{Status: tt.status, PromisedDate: "2026-08-18", SLABreachedAt: tt.flagged}
+			if got := cal.Breached(s, tt.now); got != tt.want {
+				t.Errorf("Breached = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUpdatePromise(t *testing.T) {
+	cal := testCalendar()
+	flagged := wib(14, 8, 0)
+	// A regular shipment created Friday 14 August within Jakarta, promised on Saturday
+	before := &model.Shipment{
+		Status: model.StatusOnProcess, ServiceLevel: model.ServiceRegular,
+		OriginCode: "31.71.01", DestinationCode: "31.71.06",
+		CreatedAt: wib(14, 10, 0), PromisedDate: "2026-08-15", ETA: "2026-08-15", SLABreachedAt: &flagged,
+	}
+	tests := []struct {
+		name            string
+		destinationCode string
+		promisedDate    string
+		wantChanged     bool
+		wantPromised    string
+		wantETA         string
+		wantErr         bool
+	}{
+		{"same city", "31.71.03", "2026-08-15", false, "2026-08-15", "2026-08-15", false},
+		{"to another province", "51.71.01", "2026-08-15", true, "2026-08-20", "2026-08-20", false},
+		{"to the same province", "31.74.01", "2026-08-15", true, "2026-08-18", "2026-08-18", false},
+		{"without a promise", "51.71.01", "", false, "", "2026-08-15", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := *before
+			b.PromisedDate = tt.promisedDate
+			after := b
+			after.DestinationCode = tt.destinationCode
+			changed, err := cal.UpdatePromise(&b, &after, wib(14, 11, 0))
+			if (err != nil) != tt.wantErr || changed != tt.wantChanged {
+				t.Fatalf("UpdatePromise = %v, %v; want %v, error %v", changed, err, tt.wantChanged, tt.wantErr)
+			}
+			if after.PromisedDate != tt.wantPromised || after.ETA != tt.wantETA {
+				t.Errorf("promised %s, ETA %s; want %s, %s", after.PromisedDate, after.ETA, tt.wantPromised, tt.wantETA)
+			}
+			if changed && after.SLABreachedAt != nil {
+				t.Errorf("breach of the old promise still flagged")
+			}
+		})
+	}
+
+	sameDay := *before
+	sameDay.ServiceLevel = model.ServiceSameDay
+	after := sameDay
+	after.DestinationCode = "51.71.01"
+	if _, err := cal.UpdatePromise(&sameDay, &after, wib(14, 11, 0)); err == nil {
+		t.Errorf("same day service moved out of the city: want an error")
+	}
+}
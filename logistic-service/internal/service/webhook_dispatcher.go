@@ -0,0 +1,271 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"logistic-service/internal/model"
+	"logistic-service/internal/repository"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// WebhookConfig tunes delivery retries and auto-disabling.
+type WebhookConfig struct {
+	MaxAttempts  int           // attempts per delivery before it is marked failed
+	BaseBackoff  time.Duration // delay before the 2nd attempt, doubled for every further attempt
+	MaxBackoff   time.Duration
+	DisableAfter int           // consecutive failed attempts before the webhook is disabled
+	Timeout      time.Duration // HTTP timeout per attempt
+	PollInterval time.Duration // how often Run looks for due deliveries
+	Workers      int           // deliveries sent concurrently, at least 1
+}
+
+// WebhookDispatcher queues shipment events for registered webhooks and delivers them
+// with HMAC signatures and exponential backoff.
+type WebhookDispatcher struct {
+	repo   *repository.WebhookRepository
+	cfg    WebhookConfig
+	client *http.Client
+	wake   chan struct{}
+}
+
+// NewWebhookDispatcher creates a dispatcher. Call Run in a goroutine to start delivering.
+func NewWebhookDispatcher(repo *repository.WebhookRepository, cfg WebhookConfig) *WebhookDispatcher {
+	return &WebhookDispatcher{
+		repo:   repo,
+		cfg:    cfg,
+		client: newWebhookClient(cfg.Timeout),
+		wake:   make(chan struct{}, 1),
+	}
+}
+
+// webhookPayload is the JSON body posted to webhook endpoints.
+type webhookPayload struct {
+	ID        string      `json:"id"` // delivery ID, stable across retries
+	Event     string      `json:"event"`
+	CreatedAt time.Time   `json:"created_at"`
+	Data      interface{} `json:"data"`
+}
+
+// Enqueue queues event for every active webhook of userID subscribed to it.
+// Errors are logged, never returned: webhooks must not fail the API request.
+func (d *WebhookDispatcher) Enqueue(userID, event string, data interface{}) {
+	hooks, err := d.repo.FindActiveForEvent(userID, event)
+	if err != nil {
+		log.Printf("[WebhookDispatcher] FindActiveForEvent error: %v", err)
+		return
+	}
+	for _, hook := range hooks {
+		now := time.Now()
+		delivery := &model.WebhookDelivery{
+			ID:            uuid.New().String(),
+			WebhookID:     hook.ID,
+			UserID:        userID,
+			Event:         event,
+			Status:        model.DeliveryPending,
+			Attempts:      []model.DeliveryAttempt{},
+			NextAttemptAt: now,
+			CreatedAt:     now,
+		}
+		body, err := json.Marshal(webhookPayload{ID: delivery.ID, Event: event, CreatedAt: now, Data: data})
+		if err != nil {
+			log.Printf("[WebhookDispatcher] Marshal %s payload error for webhook %s: %v", event, hook.ID, err)
+			continue
+		}
+		delivery.Payload = string(body)
+		if err := d.repo.InsertDelivery(delivery); err != nil {
+			log.Printf("[WebhookDispatcher] InsertDelivery error: %v", err)
+		}
+	}
+	if len(hooks) > 0 {
+		d.notify()
+	}
+}
+
+// Redeliver queues a new delivery with the same event and payload as an earlier one.
+func (d *WebhookDispatcher) Redeliver(original *model.WebhookDelivery) (*model.WebhookDelivery, error) {
+	now := time.Now()
+	delivery := &model.WebhookDelivery{
+		ID:            uuid.New().String(),
+		WebhookID:     original.WebhookID,
+		UserID:        original.UserID,
+		Event:         original.Event,
+		Payload:       original.Payload,
+		Status:        model.DeliveryPending,
+		Attempts:      []model.DeliveryAttempt{},
+		NextAttemptAt: now,
+		RedeliveryOf:  original.ID,
+		CreatedAt:     now,
+	}
+	if err := d.repo.InsertDelivery(delivery); err != nil {
+		return nil, err
+	}
+	d.notify()
+	return delivery, nil
+}
+
+func (d *WebhookDispatcher) notify() {
+	select {
+	case d.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Run delivers due deliveries with cfg.Workers concurrent workers until ctx is cancelled, so a
+// slow endpoint only holds up one worker.
+func (d *WebhookDispatcher) Run(ctx context.Context) {
+	workers := d.cfg.Workers
+	if workers < 1 {
+		workers = 1
+	}
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			d.work(ctx)
+		}()
+	}
+	wg.Wait()
+}
+
+// work delivers due deliveries until none is left, then waits for the next poll or notify
+func (d *WebhookDispatcher) work(ctx context.Context) {
+	ticker := time.NewTicker(d.cfg.PollInterval)
+	defer ticker.Stop()
+	for {
+		d.deliverDue(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-d.wake:
+		}
+	}
+}
+
+func (d *WebhookDispatcher) deliverDue(ctx context.Context) {
+	for ctx.Err() == nil {
+		// Lease long enough to cover the HTTP timeout, so a crashed attempt is retried later
+		delivery, err := d.repo.ClaimDueDelivery(d.cfg.Timeout + time.Minute)
+		if err != nil {
+			log.Printf("[WebhookDispatcher] ClaimDueDelivery error: %v", err)
+			return
+		}
+		if delivery == nil {
+			return
+		}
+		// More may be due: wake an idle worker to share them
+		d.notify()
+		d.attempt(delivery)
+	}
+}
+
+func (d *WebhookDispatcher) attempt(delivery *model.WebhookDelivery) {
+	hook, err := d.repo.FindByID(delivery.WebhookID)
+	if err != nil {
+		log.Printf("[WebhookDispatcher] FindByID error: %v", err)
+		return
+	}
+	if hook == nil || !hook.Active {
+		// Endpoint removed or disabled meanwhile: stop retrying this delivery
+		attempt := model.DeliveryAttempt{At: time.Now(), Error: "webhook disabled or deleted"}
+		if err := d.repo.RecordAttempt(delivery.ID, attempt, model.DeliveryFailed, attempt.At); err != nil {
+			log.Printf("[WebhookDispatcher] RecordAttempt error: %v", err)
+		}
+		return
+	}
+
+	attempt := d.send(hook, delivery)
+	attemptNo := len(delivery.Attempts) + 1
+	status, next := model.DeliveryPending, attempt.At.Add(d.backoff(attemptNo))
+	succeeded := attempt.Error == "" && attempt.StatusCode >= 200 && attempt.StatusCode < 300
+	if succeeded {
+		status = model.DeliverySucceeded
+	} else if attemptNo >= d.cfg.MaxAttempts {
+		status = model.DeliveryFailed
+	}
+	if err := d.repo.RecordAttempt(delivery.ID, attempt, status, next); err != nil {
+		log.Printf("[WebhookDispatcher] RecordAttempt error: %v", err)
+	}
+
+	if succeeded {
+		if err := d.repo.RecordSuccess(hook.ID); err != nil {
+			log.Printf("[WebhookDispatcher] RecordSuccess error: %v", err)
+		}
+		return
+	}
+	disabled, err := d.repo.RecordFailure(hook.ID, d.cfg.DisableAfter)
+	if err != nil {
+		log.Printf("[WebhookDispatcher] RecordFailure error: %v", err)
+	} else if disabled {
+		log.Printf("[WebhookDispatcher] Disabled webhook %s after %d consecutive failures", hook.ID, d.cfg.DisableAfter)
+	}
+}
+
+// send posts the delivery payload to the webhook URL and reports the outcome
+func (d *WebhookDispatcher) send(hook *model.Webhook, delivery *model.WebhookDelivery) model.DeliveryAttempt {
+	start := time.Now()
+	attempt := model.DeliveryAttempt{At: start}
+
+	timestamp := strconv.FormatInt(start.Unix(), 10)
+	req, err := http.NewRequest(http.MethodPost, hook.URL, bytes.NewBufferString(delivery.Payload))
+	if err != nil {
+		attempt.Error = err.Error()
+		return attempt
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "logistic-service-webhooks/1.0")
+	req.Header.Set("X-Webhook-Id", hook.ID)
+	req.Header.Set("X-Webhook-Event", delivery.Event)
+	req.Header.Set("X-Webhook-Delivery", delivery.ID)
+	req.Header.Set("X-Webhook-Timestamp", timestamp)
+	req.Header.Set("X-Webhook-Signature", SignWebhookPayload(hook.Secret, timestamp, delivery.Payload))
+
+	resp, err := d.client.Do(req)
+	attempt.DurationMs = time.Since(start).Milliseconds()
+	if err != nil {
+		attempt.Error = err.Error()
+		return attempt
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	attempt.StatusCode = resp.StatusCode
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		attempt.Error = fmt.Sprintf("unexpected status %d", resp.StatusCode)
+	}
+	return attempt
+}
+
+// backoff returns the delay after the given failed attempt number (1-based)
+func (d *WebhookDispatcher) backoff(attemptNo int) time.Duration {
+	delay := d.cfg.BaseBackoff
+	for i := 1; i < attemptNo && delay < d.cfg.MaxBackoff; i++ {
+		delay *= 2
+	}
+	if delay > d.cfg.MaxBackoff {
+		delay = d.cfg.MaxBackoff
+	}
+	return delay
+}
+
+// SignWebhookPayload returns the X-Webhook-Signature header value:
+// "sha256=" + hex(HMAC-SHA256(secret, timestamp + "." + body)).
+// Receivers recompute it with their secret and the X-Webhook-Timestamp header.
+func SignWebhookPayload(secret, timestamp, body string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp + "." + body))
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
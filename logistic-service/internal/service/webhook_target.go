@@ -0,0 +1,98 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/netip"
+	"syscall"
+	"time"
+)
+
+// ErrPrivateWebhookTarget is returned when a webhook URL resolves to an address that isn't
+// publicly routable
+var ErrPrivateWebhookTarget = errors.New("webhook host resolves to a private or reserved address")
+
+// reservedPrefixes are the non-public ranges netip has no predicate for
+var reservedPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),       // "this" network
+	netip.MustParsePrefix("100.64.0.0/10"),   // carrier-grade NAT
+	netip.MustParsePrefix("192.0.0.0/24"),    // IETF protocol assignments
+	netip.MustParsePrefix("192.0.2.0/24"),    // documentation
+	netip.MustParsePrefix("198.18.0.0/15"),   // benchmarking
+	netip.MustParsePrefix("198.51.100.0/24"), // documentation
+	netip.MustParsePrefix("203.0.113.0/24"),  // documentation
+	netip.MustParsePrefix("240.0.0.0/4"),     // reserved, and broadcast
+	netip.MustParsePrefix("64:ff9b::/96"),    // NAT64, may map onto private IPv4
+	netip.MustParsePrefix("2001:db8::/32"),   // documentation
+}
+
+// IsPublicAddress reports whether addr is a publicly routable unicast address. Loopback,
+// RFC 1918 and unique local, link-local (which holds cloud metadata endpoints), multicast
+// and reserved addresses are not.
+func IsPublicAddress(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	if !addr.IsValid() || addr.IsUnspecified() || addr.IsLoopback() || addr.IsPrivate() ||
+		addr.IsLinkLocalUnicast() || addr.IsMulticast() || addr.IsInterfaceLocalMulticast() {
+		return false
+	}
+	for _, p := range reservedPrefixes {
+		if p.Contains(addr) {
+			return false
+		}
+	}
+	return true
+}
+
+// CheckWebhookHost resolves host and fails unless every address it resolves to is public.
+// Used when a webhook is registered, to reject bad URLs early; deliveries are checked again
+// at dial time since DNS can change in between.
+func CheckWebhookHost(ctx context.Context, host string) error {
+	if addr, err := netip.ParseAddr(host); err == nil {
+		if !IsPublicAddress(addr) {
+			return ErrPrivateWebhookTarget
+		}
+		return nil
+	}
+	addrs, err := net.DefaultResolver.LookupNetIP(ctx, "ip", host)
+	if err != nil {
+		return err
+	}
+	for _, addr := range addrs {
+		if !IsPublicAddress(addr) {
+			return ErrPrivateWebhookTarget
+		}
+	}
+	return nil
+}
+
+// newWebhookClient returns the HTTP client deliveries are posted with. It only connects to
+// public addresses: the check runs on the address actually dialed, after resolution, so a
+// host that resolves (or is rebound) to a private address is refused. Redirects aren't
+// followed, a 3xx is a failed delivery like any other non-2xx response.
+func newWebhookClient(timeout time.Duration) *http.Client {
+	dialer := &net.Dialer{
+		Timeout: timeout,
+		Control: func(network, address string, _ syscall.RawConn) error {
+			addrPort, err := netip.ParseAddrPort(address)
+			if err != nil || !IsPublicAddress(addrPort.Addr()) {
+				return ErrPrivateWebhookTarget
+			}
+			return nil
+		},
+	}
+	return &http.Client{
+		Timeout: timeout,
+		Transport: &http.Transport{
+			// no Proxy: the dial check would vet the proxy instead of the webhook host
+			DialContext:         dialer.DialContext,
+			TLSHandshakeTimeout: timeout,
+			MaxIdleConnsPerHost: 2,
+			IdleConnTimeout:     90 * time.Second,
+		},
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
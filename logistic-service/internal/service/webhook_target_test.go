@@ -0,0 +1,57 @@
+package service
+
+import (
+	"context"
+	"net/netip"
+	"testing"
+)
+
+func TestIsPublicAddress(t *testing.T) {
+	tests := []struct {
+		addr string
+		want bool
+	}{
+		{"8.8.8.8", true},
+		{"203.0.114.1", true},
+		{"2606:4700:4700::1111", true},
+		{"127.0.0.1", false},
+		{"10.1.2.3", false},
+		{"172.16.0.1", false},
+		{"192.168.1.1", false},
+		{"169.254.169.254", false},
+		{"100.64.0.1", false},
+		{"0.0.0.0", false},
+		{"255.255.255.255", false},
+		{"224.0.0.1", false},
+		{"::1", false},
+		{"::", false},
+		{"fe80::1", false},
+		{"fd00:ec2::254", false},
+		{"::ffff:127.0.0.1", false},
+		{"::ffff:8.8.8.8", true},
+		{"64:ff9b::a00:1", false},
+	}
+	for _, tt := range tests {
+		if got := IsPublicAddress(netip.MustParseAddr(tt.addr)); got != tt.want {
+			t.Errorf("IsPublicAddress(%s) = %v, want %v", tt.addr, got, tt.want)
+		}
+	}
+}
+
+func TestCheckWebhookHostLiteral(t *testing.T) {
+	tests := []struct {
+		host    string
+		wantErr bool
+	}{
+		{"8.8.8.8", false},
+		{"127.0.0.1", true},
+		{"169.254.169.254", true},
+		{"::1", true},
+		{"localhost", true},
+	}
+	for _, tt := range tests {
+		if err := CheckWebhookHost(context.Background(), tt.host); (err != nil) != tt.wantErr {
+			t.Errorf("CheckWebhookHost(%s) = %v, want error %v", tt.host, err, tt.wantErr)
+		}
+	}
+}
@@ -6,7 +6,10 @@ import (
 	"logistic-service/internal/handler"
 	"logistic-service/internal/middleware"
 	"logistic-service/internal/repository"
+	"logistic-service/internal/service"
 	"os"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gin-contrib/cors"
@@ -39,6 +42,105 @@ func main() {
 
 	// Create shipment repository instance
 	shipmentRepo := repository.NewShipmentRepository(db)
+	if err := shipmentRepo.EnsureIndexes(); err != nil {
+		log.Printf("Warning: failed to create shipment indexes: %v", err)
+	}
+	if err := shipmentRepo.RepairNullArrays(); err != nil {
+		log.Printf("Warning: failed to repair shipment arrays: %v", err)
+	}
+	// Customer service search; shipments from before fuzzy name search get their keys in the background
+	var searcher service.ShipmentSearcher = service.NewMongoShipmentSearch(shipmentRepo)
+	go service.BackfillSearchKeys(shipmentRepo)
+
+	// Bulk upload jobs interrupted by a restart are marked failed so they can be resubmitted
+	bulkJobRepo := repository.NewBulkJobRepository(db)
+	if err := bulkJobRepo.EnsureIndexes(); err != nil {
+		log.Printf("Warning: failed to create bulk job indexes: %v", err)
+	}
+	if err := bulkJobRepo.FailInterrupted(); err != nil {
+		log.Printf("Warning: failed to mark interrupted bulk jobs: %v", err)
+	}
+
+	// Address book
+	contactRepo := repository.NewContactRepository(db)
+	if err := contactRepo.EnsureIndexes(); err != nil {
+		log.Printf("Warning: failed to create contact indexes: %v", err)
+	}
+
+	// COD ledger: collected cash owed to merchants until it is remitted
+	codRepo := repository.NewCODRepository(db)
+	if err := codRepo.EnsureIndexes(); err != nil {
+		log.Printf("Warning: failed to create COD ledger indexes: %v", err)
+	}
+
+	// Courier pickups and the per-slot booking counters
+	pickupRepo := repository.NewPickupRepository(db)
+	if err := pickupRepo.EnsureIndexes(); err != nil {
+		log.Printf("Warning: failed to create pickup indexes: %v", err)
+	}
+
+	// Sorting hubs and the manifests (bags) moving parcels between them
+	hubRepo := repository.NewHubRepository(db)
+	if err := hubRepo.EnsureIndexes(); err != nil {
+		log.Printf("Warning: failed to create hub indexes: %v", err)
+	}
+
+	// Courier profiles and the areas they serve
+	driverRepo := repository.NewDriverRepository(db)
+	if err := driverRepo.EnsureIndexes(); err != nil {
+		log.Printf("Warning: failed to create driver indexes: %v", err)
+	}
+
+	// Insurance claims on lost and damaged parcels
+	claimRepo := repository.NewClaimRepository(db)
+	if err := claimRepo.EnsureIndexes(); err != nil {
+		log.Printf("Warning: failed to create claim indexes: %v", err)
+	}
+
+	// Webhook deliveries are sent in the background with retries
+	webhookRepo := repository.NewWebhookRepository(db)
+	if err := webhookRepo.EnsureIndexes(); err != nil {
+		log.Printf("Warning: failed to create webhook indexes: %v", err)
+	}
+	webhooks := service.NewWebhookDispatcher(webhookRepo, service.WebhookConfig{
+		MaxAttempts:  envInt("WEBHOOK_MAX_ATTEMPTS", 8),
+		BaseBackoff:  30 * time.Second,
+		MaxBackoff:   6 * time.Hour,
+		DisableAfter: envInt("WEBHOOK_DISABLE_AFTER", 20),
+		Timeout:      10 * time.Second,
+		PollInterval: 5 * time.Second,
+		Workers:      envInt("WEBHOOK_WORKERS", 4),
+	})
+	go webhooks.Run(context.Background())
+
+	// Proof of delivery photos and signatures, claim evidence
+	blobs, err := service.NewLocalBlobStorage(envString("BLOB_STORAGE_DIR", "data/blobs"))
+	if err != nil {
+		log.Fatalf("Failed to initialize blob storage: %v", err)
+	}
+
+	// Indonesian region dataset for structured addresses; the bundled file is a sample subset
+	regions, err := service.LoadRegionIndex(os.Getenv("REGION_DATA_FILE"))
+	if err != nil {
+		log.Fatalf("Failed to load region dataset: %v", err)
+	}
+	log.Printf("Loaded %d regions", regions.Len())
+	if os.Getenv("REGION_DATA_FILE") == "" {
+		log.Println("Warning: using the bundled sample region dataset, set REGION_DATA_FILE to the full dataset")
+	}
+
+	// Holiday calendar for promised delivery dates; the bundled file lists national holidays only
+	calendar, err := service.LoadDeliveryCalendar(os.Getenv("HOLIDAY_CALENDAR_FILE"), envInt("SLA_CUTOFF_HOUR", 15))
+	if err != nil {
+		log.Fatalf("Failed to load holiday calendar: %v", err)
+	}
+	log.Printf("Loaded %d holidays", calendar.Len())
+
+	// Insurance premium rules and claim limits
+	insurance, err := service.LoadInsurancePolicy(os.Getenv("INSURANCE_POLICY_FILE"))
+	if err != nil {
+		log.Fatalf("Failed to load insurance policy: %v", err)
+	}
 
 	// Connect to RabbitMQ
 	rabbitURL := os.Getenv("RABBITMQ_URL")
@@ -59,9 +161,44 @@ func main() {
 	}
 	defer ch.Close()
 
+	// shipment.updated is a fanout exchange so live tracking can consume it next to the worker
+	if err := service.DeclareShipmentUpdates(ch); err != nil {
+		log.Fatalf("Failed to declare shipment.updated exchange: %v", err)
+	}
+	// Consumed by the notification service, which tells recipients about delivery changes made
+	// on the public endpoint
+	if _, err := ch.QueueDeclare("delivery.preferences_changed", true, false, false, false, nil); err != nil {
+		log.Fatalf("Failed to declare delivery.preferences_changed queue: %v", err)
+	}
+	trackingHub := service.NewTrackingHub()
+	go trackingHub.Run(context.Background(), conn)
+
+	// Running ETA on every shipment update, and the periodic check for missed promised dates
+	go service.NewETATracker(shipmentRepo, calendar).Run(context.Background(), conn)
+	slaInterval := time.Duration(envInt("SLA_CHECK_INTERVAL_MINUTES", 10)) * time.Minute
+	go service.NewSLAMonitor(shipmentRepo, calendar, ch, webhooks, slaInterval).Run(context.Background())
+
+	// Retries the COD ledger entries of deliveries whose ledger write failed
+	codSweepInterval := time.Duration(envInt("COD_LEDGER_SWEEP_MINUTES", 5)) * time.Minute
+	go service.NewCODLedgerSweeper(codRepo, shipmentRepo, ch, codSweepInterval).Run(context.Background())
+
+	// Failed phone_last4 verifications on the public endpoints, counted per tracking number
+	phoneCheckRepo := repository.NewPhoneCheckRepository(db,
+		envInt("PHONE_VERIFY_MAX_FAILURES", 5),
+		time.Duration(envInt("PHONE_VERIFY_LOCKOUT_MINUTES", 60))*time.Minute)
+	if err := phoneCheckRepo.EnsureIndexes(); err != nil {
+		log.Printf("Warning: failed to create phone check indexes: %v", err)
+	}
+
 	// Initialize Gin router with default middleware (logger & recovery)
 	r := gin.Default()
 
+	// X-Forwarded-For is only believed from the proxies listed in TRUSTED_PROXIES, otherwise
+	// clients could pick their own IP and get around the per-IP rate limit
+	if err := r.SetTrustedProxies(envList("TRUSTED_PROXIES")); err != nil {
+		log.Fatalf("Invalid TRUSTED_PROXIES: %v", err)
+	}
+
 	// Setup CORS middleware (adjust for your production environment)
 	r.Use(cors.New(cors.Config{
 		AllowAllOrigins:  true, // Replace with allowed origins in production
@@ -72,14 +209,119 @@ func main() {
 		MaxAge:           12 * time.Hour,
 	}))
 
+	// Public routes (no JWT), registered before the JWT middleware is applied.
+	// Rate limited per client IP to prevent tracking number enumeration; phone_last4 guesses
+	// are limited per tracking number as well.
+	public := r.Group("/public")
+	public.Use(middleware.RateLimitMiddleware(envInt("PUBLIC_TRACK_RATE_LIMIT", 30), time.Minute))
+	public.GET("/track/:trackingNumber", handler.PublicTrackShipment(shipmentRepo, phoneCheckRepo))
+	public.POST("/track/:trackingNumber/delivery-preferences", handler.UpdateDeliveryPreferences(shipmentRepo, phoneCheckRepo, ch, webhooks))
+
+	// Live tracking streams accept the JWT in Sec-WebSocket-Protocol as well, since WebSocket
+	// clients in browsers can't set the Authorization header
+	stream := r.Group("/", middleware.StreamAuthMiddleware())
+	stream.GET("/shipments/:trackingNumber/stream", handler.StreamShipment(shipmentRepo, trackingHub))
+	stream.GET("/ws/shipments", handler.StreamShipmentsWS(shipmentRepo, trackingHub, envList("STREAM_ALLOWED_ORIGINS")))
+
 	// Apply JWT authentication middleware to protect all routes below
 	r.Use(middleware.JWTAuthMiddleware())
 
 	// Register routes with injected repository and RabbitMQ channel
-	r.POST("/shipments", handler.CreateShipment(shipmentRepo, ch))
-	r.PATCH("/shipments/:trackingNumber/status", handler.UpdateShipmentStatus(shipmentRepo, ch))
+	r.POST("/shipments", handler.CreateShipment(shipmentRepo, contactRepo, regions, calendar, insurance, ch, webhooks))
+	r.PATCH("/shipments/:trackingNumber/status", handler.UpdateShipmentStatus(shipmentRepo, codRepo, ch, webhooks))
+	r.PATCH("/shipments/:trackingNumber/parcels/:parcelTrackingNumber/status", handler.UpdateParcelStatus(shipmentRepo, codRepo, ch, webhooks))
 	r.GET("/shipments/:trackingNumber", handler.TrackShipment(shipmentRepo))
+	r.PATCH("/shipments/:trackingNumber", handler.EditShipment(shipmentRepo, regions, calendar, ch, webhooks))
 	r.GET("/shipments", handler.GetShipments(shipmentRepo))
+	r.GET("/shipments/export", handler.ExportShipments(shipmentRepo))
+	r.GET("/search/shipments", handler.SearchShipments(searcher))
+	r.POST("/shipments/:trackingNumber/cancel", handler.CancelShipment(shipmentRepo, ch, webhooks))
+	r.GET("/cancellation-reasons", handler.GetCancelReasons())
+	r.GET("/service-levels", handler.GetServiceLevels())
+	r.GET("/sla/breaches", handler.ListSLABreaches(shipmentRepo))
+	r.POST("/shipments/:trackingNumber/return", handler.CreateReturn(shipmentRepo, calendar, ch, webhooks))
+	r.GET("/return-reasons", handler.GetReturnReasons())
+	r.POST("/shipments/:trackingNumber/attempts", handler.RecordDeliveryAttempt(shipmentRepo, calendar, ch, webhooks))
+	r.GET("/delivery-attempt-reasons", handler.GetAttemptFailureReasons())
+	r.POST("/shipments/bulk", handler.CreateBulkShipments(bulkJobRepo, shipmentRepo, regions, calendar, insurance, ch, webhooks))
+	r.GET("/shipments/bulk/template", handler.GetBulkTemplate())
+	r.GET("/shipments/bulk/:jobId", handler.GetBulkJob(bulkJobRepo))
+	r.GET("/shipments/bulk/:jobId/errors", handler.GetBulkJobErrors(bulkJobRepo))
+	r.GET("/shipments/:trackingNumber/label", handler.GetShipmentLabel(shipmentRepo))
+	r.POST("/shipments/labels", handler.GetShipmentLabels(shipmentRepo))
+	r.POST("/shipments/:trackingNumber/pod", handler.CaptureProofOfDelivery(shipmentRepo, blobs, ch, webhooks))
+	r.GET("/shipments/:trackingNumber/pod", handler.GetProofOfDelivery(shipmentRepo))
+	r.GET("/shipments/:trackingNumber/pod/photo", handler.GetProofOfDeliveryImage(shipmentRepo, blobs, "photo"))
+	r.GET("/shipments/:trackingNumber/pod/signature", handler.GetProofOfDeliveryImage(shipmentRepo, blobs, "signature"))
+
+	r.POST("/pickups", handler.CreatePickup(pickupRepo, shipmentRepo, ch, webhooks))
+	r.GET("/pickups", handler.ListPickups(pickupRepo))
+	r.GET("/pickups/slots", handler.GetPickupSlots(pickupRepo, shipmentRepo))
+	r.PUT("/pickups/capacity", handler.SetPickupCapacity(pickupRepo))
+	r.GET("/pickups/:id", handler.GetPickup(pickupRepo))
+	r.PATCH("/pickups/:id/status", handler.UpdatePickupStatus(pickupRepo, shipmentRepo, ch, webhooks))
+
+	r.POST("/hubs", handler.CreateHub(hubRepo, regions))
+	r.GET("/hubs", handler.ListHubs(hubRepo))
+	r.GET("/hubs/:code", handler.GetHub(hubRepo))
+	r.PATCH("/hubs/:code", handler.UpdateHub(hubRepo, regions))
+	r.POST("/hubs/:code/scans", handler.ScanAtHub(hubRepo, shipmentRepo, ch, webhooks))
+
+	r.POST("/manifests", handler.CreateManifest(hubRepo))
+	r.GET("/manifests", handler.ListManifests(hubRepo))
+	r.GET("/manifests/:id", handler.GetManifest(hubRepo))
+	r.POST("/manifests/:id/items", handler.AddManifestItems(hubRepo, shipmentRepo, ch, webhooks))
+	r.DELETE("/manifests/:id/items/:trackingNumber", handler.RemoveManifestItem(hubRepo, shipmentRepo, ch, webhooks))
+	r.POST("/manifests/:id/close", handler.CloseManifest(hubRepo))
+	r.POST("/manifests/:id/dispatch", handler.DispatchManifest(hubRepo, shipmentRepo, ch, webhooks))
+	r.POST("/manifests/:id/receive", handler.ReceiveManifest(hubRepo, shipmentRepo, ch, webhooks))
+
+	r.PUT("/driver/profile", handler.SaveDriverProfile(driverRepo))
+	r.GET("/driver/profile", handler.GetDriverProfile(driverRepo))
+	r.GET("/driver/tasks", handler.GetDriverTasks(driverRepo, pickupRepo, shipmentRepo))
+	r.POST("/driver/route", handler.OptimizeDriverRoute(driverRepo, pickupRepo, shipmentRepo))
+	r.PATCH("/driver/shipments/:trackingNumber/status", handler.UpdateDriverShipmentStatus(shipmentRepo, codRepo, ch, webhooks))
+	r.GET("/drivers", handler.ListDrivers(driverRepo))
+	r.POST("/drivers/auto-assign", handler.AutoAssignDrivers(driverRepo, pickupRepo, shipmentRepo, ch, webhooks))
+	r.GET("/drivers/:id", handler.GetDriver(driverRepo))
+	r.PATCH("/drivers/:id", handler.UpdateDriver(driverRepo, regions))
+	r.POST("/shipments/:trackingNumber/driver", handler.AssignShipmentDriver(driverRepo, shipmentRepo, ch, webhooks))
+
+	r.GET("/courier-rates", handler.GetCourierRates(shipmentRepo, regions))
+	r.GET("/regions/provinces", handler.ListRegions(regions, service.RegionProvince))
+	r.GET("/regions/cities", handler.ListRegions(regions, service.RegionCity))
+	r.GET("/regions/districts", handler.ListRegions(regions, service.RegionDistrict))
+	r.GET("/regions/subdistricts", handler.ListRegions(regions, service.RegionSubdistrict))
+	r.GET("/regions/:code", handler.GetRegion(regions))
+
+	r.POST("/contacts", handler.CreateContact(contactRepo, regions))
+	r.GET("/contacts", handler.ListContacts(contactRepo))
+	r.GET("/contacts/default-sender", handler.GetDefaultSender(contactRepo))
+	r.GET("/contacts/:id", handler.GetContact(contactRepo))
+	r.PATCH("/contacts/:id", handler.UpdateContact(contactRepo, regions))
+	r.DELETE("/contacts/:id", handler.DeleteContact(contactRepo))
+
+	r.GET("/cod/ledger", handler.ListCODLedger(codRepo))
+	r.GET("/cod/remittances", handler.ListCODRemittances(codRepo))
+	r.GET("/cod/remittances/:id", handler.GetCODRemittance(codRepo))
+	r.POST("/cod/remittances", handler.SettleCODRemittance(codRepo, ch))
+
+	r.GET("/insurance/policy", handler.GetInsurancePolicy(insurance))
+	r.GET("/insurance/quote", handler.QuoteInsurance(insurance))
+	r.POST("/shipments/:trackingNumber/claims", handler.FileClaim(claimRepo, shipmentRepo, insurance, blobs, ch, webhooks))
+	r.GET("/claims", handler.ListClaims(claimRepo))
+	r.GET("/claims/:id", handler.GetClaim(claimRepo))
+	r.PATCH("/claims/:id/status", handler.UpdateClaimStatus(claimRepo, ch, webhooks))
+	r.POST("/claims/:id/evidence", handler.AddClaimEvidence(claimRepo, blobs, ch, webhooks))
+	r.GET("/claims/:id/evidence/:evidenceId", handler.GetClaimEvidence(claimRepo, blobs))
+
+	r.POST("/webhooks", handler.CreateWebhook(webhookRepo))
+	r.GET("/webhooks", handler.ListWebhooks(webhookRepo))
+	r.GET("/webhooks/:id", handler.GetWebhook(webhookRepo))
+	r.PATCH("/webhooks/:id", handler.UpdateWebhook(webhookRepo))
+	r.DELETE("/webhooks/:id", handler.DeleteWebhook(webhookRepo))
+	r.GET("/webhooks/:id/deliveries", handler.ListWebhookDeliveries(webhookRepo))
+	r.POST("/webhooks/:id/deliveries/:deliveryId/redeliver", handler.RedeliverWebhook(webhookRepo, webhooks))
 
 	// Start the HTTP server on port 8082
 	log.Println("Starting logistics service on :8082")
@@ -87,3 +329,31 @@ func main() {
 		log.Fatalf("Failed to run server: %v", err)
 	}
 }
+
+// envInt reads an integer environment variable, falling back to def when unset or invalid
+func envInt(key string, def int) int {
+	v, err := strconv.Atoi(os.Getenv(key))
+	if err != nil || v <= 0 {
+		return def
+	}
+	return v
+}
+
+// envList reads a comma separated environment variable, nil when unset
+func envList(key string) []string {
+	var values []string
+	for _, v := range strings.Split(os.Getenv(key), ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			values = append(values, v)
+		}
+	}
+	return values
+}
+
+// envString reads a string environment variable, falling back to def when unset
+func envString(key, def string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return def
+}
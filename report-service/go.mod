@@ -0,0 +1,3 @@
+module report-service
+
+go 1.22.4
@@ -0,0 +1,32 @@
+package handler
+
+import (
+	"net/http"
+	"report-service/internal/service"
+
+	"github.com/gin-gonic/gin"
+	jwt "github.com/golang-jwt/jwt/v5"
+)
+
+// currentPrincipal reads the JWT claims stored by JWTAuthMiddleware.
+// It writes a 401 response and returns false when the claims are missing or invalid.
+func currentPrincipal(c *gin.Context) (*service.Principal, bool) {
+	claimsRaw, exists := c.Get("claims")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return nil, false
+	}
+
+	claims, ok := claimsRaw.(jwt.MapClaims)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid token claims"})
+		return nil, false
+	}
+
+	principal, err := service.PrincipalFromClaims(claims)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return nil, false
+	}
+	return principal, true
+}
@@ -0,0 +1,82 @@
+package handler
+
+import (
+	"log"
+	"net/http"
+	"report-service/internal/model"
+	"report-service/internal/repository"
+	"report-service/internal/service"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetShipmentReport handles GET /reports/shipments
+// Counts the shipments created between from and to (default the last 30 days), with their
+// delivery success rate and average transit time, in total and by group_by: day, status,
+// courier, merchant or route. Status, courier, merchant and route list the largest groups
+// first, up to limit (default 20, max 100). Ops see all merchants and can filter by user_id;
+// everyone else only sees their own shipments.
+func GetShipmentReport(repo *repository.ReportRepository) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		principal, ok := currentPrincipal(c)
+		if !ok {
+			return
+		}
+
+		from, to, err := service.ParseReportRange(c.Query("from"), c.Query("to"), time.Now())
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		query := repository.ReportQuery{
+			From:         from,
+			To:           to,
+			LogisticName: strings.TrimSpace(c.Query("logistic_name")),
+			UserID:       strings.TrimSpace(c.Query("user_id")),
+			Status:       strings.TrimSpace(c.Query("status")),
+		}
+		if principal.Role != service.RoleOps {
+			query.UserID = principal.UserID
+		}
+
+		groupBy := c.Query("group_by")
+		group, ok := repository.ReportGroups[groupBy]
+		if groupBy != "" && !ok {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "group_by must be day, status, courier, merchant or route"})
+			return
+		}
+		limit, err := strconv.Atoi(c.DefaultQuery("limit", "20"))
+		if err != nil || limit < 1 || limit > 100 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be between 1 and 100"})
+			return
+		}
+
+		totals, err := repo.Totals(query)
+		if err != nil {
+			log.Printf("[GetShipmentReport] Totals error: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to build report"})
+			return
+		}
+		service.CompleteReportRow(totals)
+		report := model.Report{From: from, To: to, GroupBy: groupBy, Totals: *totals, Rows: []model.ReportRow{}}
+		if groupBy != "" {
+			rows, err := repo.Grouped(query, group, limit)
+			if err != nil {
+				log.Printf("[GetShipmentReport] Grouped error: %v", err)
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to build report"})
+				return
+			}
+			for i := range rows {
+				service.CompleteReportRow(&rows[i])
+			}
+			report.Rows = rows
+		}
+		if report.RefreshedAt, err = repo.RefreshedAt(); err != nil {
+			log.Printf("[GetShipmentReport] RefreshedAt error: %v", err)
+		}
+		c.JSON(http.StatusOK, report)
+	}
+}
@@ -0,0 +1,37 @@
+package model
+
+import "time"
+
+// ReportRow is one group of a shipment report. Only the columns of the grouping are set,
+// e.g. Day for group_by=day or Origin and Destination for group_by=route; the totals row has none.
+type ReportRow struct {
+	Day          string `gorm:"column:day" json:"day,omitempty"`                     // YYYY-MM-DD, WIB
+	Status       string `gorm:"column:status" json:"status,omitempty"`               // Current status of the shipments
+	LogisticName string `gorm:"column:logistic_name" json:"logistic_name,omitempty"` // Courier company
+	UserID       string `gorm:"column:user_id" json:"user_id,omitempty"`             // Merchant
+	Origin       string `gorm:"column:origin" json:"origin,omitempty"`
+	Destination  string `gorm:"column:destination" json:"destination,omitempty"`
+
+	Shipments int64 `gorm:"column:shipments" json:"shipments"`
+	Delivered int64 `gorm:"column:delivered" json:"delivered"`
+	Failed    int64 `gorm:"column:failed" json:"failed"` // Returned to the sender
+	Cancelled int64 `gorm:"column:cancelled" json:"cancelled"`
+
+	// SuccessRate is delivered / (delivered + failed), nil until a shipment has finished
+	SuccessRate *float64 `gorm:"-" json:"success_rate"`
+	// AvgTransitHours runs from pickup to delivery, nil until a shipment was delivered
+	AvgTransitHours *float64 `gorm:"-" json:"avg_transit_hours"`
+
+	TransitSeconds float64 `gorm:"column:transit_seconds" json:"-"`
+	TransitCount   int64   `gorm:"column:transit_count" json:"-"`
+}
+
+// Report is the response of a shipment report
+type Report struct {
+	From        string      `json:"from"`
+	To          string      `json:"to"`
+	GroupBy     string      `json:"group_by,omitempty"`
+	RefreshedAt *time.Time  `json:"refreshed_at"` // Data changed after this isn't counted yet
+	Totals      ReportRow   `json:"totals"`
+	Rows        []ReportRow `json:"rows"`
+}
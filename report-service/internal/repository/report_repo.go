@@ -0,0 +1,120 @@
+package repository
+
+import (
+	"errors"
+	"time"
+
+	"report-service/internal/model"
+
+	"gorm.io/gorm"
+)
+
+// shipmentsDailyView is the materialized view maintained by the worker that shipment reports
+// are read from, one row per day (WIB), merchant, courier company, route and status
+const shipmentsDailyView = "report_shipments_daily"
+
+// reportMetrics adds up the rows of a group
+const reportMetrics = `COALESCE(SUM(shipments), 0)::bigint AS shipments,
+COALESCE(SUM(shipments) FILTER (WHERE status = 'delivered'), 0)::bigint AS delivered,
+COALESCE(SUM(shipments) FILTER (WHERE status IN ('return_to_sender', 'returned')), 0)::bigint AS failed,
+COALESCE(SUM(shipments) FILTER (WHERE status = 'cancelled'), 0)::bigint AS cancelled,
+COALESCE(SUM(transit_seconds), 0)::float8 AS transit_seconds,
+COALESCE(SUM(transit_count), 0)::bigint AS transit_count`
+
+// ReportGroup is a grouping of a shipment report
+type ReportGroup struct {
+	Select []string // columns of the group, named like the ReportRow fields
+	Group  []string
+	Order  string
+	Top    bool // the largest groups are listed first and the rows are limited
+}
+
+// ReportGroups are the values of group_by
+var ReportGroups = map[string]ReportGroup{
+	"day":      {Select: []string{"to_char(day, 'YYYY-MM-DD') AS day"}, Group: []string{"day"}, Order: "day"},
+	"status":   {Select: []string{"status"}, Group: []string{"status"}, Order: "shipments DESC, status", Top: true},
+	"courier":  {Select: []string{"logistic_name"}, Group: []string{"logistic_name"}, Order: "shipments DESC, logistic_name", Top: true},
+	"merchant": {Select: []string{"user_id"}, Group: []string{"user_id"}, Order: "shipments DESC, user_id", Top: true},
+	"route": {
+		Select: []string{"origin", "destination"},
+		Group:  []string{"origin", "destination"},
+		Order:  "shipments DESC, origin, destination",
+		Top:    true,
+	},
+}
+
+// ReportQuery selects the shipments of a report. From and To are days (YYYY-MM-DD, WIB) the
+// shipments were created on, both included. Empty filters match everything.
+type ReportQuery struct {
+	From         string
+	To           string
+	LogisticName string
+	UserID       string
+	Status       string
+}
+
+// ReportRepository reads the report views from Postgres. It never writes; the views are
+// created and refreshed by the worker.
+type ReportRepository struct {
+	db *gorm.DB
+}
+
+// NewReportRepository creates a new report repository
+func NewReportRepository(db *gorm.DB) *ReportRepository {
+	return &ReportRepository{db: db}
+}
+
+func (r *ReportRepository) shipments(q ReportQuery) *gorm.DB {
+	tx := r.db.Table(shipmentsDailyView).Where("day BETWEEN ? AND ?", q.From, q.To)
+	if q.LogisticName != "" {
+		tx = tx.Where("LOWER(logistic_name) = LOWER(?)", q.LogisticName)
+	}
+	if q.UserID != "" {
+		tx = tx.Where("user_id = ?", q.UserID)
+	}
+	if q.Status != "" {
+		tx = tx.Where("status = ?", q.Status)
+	}
+	return tx
+}
+
+// Totals adds up all shipments matching q
+func (r *ReportRepository) Totals(q ReportQuery) (*model.ReportRow, error) {
+	var row model.ReportRow
+	if err := r.shipments(q).Select(reportMetrics).Scan(&row).Error; err != nil {
+		return nil, err
+	}
+	return &row, nil
+}
+
+// Grouped adds up the shipments matching q by group. limit applies to top groups only.
+func (r *ReportRepository) Grouped(q ReportQuery, group ReportGroup, limit int) ([]model.ReportRow, error) {
+	tx := r.shipments(q).Select(append(append([]string{}, group.Select...), reportMetrics))
+	for _, column := range group.Group {
+		tx = tx.Group(column)
+	}
+	tx = tx.Order(group.Order)
+	if group.Top {
+		tx = tx.Limit(limit)
+	}
+	rows := []model.ReportRow{}
+	if err := tx.Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+	return rows, nil
+}
+
+// RefreshedAt returns when the shipment report view was last refreshed, nil if never
+func (r *ReportRepository) RefreshedAt() (*time.Time, error) {
+	var refresh struct {
+		RefreshedAt time.Time
+	}
+	err := r.db.Table("report_refreshes").Select("refreshed_at").Where("view = ?", shipmentsDailyView).Take(&refresh).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &refresh.RefreshedAt, nil
+}
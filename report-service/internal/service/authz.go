@@ -0,0 +1,36 @@
+package service
+
+import (
+	"errors"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Roles issued by auth-service in the "role" claim.
+// Tokens without a role claim are treated as customers.
+const (
+	RoleCustomer = "customer"
+	RoleCourier  = "courier"
+	RoleOps      = "ops"
+)
+
+// Principal is the authenticated caller extracted from the JWT claims.
+type Principal struct {
+	UserID   string
+	Username string
+	Role     string
+}
+
+// PrincipalFromClaims builds a Principal from the claims stored by JWTAuthMiddleware.
+func PrincipalFromClaims(claims jwt.MapClaims) (*Principal, error) {
+	userID, ok := claims["user_id"].(string)
+	if !ok || userID == "" {
+		return nil, errors.New("invalid user_id in claims")
+	}
+	username, _ := claims["username"].(string)
+	role, _ := claims["role"].(string)
+	if role == "" {
+		role = RoleCustomer
+	}
+	return &Principal{UserID: userID, Username: username, Role: role}, nil
+}
@@ -0,0 +1,59 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"report-service/internal/model"
+	"time"
+)
+
+// Limits of the date range of a report
+const (
+	DefaultReportDays = 30
+	MaxReportDays     = 366
+)
+
+// reportZone is the zone report days are counted in, WIB like the delivery dates of logistic-service
+var reportZone = time.FixedZone("WIB", 7*60*60)
+
+// ParseReportRange validates a report date range (YYYY-MM-DD, both included). An empty to is
+// today and an empty from is DefaultReportDays days up to and including to.
+func ParseReportRange(from, to string, now time.Time) (string, string, error) {
+	end := now.In(reportZone)
+	if to != "" {
+		t, err := time.ParseInLocation("2006-01-02", to, reportZone)
+		if err != nil {
+			return "", "", errors.New("to must be a date (YYYY-MM-DD)")
+		}
+		end = t
+	}
+	start := end.AddDate(0, 0, 1-DefaultReportDays)
+	if from != "" {
+		t, err := time.ParseInLocation("2006-01-02", from, reportZone)
+		if err != nil {
+			return "", "", errors.New("from must be a date (YYYY-MM-DD)")
+		}
+		start = t
+	}
+	startDay, endDay := start.Format("2006-01-02"), end.Format("2006-01-02")
+	if startDay > endDay {
+		return "", "", errors.New("from must not be after to")
+	}
+	if end.Sub(start) >= MaxReportDays*24*time.Hour {
+		return "", "", fmt.Errorf("a report covers at most %d days", MaxReportDays)
+	}
+	return startDay, endDay, nil
+}
+
+// CompleteReportRow derives the success rate and average transit time of a report row from its counts
+func CompleteReportRow(row *model.ReportRow) {
+	row.SuccessRate, row.AvgTransitHours = nil, nil
+	if finished := row.Delivered + row.Failed; finished > 0 {
+		rate := float64(row.Delivered) / float64(finished)
+		row.SuccessRate = &rate
+	}
+	if row.TransitCount > 0 {
+		hours := row.TransitSeconds / float64(row.TransitCount) / 3600
+		row.AvgTransitHours = &hours
+	}
+}
@@ -0,0 +1,58 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"report-service/internal/model"
+)
+
+func TestParseReportRange(t *testing.T) {
+	now := time.Date(2026, time.October, 19, 10, 0, 0, 0, reportZone)
+	tests := []struct {
+		name             string
+		from, to         string
+		now              time.Time
+		wantFrom, wantTo string
+		wantErr          bool
+	}{
+		{"default is the last 30 days", "", "", now, "2026-09-20", "2026-10-19", false},
+		{"default ends at to", "", "2026-10-01", now, "2026-09-02", "2026-10-01", false},
+		{"from up to today", "2026-10-01", "", now, "2026-10-01", "2026-10-19", false},
+		{"one day", "2026-10-19", "2026-10-19", now, "2026-10-19", "2026-10-19", false},
+		{"today is counted in WIB", "", "", time.Date(2026, time.October, 19, 20, 0, 0, 0, time.UTC), "2026-09-21", "2026-10-20", false},
+		{"366 days", "2025-10-19", "2026-10-19", now, "2025-10-19", "2026-10-19", false},
+		{"366 days up to today, late in the day", "2025-10-19", "", time.Date(2026, time.October, 19, 23, 30, 0, 0, reportZone), "2025-10-19", "2026-10-19", false},
+		{"367 days", "2025-10-18", "2026-10-19", now, "", "", true},
+		{"367 days up to today", "2025-10-18", "", now, "", "", true},
+		{"from after to", "2026-10-02", "2026-10-01", now, "", "", true},
+		{"bad from", "19-10-2026", "", now, "", "", true},
+		{"bad to", "", "2026-13-01", now, "", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			from, to, err := ParseReportRange(tt.from, tt.to, tt.now)
+			if (err != nil) != tt.wantErr || from != tt.wantFrom || to != tt.wantTo {
+				t.Errorf("ParseReportRange(%q, %q) = %q, %q, %v; want %q, %q, error %v",
+					tt.from, tt.to, from, to, err, tt.wantFrom, tt.wantTo, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestCompleteReportRow(t *testing.T) {
+	row := &model.ReportRow{Delivered: 3, Failed: 1, TransitCount: 2, TransitSeconds: 2 * 36 * 3600}
+	CompleteReportRow(row)
+	if row.SuccessRate == nil || *row.SuccessRate != 0.75 {
+		t.Errorf("SuccessRate = %v, want 0.75", row.SuccessRate)
+	}
+	if row.AvgTransitHours == nil || *row.AvgTransitHours != 36 {
+		t.Errorf("AvgTransitHours = %v, want 36", row.AvgTransitHours)
+	}
+
+	empty := &model.ReportRow{Shipments: 5}
+	CompleteReportRow(empty)
+	if empty.SuccessRate != nil || empty.AvgTransitHours != nil {
+		t.Errorf("a row without finished shipments got %v, %v; want nil, nil", empty.SuccessRate, empty.AvgTransitHours)
+	}
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"log"
+	"os"
+	"report-service/internal/handler"
+	"report-service/internal/middleware"
+	"report-service/internal/repository"
+	"time"
+
+	"github.com/gin-contrib/cors"
+	"github.com/gin-gonic/gin"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+func main() {
+	// Reports only read, so a Postgres replica can serve them; the views are maintained by the worker
+	dsn := os.Getenv("REPORTDB_URL")
+	if dsn == "" {
+		dsn = os.Getenv("MASTERDB_URL")
+	}
+	if dsn == "" {
+		log.Fatal("REPORTDB_URL or MASTERDB_URL environment variable not set")
+	}
+
+	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{})
+	if err != nil {
+		log.Fatalf("Failed to connect to Postgres: %v", err)
+	}
+	reportRepo := repository.NewReportRepository(db)
+
+	// Initialize Gin router with default middleware (logger & recovery)
+	r := gin.Default()
+
+	// Setup CORS middleware (adjust for your production environment)
+	r.Use(cors.New(cors.Config{
+		AllowAllOrigins:  true, // Replace with allowed origins in production
+		AllowMethods:     []string{"GET", "OPTIONS"},
+		AllowHeaders:     []string{"Origin", "Content-Type", "Accept", "Authorization"},
+		ExposeHeaders:    []string{"Content-Length"},
+		AllowCredentials: true,
+		MaxAge:           12 * time.Hour,
+	}))
+
+	// Apply JWT authentication middleware to protect all routes below
+	r.Use(middleware.JWTAuthMiddleware())
+
+	r.GET("/reports/shipments", handler.GetShipmentReport(reportRepo))
+
+	// Start the HTTP server on port 8084
+	log.Println("Starting report service on :8084")
+	if err := r.Run(":8084"); err != nil {
+		log.Fatalf("Failed to run server: %v", err)
+	}
+}
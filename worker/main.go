@@ -14,6 +14,7 @@ import (
 	amqp "github.com/rabbitmq/amqp091-go"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 // User represents user model in Postgres
@@ -28,32 +29,188 @@ type User struct {
 
 // ShipmentItem represents a shipment item in Postgres
 type ShipmentItem struct {
-	ID         uint   `gorm:"primaryKey;autoIncrement"`
-	ShipmentID string `gorm:"index;not null"`
-	Name       string
-	Quantity   int
-	Weight     float64
+	ID            uint   `gorm:"primaryKey;autoIncrement"`
+	ShipmentID    string `gorm:"index;not null"`
+	Name          string
+	Quantity      int
+	Weight        float64
+	DeclaredValue int64 // Value per item in rupiah
 }
 
-// Shipment represents shipment model with related items
+// ShipmentParcel represents one box of a multi-parcel shipment in Postgres
+type ShipmentParcel struct {
+	ID             uint   `gorm:"primaryKey;autoIncrement"`
+	ShipmentID     string `gorm:"index;not null"`
+	PieceNumber    int
+	TrackingNumber string  `gorm:"uniqueIndex"`
+	Weight         float64 // Kilograms
+	Length         float64 // Centimetres
+	Width          float64
+	Height         float64
+	Status         string `gorm:"index"`
+	UpdatedAt      time.Time
+}
+
+// Shipment represents shipment model with related items and parcels
 type Shipment struct {
-	ID               string         `gorm:"primaryKey;column:id" json:"id"`
-	LogisticName     string         `gorm:"column:logistic_name" json:"logistic_name"`
-	TrackingNumber   string         `gorm:"column:tracking_number" json:"tracking_number"`
-	Status           string         `gorm:"column:status" json:"status"`
-	Origin           string         `gorm:"column:origin" json:"origin"`
-	Destination      string         `gorm:"column:destination" json:"destination"`
-	Notes            string         `gorm:"column:notes" json:"notes"`
-	UserID           string         `gorm:"column:user_id" json:"user_id"`
-	CreatedAt        time.Time      `gorm:"column:created_at" json:"created_at"`
-	UpdatedAt        time.Time      `gorm:"column:updated_at" json:"updated_at"`
-	SenderName       string         `gorm:"column:sender_name" json:"sender_name"`
-	SenderPhone      string         `gorm:"column:sender_phone" json:"sender_phone"`
-	SenderAddress    string         `gorm:"column:sender_address" json:"sender_address"`
-	RecipientName    string         `gorm:"column:recipient_name" json:"recipient_name"`
-	RecipientPhone   string         `gorm:"column:recipient_phone" json:"recipient_phone"`
-	RecipientAddress string         `gorm:"column:recipient_address" json:"recipient_address"`
-	Items            []ShipmentItem `gorm:"foreignKey:ShipmentID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;" json:"items"`
+	ID               string           `gorm:"primaryKey;column:id" json:"id"`
+	LogisticName     string           `gorm:"column:logistic_name" json:"logistic_name"`
+	TrackingNumber   string           `gorm:"column:tracking_number" json:"tracking_number"`
+	Status           string           `gorm:"column:status" json:"status"`
+	Origin           string           `gorm:"column:origin" json:"origin"`
+	Destination      string           `gorm:"column:destination" json:"destination"`
+	OriginCode       string           `gorm:"index;column:origin_code" json:"origin_code"`
+	DestinationCode  string           `gorm:"index;column:destination_code" json:"destination_code"`
+	Notes            string           `gorm:"column:notes" json:"notes"`
+	UserID           string           `gorm:"column:user_id" json:"user_id"`
+	CreatedAt        time.Time        `gorm:"column:created_at" json:"created_at"`
+	UpdatedAt        time.Time        `gorm:"column:updated_at" json:"updated_at"`
+	SenderName       string           `gorm:"column:sender_name" json:"sender_name"`
+	SenderPhone      string           `gorm:"column:sender_phone" json:"sender_phone"`
+	SenderAddress    string           `gorm:"column:sender_address" json:"sender_address"`
+	RecipientName    string           `gorm:"column:recipient_name" json:"recipient_name"`
+	RecipientPhone   string           `gorm:"column:recipient_phone" json:"recipient_phone"`
+	RecipientAddress string           `gorm:"column:recipient_address" json:"recipient_address"`
+	CancelReason     string           `gorm:"column:cancel_reason" json:"cancel_reason"`
+	CancelNote       string           `gorm:"column:cancel_note" json:"cancel_note"`
+	CancelledBy      string           `gorm:"column:cancelled_by" json:"cancelled_by"`
+	CancelledAt      *time.Time       `gorm:"column:cancelled_at" json:"cancelled_at"`
+	CODAmount        int64            `gorm:"column:cod_amount" json:"cod_amount"`
+	CODCurrency      string           `gorm:"column:cod_currency" json:"cod_currency"`
+	ReturnOf         string           `gorm:"index;column:return_of" json:"return_of"` // Original tracking number of return shipments
+	ReturnType       string           `gorm:"column:return_type" json:"return_type"`
+	DriverID         string           `gorm:"index;column:driver_id" json:"driver_id"` // Courier assigned to deliver it
+	ServiceLevel     string           `gorm:"column:service_level" json:"service_level"`
+	PromisedDate     string           `gorm:"index;column:promised_date" json:"promised_date"` // YYYY-MM-DD, WIB
+	ETA              string           `gorm:"column:eta" json:"eta"`
+	SLABreachedAt    *time.Time       `gorm:"index;column:sla_breached_at" json:"sla_breached_at"`
+	PickedUpAt       *time.Time       `gorm:"column:picked_up_at" json:"picked_up_at"` // First picked_up event
+	DeliveredAt      *time.Time       `gorm:"column:delivered_at" json:"delivered_at"` // Last delivered event
+	DeclaredValue    int64            `gorm:"column:declared_value" json:"declared_value"`
+	InsuredValue     int64            `gorm:"column:insured_value" json:"insured_value"` // 0 when not insured
+	InsurancePremium int64            `gorm:"column:insurance_premium" json:"insurance_premium"`
+	Items            []ShipmentItem   `gorm:"foreignKey:ShipmentID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;" json:"items"`
+	Parcels          []ShipmentParcel `gorm:"foreignKey:ShipmentID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;" json:"parcels"`
+}
+
+// CODLedgerEntry mirrors the COD collected for one delivered shipment (cod.collected)
+type CODLedgerEntry struct {
+	ID             string     `gorm:"primaryKey;column:id" json:"id"`
+	MerchantID     string     `gorm:"index;column:merchant_id" json:"merchant_id"`
+	TrackingNumber string     `gorm:"uniqueIndex;column:tracking_number" json:"tracking_number"`
+	Currency       string     `gorm:"column:currency" json:"currency"`
+	Amount         int64      `gorm:"column:amount" json:"amount"`
+	Fee            int64      `gorm:"column:fee" json:"fee"`
+	NetAmount      int64      `gorm:"column:net_amount" json:"net_amount"`
+	Status         string     `gorm:"index;column:status" json:"status"`
+	RemittanceID   *string    `gorm:"index;column:remittance_id" json:"remittance_id"`
+	CollectedBy    string     `gorm:"column:collected_by" json:"collected_by"`
+	CollectedAt    time.Time  `gorm:"column:collected_at" json:"collected_at"`
+	SettledAt      *time.Time `gorm:"column:settled_at" json:"settled_at"`
+}
+
+// CODRemittance mirrors a payout of pending COD entries to a merchant (cod.remitted)
+type CODRemittance struct {
+	ID          string     `gorm:"primaryKey;column:id" json:"id"`
+	MerchantID  string     `gorm:"index;column:merchant_id" json:"merchant_id"`
+	Currency    string     `gorm:"column:currency" json:"currency"`
+	EntryCount  int        `gorm:"column:entry_count" json:"entry_count"`
+	GrossAmount int64      `gorm:"column:gross_amount" json:"gross_amount"`
+	FeeAmount   int64      `gorm:"column:fee_amount" json:"fee_amount"`
+	NetAmount   int64      `gorm:"column:net_amount" json:"net_amount"`
+	Reference   string     `gorm:"column:reference" json:"reference"`
+	SettledBy   string     `gorm:"column:settled_by" json:"settled_by"`
+	SettledAt   *time.Time `gorm:"column:settled_at" json:"settled_at"`
+}
+
+// Claim mirrors an insurance claim on a shipment (claim.updated)
+type Claim struct {
+	ID              string     `gorm:"primaryKey;column:id" json:"id"`
+	TrackingNumber  string     `gorm:"uniqueIndex;column:tracking_number" json:"tracking_number"`
+	MerchantID      string     `gorm:"index;column:merchant_id" json:"merchant_id"`
+	LogisticName    string     `gorm:"column:logistic_name" json:"logistic_name"`
+	Type            string     `gorm:"column:type" json:"type"`
+	Status          string     `gorm:"index;column:status" json:"status"`
+	Currency        string     `gorm:"column:currency" json:"currency"`
+	ClaimedAmount   int64      `gorm:"column:claimed_amount" json:"claimed_amount"`
+	Insured         bool       `gorm:"column:insured" json:"insured"`
+	PayoutLimit     int64      `gorm:"column:payout_limit" json:"payout_limit"`
+	PayoutAmount    int64      `gorm:"column:payout_amount" json:"payout_amount"`
+	PayoutReference string     `gorm:"column:payout_reference" json:"payout_reference"`
+	ReviewerID      string     `gorm:"column:reviewer_id" json:"reviewer_id"`
+	EvidenceCount   int        `gorm:"column:evidence_count" json:"-"`
+	CreatedAt       time.Time  `gorm:"column:created_at" json:"created_at"`
+	UpdatedAt       time.Time  `gorm:"column:updated_at" json:"updated_at"`
+	ResolvedAt      *time.Time `gorm:"column:resolved_at" json:"resolved_at"`
+	PaidAt          *time.Time `gorm:"column:paid_at" json:"paid_at"`
+}
+
+// Pickup mirrors a courier pickup of one or more shipments (pickup.updated)
+type Pickup struct {
+	ID            string    `gorm:"primaryKey;column:id" json:"id"`
+	UserID        string    `gorm:"index;column:user_id" json:"user_id"`
+	LogisticName  string    `gorm:"column:logistic_name" json:"logistic_name"`
+	AreaCode      string    `gorm:"index;column:area_code" json:"area_code"`
+	Date          string    `gorm:"index;column:date" json:"date"`
+	TimeSlot      string    `gorm:"column:time_slot" json:"time_slot"`
+	Status        string    `gorm:"index;column:status" json:"status"`
+	CourierID     string    `gorm:"column:courier_id" json:"courier_id"`
+	ShipmentCount int       `gorm:"column:shipment_count" json:"-"`
+	CreatedAt     time.Time `gorm:"column:created_at" json:"created_at"`
+	UpdatedAt     time.Time `gorm:"column:updated_at" json:"updated_at"`
+}
+
+// parcelPayload is a parcel of a multi-parcel shipment in shipment.created and shipment.updated
+type parcelPayload struct {
+	PieceNumber    int       `json:"piece_number"`
+	TrackingNumber string    `json:"tracking_number"`
+	Weight         float64   `json:"weight"`
+	Length         float64   `json:"length"`
+	Width          float64   `json:"width"`
+	Height         float64   `json:"height"`
+	Status         string    `json:"status"`
+	UpdatedAt      time.Time `json:"updated_at"`
+}
+
+// eventPayload is a tracking event of the shipment in shipment.updated
+type eventPayload struct {
+	Status    string    `json:"status"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// milestoneTimes returns when a shipment was first picked up and last delivered, nil when it
+// wasn't. Transit times in the reports are measured between the two.
+func milestoneTimes(events []eventPayload) (pickedUpAt, deliveredAt *time.Time) {
+	for i := range events {
+		switch events[i].Status {
+		case "picked_up":
+			if pickedUpAt == nil {
+				pickedUpAt = &events[i].Timestamp
+			}
+		case "delivered":
+			deliveredAt = &events[i].Timestamp
+		}
+	}
+	return pickedUpAt, deliveredAt
+}
+
+// shipmentParcels converts the parcels of a shipment payload to rows
+func shipmentParcels(shipmentID string, parcels []parcelPayload) []ShipmentParcel {
+	var rows []ShipmentParcel
+	for _, p := range parcels {
+		rows = append(rows, ShipmentParcel{
+			ShipmentID:     shipmentID,
+			PieceNumber:    p.PieceNumber,
+			TrackingNumber: p.TrackingNumber,
+			Weight:         p.Weight,
+			Length:         p.Length,
+			Width:          p.Width,
+			Height:         p.Height,
+			Status:         p.Status,
+			UpdatedAt:      p.UpdatedAt,
+		})
+	}
+	return rows
 }
 
 func main() {
@@ -63,12 +220,32 @@ func main() {
 		panic(fmt.Sprintf("worker: Failed to connect to Postgres: %v", err))
 	}
 
+	// Report views whose definition changed are recreated below, drop them before they block
+	// the migration of a column they read
+	if err := dropReportViews(db, false); err != nil {
+		panic(fmt.Sprintf("worker: Failed to drop changed report views: %v", err))
+	}
+
 	// Auto migrate schema
-	if err := db.AutoMigrate(&User{}, &Shipment{}, &ShipmentItem{}); err != nil {
-		panic(fmt.Sprintf("worker: Failed to migrate schema: %v", err))
+	models := []interface{}{&User{}, &Shipment{}, &ShipmentItem{}, &ShipmentParcel{}, &CODLedgerEntry{}, &CODRemittance{}, &Pickup{}, &Claim{}, &ReportRefresh{}}
+	if err := db.AutoMigrate(models...); err != nil {
+		// A column read by an unchanged report view can't change either: retry without the views
+		log.Printf("[worker] Failed to migrate schema, retrying without the report views: %v", err)
+		if err := dropReportViews(db, true); err != nil {
+			panic(fmt.Sprintf("worker: Failed to drop report views: %v", err))
+		}
+		if err := db.AutoMigrate(models...); err != nil {
+			panic(fmt.Sprintf("worker: Failed to migrate schema: %v", err))
+		}
 	}
 	log.Println("[worker] Migrated Postgres schema successfully!")
 
+	// Materialized views read by report-service, refreshed in the background
+	if err := ensureReportViews(db); err != nil {
+		panic(fmt.Sprintf("worker: Failed to create report views: %v", err))
+	}
+	go refreshReportViews(db, reportRefreshInterval())
+
 	rabbitURL := os.Getenv("RABBITMQ_URL")
 
 	var conn *amqp.Connection
@@ -93,7 +270,7 @@ func main() {
 	defer ch.Close()
 
 	// Declare queues
-	queues := []string{"user.registered", "shipment.created", "shipment.updated"}
+	queues := []string{"user.registered", "shipment.created", "shipment.updated", "shipment.cancelled", "cod.collected", "cod.remitted", "pickup.updated", "shipment.sla_breached", "claim.updated"}
 	for _, q := range queues {
 		_, err = ch.QueueDeclare(
 			q,
@@ -156,6 +333,8 @@ func main() {
 				Status         string `json:"status"`
 				Origin         string `json:"origin"`
 				Destination    string `json:"destination"`
+				OriginCode      string `json:"origin_code"`
+				DestinationCode string `json:"destination_code"`
 				Sender         struct {
 					Name    string `json:"name"`
 					Phone   string `json:"phone"`
@@ -167,12 +346,31 @@ func main() {
 					Address string `json:"address"`
 				} `json:"recipient"`
 				Items []struct {
-					Name     string  `json:"name"`
-					Quantity int     `json:"quantity"`
-					Weight   float64 `json:"weight"`
+					Name          string  `json:"name"`
+					Qty           int     `json:"qty"`
+					Weight        float64 `json:"weight"`
+					DeclaredValue int64   `json:"declared_value"`
 				} `json:"items"`
 				Notes  string `json:"notes"`
 				UserID string `json:"user_id"`
+				COD    *struct {
+					Amount   int64  `json:"amount"`
+					Currency string `json:"currency"`
+				} `json:"cod"`
+				Return *struct {
+					Type                   string `json:"type"`
+					OriginalTrackingNumber string `json:"original_tracking_number"`
+				} `json:"return"`
+				ServiceLevel  string `json:"service_level"`
+				PromisedDate  string `json:"promised_date"`
+				ETA           string `json:"eta"`
+				DeclaredValue int64  `json:"declared_value"`
+				Insurance     *struct {
+					InsuredValue int64 `json:"insured_value"`
+					Premium      int64 `json:"premium"`
+				} `json:"insurance"`
+				Parcels   []parcelPayload `json:"parcels"`
+				CreatedAt time.Time       `json:"created_at"`
 			}
 
 			if err := json.Unmarshal(msg.Body, &payload); err != nil {
@@ -187,6 +385,8 @@ func main() {
 				Status:           payload.Status,
 				Origin:           payload.Origin,
 				Destination:      payload.Destination,
+				OriginCode:       payload.OriginCode,
+				DestinationCode:  payload.DestinationCode,
 				SenderName:       payload.Sender.Name,
 				SenderPhone:      payload.Sender.Phone,
 				SenderAddress:    payload.Sender.Address,
@@ -195,19 +395,41 @@ func main() {
 				RecipientAddress: payload.Recipient.Address,
 				Notes:            payload.Notes,
 				UserID:           payload.UserID,
+				ServiceLevel:     payload.ServiceLevel,
+				PromisedDate:     payload.PromisedDate,
+				ETA:              payload.ETA,
+				DeclaredValue:    payload.DeclaredValue,
 				CreatedAt:        time.Now(),
 				UpdatedAt:        time.Now(),
 			}
+			if !payload.CreatedAt.IsZero() {
+				shipment.CreatedAt = payload.CreatedAt // reports count shipments by the day they were created
+			}
+			if payload.COD != nil {
+				shipment.CODAmount = payload.COD.Amount
+				shipment.CODCurrency = payload.COD.Currency
+			}
+			if payload.Insurance != nil {
+				shipment.InsuredValue = payload.Insurance.InsuredValue
+				shipment.InsurancePremium = payload.Insurance.Premium
+			}
+			if payload.Return != nil {
+				shipment.ReturnOf = payload.Return.OriginalTrackingNumber
+				shipment.ReturnType = payload.Return.Type
+			}
 
 			for _, itm := range payload.Items {
 				shipment.Items = append(shipment.Items, ShipmentItem{
-					Name:       itm.Name,
-					Quantity:   itm.Quantity,
-					Weight:     itm.Weight,
-					ShipmentID: shipment.ID,
+					Name:          itm.Name,
+					Quantity:      itm.Qty,
+					Weight:        itm.Weight,
+					DeclaredValue: itm.DeclaredValue,
+					ShipmentID:    shipment.ID,
 				})
 			}
 
+			shipment.Parcels = shipmentParcels(shipment.ID, payload.Parcels)
+
 			if err := db.Session(&gorm.Session{FullSaveAssociations: true}).Create(&shipment).Error; err != nil {
 				log.Println("Failed to insert shipment to Postgres:", err)
 			} else {
@@ -226,21 +448,38 @@ func main() {
 		for msg := range msgs {
 			log.Println("Received message on shipment.updated")
 
+			// logistic-service publishes the full shipment document with nested sender/recipient/items
 			var payload struct {
-				ID               string         `json:"id"`
-				TrackingNumber   string         `json:"tracking_number"`
-				Status           string         `json:"status"`
-				Origin           string         `json:"origin"`
-				Destination      string         `json:"destination"`
-				Notes            string         `json:"notes"`
-				UserID           string         `json:"user_id"`
-				SenderName       string         `json:"sender_name"`
-				SenderPhone      string         `json:"sender_phone"`
-				SenderAddress    string         `json:"sender_address"`
-				RecipientName    string         `json:"recipient_name"`
-				RecipientPhone   string         `json:"recipient_phone"`
-				RecipientAddress string         `json:"recipient_address"`
-				UpdatedAt        time.Time      `json:"updated_at"`
+				ID             string    `json:"id"`
+				TrackingNumber string    `json:"tracking_number"`
+				Status         string    `json:"status"`
+				Origin         string    `json:"origin"`
+				Destination    string    `json:"destination"`
+				OriginCode      string    `json:"origin_code"`
+				DestinationCode string    `json:"destination_code"`
+				Notes          string    `json:"notes"`
+				UserID         string    `json:"user_id"`
+				DriverID       string    `json:"driver_id"`
+				ETA            string    `json:"eta"`
+				Sender         struct {
+					Name    string `json:"name"`
+					Phone   string `json:"phone"`
+					Address string `json:"address"`
+				} `json:"sender"`
+				Recipient struct {
+					Name    string `json:"name"`
+					Phone   string `json:"phone"`
+					Address string `json:"address"`
+				} `json:"recipient"`
+				Items []struct {
+					Name          string  `json:"name"`
+					Qty           int     `json:"qty"`
+					Weight        float64 `json:"weight"`
+					DeclaredValue int64   `json:"declared_value"`
+				} `json:"items"`
+				Parcels   []parcelPayload `json:"parcels"`
+				Events    []eventPayload  `json:"events"`
+				UpdatedAt time.Time       `json:"updated_at"`
 			}
 
 			if err := json.Unmarshal(msg.Body, &payload); err != nil {
@@ -263,16 +502,73 @@ func main() {
 			shipment.Notes = payload.Notes
 			shipment.Origin = payload.Origin
 			shipment.Destination = payload.Destination
+			shipment.OriginCode = payload.OriginCode
+			shipment.DestinationCode = payload.DestinationCode
 			shipment.UserID = payload.UserID
-			shipment.SenderName = payload.SenderName
-			shipment.SenderPhone = payload.SenderPhone
-			shipment.SenderAddress = payload.SenderAddress
-			shipment.RecipientName = payload.RecipientName
-			shipment.RecipientPhone = payload.RecipientPhone
-			shipment.RecipientAddress = payload.RecipientAddress
+			shipment.DriverID = payload.DriverID
+			shipment.ETA = payload.ETA
+			shipment.SenderName = payload.Sender.Name
+			shipment.SenderPhone = payload.Sender.Phone
+			shipment.SenderAddress = payload.Sender.Address
+			shipment.RecipientName = payload.Recipient.Name
+			shipment.RecipientPhone = payload.Recipient.Phone
+			shipment.RecipientAddress = payload.Recipient.Address
 			shipment.UpdatedAt = time.Now()
 
-			if err := db.Save(&shipment).Error; err != nil {
+			var items []ShipmentItem
+			for _, itm := range payload.Items {
+				items = append(items, ShipmentItem{
+					Name:          itm.Name,
+					Quantity:      itm.Qty,
+					Weight:        itm.Weight,
+					DeclaredValue: itm.DeclaredValue,
+					ShipmentID:    shipment.ID,
+				})
+			}
+
+			// Replace the item and parcel lists together with the shipment row
+			err := db.Transaction(func(tx *gorm.DB) error {
+				// Only touch the columns carried by shipment.updated, so fields mirrored from
+				// other events (e.g. cancellation) are not overwritten by a concurrent save
+				err := tx.Model(&shipment).Select(
+					"status", "notes", "origin", "destination", "origin_code", "destination_code", "user_id", "driver_id", "eta",
+					"sender_name", "sender_phone", "sender_address",
+					"recipient_name", "recipient_phone", "recipient_address", "updated_at",
+				).Updates(&shipment).Error
+				if err != nil {
+					return err
+				}
+				// A payload without milestone events keeps the stored times, and the first
+				// pickup is never replaced by a later one
+				if pickedUpAt, deliveredAt := milestoneTimes(payload.Events); pickedUpAt != nil || deliveredAt != nil {
+					err := tx.Model(&shipment).UpdateColumns(map[string]interface{}{
+						"picked_up_at": gorm.Expr("COALESCE(picked_up_at, ?)", pickedUpAt),
+						"delivered_at": gorm.Expr("COALESCE(?, delivered_at)", deliveredAt),
+					}).Error
+					if err != nil {
+						return err
+					}
+				}
+				if len(payload.Parcels) > 0 {
+					if err := tx.Where("shipment_id = ?", shipment.ID).Delete(&ShipmentParcel{}).Error; err != nil {
+						return err
+					}
+					if err := tx.Create(shipmentParcels(shipment.ID, payload.Parcels)).Error; err != nil {
+						return err
+					}
+				}
+				if payload.Items == nil {
+					return nil
+				}
+				if err := tx.Where("shipment_id = ?", shipment.ID).Delete(&ShipmentItem{}).Error; err != nil {
+					return err
+				}
+				if len(items) == 0 {
+					return nil
+				}
+				return tx.Create(&items).Error
+			})
+			if err != nil {
 				log.Printf("Failed to update shipment in Postgres: %v", err)
 			} else {
 				log.Printf("Successfully updated shipment in Postgres: %s", shipment.TrackingNumber)
@@ -280,6 +576,213 @@ func main() {
 		}
 	}()
 
+	// Consume shipment.cancelled asynchronously
+	go func() {
+		msgs, err := ch.Consume("shipment.cancelled", "", true, false, false, false, nil)
+		if err != nil {
+			log.Printf("Error consuming shipment.cancelled: %v", err)
+			return
+		}
+		for msg := range msgs {
+			log.Println("Received message on shipment.cancelled")
+
+			var payload struct {
+				TrackingNumber string    `json:"tracking_number"`
+				ReasonCode     string    `json:"reason_code"`
+				Note           string    `json:"note"`
+				CancelledBy    string    `json:"cancelled_by"`
+				CancelledAt    time.Time `json:"cancelled_at"`
+			}
+			if err := json.Unmarshal(msg.Body, &payload); err != nil {
+				log.Printf("Failed to unmarshal shipment.cancelled message: %v", err)
+				continue
+			}
+
+			result := db.Model(&Shipment{}).
+				Where("tracking_number = ?", payload.TrackingNumber).
+				Updates(map[string]interface{}{
+					"status":        "cancelled",
+					"cancel_reason": payload.ReasonCode,
+					"cancel_note":   payload.Note,
+					"cancelled_by":  payload.CancelledBy,
+					"cancelled_at":  payload.CancelledAt,
+					"updated_at":    time.Now(),
+				})
+			if result.Error != nil {
+				log.Printf("Failed to mark shipment cancelled in Postgres: %v", result.Error)
+			} else if result.RowsAffected == 0 {
+				log.Printf("Shipment not found for cancel: %s", payload.TrackingNumber)
+			} else {
+				log.Printf("Marked shipment cancelled in Postgres: %s", payload.TrackingNumber)
+			}
+		}
+	}()
+
+	// Consume shipment.sla_breached asynchronously
+	go func() {
+		msgs, err := ch.Consume("shipment.sla_breached", "", true, false, false, false, nil)
+		if err != nil {
+			log.Printf("Error consuming shipment.sla_breached: %v", err)
+			return
+		}
+		for msg := range msgs {
+			log.Println("Received message on shipment.sla_breached")
+
+			var payload struct {
+				TrackingNumber string     `json:"tracking_number"`
+				PromisedDate   string     `json:"promised_date"`
+				SLABreachedAt  *time.Time `json:"sla_breached_at"`
+			}
+			if err := json.Unmarshal(msg.Body, &payload); err != nil {
+				log.Printf("Failed to unmarshal shipment.sla_breached message: %v", err)
+				continue
+			}
+
+			result := db.Model(&Shipment{}).
+				Where("tracking_number = ?", payload.TrackingNumber).
+				Updates(map[string]interface{}{
+					"promised_date":   payload.PromisedDate,
+					"sla_breached_at": payload.SLABreachedAt,
+				})
+			if result.Error != nil {
+				log.Printf("Failed to mark SLA breach in Postgres: %v", result.Error)
+			} else if result.RowsAffected == 0 {
+				log.Printf("Shipment not found for SLA breach: %s", payload.TrackingNumber)
+			} else {
+				log.Printf("Marked SLA breach in Postgres: %s", payload.TrackingNumber)
+			}
+		}
+	}()
+
+	// Consume cod.collected asynchronously
+	go func() {
+		msgs, err := ch.Consume("cod.collected", "", true, false, false, false, nil)
+		if err != nil {
+			log.Printf("Error consuming cod.collected: %v", err)
+			return
+		}
+		for msg := range msgs {
+			log.Println("Received message on cod.collected")
+
+			var entry CODLedgerEntry
+			if err := json.Unmarshal(msg.Body, &entry); err != nil {
+				log.Printf("Failed to unmarshal cod.collected message: %v", err)
+				continue
+			}
+			if entry.RemittanceID != nil && *entry.RemittanceID == "" {
+				entry.RemittanceID = nil
+			}
+
+			// Entries are immutable once collected; a redelivered message is a no-op
+			if err := db.Clauses(clause.OnConflict{DoNothing: true}).Create(&entry).Error; err != nil {
+				log.Printf("Failed to insert COD ledger entry to Postgres: %v", err)
+			} else {
+				log.Printf("Inserted COD ledger entry to Postgres: %s", entry.TrackingNumber)
+			}
+		}
+	}()
+
+	// Consume cod.remitted asynchronously
+	go func() {
+		msgs, err := ch.Consume("cod.remitted", "", true, false, false, false, nil)
+		if err != nil {
+			log.Printf("Error consuming cod.remitted: %v", err)
+			return
+		}
+		for msg := range msgs {
+			log.Println("Received message on cod.remitted")
+
+			var payload struct {
+				Remittance CODRemittance `json:"remittance"`
+				EntryIDs   []string      `json:"entry_ids"`
+			}
+			if err := json.Unmarshal(msg.Body, &payload); err != nil {
+				log.Printf("Failed to unmarshal cod.remitted message: %v", err)
+				continue
+			}
+
+			remittance := payload.Remittance
+			err := db.Transaction(func(tx *gorm.DB) error {
+				if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&remittance).Error; err != nil {
+					return err
+				}
+				if len(payload.EntryIDs) == 0 {
+					return nil
+				}
+				return tx.Model(&CODLedgerEntry{}).
+					Where("id IN ?", payload.EntryIDs).
+					Updates(map[string]interface{}{
+						"status":        "settled",
+						"remittance_id": remittance.ID,
+						"settled_at":    remittance.SettledAt,
+					}).Error
+			})
+			if err != nil {
+				log.Printf("Failed to save COD remittance in Postgres: %v", err)
+			} else {
+				log.Printf("Saved COD remittance in Postgres: %s (%d entries)", remittance.ID, len(payload.EntryIDs))
+			}
+		}
+	}()
+
+	// Consume pickup.updated asynchronously
+	go func() {
+		msgs, err := ch.Consume("pickup.updated", "", true, false, false, false, nil)
+		if err != nil {
+			log.Printf("Error consuming pickup.updated: %v", err)
+			return
+		}
+		for msg := range msgs {
+			log.Println("Received message on pickup.updated")
+
+			var payload struct {
+				Pickup
+				TrackingNumbers []string `json:"tracking_numbers"`
+			}
+			if err := json.Unmarshal(msg.Body, &payload); err != nil {
+				log.Printf("Failed to unmarshal pickup.updated message: %v", err)
+				continue
+			}
+
+			pickup := payload.Pickup
+			pickup.ShipmentCount = len(payload.TrackingNumbers)
+			if err := db.Clauses(clause.OnConflict{UpdateAll: true}).Create(&pickup).Error; err != nil {
+				log.Printf("Failed to upsert pickup to Postgres: %v", err)
+			} else {
+				log.Printf("Upserted pickup to Postgres: %s (%s)", pickup.ID, pickup.Status)
+			}
+		}
+	}()
+
+	// Consume claim.updated asynchronously
+	go func() {
+		msgs, err := ch.Consume("claim.updated", "", true, false, false, false, nil)
+		if err != nil {
+			log.Printf("Error consuming claim.updated: %v", err)
+			return
+		}
+		for msg := range msgs {
+			log.Println("Received message on claim.updated")
+
+			var payload struct {
+				Claim
+				Evidence []json.RawMessage `json:"evidence"`
+			}
+			if err := json.Unmarshal(msg.Body, &payload); err != nil {
+				log.Printf("Failed to unmarshal claim.updated message: %v", err)
+				continue
+			}
+
+			claim := payload.Claim
+			claim.EvidenceCount = len(payload.Evidence)
+			if err := db.Clauses(clause.OnConflict{UpdateAll: true}).Create(&claim).Error; err != nil {
+				log.Printf("Failed to upsert claim to Postgres: %v", err)
+			} else {
+				log.Printf("Upserted claim to Postgres: %s (%s)", claim.ID, claim.Status)
+			}
+		}
+	}()
+
 	// Prevent main from exiting so all goroutines keep running
 	select {}
 }
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"log"
+	"os"
+	"strconv"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ReportRefresh records the last refresh of a materialized view, so reports can tell how
+// fresh their numbers are
+type ReportRefresh struct {
+	View        string    `gorm:"primaryKey;column:view" json:"view"`
+	Definition  string    `gorm:"column:definition" json:"-"` // reportViewHash of the view as created
+	RefreshedAt time.Time `gorm:"column:refreshed_at" json:"refreshed_at"`
+	DurationMs  int64     `gorm:"column:duration_ms" json:"duration_ms"`
+}
+
+// reportView is a materialized view read by report-service
+type reportView struct {
+	Name    string
+	Create  string
+	Indexes []string
+}
+
+// reportViews are the materialized views behind report-service, in creation order. Each needs
+// a unique index to be refreshed concurrently, without blocking the reports reading it.
+//
+// report_shipments_daily counts shipments by the day (WIB) they were created, merchant, courier
+// company, route and current status. Return shipments are left out: a returned parcel counts
+// once, as return_to_sender or returned on its original shipment. Transit time runs from the
+// first pickup (or creation, when the pickup wasn't scanned) to the delivery.
+var reportViews = []reportView{
+	{
+		Name: "report_shipments_daily",
+		Create: `CREATE MATERIALIZED VIEW IF NOT EXISTS report_shipments_daily AS
+SELECT (created_at AT TIME ZONE 'Asia/Jakarta')::date AS day,
+       COALESCE(user_id, '') AS user_id,
+       COALESCE(logistic_name, '') AS logistic_name,
+       COALESCE(origin, '') AS origin,
+       COALESCE(destination, '') AS destination,
+       COALESCE(status, '') AS status,
+       COUNT(*) AS shipments,
+       COUNT(*) FILTER (WHERE status = 'delivered' AND delivered_at IS NOT NULL) AS transit_count,
+       COALESCE(SUM(EXTRACT(EPOCH FROM delivered_at - COALESCE(picked_up_at, created_at)))
+           FILTER (WHERE status = 'delivered' AND delivered_at IS NOT NULL), 0) AS transit_seconds
+FROM shipments
+WHERE COALESCE(return_of, '') = ''
+GROUP BY 1, 2, 3, 4, 5, 6`,
+		Indexes: []string{
+			`CREATE UNIQUE INDEX IF NOT EXISTS report_shipments_daily_key
+ON report_shipments_daily (day, user_id, logistic_name, origin, destination, status)`,
+			`CREATE INDEX IF NOT EXISTS report_shipments_daily_user_day ON report_shipments_daily (user_id, day)`,
+		},
+	},
+}
+
+// reportViewHash identifies the definition of a report view, its query and indexes
+func reportViewHash(v reportView) string {
+	h := sha256.New()
+	h.Write([]byte(v.Create))
+	for _, index := range v.Indexes {
+		h.Write([]byte{0})
+		h.Write([]byte(index))
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// dropReportViews drops the report views whose definition changed since they were created, or
+// every report view with all, so ensureReportViews recreates them. It runs before the schema
+// migration: the views pin the shipments columns they read, which can't change under them.
+func dropReportViews(db *gorm.DB, all bool) error {
+	created := map[string]string{}
+	if !all && db.Migrator().HasColumn(&ReportRefresh{}, "definition") {
+		var refreshes []ReportRefresh
+		if err := db.Find(&refreshes).Error; err != nil {
+			return err
+		}
+		for _, r := range refreshes {
+			created[r.View] = r.Definition
+		}
+	}
+	for _, v := range reportViews {
+		if !all && created[v.Name] == reportViewHash(v) {
+			continue
+		}
+		if err := db.Exec("DROP MATERIALIZED VIEW IF EXISTS " + v.Name).Error; err != nil {
+			return err
+		}
+		log.Printf("[worker] Dropped report view %s to recreate it", v.Name)
+	}
+	return nil
+}
+
+// ensureReportViews creates the report views and their indexes when they don't exist yet, and
+// records the definition they were created with, see dropReportViews
+func ensureReportViews(db *gorm.DB) error {
+	for _, v := range reportViews {
+		if err := db.Exec(v.Create).Error; err != nil {
+			return err
+		}
+		for _, index := range v.Indexes {
+			if err := db.Exec(index).Error; err != nil {
+				return err
+			}
+		}
+		// A new view is populated on creation; an existing one keeps its refresh time
+		refresh := ReportRefresh{View: v.Name, Definition: reportViewHash(v), RefreshedAt: time.Now()}
+		err := db.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "view"}},
+			DoUpdates: clause.AssignmentColumns([]string{"definition"}),
+		}).Create(&refresh).Error
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// reportRefreshInterval reads REPORT_REFRESH_MINUTES, default 15
+func reportRefreshInterval() time.Duration {
+	minutes := 15
+	if v, err := strconv.Atoi(os.Getenv("REPORT_REFRESH_MINUTES")); err == nil && v > 0 {
+		minutes = v
+	}
+	return time.Duration(minutes) * time.Minute
+}
+
+// refreshReportViews refreshes the report views right away and then every interval, forever
+func refreshReportViews(db *gorm.DB, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		for _, v := range reportViews {
+			start := time.Now()
+			if err := db.Exec("REFRESH MATERIALIZED VIEW CONCURRENTLY " + v.Name).Error; err != nil {
+				log.Printf("[worker] Failed to refresh %s: %v", v.Name, err)
+				continue
+			}
+			refresh := ReportRefresh{View: v.Name, Definition: reportViewHash(v), RefreshedAt: time.Now(), DurationMs: time.Since(start).Milliseconds()}
+			if err := db.Clauses(clause.OnConflict{UpdateAll: true}).Create(&refresh).Error; err != nil {
+				log.Printf("[worker] Failed to record refresh of %s: %v", v.Name, err)
+			}
+		}
+		<-ticker.C
+	}
+}